@@ -0,0 +1,60 @@
+// Command knowledge-import loads a knowledge CSV into the knowledge_entries table. Rows
+// replace entries with the same Kosakata; other entries are kept. Running servers pick the
+// changes up within KNOWLEDGE_RELOAD_INTERVAL_SECONDS.
+//
+// Usage:
+//
+//	go run ./cmd/knowledge-import data/knowledge.csv
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatalf("Usage: %s <knowledge.csv>", os.Args[0])
+	}
+
+	dbConnStr, err := config.LoadDatabase()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := sql.Open("postgres", dbConnStr)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := storage.RunMigrations(db, "migrations"); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalf("Failed to open CSV: %v", err)
+	}
+	defer file.Close()
+
+	entries, err := knowledge.ReadCSV(file)
+	if err != nil {
+		log.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	created, err := storage.NewPostgresDB(db).UpsertKnowledgeEntries(context.Background(), knowledge.ToModels(entries), time.Now())
+	if err != nil {
+		log.Fatalf("Failed to import knowledge entries: %v", err)
+	}
+	log.Printf("Imported %d entries from %s: %d new, %d updated", len(entries), os.Args[1], created, len(entries)-created)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/gemini-hackathon/app/internal/ai"
+	"github.com/gemini-hackathon/app/internal/audio"
+	"github.com/gemini-hackathon/app/internal/auth"
+	"github.com/gemini-hackathon/app/internal/cache"
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/events"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/jobs"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/ratelimit"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+// shutdownTimeout bounds how long the server waits for requests and OCR jobs in flight when
+// it is asked to stop. Jobs still running after it are handed out again once their lease
+// runs out.
+const shutdownTimeout = 30 * time.Second
+
+// annotationCachePurgeInterval is how often expired annotations are deleted from Postgres.
+const annotationCachePurgeInterval = time.Hour
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.DBConnectionString)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	if err := storage.RunMigrations(db, "migrations"); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	storageDB := storage.NewPostgresDB(db)
+
+	fileStorage, err := storage.NewLocalFileStorage(cfg.UploadDir)
+	if err != nil {
+		log.Fatalf("Failed to create file storage: %v", err)
+	}
+
+	redisClient, err := storage.NewRedisClient(cfg.RedisAddr)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to Redis: %v. OAuth state will not work.", err)
+	}
+
+	geminiClient, err := ai.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create AI client: %v", err)
+	}
+	log.Printf("Using AI provider: %s", cfg.AIProvider)
+
+	// Vocabulary lookup reads the knowledge base from Postgres; an empty table is seeded from
+	// the CSV once
+	knowledgeSvc, err := knowledge.NewDBService(context.Background(), storageDB)
+	if err != nil {
+		log.Fatalf("Failed to load knowledge base: %v", err)
+	}
+	if knowledgeSvc.Len() == 0 && cfg.KnowledgeCSVPath != "" {
+		if err := seedKnowledge(storageDB, knowledgeSvc, cfg.KnowledgeCSVPath); err != nil {
+			log.Printf("Warning: Failed to seed knowledge base from %s: %v. Continuing without knowledge context.", cfg.KnowledgeCSVPath, err)
+		} else {
+			log.Printf("Seeded knowledge base from %s", cfg.KnowledgeCSVPath)
+		}
+	}
+	log.Printf("Loaded knowledge base: %d entries", knowledgeSvc.Len())
+
+	// Scan progress events for SSE clients; Redis fans them out across replicas
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Reload the knowledge base after admin edits here and edits made through other replicas
+	go knowledgeSvc.Watch(workerCtx, time.Duration(cfg.KnowledgeReloadIntervalSeconds)*time.Second)
+
+	eventHub := events.NewHub()
+	var scanEvents events.Broker = eventHub
+	if cfg.ScanEventsRedisFanout && redisClient != nil {
+		redisBroker := events.NewRedisBroker(eventHub, redisClient)
+		go redisBroker.Run(workerCtx)
+		scanEvents = redisBroker
+	}
+
+	// Annotations are cached in Redis and Postgres; a TTL of 0 disables the cache
+	var annotationCache *cache.AnnotationCache
+	if cfg.AnnotationCacheTTLHours > 0 {
+		annotationCache = cache.NewAnnotationCache(redisClient, storageDB, time.Duration(cfg.AnnotationCacheTTLHours)*time.Hour)
+		go annotationCache.PurgeExpired(workerCtx, annotationCachePurgeInterval)
+	}
+
+	// Synthesized speech is stored once per text, context, voice and tone
+	audioService := audio.NewService(storageDB, fileStorage, geminiClient, cfg.AIProvider)
+
+	tokenService := auth.NewTokenService(cfg.JWTSecret, cfg.TokenExpiryMinutes)
+
+	googleOAuth := auth.NewGoogleOAuthService(cfg, redisClient)
+
+	authHandlers := handlers.NewAuthHandlers(googleOAuth, tokenService, storageDB, cfg)
+	voices, _ := gemini.CatalogFor(geminiClient)
+	userHandlers := handlers.NewUserHandlers(storageDB, voices, cfg)
+	scanHandlers := handlers.NewScanHandlers(storageDB, fileStorage, geminiClient, knowledgeSvc, scanEvents, audioService, cfg)
+	aiHandlers := handlers.NewAIHandlers(storageDB, geminiClient, knowledgeSvc, annotationCache, audioService)
+	annotationHandlers := handlers.NewAnnotationHandlers(storageDB, geminiClient, knowledgeSvc, audioService, cfg)
+	audioHandlers := handlers.NewAudioHandlers(audioService)
+	documentHandlers := handlers.NewDocumentHandlers(storageDB, cfg)
+	reviewHandlers := handlers.NewReviewHandlers(storageDB, cfg)
+	searchHandlers := handlers.NewSearchHandlers(storageDB, cfg)
+	knowledgeHandlers := handlers.NewKnowledgeHandlers(storageDB, knowledgeSvc, cfg)
+
+	// OCR runs on a durable job queue so uploads survive restarts and Gemini failures
+	ocrWorkers := jobs.NewOCRWorkerPool(storageDB, scanHandlers.ProcessOCRJob, cfg)
+	workersDone := make(chan struct{})
+	go func() {
+		ocrWorkers.Run(workerCtx)
+		close(workersDone)
+	}()
+
+	authMiddleware := middleware.NewAuthMiddleware(tokenService)
+
+	// Per-user AI limits share buckets through Redis and fall back to per-replica memory
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if redisClient != nil {
+		limiter = ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(redisClient), limiter)
+	}
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(limiter, storageDB, cfg)
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/v1/auth/google/state", authHandlers.GoogleStateAPI)
+	mux.HandleFunc("/v1/auth/google/callback", authHandlers.GoogleCallback)
+
+	authMux := http.NewServeMux()
+	authMux.HandleFunc("/v1/users/me/languages", userHandlers.GetLanguagesAPI)
+	authMux.HandleFunc("/v1/users/me", userHandlers.UsersMeAPI)
+	authMux.HandleFunc("/v1/users/me/usage", userHandlers.GetUsageAPI)
+	authMux.HandleFunc("/v1/scans", scanHandlers.ScansAPI)
+	authMux.HandleFunc("/v1/scans/batch", scanHandlers.CreateScanBatchAPI)
+	authMux.HandleFunc("/v1/scans/", scanHandlers.GetScanAPI)
+	authMux.HandleFunc("/v1/documents", documentHandlers.DocumentsAPI)
+	authMux.HandleFunc("/v1/documents/", documentHandlers.DocumentByIDAPI)
+	authMux.HandleFunc("/v1/ai/analyze", aiHandlers.AnalyzeAPI)
+	authMux.HandleFunc("/v1/ai/analyze/stream", aiHandlers.AnalyzeStreamAPI)
+	authMux.HandleFunc("/v1/ai/speech", aiHandlers.SpeakAPI)
+	authMux.HandleFunc("/v1/ai/voices", aiHandlers.VoicesAPI)
+	authMux.HandleFunc("/v1/annotations", annotationHandlers.AnnotationsAPI)
+	authMux.HandleFunc("/v1/annotations/", annotationHandlers.AnnotationByIDAPI)
+	authMux.HandleFunc("/v1/audio/", audioHandlers.AudioAPI)
+	authMux.HandleFunc("/v1/reviews/", reviewHandlers.ReviewsAPI)
+	authMux.HandleFunc("/v1/search", searchHandlers.SearchAPI)
+	authMux.HandleFunc("/v1/admin/knowledge", knowledgeHandlers.KnowledgeAPI)
+	authMux.HandleFunc("/v1/admin/knowledge/", knowledgeHandlers.KnowledgeByIDAPI)
+
+	mux.Handle("/v1/", authMiddleware.Handle(rateLimitMiddleware.Handle(authMux)))
+	// EventSource cannot send headers, so only the scan event stream takes ?token=
+	mux.Handle("/v1/scans/{id}/events", authMiddleware.HandleEventStream(rateLimitMiddleware.Handle(authMux)))
+	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir(cfg.UploadDir))))
+
+	reactFS := http.FileServer(http.Dir("web/dist"))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/healthz") {
+			http.NotFound(w, r)
+			return
+		}
+
+		if _, err := os.Stat("web/dist/index.html"); err == nil {
+			if r.URL.Path != "/" && !strings.HasPrefix(r.URL.Path, "/v1/") && r.URL.Path != "/healthz" {
+				r.URL.Path = "/"
+			}
+			reactFS.ServeHTTP(w, r)
+		} else {
+			http.Error(w, "Frontend not built. Run: cd web && bun run build", http.StatusServiceUnavailable)
+		}
+	})
+
+	// Runtime counters, including annotation cache hits and misses, stay off the public
+	// listener
+	if cfg.DebugAddr != "" {
+		debugMux := http.NewServeMux()
+		debugMux.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			log.Printf("Debug server listening on %s", cfg.DebugAddr)
+			if err := http.ListenAndServe(cfg.DebugAddr, debugMux); err != nil {
+				log.Printf("Warning: Debug server stopped: %v", err)
+			}
+		}()
+	}
+
+	handler := middleware.LoggingMiddleware(middleware.CORSMiddleware(mux))
+
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: handler}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on :%s", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		log.Fatalf("Server failed: %v", err)
+	case <-ctx.Done():
+	}
+
+	// Stop taking requests, let those in flight finish, then drain the OCR workers
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: Failed to finish requests in flight: %v", err)
+	}
+	stopWorkers()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		log.Printf("Warning: OCR workers did not stop in time")
+	}
+	log.Printf("Server stopped")
+}
+
+// seedKnowledge imports the knowledge CSV into the empty knowledge base and reloads it.
+func seedKnowledge(db storage.DB, svc *knowledge.DBService, csvPath string) error {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := knowledge.ReadCSV(file)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if _, err := db.UpsertKnowledgeEntries(ctx, knowledge.ToModels(entries), time.Now()); err != nil {
+		return err
+	}
+	return svc.Reload(ctx)
+}
@@ -0,0 +1,53 @@
+// Package ai selects the AI provider behind gemini.Client from configuration.
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/ai/fake"
+	"github.com/gemini-hackathon/app/internal/ai/openai"
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+)
+
+// Factory builds a client for one provider.
+type Factory func(cfg *config.Config) (gemini.Client, error)
+
+var registry = map[string]Factory{
+	config.AIProviderGemini: func(cfg *config.Config) (gemini.Client, error) {
+		return gemini.NewClient(cfg.GeminiAPIKey), nil
+	},
+	config.AIProviderFake: func(cfg *config.Config) (gemini.Client, error) {
+		return fake.NewClient(), nil
+	},
+	config.AIProviderOpenAICompatible: func(cfg *config.Config) (gemini.Client, error) {
+		return openai.NewClient(openai.Config{
+			BaseURL:     cfg.OpenAIBaseURL,
+			APIKey:      cfg.OpenAIAPIKey,
+			Model:       cfg.OpenAIModel,
+			SpeechModel: cfg.OpenAISpeechModel,
+			Voice:       cfg.OpenAIVoice,
+		}), nil
+	},
+}
+
+// NewClient returns the client for cfg.AIProvider.
+func NewClient(cfg *config.Config) (gemini.Client, error) {
+	factory, ok := registry[cfg.AIProvider]
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider %q (available: %s)", cfg.AIProvider, strings.Join(Providers(), ", "))
+	}
+	return factory(cfg)
+}
+
+// Providers lists the registered provider names.
+func Providers() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/config"
+)
+
+func TestNewClientFakeNeedsNoKey(t *testing.T) {
+	client, err := NewClient(&config.Config{AIProvider: config.AIProviderFake})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	result, err := client.OCR(context.Background(), nil, "image/jpeg")
+	if err != nil {
+		t.Fatalf("OCR failed: %v", err)
+	}
+	if result.RawText == "" {
+		t.Fatal("expected fixture OCR text")
+	}
+}
+
+func TestNewClientUnknownProvider(t *testing.T) {
+	_, err := NewClient(&config.Config{AIProvider: "mystery"})
+	if err == nil || !strings.Contains(err.Error(), config.AIProviderFake) {
+		t.Fatalf("expected error listing available providers, got %v", err)
+	}
+}
+
+func TestConfigValidateOnlyRequiresGeminiKeyForGemini(t *testing.T) {
+	base := config.Config{
+		DBConnectionString:             "host=localhost",
+		UploadDir:                      "data/uploads",
+		MaxUploadSize:                  1,
+		FrontendBaseURL:                "http://localhost",
+		TokenExpiryMinutes:             1,
+		DefaultPageSize:                1,
+		MaxBatchUploadFiles:            1,
+		ScanAudioConcurrency:           1,
+		ScanAudioMaxSentences:          1,
+		KnowledgeReloadIntervalSeconds: 1,
+		OCRWorkerCount:                 1,
+		OCRJobMaxAttempts:              1,
+		OCRJobPollIntervalSeconds:      1,
+	}
+
+	fakeCfg := base
+	fakeCfg.AIProvider = config.AIProviderFake
+	if err := fakeCfg.Validate(); err != nil {
+		t.Fatalf("expected fake provider to start without an API key, got %v", err)
+	}
+
+	geminiCfg := base
+	geminiCfg.AIProvider = config.AIProviderGemini
+	if err := geminiCfg.Validate(); err == nil {
+		t.Fatal("expected gemini provider to require an API key")
+	}
+
+	unknownCfg := base
+	unknownCfg.AIProvider = "mystery"
+	if err := unknownCfg.Validate(); err == nil {
+		t.Fatal("expected unknown provider to be rejected")
+	}
+}
@@ -0,0 +1,157 @@
+// Package fake is an offline AI provider that answers from embedded fixtures. It needs
+// no API key or network access and always returns the same output for the same input,
+// which makes it suitable for local development and CI.
+package fake
+
+import (
+	"context"
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const (
+	speechSampleRate   = 24000
+	speechToneHz       = 440
+	speechMsPerRune    = 120
+	speechMinMs        = 300
+	speechMaxMs        = 8000
+	speechAmplitude    = 0.2
+	annotationTextSlot = "{{text}}"
+)
+
+var (
+	//go:embed fixtures/ocr.json
+	ocrFixture string
+
+	//go:embed fixtures/annotation.json
+	annotationFixture string
+
+	//go:embed fixtures/readings.json
+	readingsFixture []byte
+)
+
+type client struct {
+	readings   map[string]string
+	maxTermLen int
+}
+
+// NewClient returns the fixture-backed client.
+func NewClient() gemini.Client {
+	c := &client{readings: make(map[string]string)}
+	if err := json.Unmarshal(readingsFixture, &c.readings); err != nil {
+		panic("fake: invalid readings fixture: " + err.Error())
+	}
+	for term := range c.readings {
+		c.maxTermLen = max(c.maxTermLen, utf8.RuneCountInString(term))
+	}
+	return c
+}
+
+// OCR ignores the image and returns the OCR fixture.
+func (c *client) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return gemini.ParseOCRResult(ocrFixture), nil
+}
+
+func (c *client) Annotate(ctx context.Context, ocrText string, selectedText string) (*gemini.AnnotationResponse, error) {
+	return c.AnnotateWithKnowledge(ctx, ocrText, selectedText, nil, models.DefaultLanguage)
+}
+
+// AnnotateWithKnowledge fills the selected text into the annotation fixture. When a
+// knowledge entry matches the selection exactly, its meaning is used instead. The fixture
+// is the same in every target language.
+func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*gemini.AnnotationResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Escape through JSON so quotes in the selection can't break the fixture.
+	quoted, _ := json.Marshal(selectedText)
+	filled := strings.ReplaceAll(annotationFixture, annotationTextSlot, string(quoted[1:len(quoted)-1]))
+
+	var annotation gemini.AnnotationResponse
+	if err := json.Unmarshal([]byte(filled), &annotation); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Kosakata == selectedText && entry.Arti != "" {
+			annotation.Meaning = "[fake] " + entry.Arti
+			break
+		}
+	}
+	return &annotation, nil
+}
+
+// SynthesizeSpeech returns a sine tone whose length grows with the text and shrinks with the
+// speaking rate, as 16-bit mono WAV. Voice and tone are ignored.
+func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	durationMs := utf8.RuneCountInString(highlightedText) * speechMsPerRune
+	if opts.SpeakingRate > 0 {
+		durationMs = int(float64(durationMs) / opts.SpeakingRate)
+	}
+	durationMs = min(max(durationMs, speechMinMs), speechMaxMs)
+	samples := speechSampleRate * durationMs / 1000
+
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		v := speechAmplitude * math.Sin(2*math.Pi*speechToneHz*float64(i)/speechSampleRate)
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(v*math.MaxInt16)))
+	}
+
+	return &gemini.SpeechResponse{
+		Audio:    gemini.WrapPCMAsWAV(pcm, speechSampleRate, 1, 16),
+		MIMEType: "audio/wav",
+	}, nil
+}
+
+// Furigana segments the text by greedy longest match against the readings fixture.
+// Everything between dictionary terms is returned as plain tokens without a reading.
+func (c *client) Furigana(ctx context.Context, text string) (*gemini.FuriganaResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	runes := []rune(text)
+	var tokens []gemini.FuriganaToken
+	plainStart := 0
+	for i := 0; i < len(runes); {
+		matched := 0
+		for n := min(c.maxTermLen, len(runes)-i); n > 0; n-- {
+			if _, ok := c.readings[string(runes[i:i+n])]; ok {
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+
+		if plainStart < i {
+			tokens = append(tokens, gemini.FuriganaToken{Surface: string(runes[plainStart:i])})
+		}
+		term := string(runes[i : i+matched])
+		tokens = append(tokens, gemini.FuriganaToken{Surface: term, Reading: c.readings[term]})
+		i += matched
+		plainStart = i
+	}
+	if plainStart < len(runes) {
+		tokens = append(tokens, gemini.FuriganaToken{Surface: string(runes[plainStart:])})
+	}
+
+	return &gemini.FuriganaResponse{Tokens: tokens}, nil
+}
@@ -0,0 +1,100 @@
+package fake
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+)
+
+func TestOCRReturnsFixtureLayout(t *testing.T) {
+	result, err := NewClient().OCR(context.Background(), []byte("anything"), "image/png")
+	if err != nil {
+		t.Fatalf("OCR failed: %v", err)
+	}
+	if !strings.HasPrefix(result.RawText, "業務連絡") || result.Language != "JP" {
+		t.Fatalf("unexpected fixture text: %q %q", result.RawText, result.Language)
+	}
+	if len(result.Blocks) != 2 || len(result.Blocks[1].Lines) != 2 {
+		t.Fatalf("expected fixture blocks and lines, got %+v", result.Blocks)
+	}
+}
+
+func TestAnnotateWithKnowledge(t *testing.T) {
+	client := NewClient()
+
+	result, err := client.AnnotateWithKnowledge(context.Background(), "", `say "hi"`, nil, "EN")
+	if err != nil {
+		t.Fatalf("AnnotateWithKnowledge failed: %v", err)
+	}
+	if !strings.Contains(result.Meaning, `say "hi"`) {
+		t.Errorf("expected selection in meaning, got %q", result.Meaning)
+	}
+
+	entries := []knowledge.Entry{{Kosakata: "残業", Arti: "overtime"}}
+	result, err = client.AnnotateWithKnowledge(context.Background(), "", "残業", entries, "EN")
+	if err != nil {
+		t.Fatalf("AnnotateWithKnowledge failed: %v", err)
+	}
+	if result.Meaning != "[fake] overtime" {
+		t.Errorf("expected knowledge meaning, got %q", result.Meaning)
+	}
+}
+
+func TestSynthesizeSpeechReturnsWAV(t *testing.T) {
+	client := NewClient()
+
+	short, err := client.SynthesizeSpeech(context.Background(), "あ", "", gemini.SpeechOptions{})
+	if err != nil {
+		t.Fatalf("SynthesizeSpeech failed: %v", err)
+	}
+	long, _ := client.SynthesizeSpeech(context.Background(), "本日の会議は午後三時です", "", gemini.SpeechOptions{})
+
+	for _, resp := range []*gemini.SpeechResponse{short, long} {
+		if resp.MIMEType != "audio/wav" || string(resp.Audio[:4]) != "RIFF" || string(resp.Audio[8:12]) != "WAVE" {
+			t.Fatalf("expected WAV audio, got %s with header %q", resp.MIMEType, resp.Audio[:12])
+		}
+		if dataLen := binary.LittleEndian.Uint32(resp.Audio[40:44]); int(dataLen) != len(resp.Audio)-44 {
+			t.Fatalf("data chunk size %d doesn't match payload %d", dataLen, len(resp.Audio)-44)
+		}
+	}
+	if len(long.Audio) <= len(short.Audio) {
+		t.Errorf("expected longer text to produce longer audio (%d <= %d)", len(long.Audio), len(short.Audio))
+	}
+
+	again, _ := client.SynthesizeSpeech(context.Background(), "あ", "", gemini.SpeechOptions{})
+	if string(again.Audio) != string(short.Audio) {
+		t.Error("expected deterministic audio")
+	}
+
+	slow, _ := client.SynthesizeSpeech(context.Background(), "本日の会議は午後三時です", "", gemini.SpeechOptions{SpeakingRate: 0.5})
+	if len(slow.Audio) <= len(long.Audio) {
+		t.Errorf("expected a slower rate to produce longer audio (%d <= %d)", len(slow.Audio), len(long.Audio))
+	}
+}
+
+func TestFurigana(t *testing.T) {
+	text := "本日の会議、よろしく。"
+	result, err := NewClient().Furigana(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Furigana failed: %v", err)
+	}
+
+	want := []gemini.FuriganaToken{
+		{Surface: "本日", Reading: "ほんじつ"},
+		{Surface: "の"},
+		{Surface: "会議", Reading: "かいぎ"},
+		{Surface: "、よろしく。"},
+	}
+	if len(result.Tokens) != len(want) {
+		t.Fatalf("expected %d tokens, got %+v", len(want), result.Tokens)
+	}
+	for i := range want {
+		if result.Tokens[i] != want[i] {
+			t.Errorf("token %d: expected %+v, got %+v", i, want[i], result.Tokens[i])
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Package openai implements gemini.Client against any OpenAI-compatible HTTP API
+// (OpenAI itself, vLLM, Ollama, LM Studio, ...). It reuses the Gemini prompts so the
+// providers return the same shapes.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const (
+	requestTimeout = 2 * time.Minute
+	maxAttempts    = 3
+	maxErrorBody   = 512
+)
+
+type Config struct {
+	BaseURL     string
+	APIKey      string
+	Model       string
+	SpeechModel string
+	Voice       string
+}
+
+type client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+func NewClient(cfg Config) gemini.Client {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+type chatMessage struct {
+	Role    string        `json:"role"`
+	Content []contentPart `json:"content"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatRequest struct {
+	Model          string         `json:"model"`
+	Messages       []chatMessage  `json:"messages"`
+	ResponseFormat responseFormat `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+type speechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	Instructions   string  `json:"instructions,omitempty"`
+	Speed          float64 `json:"speed,omitempty"`
+	ResponseFormat string  `json:"response_format"`
+}
+
+// voices are the built-in voices of the OpenAI speech endpoint. Compatible servers may accept
+// others; set OPENAI_VOICE to use one as the default.
+var voices = []gemini.Voice{
+	{Name: "alloy", Description: "Neutral"},
+	{Name: "ash", Description: "Clear"},
+	{Name: "ballad", Description: "Soft"},
+	{Name: "coral", Description: "Warm"},
+	{Name: "echo", Description: "Resonant"},
+	{Name: "fable", Description: "Expressive"},
+	{Name: "nova", Description: "Bright"},
+	{Name: "onyx", Description: "Deep"},
+	{Name: "sage", Description: "Calm"},
+	{Name: "shimmer", Description: "Gentle"},
+	{Name: "verse", Description: "Versatile"},
+}
+
+// Voices includes the configured default even when it is not a built-in voice.
+func (c *client) Voices() []gemini.Voice {
+	if _, ok := gemini.LookupVoice(voices, c.cfg.Voice); ok || c.cfg.Voice == "" {
+		return voices
+	}
+	return append([]gemini.Voice{{Name: c.cfg.Voice, Description: "Default"}}, voices...)
+}
+
+func (c *client) DefaultVoice() string {
+	return c.cfg.Voice
+}
+
+func (c *client) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
+	dataURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData)
+	text, err := c.completeJSON(ctx, []contentPart{
+		{Type: "text", Text: gemini.OCRPrompt},
+		{Type: "image_url", ImageURL: &imageURL{URL: dataURL}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OCR content: %w", err)
+	}
+	return gemini.ParseOCRResult(text), nil
+}
+
+func (c *client) Annotate(ctx context.Context, ocrText string, selectedText string) (*gemini.AnnotationResponse, error) {
+	return c.AnnotateWithKnowledge(ctx, ocrText, selectedText, nil, models.DefaultLanguage)
+}
+
+func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*gemini.AnnotationResponse, error) {
+	text, err := c.completeJSON(ctx, []contentPart{
+		{Type: "text", Text: gemini.BuildAnnotationPrompt(ocrText, selectedText, entries, targetLanguage)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate annotation: %w", err)
+	}
+
+	var annotation gemini.AnnotationResponse
+	if err := gemini.DecodeJSON(text, &annotation); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation JSON: %w", err)
+	}
+	return &annotation, nil
+}
+
+// SynthesizeSpeech reads the highlighted text only; the speech endpoint has no notion of
+// context-driven delivery. The tone is passed as instructions, which models without
+// instruction support ignore.
+func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	voice := opts.Voice
+	if voice == "" {
+		voice = c.cfg.Voice
+	}
+	body, err := json.Marshal(speechRequest{
+		Model:          c.cfg.SpeechModel,
+		Input:          highlightedText,
+		Voice:          voice,
+		Instructions:   gemini.ToneInstruction(opts.Tone),
+		Speed:          opts.SpeakingRate,
+		ResponseFormat: "wav",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audio, err := c.post(ctx, "/audio/speech", body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("no audio data in response")
+	}
+
+	return &gemini.SpeechResponse{Audio: audio, MIMEType: "audio/wav"}, nil
+}
+
+func (c *client) Furigana(ctx context.Context, text string) (*gemini.FuriganaResponse, error) {
+	raw, err := c.completeJSON(ctx, []contentPart{
+		{Type: "text", Text: gemini.BuildFuriganaPrompt(text)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate furigana: %w", err)
+	}
+
+	var furigana gemini.FuriganaResponse
+	if err := gemini.DecodeJSON(raw, &furigana); err != nil {
+		return nil, fmt.Errorf("failed to parse furigana JSON: %w", err)
+	}
+	return &furigana, nil
+}
+
+// completeJSON sends one user message in JSON mode and returns the reply text.
+func (c *client) completeJSON(ctx context.Context, parts []contentPart) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:          c.cfg.Model,
+		Messages:       []chatMessage{{Role: "user", Content: parts}},
+		ResponseFormat: responseFormat{Type: "json_object"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	respBody, err := c.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return "", err
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("invalid chat completion response: %w", err)
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("empty response from API")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// post sends a JSON request, retrying rate limits and server errors with backoff.
+func (c *client) post(ctx context.Context, path string, body []byte) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(500*(1<<(attempt-1))) * time.Millisecond
+			jitter := time.Duration(rand.Intn(250)) * time.Millisecond
+			select {
+			case <-ctx.Done():
+				return nil, lastErr
+			case <-time.After(backoff + jitter):
+			}
+		}
+
+		respBody, retry, err := c.doPost(ctx, path, body)
+		if err == nil {
+			return respBody, nil
+		}
+		if !retry {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c *client) doPost(ctx context.Context, path string, body []byte) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := string(respBody)
+		if len(msg) > maxErrorBody {
+			msg = msg[:maxErrorBody]
+		}
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retry, fmt.Errorf("%s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(msg))
+	}
+	return respBody, false, nil
+}
@@ -0,0 +1,126 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(Config{
+		BaseURL:     server.URL + "/",
+		APIKey:      "test-key",
+		Model:       "test-model",
+		SpeechModel: "test-tts",
+		Voice:       "alloy",
+	}).(*client)
+}
+
+func chatReply(w http.ResponseWriter, content string) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"content": content}}},
+	})
+}
+
+func TestOCRSendsImageAndParsesLayout(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected request: %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "test-model" || req.ResponseFormat.Type != "json_object" {
+			t.Errorf("unexpected request body: %+v", req)
+		}
+		if parts := req.Messages[0].Content; len(parts) != 2 || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+			t.Errorf("expected text and inline image parts, got %+v", parts)
+		}
+		chatReply(w, `{"raw_text":"仕事","language":"JP","blocks":[{"text":"仕事","box_2d":[0,0,500,500]}]}`)
+	})
+
+	result, err := c.OCR(context.Background(), []byte{0x89, 'P', 'N', 'G'}, "image/png")
+	if err != nil {
+		t.Fatalf("OCR failed: %v", err)
+	}
+	if result.RawText != "仕事" || len(result.Blocks) != 1 || result.Blocks[0].Box.Width != 0.5 {
+		t.Fatalf("unexpected OCR result: %+v", result)
+	}
+}
+
+func TestAnnotateRetriesServerErrors(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		chatReply(w, "```json\n{\"meaning\":\"work\",\"usage_example\":\"\",\"when_to_use\":\"\",\"word_breakdown\":\"\",\"alternative_meanings\":\"\"}\n```")
+	})
+
+	result, err := c.Annotate(context.Background(), "仕事です", "仕事")
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if result.Meaning != "work" || calls != 2 {
+		t.Fatalf("expected retry then success, got meaning=%q calls=%d", result.Meaning, calls)
+	}
+}
+
+func TestAnnotateDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	})
+
+	if _, err := c.Annotate(context.Background(), "", "仕事"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestSynthesizeSpeechOptions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req speechRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Voice != "nova" || req.Speed != 0.75 || req.Instructions != gemini.ToneInstruction(gemini.ToneBusiness) {
+			t.Errorf("unexpected speech request: %+v", req)
+		}
+		w.Write([]byte("RIFF....WAVE"))
+	})
+
+	opts := gemini.SpeechOptions{Voice: "nova", SpeakingRate: 0.75, Tone: gemini.ToneBusiness}
+	if _, err := c.SynthesizeSpeech(context.Background(), "仕事", "", opts); err != nil {
+		t.Fatalf("SynthesizeSpeech failed: %v", err)
+	}
+}
+
+func TestSynthesizeSpeech(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req speechRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/audio/speech" || req.Input != "仕事" || req.Voice != "alloy" || req.ResponseFormat != "wav" {
+			t.Errorf("unexpected speech request: %s %+v", r.URL.Path, req)
+		}
+		w.Write([]byte("RIFF....WAVE"))
+	})
+
+	result, err := c.SynthesizeSpeech(context.Background(), "仕事", "context is ignored", gemini.SpeechOptions{})
+	if err != nil {
+		t.Fatalf("SynthesizeSpeech failed: %v", err)
+	}
+	if result.MIMEType != "audio/wav" || string(result.Audio) != "RIFF....WAVE" {
+		t.Fatalf("unexpected speech result: %+v", result)
+	}
+}
@@ -0,0 +1,311 @@
+// Package audio stores synthesized speech so replaying a phrase is served from disk instead
+// of the TTS model.
+package audio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+// KeyVersion is mixed into every hash. Bump it when the speech prompt changes enough that
+// stored clips should be re-synthesized.
+const KeyVersion = "1"
+
+// DefaultMIMEType is assumed when the provider does not report one.
+const DefaultMIMEType = "audio/wav"
+
+// Metrics are published under "audio_cache" on /debug/vars.
+var metrics = expvar.NewMap("audio_cache")
+
+// Request is everything that shapes a clip. Zero Voice, SpeakingRate and Tone mean the
+// provider defaults.
+type Request struct {
+	Text         string
+	Context      string
+	Voice        string
+	SpeakingRate float64
+	Tone         string
+}
+
+// Clip is an open stored clip. The caller closes Content.
+type Clip struct {
+	Asset   *models.AudioAsset
+	Content io.ReadSeekCloser
+	// Cached is false when the clip was synthesized for this call.
+	Cached bool
+}
+
+// Service synthesizes clips on first use and serves them from FileStorage afterwards.
+// Concurrent requests for the same clip share a single synthesis.
+type Service struct {
+	db           storage.DB
+	files        storage.FileStorage
+	client       gemini.Client
+	provider     string
+	defaultVoice string
+	now          func() time.Time
+
+	mu       sync.Mutex
+	inflight map[string]*call
+}
+
+type call struct {
+	done  chan struct{}
+	asset *models.AudioAsset
+	err   error
+}
+
+// NewService creates the audio service. provider names the AI provider so switching providers
+// does not replay another provider's voice.
+func NewService(db storage.DB, files storage.FileStorage, client gemini.Client, provider string) *Service {
+	_, defaultVoice := gemini.CatalogFor(client)
+	return &Service{
+		db:           db,
+		files:        files,
+		client:       client,
+		provider:     provider,
+		defaultVoice: defaultVoice,
+		now:          time.Now,
+		inflight:     make(map[string]*call),
+	}
+}
+
+// Hash returns the content address of req. Defaults are resolved first, so asking for the
+// default voice by name shares the clip with asking for no voice. Surrounding whitespace in
+// the text and context is ignored.
+func (s *Service) Hash(req Request) string {
+	voice := req.Voice
+	if voice == "" {
+		voice = s.defaultVoice
+	}
+	rate := req.SpeakingRate
+	if rate == 0 {
+		rate = gemini.DefaultSpeakingRate
+	}
+
+	parts := []string{
+		KeyVersion,
+		s.provider,
+		voice,
+		strconv.FormatFloat(rate, 'f', 2, 64),
+		req.Tone,
+		strings.TrimSpace(req.Text),
+		strings.TrimSpace(req.Context),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResolveVoice returns the provider's spelling of name, or "" when the provider does not
+// offer it, such as a preference saved before switching providers.
+func (s *Service) ResolveVoice(name string) string {
+	voices, _ := gemini.CatalogFor(s.client)
+	if voice, ok := gemini.LookupVoice(voices, name); ok {
+		return voice.Name
+	}
+	return ""
+}
+
+// Fetch returns the stored clip for req, synthesizing and storing it first when needed.
+func (s *Service) Fetch(ctx context.Context, req Request) (*Clip, error) {
+	hash := s.Hash(req)
+
+	clip, err := s.Open(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if clip != nil {
+		metrics.Add("hits", 1)
+		return clip, nil
+	}
+
+	metrics.Add("misses", 1)
+	asset, err := s.synthesize(ctx, hash, req)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.files.OpenAudio(asset.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &Clip{Asset: asset, Content: content}, nil
+}
+
+// Open returns the stored clip with the given hash, or nil when it was never stored or its
+// file has gone missing.
+func (s *Service) Open(ctx context.Context, hash string) (*Clip, error) {
+	asset, err := s.db.GetAudioAsset(ctx, hash)
+	if err != nil {
+		metrics.Add("errors", 1)
+		return nil, fmt.Errorf("failed to look up audio asset: %w", err)
+	}
+	if asset == nil {
+		return nil, nil
+	}
+
+	content, err := s.files.OpenAudio(asset.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.GetDefaultLogger().WithField("audio_hash", hash).Warnf("Audio file %s is missing", asset.Path)
+		return nil, nil
+	}
+	if err != nil {
+		metrics.Add("errors", 1)
+		return nil, err
+	}
+	return &Clip{Asset: asset, Content: content, Cached: true}, nil
+}
+
+// Encoded returns clip in format. The first request for a format encodes the clip and stores
+// the result next to the original; later ones, including Range requests, open the stored copy.
+// The returned clip replaces clip, so the caller closes only its Content.
+func (s *Service) Encoded(ctx context.Context, clip *Clip, format Format) (*Clip, error) {
+	if format == FormatWAV {
+		return clip, nil
+	}
+	hash := clip.Asset.Hash
+	log := logger.GetDefaultLogger().WithField("audio_hash", hash).WithField("format", format.Name)
+
+	variant, err := s.db.GetAudioVariant(ctx, hash, format.Name)
+	if err != nil {
+		metrics.Add("errors", 1)
+		clip.Content.Close()
+		return nil, fmt.Errorf("failed to look up audio variant: %w", err)
+	}
+	if variant != nil {
+		content, err := s.files.OpenAudio(variant.Path)
+		if err == nil {
+			clip.Content.Close()
+			return &Clip{Asset: variant, Content: content, Cached: clip.Cached}, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			metrics.Add("errors", 1)
+			clip.Content.Close()
+			return nil, err
+		}
+		log.Warnf("Audio file %s is missing", variant.Path)
+	}
+
+	data, err := io.ReadAll(clip.Content)
+	clip.Content.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	encoded, mimeType, err := Encode(data, clip.Asset.MIMEType, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audio: %w", err)
+	}
+	if mimeType == clip.Asset.MIMEType {
+		// Not convertible: serve the original as it is.
+		return &Clip{Asset: clip.Asset, Content: nopCloser{bytes.NewReader(data)}, Cached: clip.Cached}, nil
+	}
+
+	metrics.Add("encodes", 1)
+	variant = &models.AudioAsset{
+		Hash:      hash,
+		MIMEType:  mimeType,
+		SizeBytes: int64(len(encoded)),
+		CreatedAt: s.now(),
+	}
+	encodedClip := &Clip{Asset: variant, Content: nopCloser{bytes.NewReader(encoded)}, Cached: clip.Cached}
+
+	// A failed write only costs encoding again next time.
+	variant.Path, err = s.files.SaveAudio(hash+"."+format.Name, encoded, mimeType)
+	if err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to store encoded audio")
+		return encodedClip, nil
+	}
+	if err := s.db.SaveAudioVariant(ctx, format.Name, variant); err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to record encoded audio")
+	}
+	return encodedClip, nil
+}
+
+// nopCloser serves clips held in memory.
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+// synthesize runs at most one synthesis per hash at a time. Waiters give up when their own
+// context ends; the synthesis itself is detached so it still lands in storage for the next
+// request.
+func (s *Service) synthesize(ctx context.Context, hash string, req Request) (*models.AudioAsset, error) {
+	s.mu.Lock()
+	c, ok := s.inflight[hash]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		s.inflight[hash] = c
+		go s.run(context.WithoutCancel(ctx), hash, req, c)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.asset, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Service) run(ctx context.Context, hash string, req Request, c *call) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, hash)
+		s.mu.Unlock()
+		close(c.done)
+	}()
+
+	resp, err := s.client.SynthesizeSpeech(ctx, req.Text, req.Context, gemini.SpeechOptions{
+		Voice:        req.Voice,
+		SpeakingRate: req.SpeakingRate,
+		Tone:         req.Tone,
+	})
+	if err != nil {
+		c.err = err
+		return
+	}
+
+	mimeType := resp.MIMEType
+	if mimeType == "" {
+		mimeType = DefaultMIMEType
+	}
+	path, err := s.files.SaveAudio(hash, resp.Audio, mimeType)
+	if err != nil {
+		metrics.Add("errors", 1)
+		c.err = err
+		return
+	}
+
+	asset := &models.AudioAsset{
+		Hash:      hash,
+		Path:      path,
+		MIMEType:  mimeType,
+		SizeBytes: int64(len(resp.Audio)),
+		CreatedAt: s.now(),
+	}
+	if err := s.db.SaveAudioAsset(ctx, asset); err != nil {
+		metrics.Add("errors", 1)
+		c.err = fmt.Errorf("failed to record audio asset: %w", err)
+		return
+	}
+	c.asset = asset
+}
@@ -0,0 +1,217 @@
+package audio
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/storage"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type speechClient struct {
+	calls atomic.Int32
+	delay time.Duration
+}
+
+func (c *speechClient) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
+	return nil, nil
+}
+
+func (c *speechClient) Annotate(ctx context.Context, ocrText string, selectedText string) (*gemini.AnnotationResponse, error) {
+	return nil, nil
+}
+
+func (c *speechClient) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*gemini.AnnotationResponse, error) {
+	return nil, nil
+}
+
+func (c *speechClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	c.calls.Add(1)
+	time.Sleep(c.delay)
+	return &gemini.SpeechResponse{Audio: []byte("RIFF" + highlightedText), MIMEType: "audio/wav"}, nil
+}
+
+func (c *speechClient) Furigana(ctx context.Context, text string) (*gemini.FuriganaResponse, error) {
+	return nil, nil
+}
+
+func newTestService(t *testing.T, client gemini.Client) *Service {
+	t.Helper()
+	files, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalFileStorage: %v", err)
+	}
+	return NewService(testutil.NewMockDB(), files, client, "gemini")
+}
+
+func readClip(t *testing.T, clip *Clip) string {
+	t.Helper()
+	defer clip.Content.Close()
+	data, err := io.ReadAll(clip.Content)
+	if err != nil {
+		t.Fatalf("read clip: %v", err)
+	}
+	return string(data)
+}
+
+func TestHash(t *testing.T) {
+	s := NewService(nil, nil, nil, "gemini")
+	base := Request{Text: "おはよう", Context: "朝の挨拶"}
+
+	if s.Hash(base) != s.Hash(Request{Text: " おはよう\n", Context: "朝の挨拶 "}) {
+		t.Error("surrounding whitespace should not change the hash")
+	}
+	if s.Hash(base) != s.Hash(Request{Text: base.Text, Context: base.Context, Voice: gemini.DefaultVoice, SpeakingRate: 1}) {
+		t.Error("explicit defaults should share the clip")
+	}
+	for name, req := range map[string]Request{
+		"text":    {Text: "こんにちは", Context: base.Context},
+		"context": {Text: base.Text, Context: "別の場面"},
+		"voice":   {Text: base.Text, Context: base.Context, Voice: "Puck"},
+		"rate":    {Text: base.Text, Context: base.Context, SpeakingRate: 0.75},
+		"tone":    {Text: base.Text, Context: base.Context, Tone: "casual"},
+	} {
+		if s.Hash(req) == s.Hash(base) {
+			t.Errorf("changing %s should change the hash", name)
+		}
+	}
+	if NewService(nil, nil, nil, "openai").Hash(base) == s.Hash(base) {
+		t.Error("providers should not share clips")
+	}
+}
+
+func TestFetchStoresClip(t *testing.T) {
+	client := &speechClient{}
+	s := newTestService(t, client)
+	req := Request{Text: "おはよう"}
+
+	first, err := s.Fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if first.Cached {
+		t.Error("first fetch should synthesize")
+	}
+	if got := readClip(t, first); got != "RIFFおはよう" {
+		t.Errorf("clip = %q", got)
+	}
+
+	second, err := s.Fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !second.Cached {
+		t.Error("second fetch should be served from storage")
+	}
+	readClip(t, second)
+
+	if n := client.calls.Load(); n != 1 {
+		t.Errorf("synthesized %d times, want 1", n)
+	}
+	if first.Asset.SizeBytes != int64(len("RIFFおはよう")) || first.Asset.MIMEType != "audio/wav" {
+		t.Errorf("asset = %+v", first.Asset)
+	}
+}
+
+func TestFetchSharesConcurrentSynthesis(t *testing.T) {
+	client := &speechClient{delay: 50 * time.Millisecond}
+	s := newTestService(t, client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clip, err := s.Fetch(context.Background(), Request{Text: "同時"})
+			if err != nil {
+				t.Errorf("Fetch: %v", err)
+				return
+			}
+			clip.Content.Close()
+		}()
+	}
+	wg.Wait()
+
+	if n := client.calls.Load(); n != 1 {
+		t.Errorf("synthesized %d times, want 1", n)
+	}
+}
+
+func TestFetchResynthesizesMissingFile(t *testing.T) {
+	client := &speechClient{}
+	s := newTestService(t, client)
+	req := Request{Text: "消えた"}
+
+	clip, err := s.Fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	clip.Content.Close()
+	if err := os.Remove(clip.Asset.Path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if missing, err := s.Open(context.Background(), clip.Asset.Hash); err != nil || missing != nil {
+		t.Fatalf("Open = %v, %v; want nil, nil", missing, err)
+	}
+
+	clip, err = s.Fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	clip.Content.Close()
+	if n := client.calls.Load(); n != 2 {
+		t.Errorf("synthesized %d times, want 2", n)
+	}
+}
+
+type wavClient struct {
+	speechClient
+}
+
+func (c *wavClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	return &gemini.SpeechResponse{Audio: gemini.WrapPCMAsWAV(make([]byte, 4800), 24000, 1, 16), MIMEType: "audio/wav"}, nil
+}
+
+func TestEncodedStoresVariant(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, &wavClient{})
+
+	encode := func() *Clip {
+		t.Helper()
+		clip, err := s.Fetch(ctx, Request{Text: "圧縮"})
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		encoded, err := s.Encoded(ctx, clip, FormatIMAADPCM)
+		if err != nil {
+			t.Fatalf("Encoded: %v", err)
+		}
+		return encoded
+	}
+
+	first := encode()
+	want := readClip(t, first)
+	variant, err := s.db.GetAudioVariant(ctx, first.Asset.Hash, FormatIMAADPCM.Name)
+	if err != nil || variant == nil {
+		t.Fatalf("GetAudioVariant = %v, %v; want stored variant", variant, err)
+	}
+	if variant.MIMEType != FormatIMAADPCM.MIMEType {
+		t.Errorf("variant MIME type = %q, want %q", variant.MIMEType, FormatIMAADPCM.MIMEType)
+	}
+
+	second := encode()
+	if _, ok := second.Content.(*os.File); !ok {
+		t.Errorf("expected the stored variant to be served from disk, got %T", second.Content)
+	}
+	if got := readClip(t, second); got != want {
+		t.Error("stored variant differs from the first encoding")
+	}
+}
@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"errors"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/audio/wav"
+)
+
+// Format is an output encoding clients can ask for with the Accept header.
+type Format struct {
+	// Name is appended to the clip's ETag for every format but the stored one.
+	Name     string
+	MIMEType string
+}
+
+var (
+	// FormatWAV is the stored clip as synthesized, 16-bit PCM WAV. It is the default.
+	FormatWAV = Format{Name: "wav", MIMEType: DefaultMIMEType}
+	// FormatIMAADPCM is 4-bit IMA-ADPCM WAV, about a quarter of the size of PCM.
+	FormatIMAADPCM = Format{Name: "ima-adpcm", MIMEType: wav.MIMETypeIMAADPCM}
+)
+
+// formats in order of preference when the Accept header ranks several equally.
+var formats = []Format{FormatWAV, FormatIMAADPCM}
+
+// Negotiate picks the output format for an Accept header. The highest quality wins, then the
+// most specific media range, then the range listed first, so browsers sending audio/* or */*
+// keep getting PCM WAV. It returns false when the header rules out every format.
+func Negotiate(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return FormatWAV, true
+	}
+
+	type match struct {
+		q           float64
+		specificity int
+		position    int
+	}
+	best := make([]match, len(formats))
+	for i := range best {
+		best[i] = match{q: -1, specificity: -1}
+	}
+
+	for position, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		for i, f := range formats {
+			specificity := mediaRangeSpecificity(f, mediaType, params)
+			if specificity < 0 {
+				continue
+			}
+			// The most specific range decides a format's quality, even if it is lower.
+			if specificity > best[i].specificity {
+				best[i] = match{q: q, specificity: specificity, position: position}
+			}
+		}
+	}
+
+	chosen := -1
+	for i, m := range best {
+		if m.q <= 0 {
+			continue
+		}
+		if chosen < 0 {
+			chosen = i
+			continue
+		}
+		c := best[chosen]
+		if m.q > c.q ||
+			(m.q == c.q && m.specificity > c.specificity) ||
+			(m.q == c.q && m.specificity == c.specificity && m.position < c.position) {
+			chosen = i
+		}
+	}
+	if chosen < 0 {
+		return Format{}, false
+	}
+	return formats[chosen], true
+}
+
+// mediaRangeSpecificity reports how closely a media range names f: 3 for a codec match, 2
+// for the exact type, 1 for audio/*, 0 for */*, and -1 when it does not cover f.
+func mediaRangeSpecificity(f Format, mediaType string, params map[string]string) int {
+	switch mediaType {
+	case "*/*":
+		return 0
+	case "audio/*":
+		return 1
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		if f == FormatWAV {
+			return 2
+		}
+	case "audio/vnd.wave":
+		// RFC 2361 names the WAVE format tag in hex; a missing codec means PCM.
+		switch codec := strings.ToLower(params["codec"]); {
+		case f == FormatIMAADPCM && codec == "11":
+			return 3
+		case f == FormatWAV && (codec == "" || codec == "1" || codec == "01"):
+			return 2
+		}
+	}
+	return -1
+}
+
+// Encode converts a stored clip to format. Clips that are not 16-bit PCM WAV cannot be
+// converted and are returned unchanged, so callers label the result with the returned MIME
+// type rather than the format's.
+func Encode(data []byte, mimeType string, format Format) ([]byte, string, error) {
+	if format == FormatWAV {
+		return data, mimeType, nil
+	}
+
+	pcm, err := wav.DecodePCM(data)
+	if errors.Is(err, wav.ErrUnsupported) {
+		return data, mimeType, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return wav.EncodeIMAADPCM(pcm), format.MIMEType, nil
+}
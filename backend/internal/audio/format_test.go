@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+		ok     bool
+	}{
+		{"", FormatWAV, true},
+		{"*/*", FormatWAV, true},
+		{"audio/*", FormatWAV, true},
+		{"audio/wav", FormatWAV, true},
+		{"audio/vnd.wave; codec=11", FormatIMAADPCM, true},
+		{"audio/vnd.wave;codec=11, audio/wav", FormatIMAADPCM, true},
+		{"audio/wav, audio/vnd.wave;codec=11", FormatIMAADPCM, true},
+		{"audio/wav;q=0.5, audio/vnd.wave;codec=11", FormatIMAADPCM, true},
+		{"audio/vnd.wave;codec=11;q=0.5, audio/wav", FormatWAV, true},
+		{"audio/wav;q=0, */*", FormatIMAADPCM, true},
+		// A browser media element: only the wildcards cover ADPCM.
+		{"audio/webm,audio/ogg,audio/wav,audio/*;q=0.9,application/ogg;q=0.7,video/*;q=0.6,*/*;q=0.5", FormatWAV, true},
+		{"audio/mpeg", Format{}, false},
+		{"application/json", Format{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Negotiate(tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Negotiate(%q) = %v, %v; want %v, %v", tt.accept, got.Name, ok, tt.want.Name, tt.ok)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	pcmWAV := gemini.WrapPCMAsWAV(make([]byte, 4800), 24000, 1, 16)
+
+	data, mimeType, err := Encode(pcmWAV, "audio/wav", FormatIMAADPCM)
+	if err != nil || mimeType != FormatIMAADPCM.MIMEType || len(data) >= len(pcmWAV) {
+		t.Errorf("expected smaller ADPCM, got %d bytes of %q (err %v)", len(data), mimeType, err)
+	}
+
+	mp3 := []byte("ID3 not a wav")
+	if data, mimeType, _ := Encode(mp3, "audio/mpeg", FormatIMAADPCM); string(data) != string(mp3) || mimeType != "audio/mpeg" {
+		t.Errorf("expected unconvertible audio unchanged, got %q", mimeType)
+	}
+}
@@ -0,0 +1,200 @@
+package audio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/audio/wav"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// ErrNotConcatenable is returned by Concat when a clip is not 16-bit PCM WAV, or the clips
+// differ in sample rate or channel count.
+var ErrNotConcatenable = errors.New("audio: clips cannot be concatenated")
+
+// Sentence is one stored clip of a passage.
+type Sentence struct {
+	Text  string
+	Asset *models.AudioAsset
+	// Duration is zero when the clip is not PCM WAV and its length is unknown.
+	Duration time.Duration
+	Cached   bool
+}
+
+// SentenceRequests builds one request per sentence from base, which supplies the voice, rate
+// and tone. Each sentence gets its neighbours as context so the delivery follows the passage.
+func SentenceRequests(sentences []string, base Request) []Request {
+	reqs := make([]Request, len(sentences))
+	for i, text := range sentences {
+		req := base
+		req.Text = text
+		req.Context = strings.Join(sentences[max(0, i-1):min(len(sentences), i+2)], " ")
+		reqs[i] = req
+	}
+	return reqs
+}
+
+// Missing counts the requests that have no stored clip yet, which is how many TTS calls
+// FetchAll will make.
+func (s *Service) Missing(ctx context.Context, reqs []Request) (int, error) {
+	missing := 0
+	for _, req := range reqs {
+		asset, err := s.db.GetAudioAsset(ctx, s.Hash(req))
+		if err != nil {
+			return 0, fmt.Errorf("failed to look up audio asset: %w", err)
+		}
+		if asset == nil {
+			missing++
+		}
+	}
+	return missing, nil
+}
+
+// FetchAll fetches a clip for every request, running at most concurrency fetches at once.
+// The first failure cancels the fetches still waiting and is returned.
+func (s *Service) FetchAll(ctx context.Context, reqs []Request, concurrency int) ([]Sentence, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sentences := make([]Sentence, len(reqs))
+	sem := make(chan struct{}, max(1, concurrency))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for i, req := range reqs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			sentence, err := s.fetchSentence(ctx, req)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			sentences[i] = sentence
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return sentences, nil
+}
+
+func (s *Service) fetchSentence(ctx context.Context, req Request) (Sentence, error) {
+	clip, err := s.Fetch(ctx, req)
+	if err != nil {
+		return Sentence{}, err
+	}
+	defer clip.Content.Close()
+
+	sentence := Sentence{Text: req.Text, Asset: clip.Asset, Cached: clip.Cached}
+	data, err := io.ReadAll(clip.Content)
+	if err != nil {
+		return Sentence{}, err
+	}
+	if pcm, err := wav.DecodePCM(data); err == nil {
+		sentence.Duration = pcm.Duration()
+	}
+	return sentence, nil
+}
+
+// Concat stores the sentences' clips joined end to end as one WAV file. The result is
+// addressed by the sentence hashes, so asking again for the same passage reuses it.
+func (s *Service) Concat(ctx context.Context, sentences []Sentence) (*models.AudioAsset, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "concat\x00%s", KeyVersion)
+	for _, sentence := range sentences {
+		fmt.Fprintf(h, "\x00%s", sentence.Asset.Hash)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	clip, err := s.Open(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if clip != nil {
+		clip.Content.Close()
+		return clip.Asset, nil
+	}
+
+	var joined *wav.PCM
+	for _, sentence := range sentences {
+		pcm, err := s.readPCM(sentence.Asset)
+		if err != nil {
+			return nil, err
+		}
+		if joined == nil {
+			joined = &wav.PCM{SampleRate: pcm.SampleRate, Channels: pcm.Channels}
+		} else if pcm.SampleRate != joined.SampleRate || pcm.Channels != joined.Channels {
+			return nil, ErrNotConcatenable
+		}
+		joined.Samples = append(joined.Samples, pcm.Samples...)
+	}
+	if joined == nil {
+		return nil, ErrNotConcatenable
+	}
+
+	data := wav.EncodePCM(joined)
+	path, err := s.files.SaveAudio(hash, data, DefaultMIMEType)
+	if err != nil {
+		metrics.Add("errors", 1)
+		return nil, err
+	}
+	asset := &models.AudioAsset{
+		Hash:      hash,
+		Path:      path,
+		MIMEType:  DefaultMIMEType,
+		SizeBytes: int64(len(data)),
+		CreatedAt: s.now(),
+	}
+	if err := s.db.SaveAudioAsset(ctx, asset); err != nil {
+		metrics.Add("errors", 1)
+		return nil, fmt.Errorf("failed to record audio asset: %w", err)
+	}
+	return asset, nil
+}
+
+func (s *Service) readPCM(asset *models.AudioAsset) (*wav.PCM, error) {
+	content, err := s.files.OpenAudio(asset.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := wav.DecodePCM(data)
+	if errors.Is(err, wav.ErrUnsupported) {
+		return nil, ErrNotConcatenable
+	}
+	return pcm, err
+}
@@ -0,0 +1,135 @@
+package audio
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/audio/wav"
+	"github.com/gemini-hackathon/app/internal/gemini"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"今日は晴れ。明日は雨です！", []string{"今日は晴れ。", "明日は雨です！"}},
+		{"「はい。」と言った。本当？！", []string{"「はい。」", "と言った。", "本当？！"}},
+		{"一行目\n二行目\r\n\n三行目", []string{"一行目", "二行目", "三行目"}},
+		{"It costs 3.5 yen. Really?! Yes", []string{"It costs 3.5 yen.", "Really?!", "Yes"}},
+		{"待って……それで", []string{"待って……", "それで"}},
+		{"  \n 。 ", []string{"。"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := SplitSentences(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSentenceRequests(t *testing.T) {
+	reqs := SentenceRequests([]string{"A.", "B.", "C."}, Request{Voice: "Puck", Tone: gemini.TonePolite})
+
+	wantContexts := []string{"A. B.", "A. B. C.", "B. C."}
+	for i, req := range reqs {
+		if req.Context != wantContexts[i] {
+			t.Errorf("sentence %d: context %q, want %q", i, req.Context, wantContexts[i])
+		}
+		if req.Voice != "Puck" || req.Tone != gemini.TonePolite {
+			t.Errorf("sentence %d: delivery not copied from base: %+v", i, req)
+		}
+	}
+}
+
+// pcmSpeechClient returns 16-bit mono PCM lasting 10ms per byte of text.
+type pcmSpeechClient struct {
+	speechClient
+	active, peak atomic.Int32
+}
+
+func (c *pcmSpeechClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	c.calls.Add(1)
+	n := c.active.Add(1)
+	defer c.active.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	pcm := &wav.PCM{SampleRate: 8000, Channels: 1, Samples: make([]int16, 80*len(highlightedText))}
+	return &gemini.SpeechResponse{Audio: wav.EncodePCM(pcm), MIMEType: "audio/wav"}, nil
+}
+
+func TestFetchAllAndConcat(t *testing.T) {
+	ctx := context.Background()
+	client := &pcmSpeechClient{}
+	svc := newTestService(t, client)
+	reqs := SentenceRequests([]string{"a", "bb", "ccc", "dddd", "eeeee"}, Request{})
+
+	missing, err := svc.Missing(ctx, reqs)
+	if err != nil || missing != 5 {
+		t.Fatalf("Missing = %d, %v; want 5", missing, err)
+	}
+
+	sentences, err := svc.FetchAll(ctx, reqs, 2)
+	if err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	if got := client.peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 concurrent syntheses, got %d", got)
+	}
+	for i, sentence := range sentences {
+		if want := time.Duration(i+1) * 10 * time.Millisecond; sentence.Duration != want {
+			t.Errorf("sentence %d: duration %s, want %s", i, sentence.Duration, want)
+		}
+	}
+
+	if missing, _ := svc.Missing(ctx, reqs); missing != 0 {
+		t.Errorf("expected every sentence stored, %d missing", missing)
+	}
+
+	asset, err := svc.Concat(ctx, sentences)
+	if err != nil {
+		t.Fatalf("Concat: %v", err)
+	}
+	clip, err := svc.Open(ctx, asset.Hash)
+	if err != nil || clip == nil {
+		t.Fatalf("Open concatenated clip: %v", err)
+	}
+	pcm, err := wav.DecodePCM([]byte(readClip(t, clip)))
+	if err != nil {
+		t.Fatalf("DecodePCM: %v", err)
+	}
+	if got := pcm.Duration(); got != 150*time.Millisecond {
+		t.Errorf("concatenated duration %s, want 150ms", got)
+	}
+
+	again, err := svc.Concat(ctx, sentences)
+	if err != nil || again.Hash != asset.Hash {
+		t.Errorf("expected the concatenation to be reused, got %v, %v", again, err)
+	}
+}
+
+func TestConcatRejectsNonPCM(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t, &speechClient{})
+
+	sentences, err := svc.FetchAll(ctx, SentenceRequests([]string{"a", "b"}, Request{}), 2)
+	if err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	if sentences[0].Duration != 0 {
+		t.Errorf("expected unknown duration for non-PCM audio, got %s", sentences[0].Duration)
+	}
+	if _, err := svc.Concat(ctx, sentences); err != ErrNotConcatenable {
+		t.Errorf("expected ErrNotConcatenable, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sentenceEnders end a sentence wherever they appear. An ASCII period only ends one before
+// whitespace or the end of the text, so "3.5" and "e.g" stay whole.
+const sentenceEnders = "。．！？!?…"
+
+// closers stay attached to the sentence they close, so 「はい。」 is one sentence.
+const closers = "」』）)】〉》”’\"'"
+
+// SplitSentences splits OCR text into sentences for playback. Line breaks also end a
+// sentence, since scanned text rarely carries punctuation across them. Runs of enders such as
+// "!?" or "……" and any closing brackets or quotes that follow stay with the sentence.
+// Sentences are trimmed and empty ones dropped.
+func SplitSentences(text string) []string {
+	runes := []rune(text)
+	var sentences []string
+	start := 0
+
+	emit := func(end int) {
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n' || r == '\r':
+			emit(i + 1)
+		case strings.ContainsRune(sentenceEnders, r) || (r == '.' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]))):
+			end := i + 1
+			for end < len(runes) && (strings.ContainsRune(sentenceEnders, runes[end]) || runes[end] == '.' || strings.ContainsRune(closers, runes[end])) {
+				end++
+			}
+			emit(end)
+			i = end - 1
+		}
+	}
+	emit(len(runes))
+	return sentences
+}
@@ -0,0 +1,149 @@
+package wav
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// MIMETypeIMAADPCM identifies IMA-ADPCM WAV by its format tag, following RFC 2361.
+const MIMETypeIMAADPCM = "audio/vnd.wave; codec=11"
+
+var imaStepTable = [89]int32{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17,
+	19, 21, 23, 25, 28, 31, 34, 37, 41, 45,
+	50, 55, 60, 66, 73, 80, 88, 97, 107, 118,
+	130, 143, 157, 173, 190, 209, 230, 253, 279, 307,
+	337, 371, 408, 449, 494, 544, 598, 658, 724, 796,
+	876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066,
+	2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871, 5358,
+	5894, 6484, 7132, 7845, 8630, 9493, 10442, 11487, 12635, 13899,
+	15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794, 32767,
+}
+
+var imaIndexTable = [16]int32{
+	-1, -1, -1, -1, 2, 4, 6, 8,
+	-1, -1, -1, -1, 2, 4, 6, 8,
+}
+
+// imaState is the predictor of one channel.
+type imaState struct {
+	predictor int32
+	index     int32
+}
+
+func (s *imaState) encode(sample int16) byte {
+	step := imaStepTable[s.index]
+	diff := int32(sample) - s.predictor
+
+	var nibble byte
+	if diff < 0 {
+		nibble = 8
+		diff = -diff
+	}
+
+	delta := step >> 3
+	if diff >= step {
+		nibble |= 4
+		diff -= step
+		delta += step
+	}
+	step >>= 1
+	if diff >= step {
+		nibble |= 2
+		diff -= step
+		delta += step
+	}
+	step >>= 1
+	if diff >= step {
+		nibble |= 1
+		delta += step
+	}
+
+	if nibble&8 != 0 {
+		s.predictor -= delta
+	} else {
+		s.predictor += delta
+	}
+	s.predictor = min(max(s.predictor, math.MinInt16), math.MaxInt16)
+	s.index = min(max(s.index+imaIndexTable[nibble], 0), int32(len(imaStepTable)-1))
+	return nibble
+}
+
+// imaBlockAlign is the block size Microsoft's encoder uses: 256 bytes per channel, doubled
+// for every doubling of the sample rate above 11025 Hz.
+func imaBlockAlign(sampleRate, channels int) int {
+	return 256 * channels * max(1, sampleRate/11025)
+}
+
+// imaSamplesPerBlock counts the header sample plus two samples per data byte.
+func imaSamplesPerBlock(blockAlign, channels int) int {
+	return (blockAlign-4*channels)*2/channels + 1
+}
+
+// EncodeIMAADPCM encodes p as an IMA-ADPCM WAV file, about a quarter of the PCM size. The
+// final block is padded with silence; the fact chunk records the true length.
+func EncodeIMAADPCM(p *PCM) []byte {
+	channels := p.Channels
+	blockAlign := imaBlockAlign(p.SampleRate, channels)
+	samplesPerBlock := imaSamplesPerBlock(blockAlign, channels)
+	frames := p.Frames()
+	blocks := (frames + samplesPerBlock - 1) / samplesPerBlock
+
+	frame := func(i, ch int) int16 {
+		if i >= frames {
+			return 0
+		}
+		return p.Samples[i*channels+ch]
+	}
+
+	// Start each channel at a step that fits its opening slope instead of ramping up from the
+	// smallest step, which would smear the start of short clips.
+	states := make([]imaState, channels)
+	for ch := range states {
+		diff := int32(frame(1, ch)) - int32(frame(0, ch))
+		for states[ch].index < int32(len(imaStepTable)-1) && imaStepTable[states[ch].index] < max(diff, -diff) {
+			states[ch].index++
+		}
+	}
+
+	data := make([]byte, 0, blocks*blockAlign)
+	for b := 0; b < blocks; b++ {
+		start := b * samplesPerBlock
+
+		// Header: the first sample verbatim and the step index carried from the last block.
+		for ch := range states {
+			first := frame(start, ch)
+			states[ch].predictor = int32(first)
+			data = binary.LittleEndian.AppendUint16(data, uint16(first))
+			data = append(data, byte(states[ch].index), 0)
+		}
+
+		// Body: per channel, groups of 8 samples packed low nibble first, channels interleaved
+		// every 4 bytes.
+		for group := start + 1; group < start+samplesPerBlock; group += 8 {
+			for ch := range states {
+				for i := 0; i < 8; i += 2 {
+					lo := states[ch].encode(frame(group+i, ch))
+					hi := states[ch].encode(frame(group+i+1, ch))
+					data = append(data, lo|hi<<4)
+				}
+			}
+		}
+	}
+
+	format := make([]byte, 20)
+	binary.LittleEndian.PutUint16(format[0:], formatIMAADPCM)
+	binary.LittleEndian.PutUint16(format[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(format[4:], uint32(p.SampleRate))
+	binary.LittleEndian.PutUint32(format[8:], uint32(p.SampleRate*blockAlign/samplesPerBlock))
+	binary.LittleEndian.PutUint16(format[12:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(format[14:], 4)
+	binary.LittleEndian.PutUint16(format[16:], 2)
+	binary.LittleEndian.PutUint16(format[18:], uint16(samplesPerBlock))
+
+	var w riffWriter
+	w.chunk("fmt ", format)
+	w.chunk("fact", binary.LittleEndian.AppendUint32(nil, uint32(frames)))
+	w.chunk("data", data)
+	return w.bytes()
+}
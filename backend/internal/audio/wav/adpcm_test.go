@@ -0,0 +1,162 @@
+package wav
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+)
+
+// decodeIMAADPCM is the reference decoder used to check the encoder.
+func decodeIMAADPCM(t *testing.T, data []byte) *PCM {
+	t.Helper()
+	var channels, sampleRate, blockAlign, samplesPerBlock, frames int
+	var body []byte
+	for rest := data[12:]; len(rest) >= 8; {
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		chunk := rest[8 : 8+size]
+		switch string(rest[0:4]) {
+		case "fmt ":
+			if tag := binary.LittleEndian.Uint16(chunk[0:2]); tag != formatIMAADPCM {
+				t.Fatalf("format tag = %#x", tag)
+			}
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			blockAlign = int(binary.LittleEndian.Uint16(chunk[12:14]))
+			samplesPerBlock = int(binary.LittleEndian.Uint16(chunk[18:20]))
+		case "fact":
+			frames = int(binary.LittleEndian.Uint32(chunk))
+		case "data":
+			body = chunk
+		}
+		rest = rest[8+size+size%2:]
+	}
+	if len(body)%blockAlign != 0 {
+		t.Fatalf("data size %d is not a multiple of block align %d", len(body), blockAlign)
+	}
+
+	out := &PCM{SampleRate: sampleRate, Channels: channels}
+	for ; len(body) > 0; body = body[blockAlign:] {
+		block := make([][]int16, channels)
+		states := make([]imaState, channels)
+		for ch := range states {
+			h := body[ch*4:]
+			states[ch] = imaState{predictor: int32(int16(binary.LittleEndian.Uint16(h))), index: int32(h[2])}
+			block[ch] = append(block[ch], int16(states[ch].predictor))
+		}
+		for i := 4 * channels; i < blockAlign; i += 4 * channels {
+			for ch := range states {
+				for _, b := range body[i+ch*4 : i+ch*4+4] {
+					for _, nibble := range []byte{b & 0x0f, b >> 4} {
+						s := &states[ch]
+						step := imaStepTable[s.index]
+						delta := step >> 3
+						if nibble&4 != 0 {
+							delta += step
+						}
+						if nibble&2 != 0 {
+							delta += step >> 1
+						}
+						if nibble&1 != 0 {
+							delta += step >> 2
+						}
+						if nibble&8 != 0 {
+							s.predictor -= delta
+						} else {
+							s.predictor += delta
+						}
+						s.predictor = min(max(s.predictor, math.MinInt16), math.MaxInt16)
+						s.index = min(max(s.index+imaIndexTable[nibble], 0), 88)
+						block[ch] = append(block[ch], int16(s.predictor))
+					}
+				}
+			}
+		}
+		for i := 0; i < samplesPerBlock; i++ {
+			for ch := range block {
+				out.Samples = append(out.Samples, block[ch][i])
+			}
+		}
+	}
+	out.Samples = out.Samples[:frames*channels]
+	return out
+}
+
+func sine(sampleRate, channels, frames int) *PCM {
+	p := &PCM{SampleRate: sampleRate, Channels: channels, Samples: make([]int16, frames*channels)}
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < channels; ch++ {
+			freq := 440.0 * float64(ch+1)
+			p.Samples[i*channels+ch] = int16(8000 * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+		}
+	}
+	return p
+}
+
+func snr(original, decoded []int16) float64 {
+	var signal, noise float64
+	for i := range original {
+		d := float64(original[i]) - float64(decoded[i])
+		signal += float64(original[i]) * float64(original[i])
+		noise += d * d
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+func TestDecodePCM(t *testing.T) {
+	pcm := []byte{1, 0, 2, 0, 0xff, 0xff, 0, 0x80}
+	p, err := DecodePCM(gemini.WrapPCMAsWAV(pcm, 24000, 1, 16))
+	if err != nil {
+		t.Fatalf("DecodePCM: %v", err)
+	}
+	want := []int16{1, 2, -1, math.MinInt16}
+	if p.SampleRate != 24000 || p.Channels != 1 || len(p.Samples) != len(want) {
+		t.Fatalf("unexpected PCM: %+v", p)
+	}
+	for i := range want {
+		if p.Samples[i] != want[i] {
+			t.Errorf("sample %d = %d, want %d", i, p.Samples[i], want[i])
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"mp3":    []byte("ID3\x04\x00\x00\x00\x00\x00\x00"),
+		"8-bit":  gemini.WrapPCMAsWAV([]byte{1, 2}, 8000, 1, 8),
+		"adpcm":  EncodeIMAADPCM(p),
+		"no fmt": []byte("RIFF\x0c\x00\x00\x00WAVEdata\x00\x00\x00\x00"),
+	} {
+		if _, err := DecodePCM(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEncodeIMAADPCM(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		sampleRate int
+		channels   int
+		frames     int
+	}{
+		{"mono 24kHz", 24000, 1, 24000},
+		{"stereo 44.1kHz", 44100, 2, 5000},
+		{"shorter than a block", 24000, 1, 10},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := sine(tt.sampleRate, tt.channels, tt.frames)
+			encoded := EncodeIMAADPCM(p)
+			decoded := decodeIMAADPCM(t, encoded)
+
+			if decoded.SampleRate != tt.sampleRate || decoded.Channels != tt.channels || decoded.Frames() != tt.frames {
+				t.Fatalf("decoded %d Hz, %d channels, %d frames", decoded.SampleRate, decoded.Channels, decoded.Frames())
+			}
+			if got := snr(p.Samples, decoded.Samples); got < 25 {
+				t.Errorf("SNR = %.1f dB, want at least 25", got)
+			}
+			if pcmSize := len(p.Samples) * 2; tt.frames > 1000 && len(encoded) > pcmSize/3 {
+				t.Errorf("encoded %d bytes from %d bytes of PCM", len(encoded), pcmSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package wav reads 16-bit PCM WAV files and re-encodes them as IMA-ADPCM WAV, a 4:1
+// compressed format that needs no cgo or external codec.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WAVE format tags.
+const (
+	formatPCM      = 0x0001
+	formatIMAADPCM = 0x0011
+)
+
+// ErrUnsupported is returned for input that is not a 16-bit PCM WAV file.
+var ErrUnsupported = errors.New("wav: not 16-bit PCM")
+
+// PCM is 16-bit linear audio. Samples are interleaved by channel.
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []int16
+}
+
+// Frames returns the number of samples per channel.
+func (p *PCM) Frames() int {
+	if p.Channels == 0 {
+		return 0
+	}
+	return len(p.Samples) / p.Channels
+}
+
+// Duration returns the playing time of p.
+func (p *PCM) Duration() time.Duration {
+	if p.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(p.Frames()) * time.Second / time.Duration(p.SampleRate)
+}
+
+// EncodePCM writes p as a 16-bit PCM WAV file.
+func EncodePCM(p *PCM) []byte {
+	blockAlign := p.Channels * 2
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:], formatPCM)
+	binary.LittleEndian.PutUint16(format[2:], uint16(p.Channels))
+	binary.LittleEndian.PutUint32(format[4:], uint32(p.SampleRate))
+	binary.LittleEndian.PutUint32(format[8:], uint32(p.SampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(format[12:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(format[14:], 16)
+
+	data := make([]byte, 0, len(p.Samples)*2)
+	for _, s := range p.Samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(s))
+	}
+
+	var w riffWriter
+	w.chunk("fmt ", format)
+	w.chunk("data", data)
+	return w.bytes()
+}
+
+// DecodePCM parses a RIFF/WAVE file with a 16-bit PCM fmt chunk. Chunks other than fmt and
+// data are skipped.
+func DecodePCM(data []byte) (*PCM, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: missing RIFF/WAVE header", ErrUnsupported)
+	}
+
+	var pcm PCM
+	var haveFormat bool
+	for rest := data[12:]; len(rest) >= 8; {
+		id := string(rest[0:4])
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		body := rest[8:]
+		if size > len(body) {
+			// Streamed WAVs may carry a placeholder data size; take what is there.
+			size = len(body)
+		}
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("%w: short fmt chunk", ErrUnsupported)
+			}
+			if tag := binary.LittleEndian.Uint16(body[0:2]); tag != formatPCM {
+				return nil, fmt.Errorf("%w: format tag %#x", ErrUnsupported, tag)
+			}
+			if bits := binary.LittleEndian.Uint16(body[14:16]); bits != 16 {
+				return nil, fmt.Errorf("%w: %d bits per sample", ErrUnsupported, bits)
+			}
+			pcm.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			pcm.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			if pcm.Channels < 1 || pcm.SampleRate < 1 {
+				return nil, fmt.Errorf("%w: invalid fmt chunk", ErrUnsupported)
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("%w: data before fmt chunk", ErrUnsupported)
+			}
+			n := size / 2
+			n -= n % pcm.Channels
+			pcm.Samples = make([]int16, n)
+			for i := range pcm.Samples {
+				pcm.Samples[i] = int16(binary.LittleEndian.Uint16(body[i*2:]))
+			}
+			return &pcm, nil
+		}
+
+		// Chunks are padded to an even size.
+		next := 8 + size + size%2
+		if next > len(rest) {
+			break
+		}
+		rest = rest[next:]
+	}
+	return nil, fmt.Errorf("%w: no data chunk", ErrUnsupported)
+}
+
+// riffWriter builds a RIFF/WAVE file from chunks.
+type riffWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *riffWriter) chunk(id string, body []byte) {
+	w.buf.WriteString(id)
+	_ = binary.Write(&w.buf, binary.LittleEndian, uint32(len(body)))
+	w.buf.Write(body)
+	if len(body)%2 == 1 {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *riffWriter) bytes() []byte {
+	out := make([]byte, 0, 12+w.buf.Len())
+	out = append(out, "RIFF"...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(4+w.buf.Len()))
+	out = append(out, "WAVE"...)
+	return append(out, w.buf.Bytes()...)
+}
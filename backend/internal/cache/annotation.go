@@ -0,0 +1,187 @@
+// Package cache stores AI annotations so repeated lookups of the same phrase skip the model.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+// Tier names used in metrics.
+const (
+	TierRedis    = "redis"
+	TierPostgres = "postgres"
+)
+
+// Metrics are published under "annotation_cache" on /debug/vars.
+var metrics = expvar.NewMap("annotation_cache")
+
+// AnnotationKey is everything that influences the model's answer for a selection.
+type AnnotationKey struct {
+	Text           string
+	Context        string
+	EntryIDs       []string
+	TargetLanguage string
+	PromptVersion  string
+}
+
+// Hash returns the content address of the key. Context whitespace is normalized and entry IDs
+// are order-independent, so equivalent requests share an entry.
+func (k AnnotationKey) Hash() string {
+	ids := append([]string(nil), k.EntryIDs...)
+	sort.Strings(ids)
+
+	parts := []string{
+		k.PromptVersion,
+		k.TargetLanguage,
+		strings.TrimSpace(k.Text),
+		NormalizeContext(k.Context),
+		strings.Join(ids, ","),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeContext collapses runs of whitespace, including the line breaks OCR leaves behind.
+func NormalizeContext(context string) string {
+	return strings.Join(strings.Fields(context), " ")
+}
+
+// AnnotationCache reads through Redis, then Postgres. Redis is optional; Postgres is the
+// durable copy shared across restarts. Cache failures are logged and treated as misses so
+// they never fail a request.
+type AnnotationCache struct {
+	redis storage.RedisClient
+	db    storage.DB
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func NewAnnotationCache(redis storage.RedisClient, db storage.DB, ttl time.Duration) *AnnotationCache {
+	return &AnnotationCache{
+		redis: redis,
+		db:    db,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+func redisKey(hash string) string {
+	return "annotation:" + hash
+}
+
+// Get returns the cached annotation for key and the tier it came from.
+func (c *AnnotationCache) Get(ctx context.Context, key AnnotationKey) (*gemini.AnnotationResponse, string, bool) {
+	hash := key.Hash()
+	log := logger.GetDefaultLogger().WithField("cache_key", hash)
+
+	if c.redis != nil {
+		value, ok, err := c.redis.GetValue(ctx, redisKey(hash))
+		if err != nil {
+			metrics.Add("errors", 1)
+			log.ErrorWithErr(err, "Failed to read annotation cache from Redis")
+		} else if ok {
+			if resp, ok := decode([]byte(value), log); ok {
+				metrics.Add("hits_"+TierRedis, 1)
+				return resp, TierRedis, true
+			}
+		}
+	}
+
+	value, err := c.db.GetAnnotationCacheEntry(ctx, hash, c.now())
+	if err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to read annotation cache from database")
+	} else if value != nil {
+		if resp, ok := decode(value, log); ok {
+			metrics.Add("hits_"+TierPostgres, 1)
+			c.setRedis(ctx, hash, value, log)
+			return resp, TierPostgres, true
+		}
+	}
+
+	metrics.Add("misses", 1)
+	return nil, "", false
+}
+
+// Put stores an annotation in every tier.
+func (c *AnnotationCache) Put(ctx context.Context, key AnnotationKey, resp *gemini.AnnotationResponse) {
+	hash := key.Hash()
+	log := logger.GetDefaultLogger().WithField("cache_key", hash)
+
+	value, err := json.Marshal(resp)
+	if err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to encode annotation for cache")
+		return
+	}
+
+	if err := c.db.SaveAnnotationCacheEntry(ctx, hash, value, c.now().Add(c.ttl)); err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to write annotation cache to database")
+	}
+	c.setRedis(ctx, hash, value, log)
+}
+
+// PurgeExpired deletes expired Postgres entries every interval until ctx is done. Redis
+// expires its copies on its own.
+func (c *AnnotationCache) PurgeExpired(ctx context.Context, interval time.Duration) {
+	log := logger.GetDefaultLogger().WithField("component", "annotation_cache_purge")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := c.db.DeleteExpiredAnnotationCacheEntries(ctx, c.now())
+		if err != nil {
+			if ctx.Err() == nil {
+				metrics.Add("errors", 1)
+				log.ErrorWithErr(err, "Failed to purge expired annotation cache entries")
+			}
+			continue
+		}
+		if deleted > 0 {
+			metrics.Add("purged", deleted)
+			log.Infof("Purged %d expired annotation cache entries", deleted)
+		}
+	}
+}
+
+// RecordBypass counts a request that skipped the cache on purpose.
+func RecordBypass() {
+	metrics.Add("bypass", 1)
+}
+
+func (c *AnnotationCache) setRedis(ctx context.Context, hash string, value []byte, log *logger.Logger) {
+	if c.redis == nil {
+		return
+	}
+	if err := c.redis.SetValue(ctx, redisKey(hash), string(value), c.ttl); err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to write annotation cache to Redis")
+	}
+}
+
+func decode(value []byte, log *logger.Logger) (*gemini.AnnotationResponse, bool) {
+	var resp gemini.AnnotationResponse
+	if err := json.Unmarshal(value, &resp); err != nil {
+		metrics.Add("errors", 1)
+		log.ErrorWithErr(err, "Failed to decode cached annotation")
+		return nil, false
+	}
+	return &resp, true
+}
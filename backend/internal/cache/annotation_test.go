@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/storage"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type memoryRedis struct {
+	storage.RedisClient
+	values map[string]string
+	err    error
+}
+
+func newMemoryRedis() *memoryRedis {
+	return &memoryRedis{values: make(map[string]string)}
+}
+
+func (m *memoryRedis) GetValue(ctx context.Context, key string) (string, bool, error) {
+	if m.err != nil {
+		return "", false, m.err
+	}
+	value, ok := m.values[key]
+	return value, ok, nil
+}
+
+func (m *memoryRedis) SetValue(ctx context.Context, key, value string, ttl time.Duration) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.values[key] = value
+	return nil
+}
+
+func TestAnnotationKeyHash(t *testing.T) {
+	base := AnnotationKey{
+		Text:           "お疲れ様です",
+		Context:        "皆さん、\n  お疲れ様です。",
+		EntryIDs:       []string{"a", "b"},
+		TargetLanguage: "ID",
+		PromptVersion:  "1",
+	}
+
+	same := base
+	same.Context = "皆さん、 お疲れ様です。"
+	same.EntryIDs = []string{"b", "a"}
+	if base.Hash() != same.Hash() {
+		t.Error("expected whitespace and entry order not to change the key")
+	}
+
+	for name, mutate := range map[string]func(*AnnotationKey){
+		"text":     func(k *AnnotationKey) { k.Text = "お疲れ様" },
+		"context":  func(k *AnnotationKey) { k.Context = "別の文脈" },
+		"entries":  func(k *AnnotationKey) { k.EntryIDs = []string{"a"} },
+		"language": func(k *AnnotationKey) { k.TargetLanguage = "EN" },
+		"prompt":   func(k *AnnotationKey) { k.PromptVersion = "2" },
+	} {
+		other := base
+		mutate(&other)
+		if other.Hash() == base.Hash() {
+			t.Errorf("expected %s to change the key", name)
+		}
+	}
+}
+
+func TestAnnotationCacheTiers(t *testing.T) {
+	ctx := context.Background()
+	key := AnnotationKey{Text: "残業", TargetLanguage: "EN", PromptVersion: "1"}
+	resp := &gemini.AnnotationResponse{Meaning: "overtime"}
+
+	t.Run("miss then redis hit", func(t *testing.T) {
+		redis := newMemoryRedis()
+		c := NewAnnotationCache(redis, testutil.NewMockDB(), time.Hour)
+
+		if _, _, ok := c.Get(ctx, key); ok {
+			t.Fatal("expected miss on empty cache")
+		}
+		c.Put(ctx, key, resp)
+
+		got, tier, ok := c.Get(ctx, key)
+		if !ok || tier != TierRedis || got.Meaning != "overtime" {
+			t.Fatalf("expected redis hit, got %v %q %+v", ok, tier, got)
+		}
+	})
+
+	t.Run("postgres hit backfills redis", func(t *testing.T) {
+		db := testutil.NewMockDB()
+		NewAnnotationCache(nil, db, time.Hour).Put(ctx, key, resp)
+
+		redis := newMemoryRedis()
+		c := NewAnnotationCache(redis, db, time.Hour)
+		if _, tier, ok := c.Get(ctx, key); !ok || tier != TierPostgres {
+			t.Fatalf("expected postgres hit, got %v %q", ok, tier)
+		}
+		if _, ok := redis.values[redisKey(key.Hash())]; !ok {
+			t.Fatal("expected postgres hit to be copied into redis")
+		}
+	})
+
+	t.Run("redis errors fall back to postgres", func(t *testing.T) {
+		redis := newMemoryRedis()
+		redis.err = errors.New("connection refused")
+		c := NewAnnotationCache(redis, testutil.NewMockDB(), time.Hour)
+
+		c.Put(ctx, key, resp)
+		if _, tier, ok := c.Get(ctx, key); !ok || tier != TierPostgres {
+			t.Fatalf("expected postgres hit, got %v %q", ok, tier)
+		}
+	})
+
+	t.Run("expired postgres entries miss", func(t *testing.T) {
+		c := NewAnnotationCache(nil, testutil.NewMockDB(), time.Hour)
+		c.Put(ctx, key, resp)
+
+		c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		if _, _, ok := c.Get(ctx, key); ok {
+			t.Fatal("expected expired entry to miss")
+		}
+	})
+}
+
+type purgeDB struct {
+	*testutil.MockDB
+	purged chan struct{}
+}
+
+func (p *purgeDB) DeleteExpiredAnnotationCacheEntries(ctx context.Context, now time.Time) (int64, error) {
+	deleted, err := p.MockDB.DeleteExpiredAnnotationCacheEntries(ctx, now)
+	select {
+	case p.purged <- struct{}{}:
+	default:
+	}
+	return deleted, err
+}
+
+func TestAnnotationCachePurgeExpired(t *testing.T) {
+	db := &purgeDB{MockDB: testutil.NewMockDB(), purged: make(chan struct{}, 1)}
+	c := NewAnnotationCache(nil, db, time.Hour)
+	c.Put(context.Background(), AnnotationKey{Text: "残業"}, &gemini.AnnotationResponse{})
+	c.ttl = 3 * time.Hour
+	c.Put(context.Background(), AnnotationKey{Text: "定時"}, &gemini.AnnotationResponse{})
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.PurgeExpired(ctx, time.Millisecond)
+		close(done)
+	}()
+	<-db.purged
+	cancel()
+	<-done
+
+	if got := db.AnnotationCacheEntries(); got != 1 {
+		t.Fatalf("expected only the unexpired entry to remain, got %d", got)
+	}
+}
+
+func TestAnnotationCacheMetrics(t *testing.T) {
+	misses := counter("misses")
+	NewAnnotationCache(nil, testutil.NewMockDB(), time.Hour).Get(context.Background(), AnnotationKey{Text: "x"})
+	if got := counter("misses"); got != misses+1 {
+		t.Fatalf("expected misses to go from %d to %d, got %d", misses, misses+1, got)
+	}
+}
+
+func counter(name string) int64 {
+	if v, ok := metrics.Get(name).(interface{ Value() int64 }); ok {
+		return v.Value()
+	}
+	return 0
+}
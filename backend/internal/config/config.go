@@ -2,17 +2,30 @@ package config
 
 import (
 	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
+const (
+	AIProviderGemini           = "gemini"
+	AIProviderFake             = "fake"
+	AIProviderOpenAICompatible = "openai-compatible"
+)
+
 type Config struct {
-	GeminiAPIKey       string
-	AppBaseURL         string
-	FrontendBaseURL    string
-	Port               string
+	AIProvider      string
+	GeminiAPIKey    string
+	AppBaseURL      string
+	FrontendBaseURL string
+	Port            string
+	// DebugAddr serves runtime counters on /debug/vars on a separate listener, which should
+	// only be reachable internally (e.g. "127.0.0.1:6060"). Empty disables it.
+	DebugAddr          string
 	DBConnectionString string
 	UploadDir          string
 	MaxUploadSize      int64
@@ -23,48 +36,68 @@ type Config struct {
 	GoogleOAuthClientSecret string
 	RedisAddr               string
 	JWTSecret               string
+	// CursorSecret signs pagination cursors. It defaults to a key derived from JWTSecret, so a
+	// cursor signature never doubles as a token signature.
+	CursorSecret            string
 	TokenExpiryMinutes      int
 	DefaultPageSize         int
+	MaxBatchUploadFiles     int
 	KnowledgeCSVPath        string
+	AnnotationCacheTTLHours int
+
+	// AdminEmails may edit the knowledge base. Replicas pick up edits made elsewhere every
+	// KnowledgeReloadIntervalSeconds.
+	AdminEmails                    []string
+	KnowledgeReloadIntervalSeconds int
+
+	OCRWorkerCount            int
+	OCRJobMaxAttempts         int
+	OCRJobPollIntervalSeconds int
+
+	ScanEventsRedisFanout bool
+
+	// Whole-scan speech: sentences synthesized at once per request, and the most sentences
+	// a scan may be split into.
+	ScanAudioConcurrency  int
+	ScanAudioMaxSentences int
+
+	// Per-user AI limits by route class: token buckets refilled per minute, and calls per
+	// UTC day. Zero disables a limit.
+	RateLimitOCRPerMinute     int
+	RateLimitAnalyzePerMinute int
+	RateLimitSpeechPerMinute  int
+	DailyQuotaOCR             int
+	DailyQuotaAnalyze         int
+	DailyQuotaSpeech          int
+
+	// OpenAI-compatible provider (AI_PROVIDER=openai-compatible)
+	OpenAIBaseURL     string
+	OpenAIAPIKey      string
+	OpenAIModel       string
+	OpenAISpeechModel string
+	OpenAIVoice       string
 }
 
 func Load() (*Config, error) {
-	// Try loading from current directory and parent directory (project root)
-	loadEnvFile(".env.local")
-	loadEnvFile("../.env.local")
-	loadEnvFile(".env")
-	loadEnvFile("../.env")
+	loadEnvFiles()
 
 	geminiAPIKey := os.Getenv("GOOGLE_API_KEY")
 	if geminiAPIKey == "" {
 		geminiAPIKey = os.Getenv("GEMINI_API_KEY")
 	}
 
-	// Build PostgreSQL connection string if individual components are provided
-	dbConnStr := os.Getenv("DB_CONNECTION_STRING")
-	if dbConnStr == "" {
-		// Build from individual components
-		host := getEnvOrDefault("POSTGRES_HOST", "localhost")
-		port := getEnvOrDefault("POSTGRES_PORT", "5432")
-		user := getEnvOrDefault("POSTGRES_USER", "gemini_user")
-		password := getEnvOrDefault("POSTGRES_PASSWORD", "gemini_password")
-		dbname := getEnvOrDefault("POSTGRES_DB", "gemini_db")
-		sslmode := getEnvOrDefault("POSTGRES_SSLMODE", "disable")
-
-		if host != "" && port != "" && user != "" && password != "" && dbname != "" {
-			dbConnStr = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-				host, port, user, password, dbname, sslmode)
-		}
-	}
+	dbConnStr := databaseConnectionString()
 
 	appBaseURL := getEnvOrDefault("APP_BASE_URL", "http://localhost:8080")
 	frontendBaseURL := getEnvOrDefault("FRONTEND_BASE_URL", appBaseURL)
 
 	cfg := &Config{
+		AIProvider:              getEnvOrDefault("AI_PROVIDER", AIProviderGemini),
 		GeminiAPIKey:            geminiAPIKey,
 		AppBaseURL:              appBaseURL,
 		FrontendBaseURL:         frontendBaseURL,
 		Port:                    getEnvOrDefault("PORT", "8080"),
+		DebugAddr:               os.Getenv("DEBUG_ADDR"),
 		DBConnectionString:      dbConnStr,
 		UploadDir:               getEnvOrDefault("UPLOAD_DIR", "data/uploads"),
 		MaxUploadSize:           getEnvAsInt64OrDefault("MAX_UPLOAD_SIZE", 10*1024*1024),
@@ -74,9 +107,37 @@ func Load() (*Config, error) {
 		GoogleOAuthClientSecret: os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
 		RedisAddr:               getEnvOrDefault("REDIS_ADDR", "localhost:6379"),
 		JWTSecret:               os.Getenv("JWT_SECRET"),
+		CursorSecret:            getEnvOrDefault("CURSOR_SECRET", deriveSecret(os.Getenv("JWT_SECRET"), "cursor")),
 		TokenExpiryMinutes:      getEnvAsIntOrDefault("TOKEN_EXPIRY_MINUTES", 30),
 		DefaultPageSize:         getEnvAsIntOrDefault("DEFAULT_PAGE_SIZE", 20),
+		MaxBatchUploadFiles:     getEnvAsIntOrDefault("MAX_BATCH_UPLOAD_FILES", 20),
 		KnowledgeCSVPath:        getEnvOrDefault("KNOWLEDGE_CSV_PATH", "data/knowledge.csv"),
+		AnnotationCacheTTLHours: getEnvAsIntOrDefault("ANNOTATION_CACHE_TTL_HOURS", 24*30),
+
+		AdminEmails:                    getEnvAsListOrDefault("ADMIN_EMAILS", nil),
+		KnowledgeReloadIntervalSeconds: getEnvAsIntOrDefault("KNOWLEDGE_RELOAD_INTERVAL_SECONDS", 60),
+
+		OCRWorkerCount:            getEnvAsIntOrDefault("OCR_WORKER_COUNT", 2),
+		OCRJobMaxAttempts:         getEnvAsIntOrDefault("OCR_JOB_MAX_ATTEMPTS", 5),
+		OCRJobPollIntervalSeconds: getEnvAsIntOrDefault("OCR_JOB_POLL_INTERVAL_SECONDS", 2),
+
+		ScanEventsRedisFanout: getEnvAsBoolOrDefault("SCAN_EVENTS_REDIS_FANOUT", false),
+
+		ScanAudioConcurrency:  getEnvAsIntOrDefault("SCAN_AUDIO_CONCURRENCY", 3),
+		ScanAudioMaxSentences: getEnvAsIntOrDefault("SCAN_AUDIO_MAX_SENTENCES", 100),
+
+		RateLimitOCRPerMinute:     getEnvAsIntOrDefault("RATE_LIMIT_OCR_PER_MINUTE", 10),
+		RateLimitAnalyzePerMinute: getEnvAsIntOrDefault("RATE_LIMIT_ANALYZE_PER_MINUTE", 30),
+		RateLimitSpeechPerMinute:  getEnvAsIntOrDefault("RATE_LIMIT_SPEECH_PER_MINUTE", 20),
+		DailyQuotaOCR:             getEnvAsIntOrDefault("DAILY_QUOTA_OCR", 200),
+		DailyQuotaAnalyze:         getEnvAsIntOrDefault("DAILY_QUOTA_ANALYZE", 1000),
+		DailyQuotaSpeech:          getEnvAsIntOrDefault("DAILY_QUOTA_SPEECH", 500),
+
+		OpenAIBaseURL:     getEnvOrDefault("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIAPIKey:      os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:       getEnvOrDefault("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAISpeechModel: getEnvOrDefault("OPENAI_SPEECH_MODEL", "tts-1"),
+		OpenAIVoice:       getEnvOrDefault("OPENAI_VOICE", "alloy"),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -86,9 +147,58 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// LoadDatabase returns only the database connection string, for tools that do not need the
+// rest of the configuration.
+func LoadDatabase() (string, error) {
+	loadEnvFiles()
+	dbConnStr := databaseConnectionString()
+	if dbConnStr == "" {
+		return "", fmt.Errorf("DB_CONNECTION_STRING or PostgreSQL connection details are required")
+	}
+	return dbConnStr, nil
+}
+
+func loadEnvFiles() {
+	// Try loading from current directory and parent directory (project root)
+	loadEnvFile(".env.local")
+	loadEnvFile("../.env.local")
+	loadEnvFile(".env")
+	loadEnvFile("../.env")
+}
+
+func databaseConnectionString() string {
+	// Build PostgreSQL connection string if individual components are provided
+	dbConnStr := os.Getenv("DB_CONNECTION_STRING")
+	if dbConnStr == "" {
+		// Build from individual components
+		host := getEnvOrDefault("POSTGRES_HOST", "localhost")
+		port := getEnvOrDefault("POSTGRES_PORT", "5432")
+		user := getEnvOrDefault("POSTGRES_USER", "gemini_user")
+		password := getEnvOrDefault("POSTGRES_PASSWORD", "gemini_password")
+		dbname := getEnvOrDefault("POSTGRES_DB", "gemini_db")
+		sslmode := getEnvOrDefault("POSTGRES_SSLMODE", "disable")
+
+		if host != "" && port != "" && user != "" && password != "" && dbname != "" {
+			dbConnStr = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+				host, port, user, password, dbname, sslmode)
+		}
+	}
+	return dbConnStr
+}
+
 func (c *Config) Validate() error {
-	if c.GeminiAPIKey == "" {
-		return fmt.Errorf("GEMINI_API_KEY or GOOGLE_API_KEY is required")
+	switch c.AIProvider {
+	case AIProviderGemini:
+		if c.GeminiAPIKey == "" {
+			return fmt.Errorf("GEMINI_API_KEY or GOOGLE_API_KEY is required")
+		}
+	case AIProviderOpenAICompatible:
+		if c.OpenAIBaseURL == "" || c.OpenAIModel == "" {
+			return fmt.Errorf("OPENAI_BASE_URL and OPENAI_MODEL are required for AI_PROVIDER=%s", c.AIProvider)
+		}
+	case AIProviderFake:
+	default:
+		return fmt.Errorf("AI_PROVIDER must be one of %s, %s, %s", AIProviderGemini, AIProviderFake, AIProviderOpenAICompatible)
 	}
 	if c.DBConnectionString == "" {
 		return fmt.Errorf("DB_CONNECTION_STRING or PostgreSQL connection details are required")
@@ -108,9 +218,65 @@ func (c *Config) Validate() error {
 	if c.DefaultPageSize <= 0 {
 		return fmt.Errorf("DEFAULT_PAGE_SIZE must be positive")
 	}
+	if c.MaxBatchUploadFiles <= 0 {
+		return fmt.Errorf("MAX_BATCH_UPLOAD_FILES must be positive")
+	}
+	if c.ScanAudioConcurrency <= 0 {
+		return fmt.Errorf("SCAN_AUDIO_CONCURRENCY must be positive")
+	}
+	if c.ScanAudioMaxSentences <= 0 {
+		return fmt.Errorf("SCAN_AUDIO_MAX_SENTENCES must be positive")
+	}
+	if c.AnnotationCacheTTLHours < 0 {
+		return fmt.Errorf("ANNOTATION_CACHE_TTL_HOURS cannot be negative")
+	}
+	for name, value := range map[string]int{
+		"RATE_LIMIT_OCR_PER_MINUTE":     c.RateLimitOCRPerMinute,
+		"RATE_LIMIT_ANALYZE_PER_MINUTE": c.RateLimitAnalyzePerMinute,
+		"RATE_LIMIT_SPEECH_PER_MINUTE":  c.RateLimitSpeechPerMinute,
+		"DAILY_QUOTA_OCR":               c.DailyQuotaOCR,
+		"DAILY_QUOTA_ANALYZE":           c.DailyQuotaAnalyze,
+		"DAILY_QUOTA_SPEECH":            c.DailyQuotaSpeech,
+	} {
+		if value < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if c.KnowledgeReloadIntervalSeconds <= 0 {
+		return fmt.Errorf("KNOWLEDGE_RELOAD_INTERVAL_SECONDS must be positive")
+	}
+	if c.OCRWorkerCount <= 0 {
+		return fmt.Errorf("OCR_WORKER_COUNT must be positive")
+	}
+	if c.OCRJobMaxAttempts <= 0 {
+		return fmt.Errorf("OCR_JOB_MAX_ATTEMPTS must be positive")
+	}
+	if c.OCRJobPollIntervalSeconds <= 0 {
+		return fmt.Errorf("OCR_JOB_POLL_INTERVAL_SECONDS must be positive")
+	}
 	return nil
 }
 
+// IsAdmin reports whether the email belongs to an administrator. Emails compare case-insensitively.
+func (c *Config) IsAdmin(email string) bool {
+	for _, admin := range c.AdminEmails {
+		if email != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// deriveSecret returns a subkey of secret for purpose, or "" when secret is empty.
+func deriveSecret(secret, purpose string) string {
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -145,6 +311,21 @@ func getEnvAsBoolOrDefault(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvAsListOrDefault splits a comma-separated value, dropping empty items.
+func getEnvAsListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func loadEnvFile(filename string) {
 	// Try multiple locations: current dir, parent dir (project root)
 	paths := []string{
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Scan event types, in the order a scan normally goes through them.
+const (
+	TypeQueued       = "queued"
+	TypeOCRStarted   = "ocr_started"
+	TypeOCRCompleted = "ocr_completed"
+	TypeOCRFailed    = "ocr_failed"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 16
+
+// ScanEvent is a progress update for one scan's OCR pipeline.
+type ScanEvent struct {
+	ScanID   int64  `json:"scanId"`
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Language string `json:"language,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// IsTerminal reports whether no further events are expected for the scan.
+func (e ScanEvent) IsTerminal() bool {
+	return e.Type == TypeOCRCompleted || e.Type == TypeOCRFailed
+}
+
+// Broker delivers scan events to subscribers keyed by scan ID.
+type Broker interface {
+	Publish(ctx context.Context, event ScanEvent)
+	// Subscribe returns a channel of events for the scan and a function that
+	// must be called to release the subscription.
+	Subscribe(scanID int64) (<-chan ScanEvent, func())
+}
+
+// Hub is an in-process Broker.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan ScanEvent]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[int64]map[chan ScanEvent]struct{}),
+	}
+}
+
+func (h *Hub) Publish(ctx context.Context, event ScanEvent) {
+	h.deliver(event)
+}
+
+func (h *Hub) Subscribe(scanID int64) (<-chan ScanEvent, func()) {
+	ch := make(chan ScanEvent, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[scanID] == nil {
+		h.subscribers[scanID] = make(map[chan ScanEvent]struct{})
+	}
+	h.subscribers[scanID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[scanID], ch)
+			if len(h.subscribers[scanID]) == 0 {
+				delete(h.subscribers, scanID)
+			}
+			h.mu.Unlock()
+		})
+	}
+	return ch, unsubscribe
+}
+
+// deliver fans an event out to local subscribers without blocking the publisher.
+func (h *Hub) deliver(event ScanEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.ScanID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
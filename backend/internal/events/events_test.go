@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+func receive(t *testing.T, ch <-chan ScanEvent) ScanEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return ScanEvent{}
+	}
+}
+
+func TestHubDeliversOnlyToMatchingScan(t *testing.T) {
+	hub := NewHub()
+	first, unsubscribeFirst := hub.Subscribe(1)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := hub.Subscribe(2)
+	defer unsubscribeSecond()
+
+	hub.Publish(context.Background(), ScanEvent{ScanID: 1, Type: TypeOCRStarted})
+
+	if event := receive(t, first); event.Type != TypeOCRStarted {
+		t.Fatalf("expected %q, got %q", TypeOCRStarted, event.Type)
+	}
+	select {
+	case event := <-second:
+		t.Fatalf("unexpected event for scan 2: %+v", event)
+	default:
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+	ch, unsubscribe := hub.Subscribe(1)
+	unsubscribe()
+	unsubscribe()
+
+	hub.Publish(context.Background(), ScanEvent{ScanID: 1, Type: TypeQueued})
+
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected event after unsubscribe: %+v", event)
+	default:
+	}
+	if len(hub.subscribers) != 0 {
+		t.Fatalf("expected no subscribers left, got %d", len(hub.subscribers))
+	}
+}
+
+func TestHubDropsEventsForSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	_, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			hub.Publish(context.Background(), ScanEvent{ScanID: 1, Type: TypeQueued})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+}
+
+func TestScanEventIsTerminal(t *testing.T) {
+	for _, tt := range []struct {
+		eventType string
+		terminal  bool
+	}{
+		{TypeQueued, false},
+		{TypeOCRStarted, false},
+		{TypeOCRCompleted, true},
+		{TypeOCRFailed, true},
+	} {
+		if got := (ScanEvent{Type: tt.eventType}).IsTerminal(); got != tt.terminal {
+			t.Errorf("IsTerminal(%q) = %v; want %v", tt.eventType, got, tt.terminal)
+		}
+	}
+}
+
+// fakeRedis drops every published message, as if the subscription were down.
+type fakeRedis struct {
+	storage.RedisClient
+}
+
+func (fakeRedis) Publish(ctx context.Context, channel, message string) error {
+	return nil
+}
+
+func TestRedisBrokerDeliversLocallyWhileUnsubscribed(t *testing.T) {
+	broker := NewRedisBroker(NewHub(), fakeRedis{})
+	events, unsubscribe := broker.Subscribe(1)
+	defer unsubscribe()
+
+	broker.Publish(context.Background(), ScanEvent{ScanID: 1, Type: TypeOCRStarted})
+
+	if event := receive(t, events); event.Type != TypeOCRStarted {
+		t.Fatalf("expected %q, got %q", TypeOCRStarted, event.Type)
+	}
+}
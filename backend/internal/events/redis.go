@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const redisChannel = "scan_events"
+
+// Delays between attempts to resubscribe after the Redis subscription drops.
+const (
+	minResubscribeDelay = time.Second
+	maxResubscribeDelay = time.Minute
+)
+
+// RedisBroker fans events out across replicas through Redis pub/sub. Every
+// replica publishes to the shared channel and delivers what it receives to its
+// own in-process Hub, so an SSE client sees events no matter which replica ran OCR.
+//
+// While the subscription is down, events published here go straight to the local Hub as
+// well, so subscribers on this replica keep hearing about scans it processes.
+type RedisBroker struct {
+	hub        *Hub
+	redis      storage.RedisClient
+	subscribed atomic.Bool
+}
+
+func NewRedisBroker(hub *Hub, redis storage.RedisClient) *RedisBroker {
+	return &RedisBroker{hub: hub, redis: redis}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, event ScanEvent) {
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = b.redis.Publish(ctx, redisChannel, string(payload))
+	}
+	if err != nil {
+		// Local subscribers should still hear about it.
+		logger.GetDefaultLogger().WithField("scan_id", event.ScanID).Warnf("Failed to publish scan event to Redis: %v", err)
+		b.hub.deliver(event)
+	} else if !b.subscribed.Load() {
+		// It won't come back through Redis.
+		b.hub.deliver(event)
+	}
+}
+
+func (b *RedisBroker) Subscribe(scanID int64) (<-chan ScanEvent, func()) {
+	return b.hub.Subscribe(scanID)
+}
+
+// Run forwards events from Redis to the local hub until ctx is cancelled. When the
+// subscription fails or drops, it resubscribes with exponential backoff.
+func (b *RedisBroker) Run(ctx context.Context) {
+	log := logger.GetDefaultLogger()
+	delay := minResubscribeDelay
+	for {
+		messages, err := b.redis.Subscribe(ctx, redisChannel)
+		if err == nil {
+			b.subscribed.Store(true)
+			delay = minResubscribeDelay
+			b.forward(messages)
+			b.subscribed.Store(false)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warnf("Failed to subscribe to scan events in Redis, retrying in %s: %v", delay, err)
+		} else {
+			log.Warnf("Scan event subscription to Redis dropped, resubscribing in %s", delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
+
+func (b *RedisBroker) forward(messages <-chan string) {
+	for payload := range messages {
+		var event ScanEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			logger.GetDefaultLogger().Warnf("Ignoring malformed scan event from Redis: %v", err)
+			continue
+		}
+		b.hub.deliver(event)
+	}
+}
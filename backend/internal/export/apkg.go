@@ -0,0 +1,425 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// modelID identifies the note type in every deck we export, so importing a second export
+// reuses the note type the first one created.
+const modelID = 1731456000000
+
+const collectionFile = "collection.anki2"
+
+var errClosed = errors.New("export: writer is closed")
+
+// ankiSchema is the legacy (schema 11) collection layout every Anki version can import.
+const ankiSchema = `
+CREATE TABLE col (
+	id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+	conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+	id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null,
+	csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+	id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null,
+	due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+	lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+	flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+	id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+	type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+const (
+	frontTemplate = `<div class="front">{{Front}}</div>
+{{#Context}}<div class="context">{{Context}}</div>{{/Context}}
+{{Audio}}`
+	backTemplate = `{{FrontSide}}
+<hr id="answer">
+{{#Meaning}}<div class="meaning">{{Meaning}}</div>{{/Meaning}}
+{{#UsageExample}}<h4>Example</h4><div>{{UsageExample}}</div>{{/UsageExample}}
+{{#UsageTiming}}<h4>When to use</h4><div>{{UsageTiming}}</div>{{/UsageTiming}}
+{{#WordBreakdown}}<h4>Word breakdown</h4><div>{{WordBreakdown}}</div>{{/WordBreakdown}}
+{{#AlternativeMeaning}}<h4>Other meanings</h4><div>{{AlternativeMeaning}}</div>{{/AlternativeMeaning}}
+{{#Notes}}<h4>Notes</h4><div class="notes">{{Notes}}</div>{{/Notes}}`
+	cardCSS = `.card { font-family: sans-serif; font-size: 18px; text-align: left; }
+.front { font-size: 32px; text-align: center; }
+.context { color: #666; margin-top: 8px; text-align: center; }
+.meaning { font-size: 22px; }
+.notes { font-style: italic; }`
+)
+
+type apkgWriter struct {
+	w      io.Writer
+	media  Media
+	dir    string
+	db     *sql.DB
+	tx     *sql.Tx
+	now    time.Time
+	deckID int64
+
+	lastNoteID int64
+	notes      int
+	// mediaNames maps the hashes of clips already copied to their file names in the deck.
+	mediaNames map[string]string
+	mediaFiles []string
+	err        error
+}
+
+// NewAPKG returns a Writer of an Anki deck package named deckName. Notes and cards go into a
+// SQLite collection in a temporary directory; Close zips it, with the clips media finds for
+// annotations that have audio, and streams the package to w. Nothing is written to w before
+// Close. media may be nil to leave audio out.
+func NewAPKG(ctx context.Context, w io.Writer, deckName string, media Media, now time.Time) (Writer, error) {
+	dir, err := os.MkdirTemp("", "apkg-*")
+	if err != nil {
+		return nil, err
+	}
+	a := &apkgWriter{
+		w:          w,
+		media:      media,
+		dir:        dir,
+		now:        now,
+		deckID:     now.UnixMilli(),
+		mediaNames: make(map[string]string),
+	}
+	if err := a.open(ctx, deckName); err != nil {
+		a.cleanup()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *apkgWriter) open(ctx context.Context, deckName string) error {
+	db, err := sql.Open("sqlite", filepath.Join(a.dir, collectionFile))
+	if err != nil {
+		return err
+	}
+	a.db = db
+	if _, err := db.ExecContext(ctx, ankiSchema); err != nil {
+		return fmt.Errorf("failed to create anki schema: %w", err)
+	}
+
+	conf, noteTypes, decks, dconf, err := a.collectionConfig(deckName)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		a.now.Unix(), a.now.UnixMilli(), a.now.UnixMilli(), conf, noteTypes, decks, dconf,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write anki collection: %w", err)
+	}
+
+	a.tx, err = db.BeginTx(ctx, nil)
+	return err
+}
+
+func (a *apkgWriter) Add(ctx context.Context, annotation *models.Annotation) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.err = a.add(ctx, annotation)
+	return a.err
+}
+
+func (a *apkgWriter) add(ctx context.Context, annotation *models.Annotation) error {
+	fs := fields(annotation)
+	values := make([]string, 0, len(fs)+1)
+	for _, f := range fs {
+		values = append(values, strings.ReplaceAll(html.EscapeString(f.value), "\n", "<br>"))
+	}
+	sound, err := a.addMedia(ctx, annotation)
+	if err != nil {
+		return err
+	}
+	if sound != "" {
+		sound = "[sound:" + sound + "]"
+	}
+	values = append(values, sound)
+
+	// Anki wants unique millisecond-style IDs; follow the creation time but never repeat.
+	noteID := max(annotation.CreatedAt.UnixMilli(), a.lastNoteID+1)
+	a.lastNoteID = noteID
+	a.notes++
+
+	sortField := annotation.HighlightedText
+	checksum := sha1.Sum([]byte(sortField))
+	tags := ankiTags(annotation.Tags)
+	if tags != "" {
+		tags = " " + tags + " "
+	}
+	mod := a.now.Unix()
+
+	_, err = a.tx.ExecContext(ctx,
+		`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+		noteID, noteGUID(annotation.ID), modelID, mod, tags, strings.Join(values, "\x1f"),
+		sortField, binary.BigEndian.Uint32(checksum[:4]),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write anki note: %w", err)
+	}
+	_, err = a.tx.ExecContext(ctx,
+		`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+		noteID, noteID, a.deckID, mod, a.notes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write anki card: %w", err)
+	}
+	return nil
+}
+
+// addMedia copies the annotation's clip into the package and returns its file name, or ""
+// when the annotation has no stored audio.
+func (a *apkgWriter) addMedia(ctx context.Context, annotation *models.Annotation) (string, error) {
+	if a.media == nil || annotation.AudioHash == nil {
+		return "", nil
+	}
+	hash := *annotation.AudioHash
+	if name, ok := a.mediaNames[hash]; ok {
+		return name, nil
+	}
+
+	content, mimeType, err := a.media(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if content == nil {
+		return "", nil
+	}
+	defer content.Close()
+
+	ext := audioExtension(mimeType)
+	if ext == "" {
+		return "", nil
+	}
+	// Inside the package media files are numbered; the media manifest maps them to names.
+	f, err := os.Create(filepath.Join(a.dir, strconv.Itoa(len(a.mediaFiles))))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	name := hash[:min(len(hash), 16)] + ext
+	a.mediaNames[hash] = name
+	a.mediaFiles = append(a.mediaFiles, name)
+	return name, nil
+}
+
+func (a *apkgWriter) Close() error {
+	defer a.cleanup()
+	if a.err != nil {
+		return a.err
+	}
+	if err := a.finish(); err != nil {
+		a.err = err
+		return err
+	}
+	a.err = errClosed
+	return nil
+}
+
+func (a *apkgWriter) finish() error {
+	// New cards are numbered from nextPos, so cards added in Anki later sort after ours.
+	conf, err := json.Marshal(a.colConf())
+	if err != nil {
+		return err
+	}
+	if _, err := a.tx.Exec(`UPDATE col SET conf = ?`, string(conf)); err != nil {
+		return err
+	}
+	if err := a.tx.Commit(); err != nil {
+		return err
+	}
+	a.tx = nil
+	if err := a.db.Close(); err != nil {
+		return err
+	}
+	a.db = nil
+
+	zw := zip.NewWriter(a.w)
+	if err := a.zipFile(zw, collectionFile, filepath.Join(a.dir, collectionFile)); err != nil {
+		return err
+	}
+	manifest := make(map[string]string, len(a.mediaFiles))
+	for i, name := range a.mediaFiles {
+		entry := strconv.Itoa(i)
+		manifest[entry] = name
+		if err := a.zipFile(zw, entry, filepath.Join(a.dir, entry)); err != nil {
+			return err
+		}
+	}
+	mw, err := zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(mw).Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (a *apkgWriter) zipFile(zw *zip.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.now})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, f)
+	return err
+}
+
+func (a *apkgWriter) cleanup() {
+	if a.tx != nil {
+		a.tx.Rollback()
+		a.tx = nil
+	}
+	if a.db != nil {
+		a.db.Close()
+		a.db = nil
+	}
+	os.RemoveAll(a.dir)
+}
+
+// collectionConfig returns the JSON blobs of the col row: a single deck and note type.
+func (a *apkgWriter) collectionConfig(deckName string) (conf, noteTypes, decks, dconf string, err error) {
+	mod := a.now.Unix()
+
+	fieldDefs := make([]map[string]any, 0, len(fieldNames)+1)
+	for i, name := range append(fieldNames, "Audio") {
+		fieldDefs = append(fieldDefs, map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		})
+	}
+	noteType := map[string]any{
+		"id": modelID, "name": "Annotation", "type": 0, "mod": mod, "usn": -1,
+		"sortf": 0, "did": a.deckID, "flds": fieldDefs, "css": cardCSS,
+		"tmpls": []map[string]any{{
+			"name": "Card 1", "ord": 0, "qfmt": frontTemplate, "afmt": backTemplate,
+			"did": nil, "bqfmt": "", "bafmt": "",
+		}},
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []any{},
+		"req":       []any{[]any{0, "any", []int{0}}},
+	}
+
+	deck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "mod": mod, "usn": -1, "desc": "", "dyn": 0, "conf": 1,
+			"collapsed": false, "extendNew": 10, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	deckConf := map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+		"timer": 0, "replayq": true, "dyn": false,
+		"new": map[string]any{
+			"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+			"order": 1, "perDay": 20, "bury": true, "separate": true,
+		},
+		"rev": map[string]any{
+			"perDay": 100, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500,
+			"minSpace": 1, "bury": true,
+		},
+		"lapse": map[string]any{
+			"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+		},
+	}
+
+	blobs := []any{
+		a.colConf(),
+		map[string]any{strconv.FormatInt(modelID, 10): noteType},
+		map[string]any{"1": deck(1, "Default"), strconv.FormatInt(a.deckID, 10): deck(a.deckID, deckName)},
+		map[string]any{"1": deckConf},
+	}
+	encoded := make([]string, len(blobs))
+	for i, blob := range blobs {
+		data, err := json.Marshal(blob)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		encoded[i] = string(data)
+	}
+	return encoded[0], encoded[1], encoded[2], encoded[3], nil
+}
+
+func (a *apkgWriter) colConf() map[string]any {
+	return map[string]any{
+		"nextPos": a.notes + 1, "estTimes": true, "activeDecks": []int64{a.deckID},
+		"sortType": "noteFld", "timeLim": 0, "sortBackwards": false, "addToCur": true,
+		"curDeck": a.deckID, "newBury": true, "newSpread": 0, "dueCounts": true,
+		"curModel": modelID, "collapseTime": 1200,
+	}
+}
+
+// noteGUID derives a note's GUID from its annotation, so importing a newer export of the same
+// annotations updates the notes instead of duplicating them.
+func noteGUID(annotationID int64) string {
+	sum := sha256.Sum256([]byte("annotation\x00" + strconv.FormatInt(annotationID, 10)))
+	return base64.RawStdEncoding.EncodeToString(sum[:8])
+}
+
+func audioExtension(mimeType string) string {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0])) {
+	case "audio/wav", "audio/wave", "audio/x-wav", "audio/vnd.wave":
+		return ".wav"
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/ogg", "audio/opus":
+		return ".ogg"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+type delimitedWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+	err         error
+}
+
+// NewCSV returns a Writer of comma-separated values with a header row. Tags are space
+// separated, as Anki's importer expects.
+func NewCSV(w io.Writer) Writer {
+	return newDelimited(w, ',')
+}
+
+// NewTSV is NewCSV with tabs between fields.
+func NewTSV(w io.Writer) Writer {
+	return newDelimited(w, '\t')
+}
+
+func newDelimited(w io.Writer, comma rune) *delimitedWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &delimitedWriter{w: cw}
+}
+
+func (d *delimitedWriter) writeHeader() {
+	if d.wroteHeader || d.err != nil {
+		return
+	}
+	d.wroteHeader = true
+	header := make([]string, 0, len(fieldNames)+2)
+	for _, name := range fieldNames {
+		header = append(header, lowerFirst(name))
+	}
+	d.err = d.w.Write(append(header, "tags", "createdAt"))
+}
+
+func (d *delimitedWriter) Add(ctx context.Context, a *models.Annotation) error {
+	d.writeHeader()
+	if d.err != nil {
+		return d.err
+	}
+	fs := fields(a)
+	record := make([]string, 0, len(fs)+2)
+	for _, f := range fs {
+		record = append(record, f.value)
+	}
+	record = append(record, ankiTags(a.Tags), a.CreatedAt.UTC().Format(time.RFC3339))
+	d.err = d.w.Write(record)
+	return d.err
+}
+
+func (d *delimitedWriter) Close() error {
+	d.writeHeader()
+	if d.err != nil {
+		return d.err
+	}
+	d.w.Flush()
+	d.err = d.w.Error()
+	return d.err
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,91 @@
+// Package export writes saved annotations to files that flashcard apps such as Anki import.
+package export
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// Export formats.
+const (
+	FormatAPKG = "apkg"
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+)
+
+// Writer adds annotations to an export one at a time, so an export never holds every
+// annotation in memory. Close must be called even after Add fails: it releases the writer's
+// resources and, when every Add succeeded, finishes the file. Errors are sticky; once one
+// occurs, later calls return it.
+type Writer interface {
+	Add(ctx context.Context, annotation *models.Annotation) error
+	Close() error
+}
+
+// Media opens the stored audio clip with the given hash and reports its MIME type. It
+// returns a nil reader when there is no clip.
+type Media func(ctx context.Context, hash string) (io.ReadCloser, string, error)
+
+// ContentType returns the Content-Type of an export in format, or "" for unknown formats.
+func ContentType(format string) string {
+	switch format {
+	case FormatAPKG:
+		return "application/octet-stream"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatTSV:
+		return "text/tab-separated-values; charset=utf-8"
+	default:
+		return ""
+	}
+}
+
+// field is one named piece of an annotation as it appears in every format.
+type field struct {
+	name  string
+	value string
+}
+
+// fields lays out an annotation as the card's front, the sentence it came from, and the
+// explanation the user saved.
+func fields(a *models.Annotation) []field {
+	return []field{
+		{"Front", a.HighlightedText},
+		{"Context", deref(a.ContextText)},
+		{"Meaning", a.NuanceData.Meaning},
+		{"UsageExample", a.NuanceData.UsageExample},
+		{"UsageTiming", a.NuanceData.UsageTiming},
+		{"WordBreakdown", a.NuanceData.WordBreakdown},
+		{"AlternativeMeaning", a.NuanceData.AlternativeMeaning},
+		{"Notes", deref(a.Notes)},
+	}
+}
+
+// fieldNames are the names fields returns, in order.
+var fieldNames = func() []string {
+	var names []string
+	for _, f := range fields(&models.Annotation{}) {
+		names = append(names, f.name)
+	}
+	return names
+}()
+
+// ankiTags formats tags the way Anki stores them: space separated, with spaces inside a tag
+// replaced so it stays one tag.
+func ankiTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = strings.Join(strings.Fields(tag), "_")
+	}
+	return strings.Join(formatted, " ")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+func testAnnotations() []*models.Annotation {
+	contextText := "今日もお疲れ様でした。"
+	notes := "said by my boss\nevery evening"
+	hash := "0123456789abcdef0123456789abcdef"
+	created := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	return []*models.Annotation{
+		{
+			ID:              1,
+			HighlightedText: "お疲れ様",
+			ContextText:     &contextText,
+			NuanceData:      models.NuanceData{Meaning: "Thanks for your hard work", UsageExample: "<b>お疲れ様です</b>"},
+			Notes:           &notes,
+			Tags:            []string{"work", "set phrase"},
+			AudioHash:       &hash,
+			CreatedAt:       created,
+		},
+		{
+			ID:              2,
+			HighlightedText: "よろしく",
+			NuanceData:      models.NuanceData{Meaning: "Please treat me well"},
+			AudioHash:       &hash,
+			CreatedAt:       created,
+		},
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTSV(&buf)
+	for _, a := range testAnnotations() {
+		if err := w.Add(context.Background(), a); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := csv.NewReader(&buf)
+	r.Comma = '\t'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read TSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(records))
+	}
+	if strings.Join(records[0], ",") != "front,context,meaning,usageExample,usageTiming,wordBreakdown,alternativeMeaning,notes,tags,createdAt" {
+		t.Errorf("unexpected header %q", records[0])
+	}
+	first := records[1]
+	if first[0] != "お疲れ様" || first[1] != "今日もお疲れ様でした。" || first[7] != "said by my boss\nevery evening" {
+		t.Errorf("unexpected row %q", first)
+	}
+	if first[8] != "work set_phrase" || first[9] != "2026-05-01T09:00:00Z" {
+		t.Errorf("unexpected tags or date %q", first[8:])
+	}
+}
+
+func TestCSVWithoutAnnotationsHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSV(&buf)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "front,context,") {
+		t.Errorf("expected a header row, got %q", buf.String())
+	}
+}
+
+func TestAPKG(t *testing.T) {
+	opened := 0
+	media := func(ctx context.Context, hash string) (io.ReadCloser, string, error) {
+		opened++
+		return io.NopCloser(strings.NewReader("RIFF-clip")), "audio/wav", nil
+	}
+
+	var buf bytes.Buffer
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	w, err := NewAPKG(context.Background(), &buf, "Japanese", media, now)
+	if err != nil {
+		t.Fatalf("NewAPKG: %v", err)
+	}
+	for _, a := range testAnnotations() {
+		if err := w.Add(context.Background(), a); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatal("expected nothing written before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if opened != 1 {
+		t.Errorf("expected the shared clip to be opened once, got %d", opened)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(files["media"], &manifest); err != nil {
+		t.Fatalf("bad media manifest: %v", err)
+	}
+	if len(manifest) != 1 || string(files["0"]) != "RIFF-clip" {
+		t.Fatalf("unexpected media %v", manifest)
+	}
+	sound := "[sound:" + manifest["0"] + "]"
+
+	path := filepath.Join(t.TempDir(), collectionFile)
+	if err := os.WriteFile(path, files[collectionFile], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT n.id, n.flds, n.tags, c.due FROM notes n JOIN cards c ON c.nid = n.id ORDER BY n.id`)
+	if err != nil {
+		t.Fatalf("failed to query notes: %v", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	var notes [][]string
+	var tags []string
+	for rows.Next() {
+		var id, due int64
+		var flds, tag string
+		rows.Scan(&id, &flds, &tag, &due)
+		ids = append(ids, id)
+		notes = append(notes, strings.Split(flds, "\x1f"))
+		tags = append(tags, tag)
+	}
+	if len(notes) != 2 || ids[0] == ids[1] {
+		t.Fatalf("expected 2 notes with distinct IDs, got %v", ids)
+	}
+	first := notes[0]
+	if first[0] != "お疲れ様" || first[3] != "&lt;b&gt;お疲れ様です&lt;/b&gt;" || first[7] != "said by my boss<br>every evening" {
+		t.Errorf("unexpected fields %q", first)
+	}
+	if first[len(first)-1] != sound || notes[1][len(notes[1])-1] != sound {
+		t.Errorf("expected both notes to play %s, got %q and %q", sound, first[len(first)-1], notes[1][len(notes[1])-1])
+	}
+	if tags[0] != " work set_phrase " {
+		t.Errorf("unexpected tags %q", tags[0])
+	}
+
+	var decks string
+	db.QueryRow(`SELECT decks FROM col`).Scan(&decks)
+	if !strings.Contains(decks, `"name":"Japanese"`) {
+		t.Errorf("deck not named: %s", decks)
+	}
+}
+
+func TestAPKGWithoutMedia(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewAPKG(context.Background(), &buf, "Japanese", nil, time.Now())
+	if err != nil {
+		t.Fatalf("NewAPKG: %v", err)
+	}
+	w.Add(context.Background(), testAnnotations()[0])
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name == "media" {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if strings.TrimSpace(string(data)) != "{}" {
+				t.Errorf("expected an empty media manifest, got %s", data)
+			}
+		}
+	}
+}
@@ -6,19 +6,25 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/models"
 	"google.golang.org/genai"
 )
 
 type Client interface {
 	OCR(ctx context.Context, imageData []byte, mimeType string) (*OCRResponse, error)
 	Annotate(ctx context.Context, ocrText string, selectedText string) (*AnnotationResponse, error)
-	AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry) (*AnnotationResponse, error)
-	SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string) (*SpeechResponse, error)
+	// AnnotateWithKnowledge explains selectedText in targetLanguage, a models.Language code.
+	// Unknown or empty codes fall back to models.DefaultLanguage.
+	AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*AnnotationResponse, error)
+	// SynthesizeSpeech reads highlightedText aloud, using contextText only to pick the delivery.
+	SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts SpeechOptions) (*SpeechResponse, error)
+	Furigana(ctx context.Context, text string) (*FuriganaResponse, error)
 }
 
 type client struct {
@@ -52,6 +58,39 @@ type OCRResponse struct {
 	RawText        string
 	StructuredJSON string
 	Language       string
+	// Orientation is the dominant writing direction: "horizontal" or "vertical".
+	Orientation string
+	// Blocks are the paragraphs of the page in reading order. Empty when the model
+	// returned plain text only.
+	Blocks []OCRBlock
+}
+
+const (
+	OrientationHorizontal = "horizontal"
+	OrientationVertical   = "vertical"
+)
+
+// OCRBlock is one paragraph (or heading, caption, ...) of recognized text.
+type OCRBlock struct {
+	Text        string
+	Orientation string
+	Box         BoundingBox
+	Lines       []OCRLine
+}
+
+// OCRLine is a single line (a column, for vertical text) inside a block.
+type OCRLine struct {
+	Text string
+	Box  BoundingBox
+}
+
+// BoundingBox is a rectangle in image coordinates normalized to 0..1, with the origin
+// at the top-left corner.
+type BoundingBox struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
 }
 
 type AnnotationResponse struct {
@@ -62,6 +101,19 @@ type AnnotationResponse struct {
 	AlternativeMeanings string `json:"alternative_meanings"`
 }
 
+// FuriganaResponse is text split into tokens; concatenating the surfaces gives back the
+// original text.
+type FuriganaResponse struct {
+	Tokens []FuriganaToken `json:"tokens"`
+}
+
+// FuriganaToken is a word or run of characters. Reading is the hiragana reading of the
+// surface and is empty for tokens without kanji.
+type FuriganaToken struct {
+	Surface string `json:"surface"`
+	Reading string `json:"reading"`
+}
+
 type SpeechResponse struct {
 	Audio    []byte
 	MIMEType string
@@ -75,10 +127,8 @@ func (c *client) OCR(ctx context.Context, imageData []byte, mimeType string) (*O
 		return nil, fmt.Errorf("gemini client not initialized: check API key")
 	}
 
-	prompt := "Extract all Japanese text from this image. Return ONLY a JSON object with keys 'raw_text' (the extracted text) and 'language' (detected language code 'JP' for japan). Preserve line breaks and formatting. Do not include markdown, code fences, or any extra text."
-
 	parts := []*genai.Part{
-		{Text: prompt},
+		{Text: OCRPrompt},
 		{
 			InlineData: &genai.Blob{
 				Data:     imageData,
@@ -89,15 +139,7 @@ func (c *client) OCR(ctx context.Context, imageData []byte, mimeType string) (*O
 
 	cfg := &genai.GenerateContentConfig{
 		ResponseMIMEType: "application/json",
-		ResponseSchema: &genai.Schema{
-			Type: genai.TypeObject,
-			Properties: map[string]*genai.Schema{
-				"raw_text": {Type: genai.TypeString},
-				"language": {Type: genai.TypeString},
-			},
-			Required:         []string{"raw_text", "language"},
-			PropertyOrdering: []string{"raw_text", "language"},
-		},
+		ResponseSchema:   ocrResponseSchema(),
 	}
 
 	var result *genai.GenerateContentResponse
@@ -132,37 +174,147 @@ func (c *client) OCR(ctx context.Context, imageData []byte, mimeType string) (*O
 		return nil, fmt.Errorf("empty response from API")
 	}
 
-	var structured struct {
-		RawText  string `json:"raw_text"`
-		Language string `json:"language"`
+	return ParseOCRResult(text), nil
+}
+
+// OCRPrompt asks for the page text plus its layout as JSON in the shape read by ParseOCRResult.
+const OCRPrompt = `Extract all Japanese text from this image. Return ONLY a JSON object with these keys:
+- raw_text: the full extracted text in reading order. Preserve line breaks and formatting.
+- language: detected language code ('JP' for Japanese).
+- orientation: the dominant writing direction of the page, "horizontal" or "vertical".
+- blocks: the paragraphs, headings and captions of the page, listed in reading order. Vertical Japanese text is read in columns from top to bottom, with columns going from right to left; horizontal text is read in rows from left to right, top to bottom. Each block has text, orientation, box_2d and lines; each line (a column for vertical text) has text and box_2d.
+Every box_2d is [ymin, xmin, ymax, xmax] with coordinates normalized to 0-1000.
+Do not include markdown, code fences, or any extra text.`
+
+func ocrResponseSchema() *genai.Schema {
+	box := &genai.Schema{
+		Type:        genai.TypeArray,
+		Items:       &genai.Schema{Type: genai.TypeInteger},
+		Description: "[ymin, xmin, ymax, xmax] normalized to 0-1000",
+	}
+	orientation := &genai.Schema{
+		Type: genai.TypeString,
+		Enum: []string{OrientationHorizontal, OrientationVertical},
+	}
+	line := &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"text":   {Type: genai.TypeString},
+			"box_2d": box,
+		},
+		Required:         []string{"text", "box_2d"},
+		PropertyOrdering: []string{"text", "box_2d"},
+	}
+	block := &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"text":        {Type: genai.TypeString},
+			"orientation": orientation,
+			"box_2d":      box,
+			"lines":       {Type: genai.TypeArray, Items: line},
+		},
+		Required:         []string{"text", "box_2d"},
+		PropertyOrdering: []string{"text", "orientation", "box_2d", "lines"},
+	}
+
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"raw_text":    {Type: genai.TypeString},
+			"language":    {Type: genai.TypeString},
+			"orientation": orientation,
+			"blocks":      {Type: genai.TypeArray, Items: block},
+		},
+		Required:         []string{"raw_text", "language"},
+		PropertyOrdering: []string{"raw_text", "language", "orientation", "blocks"},
 	}
+}
+
+type ocrResult struct {
+	RawText     string           `json:"raw_text"`
+	Language    string           `json:"language"`
+	Orientation string           `json:"orientation,omitempty"`
+	Blocks      []ocrResultBlock `json:"blocks,omitempty"`
+}
 
+type ocrResultBlock struct {
+	Text        string          `json:"text"`
+	Orientation string          `json:"orientation,omitempty"`
+	Box2D       []float64       `json:"box_2d"`
+	Lines       []ocrResultLine `json:"lines,omitempty"`
+}
+
+type ocrResultLine struct {
+	Text  string    `json:"text"`
+	Box2D []float64 `json:"box_2d"`
+}
+
+// ParseOCRResult turns the model output into an OCRResponse. Output that isn't valid
+// JSON is kept as plain text so a formatting slip doesn't lose the recognized text.
+func ParseOCRResult(text string) *OCRResponse {
+	var structured ocrResult
 	if err := json.Unmarshal([]byte(text), &structured); err != nil {
 		normalized := normalizeJSONCandidate(text)
-		if normalized != text {
-			if err2 := json.Unmarshal([]byte(normalized), &structured); err2 == nil {
-				structuredJSON, _ := json.Marshal(structured)
-				return &OCRResponse{
-					RawText:        structured.RawText,
-					StructuredJSON: string(structuredJSON),
-					Language:       structured.Language,
-				}, nil
+		if normalized == text || json.Unmarshal([]byte(normalized), &structured) != nil {
+			return &OCRResponse{
+				RawText:        text,
+				StructuredJSON: "",
+				Language:       "ja",
 			}
 		}
-		return &OCRResponse{
-			RawText:        text,
-			StructuredJSON: "",
-			Language:       "ja",
-		}, nil
 	}
 
 	structuredJSON, _ := json.Marshal(structured)
 
-	return &OCRResponse{
+	response := &OCRResponse{
 		RawText:        structured.RawText,
 		StructuredJSON: string(structuredJSON),
 		Language:       structured.Language,
-	}, nil
+		Orientation:    normalizeOrientation(structured.Orientation, OrientationHorizontal),
+	}
+	for _, raw := range structured.Blocks {
+		block := OCRBlock{
+			Text:        raw.Text,
+			Orientation: normalizeOrientation(raw.Orientation, response.Orientation),
+			Box:         boxFromBox2D(raw.Box2D),
+		}
+		for _, line := range raw.Lines {
+			block.Lines = append(block.Lines, OCRLine{Text: line.Text, Box: boxFromBox2D(line.Box2D)})
+		}
+		response.Blocks = append(response.Blocks, block)
+	}
+
+	return response
+}
+
+func normalizeOrientation(orientation, fallback string) string {
+	switch strings.ToLower(strings.TrimSpace(orientation)) {
+	case OrientationHorizontal:
+		return OrientationHorizontal
+	case OrientationVertical:
+		return OrientationVertical
+	}
+	return fallback
+}
+
+// boxFromBox2D converts a Gemini [ymin, xmin, ymax, xmax] box on the 0-1000 grid into a
+// normalized BoundingBox. Out-of-range values are clamped and swapped corners are
+// reordered; a malformed box becomes the zero box.
+func boxFromBox2D(box []float64) BoundingBox {
+	if len(box) != 4 {
+		return BoundingBox{}
+	}
+
+	clamp := func(v float64) float64 { return math.Min(math.Max(v, 0), 1000) / 1000 }
+	ymin, xmin, ymax, xmax := clamp(box[0]), clamp(box[1]), clamp(box[2]), clamp(box[3])
+	if ymin > ymax {
+		ymin, ymax = ymax, ymin
+	}
+	if xmin > xmax {
+		xmin, xmax = xmax, xmin
+	}
+
+	return BoundingBox{X: xmin, Y: ymin, Width: xmax - xmin, Height: ymax - ymin}
 }
 
 func (c *client) Annotate(ctx context.Context, ocrText string, selectedText string) (*AnnotationResponse, error) {
@@ -263,7 +415,7 @@ Return only valid JSON, no markdown formatting.`, ocrText, selectedText)
 	return &annotation, nil
 }
 
-func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry) (*AnnotationResponse, error) {
+func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*AnnotationResponse, error) {
 	if c.genaiClient == nil {
 		if c.initErr != nil {
 			return nil, fmt.Errorf("gemini client not initialized: %w", c.initErr)
@@ -271,9 +423,41 @@ func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, sele
 		return nil, fmt.Errorf("gemini client not initialized: check API key")
 	}
 
-	prompt := buildEnhancedPrompt(ocrText, selectedText, entries)
+	prompt := BuildAnnotationPrompt(ocrText, selectedText, entries, targetLanguage)
+	cfg := annotationConfig()
 
-	cfg := &genai.GenerateContentConfig{
+	var result *genai.GenerateContentResponse
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		result, err = c.genaiClient.Models.GenerateContent(
+			ctx,
+			c.modelName,
+			genai.Text(prompt),
+			cfg,
+		)
+		if err == nil {
+			break
+		}
+		if !isOverloadedError(err) || attempt == 2 {
+			return nil, fmt.Errorf("failed to generate annotation: %w", err)
+		}
+
+		backoff := time.Duration(500*(1<<attempt)) * time.Millisecond
+		jitter := time.Duration(rand.Intn(250)) * time.Millisecond
+		if !sleepWithContext(ctx, backoff+jitter) {
+			return nil, fmt.Errorf("failed to generate annotation: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate annotation: %w", err)
+	}
+
+	return parseAnnotation(result.Text())
+}
+
+// annotationConfig asks for the AnnotationResponse fields as JSON, in the order the UI shows them.
+func annotationConfig() *genai.GenerateContentConfig {
+	return &genai.GenerateContentConfig{
 		ResponseMIMEType: "application/json",
 		ResponseSchema: &genai.Schema{
 			Type: genai.TypeObject,
@@ -300,34 +484,9 @@ func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, sele
 			},
 		},
 	}
+}
 
-	var result *genai.GenerateContentResponse
-	var err error
-	for attempt := 0; attempt < 3; attempt++ {
-		result, err = c.genaiClient.Models.GenerateContent(
-			ctx,
-			c.modelName,
-			genai.Text(prompt),
-			cfg,
-		)
-		if err == nil {
-			break
-		}
-		if !isOverloadedError(err) || attempt == 2 {
-			return nil, fmt.Errorf("failed to generate annotation: %w", err)
-		}
-
-		backoff := time.Duration(500*(1<<attempt)) * time.Millisecond
-		jitter := time.Duration(rand.Intn(250)) * time.Millisecond
-		if !sleepWithContext(ctx, backoff+jitter) {
-			return nil, fmt.Errorf("failed to generate annotation: %w", err)
-		}
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate annotation: %w", err)
-	}
-
-	text := result.Text()
+func parseAnnotation(text string) (*AnnotationResponse, error) {
 	if text == "" {
 		return nil, fmt.Errorf("empty response from API")
 	}
@@ -346,7 +505,7 @@ func (c *client) AnnotateWithKnowledge(ctx context.Context, ocrText string, sele
 	return &annotation, nil
 }
 
-func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string) (*SpeechResponse, error) {
+func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts SpeechOptions) (*SpeechResponse, error) {
 	if c.genaiClient == nil {
 		if c.initErr != nil {
 			return nil, fmt.Errorf("gemini client not initialized: %w", c.initErr)
@@ -354,13 +513,18 @@ func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, c
 		return nil, fmt.Errorf("gemini client not initialized: check API key")
 	}
 
-	prompt := buildSpeechPrompt(highlightedText, contextText)
+	voice := opts.Voice
+	if voice == "" {
+		voice = DefaultVoice
+	}
+
+	prompt := buildSpeechPrompt(highlightedText, contextText, opts)
 	cfg := &genai.GenerateContentConfig{
 		ResponseModalities: []string{"AUDIO"},
 		SpeechConfig: &genai.SpeechConfig{
 			VoiceConfig: &genai.VoiceConfig{
 				PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{
-					VoiceName: "Kore",
+					VoiceName: voice,
 				},
 			},
 			LanguageCode: "ja-JP",
@@ -388,7 +552,7 @@ func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, c
 			mimeType := strings.ToLower(strings.TrimSpace(part.InlineData.MIMEType))
 			audio := part.InlineData.Data
 			if mimeType == "" || strings.Contains(mimeType, "pcm") {
-				audio = wrapPCMAsWAV(audio, 24000, 1, 16)
+				audio = WrapPCMAsWAV(audio, 24000, 1, 16)
 				mimeType = "audio/wav"
 			}
 			return &SpeechResponse{
@@ -401,8 +565,89 @@ func (c *client) SynthesizeSpeech(ctx context.Context, highlightedText string, c
 	return nil, fmt.Errorf("no audio data in Gemini response")
 }
 
-// buildEnhancedPrompt creates a prompt that includes reference knowledge from CSV.
-func buildEnhancedPrompt(ocrText string, selectedText string, entries []knowledge.Entry) string {
+func (c *client) Furigana(ctx context.Context, text string) (*FuriganaResponse, error) {
+	if c.genaiClient == nil {
+		if c.initErr != nil {
+			return nil, fmt.Errorf("gemini client not initialized: %w", c.initErr)
+		}
+		return nil, fmt.Errorf("gemini client not initialized: check API key")
+	}
+
+	prompt := BuildFuriganaPrompt(text)
+
+	cfg := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"tokens": {
+					Type: genai.TypeArray,
+					Items: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"surface": {Type: genai.TypeString},
+							"reading": {Type: genai.TypeString},
+						},
+						Required:         []string{"surface", "reading"},
+						PropertyOrdering: []string{"surface", "reading"},
+					},
+				},
+			},
+			Required: []string{"tokens"},
+		},
+	}
+
+	var result *genai.GenerateContentResponse
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		result, err = c.genaiClient.Models.GenerateContent(
+			ctx,
+			c.modelName,
+			genai.Text(prompt),
+			cfg,
+		)
+		if err == nil {
+			break
+		}
+		if !isOverloadedError(err) || attempt == 2 {
+			return nil, fmt.Errorf("failed to generate furigana: %w", err)
+		}
+
+		backoff := time.Duration(500*(1<<attempt)) * time.Millisecond
+		jitter := time.Duration(rand.Intn(250)) * time.Millisecond
+		if !sleepWithContext(ctx, backoff+jitter) {
+			return nil, fmt.Errorf("failed to generate furigana: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate furigana: %w", err)
+	}
+
+	raw := result.Text()
+	if raw == "" {
+		return nil, fmt.Errorf("empty response from API")
+	}
+
+	var furigana FuriganaResponse
+	if err := DecodeJSON(raw, &furigana); err != nil {
+		return nil, fmt.Errorf("failed to parse furigana JSON: %w", err)
+	}
+
+	return &furigana, nil
+}
+
+// AnnotationPromptVersion identifies the wording of BuildAnnotationPrompt. Bump it whenever the
+// prompt changes so cached annotations produced by the old prompt are no longer served.
+const AnnotationPromptVersion = "2"
+
+// BuildAnnotationPrompt creates a prompt that includes reference knowledge from CSV and asks
+// for the explanation in targetLanguage.
+func BuildAnnotationPrompt(ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) string {
+	language, ok := models.LookupLanguage(targetLanguage)
+	if !ok {
+		language, _ = models.LookupLanguage(models.DefaultLanguage)
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString("You are helping a Japanese language learner understand text in a professional/work context.\n\n")
@@ -448,11 +693,30 @@ func buildEnhancedPrompt(ocrText string, selectedText string, entries []knowledg
 	sb.WriteString("- when_to_use: When and in what situation this phrase is used\n")
 	sb.WriteString("- word_breakdown: Explanation of each word/component in the selected text\n")
 	sb.WriteString("- alternative_meanings: Alternative meanings in different fields or contexts\n\n")
+	if language.Code == "JP" {
+		sb.WriteString("Write every field in plain Japanese that a learner can follow.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Write meaning, when_to_use, word_breakdown and alternative_meanings in %s. ", language.Name))
+		sb.WriteString(fmt.Sprintf("Write usage_example in Japanese followed by its %s translation.\n", language.Name))
+	}
 	sb.WriteString("Return only valid JSON, no markdown formatting.")
 
 	return sb.String()
 }
 
+// BuildFuriganaPrompt asks for text split into {surface, reading} tokens as JSON.
+func BuildFuriganaPrompt(text string) string {
+	return fmt.Sprintf(`Split the following Japanese text into words for a furigana reader.
+For every word that contains kanji, give its reading in hiragana as used in this context.
+Words without kanji (kana, punctuation, numbers, Latin text, whitespace) get an empty reading.
+Concatenating every surface in order must reproduce the text exactly, including spaces and line breaks.
+
+Text:
+%s
+
+Return only valid JSON, no markdown formatting.`, text)
+}
+
 func isOverloadedError(err error) bool {
 	msg := strings.ToLower(err.Error())
 	return strings.Contains(msg, "503") || strings.Contains(msg, "unavailable") || strings.Contains(msg, "overloaded")
@@ -469,6 +733,21 @@ func sleepWithContext(ctx context.Context, d time.Duration) bool {
 	}
 }
 
+// DecodeJSON unmarshals model output into v, retrying with code fences and surrounding
+// prose stripped when the raw text isn't valid JSON.
+func DecodeJSON(text string, v any) error {
+	err := json.Unmarshal([]byte(text), v)
+	if err == nil {
+		return nil
+	}
+	if normalized := normalizeJSONCandidate(text); normalized != text {
+		if err2 := json.Unmarshal([]byte(normalized), v); err2 == nil {
+			return nil
+		}
+	}
+	return err
+}
+
 func normalizeJSONCandidate(s string) string {
 	trimmed := strings.TrimSpace(s)
 
@@ -490,30 +769,40 @@ func normalizeJSONCandidate(s string) string {
 	return strings.TrimSpace(s)
 }
 
-func buildSpeechPrompt(highlightedText string, contextText string) string {
+func buildSpeechPrompt(highlightedText string, contextText string, opts SpeechOptions) string {
 	context := strings.TrimSpace(contextText)
 	if len(context) > 400 {
 		context = context[:400]
 	}
 
+	var delivery strings.Builder
+	for _, instruction := range []string{ToneInstruction(opts.Tone), rateInstruction(opts.SpeakingRate)} {
+		if instruction != "" {
+			delivery.WriteString(instruction)
+			delivery.WriteString("\n")
+		}
+	}
+
 	return fmt.Sprintf(
 		`You are a Japanese text-to-speech assistant.
 Speak only the "Selected text" exactly as written.
 Use "Context" only to infer subtle, natural tone and pacing.
 Do not read context out loud.
 Avoid exaggerated acting; keep delivery clear and realistic.
-
+%s
 Selected text:
 %s
 
 Context:
 %s`,
+		delivery.String(),
 		highlightedText,
 		context,
 	)
 }
 
-func wrapPCMAsWAV(pcm []byte, sampleRate, channels, bitsPerSample int) []byte {
+// WrapPCMAsWAV prefixes raw little-endian PCM samples with a canonical 44-byte WAV header.
+func WrapPCMAsWAV(pcm []byte, sampleRate, channels, bitsPerSample int) []byte {
 	dataLen := len(pcm)
 	byteRate := sampleRate * channels * bitsPerSample / 8
 	blockAlign := channels * bitsPerSample / 8
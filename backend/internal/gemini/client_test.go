@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -225,9 +226,120 @@ func TestAnnotate_NoAPIKey(t *testing.T) {
 	}
 }
 
+func TestParseOCRResult_Layout(t *testing.T) {
+	text := `{
+		"raw_text": "仕事\n会社",
+		"language": "JP",
+		"orientation": "vertical",
+		"blocks": [
+			{"text": "仕事", "box_2d": [100, 800, 600, 900], "lines": [{"text": "仕事", "box_2d": [100, 800, 600, 900]}]},
+			{"text": "会社", "orientation": "horizontal", "box_2d": [700, 500, 650, 1200]}
+		]
+	}`
+
+	result := ParseOCRResult(text)
+
+	if result.RawText != "仕事\n会社" || result.Language != "JP" {
+		t.Fatalf("unexpected text/language: %q %q", result.RawText, result.Language)
+	}
+	if result.Orientation != OrientationVertical {
+		t.Fatalf("expected vertical orientation, got %q", result.Orientation)
+	}
+	if len(result.Blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(result.Blocks))
+	}
+
+	first := result.Blocks[0]
+	if first.Orientation != OrientationVertical {
+		t.Errorf("expected block to inherit page orientation, got %q", first.Orientation)
+	}
+	want := BoundingBox{X: 0.8, Y: 0.1, Width: 0.1, Height: 0.5}
+	if !boxAlmostEqual(first.Box, want) {
+		t.Errorf("expected box %+v, got %+v", want, first.Box)
+	}
+	if len(first.Lines) != 1 || !boxAlmostEqual(first.Lines[0].Box, want) {
+		t.Errorf("unexpected lines: %+v", first.Lines)
+	}
+
+	// Swapped corners are reordered and out-of-range values clamped.
+	second := result.Blocks[1]
+	want = BoundingBox{X: 0.5, Y: 0.65, Width: 0.5, Height: 0.05}
+	if second.Orientation != OrientationHorizontal || !boxAlmostEqual(second.Box, want) {
+		t.Errorf("expected horizontal block with box %+v, got %q %+v", want, second.Orientation, second.Box)
+	}
+}
+
+func TestParseOCRResult_PlainText(t *testing.T) {
+	t.Run("without blocks", func(t *testing.T) {
+		result := ParseOCRResult("```json\n{\"raw_text\": \"請求書\", \"language\": \"JP\"}\n```")
+		if result.RawText != "請求書" || len(result.Blocks) != 0 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		if result.Orientation != OrientationHorizontal {
+			t.Fatalf("expected default horizontal orientation, got %q", result.Orientation)
+		}
+	})
+
+	t.Run("not JSON", func(t *testing.T) {
+		result := ParseOCRResult("請求書")
+		if result.RawText != "請求書" || result.StructuredJSON != "" {
+			t.Fatalf("expected raw text fallback, got %+v", result)
+		}
+	})
+}
+
+func TestBoxFromBox2D_Malformed(t *testing.T) {
+	if box := boxFromBox2D([]float64{1, 2, 3}); box != (BoundingBox{}) {
+		t.Fatalf("expected zero box, got %+v", box)
+	}
+}
+
+func boxAlmostEqual(a, b BoundingBox) bool {
+	const eps = 1e-9
+	return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps &&
+		math.Abs(a.Width-b.Width) < eps && math.Abs(a.Height-b.Height) < eps
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+func TestBuildAnnotationPrompt_TargetLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"EN", "alternative_meanings in English"},
+		{"ID", "alternative_meanings in Indonesian"},
+		{"JP", "plain Japanese"},
+		{"", "alternative_meanings in Indonesian"},
+		{"XX", "alternative_meanings in Indonesian"},
+	}
+
+	for _, tt := range tests {
+		prompt := BuildAnnotationPrompt("お疲れ様です。", "お疲れ様です", nil, tt.language)
+		if !strings.Contains(prompt, tt.want) {
+			t.Errorf("language %q: expected prompt to contain %q", tt.language, tt.want)
+		}
+	}
+}
+
+func TestBuildSpeechPrompt_Options(t *testing.T) {
+	plain := buildSpeechPrompt("はい", "会議", SpeechOptions{})
+	if strings.Contains(plain, "delivery,") || strings.Contains(plain, "normal speed") {
+		t.Errorf("expected no delivery instructions by default, got:\n%s", plain)
+	}
+
+	prompt := buildSpeechPrompt("はい", "会議", SpeechOptions{Tone: ToneBusiness, SpeakingRate: 0.75})
+	for _, want := range []string{ToneInstruction(ToneBusiness), "Speak slowly, at about 0.75x normal speed"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if !strings.Contains(buildSpeechPrompt("はい", "", SpeechOptions{SpeakingRate: 1.5}), "Speak briskly") {
+		t.Error("expected a faster rate to ask for brisk delivery")
+	}
+}
@@ -0,0 +1,142 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tones the speech prompt understands.
+const (
+	TonePolite   = "polite"
+	ToneCasual   = "casual"
+	ToneBusiness = "business"
+)
+
+// Tones lists the accepted tones in display order.
+var Tones = []string{TonePolite, ToneCasual, ToneBusiness}
+
+// Speaking rate bounds. A rate of 1 is normal speed.
+const (
+	MinSpeakingRate     = 0.5
+	MaxSpeakingRate     = 2.0
+	DefaultSpeakingRate = 1.0
+)
+
+// SpeechOptions shape how text is read. Zero values mean the provider defaults.
+type SpeechOptions struct {
+	Voice        string
+	SpeakingRate float64
+	Tone         string
+}
+
+// Voice is a prebuilt voice a provider can speak with.
+type Voice struct {
+	Name        string
+	Description string
+}
+
+// VoiceCatalog is implemented by clients whose voices differ from the Gemini catalog.
+type VoiceCatalog interface {
+	Voices() []Voice
+	DefaultVoice() string
+}
+
+// DefaultVoice is the Gemini voice used when none is requested.
+const DefaultVoice = "Kore"
+
+// GeminiVoices are the prebuilt voices of the Gemini TTS models.
+var GeminiVoices = []Voice{
+	{Name: "Zephyr", Description: "Bright"},
+	{Name: "Puck", Description: "Upbeat"},
+	{Name: "Charon", Description: "Informative"},
+	{Name: "Kore", Description: "Firm"},
+	{Name: "Fenrir", Description: "Excitable"},
+	{Name: "Leda", Description: "Youthful"},
+	{Name: "Orus", Description: "Firm"},
+	{Name: "Aoede", Description: "Breezy"},
+	{Name: "Callirrhoe", Description: "Easy-going"},
+	{Name: "Autonoe", Description: "Bright"},
+	{Name: "Enceladus", Description: "Breathy"},
+	{Name: "Iapetus", Description: "Clear"},
+	{Name: "Umbriel", Description: "Easy-going"},
+	{Name: "Algieba", Description: "Smooth"},
+	{Name: "Despina", Description: "Smooth"},
+	{Name: "Erinome", Description: "Clear"},
+	{Name: "Algenib", Description: "Gravelly"},
+	{Name: "Rasalgethi", Description: "Informative"},
+	{Name: "Laomedeia", Description: "Upbeat"},
+	{Name: "Achernar", Description: "Soft"},
+	{Name: "Alnilam", Description: "Firm"},
+	{Name: "Schedar", Description: "Even"},
+	{Name: "Gacrux", Description: "Mature"},
+	{Name: "Pulcherrima", Description: "Forward"},
+	{Name: "Achird", Description: "Friendly"},
+	{Name: "Zubenelgenubi", Description: "Casual"},
+	{Name: "Vindemiatrix", Description: "Gentle"},
+	{Name: "Sadachbia", Description: "Lively"},
+	{Name: "Sadaltager", Description: "Knowledgeable"},
+	{Name: "Sulafat", Description: "Warm"},
+}
+
+func (c *client) Voices() []Voice {
+	return GeminiVoices
+}
+
+func (c *client) DefaultVoice() string {
+	return DefaultVoice
+}
+
+// CatalogFor returns the voices c can speak with and its default voice. Clients without a
+// catalog of their own use the Gemini voices.
+func CatalogFor(c Client) ([]Voice, string) {
+	if catalog, ok := c.(VoiceCatalog); ok {
+		return catalog.Voices(), catalog.DefaultVoice()
+	}
+	return GeminiVoices, DefaultVoice
+}
+
+// LookupVoice finds a voice by name, ignoring case.
+func LookupVoice(voices []Voice, name string) (Voice, bool) {
+	for _, v := range voices {
+		if strings.EqualFold(v.Name, name) {
+			return v, true
+		}
+	}
+	return Voice{}, false
+}
+
+// IsValidTone reports whether tone is one of Tones.
+func IsValidTone(tone string) bool {
+	for _, t := range Tones {
+		if t == tone {
+			return true
+		}
+	}
+	return false
+}
+
+// ToneInstruction describes the delivery for tone, or returns "" for the default delivery.
+func ToneInstruction(tone string) string {
+	switch tone {
+	case TonePolite:
+		return "Use a polite, gentle delivery, as when speaking to a customer or someone senior."
+	case ToneCasual:
+		return "Use a relaxed, friendly delivery, as between close friends."
+	case ToneBusiness:
+		return "Use a crisp, composed delivery suited to a meeting or a formal announcement."
+	default:
+		return ""
+	}
+}
+
+// rateInstruction describes the pace for rate, or returns "" for normal speed.
+func rateInstruction(rate float64) string {
+	switch {
+	case rate == 0 || rate == DefaultSpeakingRate:
+		return ""
+	case rate < DefaultSpeakingRate:
+		return fmt.Sprintf("Speak slowly, at about %.2gx normal speed, so a learner can follow each mora.", rate)
+	default:
+		return fmt.Sprintf("Speak briskly, at about %.2gx normal speed.", rate)
+	}
+}
@@ -0,0 +1,154 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"google.golang.org/genai"
+)
+
+// AnnotationFields are the JSON names of the AnnotationResponse fields in generation order.
+var AnnotationFields = []string{
+	"meaning",
+	"usage_example",
+	"when_to_use",
+	"word_breakdown",
+	"alternative_meanings",
+}
+
+// AnnotationStreamer is implemented by clients that can report annotation fields while the
+// model is still writing the rest. Clients without it are used through AnnotateWithKnowledge.
+type AnnotationStreamer interface {
+	// AnnotateWithKnowledgeStream calls onField once per field, named as in AnnotationFields,
+	// as soon as its value is complete, and returns the full annotation at the end.
+	AnnotateWithKnowledgeStream(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string, onField func(field, value string)) (*AnnotationResponse, error)
+}
+
+// AnnotationFieldValue returns the value of the field with the given JSON name.
+func AnnotationFieldValue(annotation *AnnotationResponse, field string) string {
+	switch field {
+	case "meaning":
+		return annotation.Meaning
+	case "usage_example":
+		return annotation.UsageExample
+	case "when_to_use":
+		return annotation.WhenToUse
+	case "word_breakdown":
+		return annotation.WordBreakdown
+	case "alternative_meanings":
+		return annotation.AlternativeMeanings
+	}
+	return ""
+}
+
+func (c *client) AnnotateWithKnowledgeStream(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string, onField func(field, value string)) (*AnnotationResponse, error) {
+	if c.genaiClient == nil {
+		if c.initErr != nil {
+			return nil, fmt.Errorf("gemini client not initialized: %w", c.initErr)
+		}
+		return nil, fmt.Errorf("gemini client not initialized: check API key")
+	}
+
+	prompt := BuildAnnotationPrompt(ocrText, selectedText, entries, targetLanguage)
+	cfg := annotationConfig()
+
+	var scanner fieldScanner
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		scanner = fieldScanner{}
+		err = nil
+		for chunk, chunkErr := range c.genaiClient.Models.GenerateContentStream(ctx, c.modelName, genai.Text(prompt), cfg) {
+			if chunkErr != nil {
+				err = chunkErr
+				break
+			}
+			for _, field := range scanner.Feed(chunk.Text()) {
+				onField(field.name, field.value)
+			}
+		}
+		if err == nil {
+			break
+		}
+		// Fields already sent can't be taken back, so only retry before the first one.
+		if !isOverloadedError(err) || scanner.Emitted() > 0 || attempt == 2 {
+			return nil, fmt.Errorf("failed to stream annotation: %w", err)
+		}
+
+		backoff := time.Duration(500*(1<<attempt)) * time.Millisecond
+		jitter := time.Duration(rand.Intn(250)) * time.Millisecond
+		if !sleepWithContext(ctx, backoff+jitter) {
+			return nil, fmt.Errorf("failed to stream annotation: %w", err)
+		}
+	}
+
+	return parseAnnotation(scanner.Text())
+}
+
+type streamedField struct {
+	name  string
+	value string
+}
+
+// fieldScanner reads a JSON object as it arrives in chunks and reports each top-level string
+// field once its closing quote has been received.
+type fieldScanner struct {
+	buf     strings.Builder
+	emitted map[string]bool
+}
+
+// Feed appends a chunk and returns the fields that became complete with it.
+func (s *fieldScanner) Feed(chunk string) []streamedField {
+	s.buf.WriteString(chunk)
+	if s.emitted == nil {
+		s.emitted = make(map[string]bool)
+	}
+
+	text := s.buf.String()
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return nil
+	}
+
+	// Re-decode from the start on every chunk: annotations are a few KB, and the decoder
+	// stops cleanly at the first incomplete token.
+	dec := json.NewDecoder(strings.NewReader(text[start:]))
+	if _, err := dec.Token(); err != nil {
+		return nil
+	}
+
+	var fields []streamedField
+	for dec.More() {
+		keyToken, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, _ := keyToken.(string)
+
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			break
+		}
+		str, ok := value.(string)
+		if !ok || key == "" || s.emitted[key] {
+			continue
+		}
+		s.emitted[key] = true
+		fields = append(fields, streamedField{name: key, value: str})
+	}
+	return fields
+}
+
+// Emitted returns how many fields have been reported so far.
+func (s *fieldScanner) Emitted() int {
+	return len(s.emitted)
+}
+
+// Text returns everything received so far.
+func (s *fieldScanner) Text() string {
+	return s.buf.String()
+}
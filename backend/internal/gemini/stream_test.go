@@ -0,0 +1,71 @@
+package gemini
+
+import (
+	"testing"
+)
+
+func TestFieldScanner(t *testing.T) {
+	chunks := []string{
+		"```json\n{\"mean",
+		"ing\": \"thanks \\\"for\\\" your",
+		" work\", \"usage_example\": \"お疲",
+		"れ様です。\", \"when_to_use\":",
+		" \"evenings\"}\n```",
+	}
+	want := [][]streamedField{
+		nil,
+		nil,
+		{{name: "meaning", value: `thanks "for" your work`}},
+		{{name: "usage_example", value: "お疲れ様です。"}},
+		{{name: "when_to_use", value: "evenings"}},
+	}
+
+	var scanner fieldScanner
+	for i, chunk := range chunks {
+		got := scanner.Feed(chunk)
+		if len(got) != len(want[i]) {
+			t.Fatalf("chunk %d: expected %v, got %v", i, want[i], got)
+		}
+		for j := range got {
+			if got[j] != want[i][j] {
+				t.Errorf("chunk %d: expected %v, got %v", i, want[i][j], got[j])
+			}
+		}
+	}
+
+	if scanner.Emitted() != 3 {
+		t.Errorf("expected 3 emitted fields, got %d", scanner.Emitted())
+	}
+	annotation, err := parseAnnotation(scanner.Text())
+	if err != nil {
+		t.Fatalf("parseAnnotation failed: %v", err)
+	}
+	if annotation.WhenToUse != "evenings" {
+		t.Errorf("unexpected final annotation: %+v", annotation)
+	}
+}
+
+func TestFieldScanner_SkipsNonStringValues(t *testing.T) {
+	var scanner fieldScanner
+	got := scanner.Feed(`{"score": 3, "nested": {"a": "b"}, "meaning": "ok"}`)
+	if len(got) != 1 || got[0].name != "meaning" {
+		t.Fatalf("expected only the string field, got %v", got)
+	}
+}
+
+func TestAnnotationFieldValue(t *testing.T) {
+	annotation := &AnnotationResponse{
+		Meaning:             "a",
+		UsageExample:        "b",
+		WhenToUse:           "c",
+		WordBreakdown:       "d",
+		AlternativeMeanings: "e",
+	}
+	var got string
+	for _, field := range AnnotationFields {
+		got += AnnotationFieldValue(annotation, field)
+	}
+	if got != "abcde" {
+		t.Fatalf("expected every field in order, got %q", got)
+	}
+}
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gemini-hackathon/app/internal/audio"
+	"github.com/gemini-hackathon/app/internal/cache"
 	"github.com/gemini-hackathon/app/internal/gemini"
 	"github.com/gemini-hackathon/app/internal/knowledge"
 	"github.com/gemini-hackathon/app/internal/middleware"
@@ -17,27 +22,46 @@ type AIHandlers struct {
 	db           storage.DB
 	geminiClient gemini.Client
 	knowledge    knowledge.Service
+	cache        *cache.AnnotationCache
+	audio        *audio.Service
 }
 
-func NewAIHandlers(db storage.DB, geminiClient gemini.Client, knowledgeSvc knowledge.Service) *AIHandlers {
+// NewAIHandlers creates the AI handlers. annotationCache may be nil to always call the model,
+// and audioSvc may be nil to synthesize speech on every request.
+func NewAIHandlers(db storage.DB, geminiClient gemini.Client, knowledgeSvc knowledge.Service, annotationCache *cache.AnnotationCache, audioSvc *audio.Service) *AIHandlers {
 	return &AIHandlers{
 		db:           db,
 		geminiClient: geminiClient,
 		knowledge:    knowledgeSvc,
+		cache:        annotationCache,
+		audio:        audioSvc,
 	}
 }
 
 type AnalyzeRequest struct {
 	TextToAnalyze string `json:"textToAnalyze"`
 	Context       string `json:"context"`
+	// TargetLanguage overrides the user's preferred explanation language for this call.
+	TargetLanguage string `json:"targetLanguage,omitempty"`
+	// NoCache skips cached answers and refreshes the cache, like Cache-Control: no-cache.
+	NoCache bool `json:"noCache,omitempty"`
 }
 
+// X-Cache values reported by AnalyzeAPI.
+const (
+	cacheStatusHit    = "HIT"
+	cacheStatusMiss   = "MISS"
+	cacheStatusBypass = "BYPASS"
+)
+
 type AnalyzeResponse struct {
 	Meaning            string `json:"meaning"`
 	UsageExample       string `json:"usageExample"`
 	UsageTiming        string `json:"usageTiming"`
 	WordBreakdown      string `json:"wordBreakdown"`
 	AlternativeMeaning string `json:"alternativeMeaning"`
+	// Language is the code of the language the explanation is written in.
+	Language string `json:"language"`
 }
 
 type NuanceSummary struct {
@@ -47,7 +71,31 @@ type NuanceSummary struct {
 type SpeakRequest struct {
 	HighlightedText string `json:"highlightedText"`
 	ContextText     string `json:"contextText,omitempty"`
-	Tone            string `json:"tone,omitempty"`
+	SpeechParams
+}
+
+// SpeechParams choose how text is read aloud.
+type SpeechParams struct {
+	// Voice overrides the user's preferred voice; see GET /v1/ai/voices.
+	Voice string `json:"voice,omitempty"`
+	// SpeakingRate is relative to normal speed, between gemini.MinSpeakingRate and
+	// gemini.MaxSpeakingRate. Zero means normal speed.
+	SpeakingRate float64 `json:"speakingRate,omitempty"`
+	// Tone is one of gemini.Tones, or empty for a neutral delivery.
+	Tone string `json:"tone,omitempty"`
+}
+
+type VoiceItem struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type GetVoicesResponse struct {
+	Voices          []VoiceItem `json:"voices"`
+	DefaultVoice    string      `json:"defaultVoice"`
+	Tones           []string    `json:"tones"`
+	MinSpeakingRate float64     `json:"minSpeakingRate"`
+	MaxSpeakingRate float64     `json:"maxSpeakingRate"`
 }
 
 func (h *AIHandlers) AnalyzeAPI(w http.ResponseWriter, r *http.Request) {
@@ -56,106 +104,202 @@ func (h *AIHandlers) AnalyzeAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	in, ok := h.parseAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call Gemini with knowledge context, unless an identical request was answered before
+	resp, cacheStatus := h.cachedAnnotation(r, in)
+	if resp != nil {
+		middleware.WaiveUsage(r.Context())
+	} else {
+		var err error
+		resp, err = h.generateAnnotation(r, in, nil)
+		if err != nil {
+			log.Printf("Failed to generate annotation: %v", err)
+			http.Error(w, "Failed to analyze text", http.StatusInternalServerError)
+			return
+		}
+	}
+	if cacheStatus != "" {
+		w.Header().Set("X-Cache", cacheStatus)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toAnalyzeResponse(resp, in.targetLanguage))
+}
+
+// analyzeInput is a validated analyze request with everything that shapes the answer.
+type analyzeInput struct {
+	req            AnalyzeRequest
+	targetLanguage string
+	entries        []knowledge.Entry
+	cacheKey       cache.AnnotationKey
+}
+
+// parseAnalyzeRequest validates an analyze request and resolves its explanation language and
+// knowledge context. It writes the error response and returns false when the request can't
+// be served.
+func (h *AIHandlers) parseAnalyzeRequest(w http.ResponseWriter, r *http.Request) (*analyzeInput, bool) {
 	userID := middleware.GetUserID(r.Context())
 	if userID == 0 {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
 	var req AnalyzeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	if req.TextToAnalyze == "" {
 		http.Error(w, "textToAnalyze is required", http.StatusBadRequest)
-		return
+		return nil, false
+	}
+
+	if req.TargetLanguage != "" && !isValidLanguage(req.TargetLanguage) {
+		http.Error(w, "Invalid targetLanguage", http.StatusBadRequest)
+		return nil, false
 	}
 
 	user, err := h.db.GetUserByID(r.Context(), userID)
 	if err != nil {
 		log.Printf("Failed to get user: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
+		return nil, false
 	}
 
 	if user == nil {
 		http.Error(w, "User not found", http.StatusNotFound)
-		return
+		return nil, false
 	}
 
-	targetLanguage := user.PreferredLanguage
+	targetLanguage := req.TargetLanguage
 	if targetLanguage == "" {
-		targetLanguage = "ID"
+		targetLanguage = user.PreferredLanguage
 	}
-
-	// Lookup knowledge context for the selected text
-	entries := h.knowledge.Lookup(req.TextToAnalyze)
-
-	// Call Gemini with knowledge context
-	resp, err := h.geminiClient.AnnotateWithKnowledge(r.Context(), req.Context, req.TextToAnalyze, entries)
-	if err != nil {
-		log.Printf("Failed to generate annotation: %v", err)
-		http.Error(w, "Failed to analyze text", http.StatusInternalServerError)
-		return
+	if !isValidLanguage(targetLanguage) {
+		targetLanguage = models.DefaultLanguage
 	}
 
-	response := AnalyzeResponse{
-		Meaning:            resp.Meaning,
-		UsageExample:       resp.UsageExample,
-		UsageTiming:        resp.WhenToUse,
-		WordBreakdown:      resp.WordBreakdown,
-		AlternativeMeaning: resp.AlternativeMeanings,
+	// Lookup knowledge context for the selected text and the sentence around it
+	entries := h.knowledge.LookupInContext(req.TextToAnalyze, req.Context, knowledge.MaxPromptEntries)
+
+	entryIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.ID()
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return &analyzeInput{
+		req:            req,
+		targetLanguage: targetLanguage,
+		entries:        entries,
+		cacheKey: cache.AnnotationKey{
+			Text:           req.TextToAnalyze,
+			Context:        req.Context,
+			EntryIDs:       entryIDs,
+			TargetLanguage: targetLanguage,
+			PromptVersion:  gemini.AnnotationPromptVersion,
+		},
+	}, true
 }
 
-func (h *AIHandlers) AnalyzeWithLanguageAPI(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// cachedAnnotation returns the cached answer for in, or nil, and the X-Cache status to report.
+// The status is empty when no cache is configured.
+func (h *AIHandlers) cachedAnnotation(r *http.Request, in *analyzeInput) (*gemini.AnnotationResponse, string) {
+	if h.cache == nil {
+		return nil, ""
 	}
 
-	userID := middleware.GetUserID(r.Context())
-	if userID == 0 {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+	noCache, _ := cacheDirectives(r)
+	if noCache || in.req.NoCache {
+		cache.RecordBypass()
+		return nil, cacheStatusBypass
 	}
-
-	var req AnalyzeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	if resp, _, ok := h.cache.Get(r.Context(), in.cacheKey); ok {
+		return resp, cacheStatusHit
 	}
+	return nil, cacheStatusMiss
+}
 
-	if req.TextToAnalyze == "" {
-		http.Error(w, "textToAnalyze is required", http.StatusBadRequest)
-		return
+// generateAnnotation asks the model and caches the answer unless the request says no-store.
+// When onField is set and the client can stream, it receives each field as soon as it is
+// complete.
+func (h *AIHandlers) generateAnnotation(r *http.Request, in *analyzeInput, onField func(field, value string)) (*gemini.AnnotationResponse, error) {
+	var resp *gemini.AnnotationResponse
+	var err error
+	if streamer, ok := h.geminiClient.(gemini.AnnotationStreamer); ok && onField != nil {
+		resp, err = streamer.AnnotateWithKnowledgeStream(r.Context(), in.req.Context, in.req.TextToAnalyze, in.entries, in.targetLanguage, onField)
+	} else {
+		resp, err = h.geminiClient.AnnotateWithKnowledge(r.Context(), in.req.Context, in.req.TextToAnalyze, in.entries, in.targetLanguage)
 	}
-
-	// Lookup knowledge context for the selected text
-	entries := h.knowledge.Lookup(req.TextToAnalyze)
-
-	resp, err := h.geminiClient.AnnotateWithKnowledge(r.Context(), req.Context, req.TextToAnalyze, entries)
 	if err != nil {
-		log.Printf("Failed to generate annotation with language: %v", err)
-		http.Error(w, "Failed to analyze text", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	response := AnalyzeResponse{
+	if _, noStore := cacheDirectives(r); h.cache != nil && !noStore {
+		// Keep the answer even if the client hung up while we were writing it
+		h.cache.Put(context.WithoutCancel(r.Context()), in.cacheKey, resp)
+	}
+	return resp, nil
+}
+
+func toAnalyzeResponse(resp *gemini.AnnotationResponse, language string) AnalyzeResponse {
+	return AnalyzeResponse{
 		Meaning:            resp.Meaning,
 		UsageExample:       resp.UsageExample,
 		UsageTiming:        resp.WhenToUse,
 		WordBreakdown:      resp.WordBreakdown,
 		AlternativeMeaning: resp.AlternativeMeanings,
+		Language:           language,
+	}
+}
+
+// cacheDirectives reads the request's Cache-Control header. no-cache skips cached answers;
+// no-store additionally keeps the fresh answer out of the cache.
+func cacheDirectives(r *http.Request) (noCache, noStore bool) {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			noCache = true
+		case "no-store":
+			noCache = true
+			noStore = true
+		}
+	}
+	return noCache, noStore
+}
+
+// AnalyzeWithLanguageAPI is kept for older clients; AnalyzeAPI honors the user's language and
+// the targetLanguage override itself.
+func (h *AIHandlers) AnalyzeWithLanguageAPI(w http.ResponseWriter, r *http.Request) {
+	h.AnalyzeAPI(w, r)
+}
+
+// VoicesAPI handles GET /v1/ai/voices: the voices, tones and speaking rates SpeakAPI accepts
+// for the configured AI provider.
+func (h *AIHandlers) VoicesAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	voices, defaultVoice := gemini.CatalogFor(h.geminiClient)
+	items := make([]VoiceItem, len(voices))
+	for i, v := range voices {
+		items[i] = VoiceItem{Name: v.Name, Description: v.Description}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(GetVoicesResponse{
+		Voices:          items,
+		DefaultVoice:    defaultVoice,
+		Tones:           gemini.Tones,
+		MinSpeakingRate: gemini.MinSpeakingRate,
+		MaxSpeakingRate: gemini.MaxSpeakingRate,
+	})
 }
 
 func (h *AIHandlers) SpeakAPI(w http.ResponseWriter, r *http.Request) {
@@ -181,7 +325,39 @@ func (h *AIHandlers) SpeakAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.geminiClient.SynthesizeSpeech(r.Context(), req.HighlightedText, req.ContextText)
+	opts, ok := h.speechOptions(w, r, userID, req.SpeechParams)
+	if !ok {
+		return
+	}
+
+	if h.audio != nil {
+		clip, err := h.audio.Fetch(r.Context(), audio.Request{
+			Text:         req.HighlightedText,
+			Context:      req.ContextText,
+			Voice:        opts.Voice,
+			SpeakingRate: opts.SpeakingRate,
+			Tone:         opts.Tone,
+		})
+		if err != nil {
+			log.Printf("Failed to synthesize speech: %v", err)
+			http.Error(w, "Failed to synthesize speech", http.StatusInternalServerError)
+			return
+		}
+		if clip.Cached {
+			middleware.WaiveUsage(r.Context())
+		}
+		serveClip(w, r, h.audio, clip)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	format, ok := audio.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		writeNotAcceptable(w)
+		return
+	}
+
+	resp, err := h.geminiClient.SynthesizeSpeech(r.Context(), req.HighlightedText, req.ContextText, opts)
 	if err != nil {
 		log.Printf("Failed to synthesize speech: %v", err)
 		http.Error(w, "Failed to synthesize speech", http.StatusInternalServerError)
@@ -190,11 +366,69 @@ func (h *AIHandlers) SpeakAPI(w http.ResponseWriter, r *http.Request) {
 
 	contentType := resp.MIMEType
 	if contentType == "" {
-		contentType = "audio/wav"
+		contentType = audio.DefaultMIMEType
 	}
+	data, contentType, err := audio.Encode(resp.Audio, contentType, format)
+	if err != nil {
+		log.Printf("Failed to encode speech: %v", err)
+		http.Error(w, "Failed to encode speech", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(resp.Audio)
+	_, _ = w.Write(data)
+}
+
+// speechOptions validates the delivery requested in params and writes the error response
+// itself when it returns false.
+func (h *AIHandlers) speechOptions(w http.ResponseWriter, r *http.Request, userID int64, params SpeechParams) (gemini.SpeechOptions, bool) {
+	voices, _ := gemini.CatalogFor(h.geminiClient)
+	opts, invalid, err := resolveSpeechOptions(r.Context(), h.db, voices, userID, params)
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return gemini.SpeechOptions{}, false
+	}
+	if invalid != "" {
+		http.Error(w, invalid, http.StatusBadRequest)
+		return gemini.SpeechOptions{}, false
+	}
+	return opts, true
+}
+
+// resolveSpeechOptions validates params against the provider's voices. Without an explicit
+// voice the user's preferred voice is used, as long as the provider still offers it. A
+// non-empty message describes why the request is invalid.
+func resolveSpeechOptions(ctx context.Context, db storage.DB, voices []gemini.Voice, userID int64, params SpeechParams) (gemini.SpeechOptions, string, error) {
+	if params.Tone != "" && !gemini.IsValidTone(params.Tone) {
+		return gemini.SpeechOptions{}, "tone must be one of " + strings.Join(gemini.Tones, ", "), nil
+	}
+	if params.SpeakingRate != 0 && (params.SpeakingRate < gemini.MinSpeakingRate || params.SpeakingRate > gemini.MaxSpeakingRate) {
+		return gemini.SpeechOptions{}, fmt.Sprintf("speakingRate must be between %g and %g", gemini.MinSpeakingRate, gemini.MaxSpeakingRate), nil
+	}
+
+	opts := gemini.SpeechOptions{SpeakingRate: params.SpeakingRate, Tone: params.Tone}
+	if params.Voice != "" {
+		voice, ok := gemini.LookupVoice(voices, params.Voice)
+		if !ok {
+			return gemini.SpeechOptions{}, "Unknown voice", nil
+		}
+		opts.Voice = voice.Name
+		return opts, "", nil
+	}
+
+	user, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return gemini.SpeechOptions{}, "", err
+	}
+	if user != nil && user.PreferredVoice != "" {
+		if voice, ok := gemini.LookupVoice(voices, user.PreferredVoice); ok {
+			opts.Voice = voice.Name
+		}
+	}
+	return opts, "", nil
 }
 
 type AnnotationAnnotation struct {
@@ -205,13 +439,14 @@ type AnnotationAnnotation struct {
 	AlternativeMeaning string `json:"alternativeMeaning"`
 }
 
-func toNuanceData(resp *gemini.AnnotationResponse) models.NuanceData {
+func toNuanceData(resp *gemini.AnnotationResponse, language string) models.NuanceData {
 	return models.NuanceData{
 		Meaning:            resp.Meaning,
 		UsageExample:       resp.UsageExample,
 		UsageTiming:        resp.WhenToUse,
 		WordBreakdown:      resp.WordBreakdown,
 		AlternativeMeaning: resp.AlternativeMeanings,
+		Language:           language,
 	}
 }
 
@@ -221,26 +456,3 @@ func summarizeNuance(nuance models.NuanceData) string {
 	}
 	return nuance.Meaning
 }
-
-func (h *AIHandlers) createAnalyzePrompt(targetLanguage, textToAnalyze, context string) string {
-	languageName := map[string]string{
-		"ID": "Indonesian",
-		"JP": "Japanese",
-		"EN": "English",
-	}[targetLanguage]
-
-	return fmt.Sprintf(`You are helping a language learner understand Japanese text.
-
-Text to analyze: %s
-Context: %s
-Target language for explanation: %s
-
-Provide a detailed annotation in JSON format with these exact fields:
-- meaning: Direct translation and explanation in %s
-- usage_example: Example sentence in a professional context
-- usage_timing: When and in what situation this phrase is used
-- word_breakdown: Breakdown of each word with translations
-- alternative_meaning: Alternative meanings if any
-
-Return only valid JSON, no markdown formatting.`, textToAnalyze, context, languageName, languageName)
-}
@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/cache"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type countingAnnotateClient struct {
+	mockGeminiClient
+	calls        int
+	lastLanguage string
+}
+
+func (m *countingAnnotateClient) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*gemini.AnnotationResponse, error) {
+	m.calls++
+	m.lastLanguage = targetLanguage
+	return &gemini.AnnotationResponse{Meaning: "thanks for your hard work"}, nil
+}
+
+func TestAnalyzeAPICache(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	user := &models.User{Email: "cache@example.com", PreferredLanguage: "EN"}
+	if err := mockDB.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	client := &countingAnnotateClient{}
+	h := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), cache.NewAnnotationCache(nil, mockDB, time.Hour), nil)
+
+	analyze := func(body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/ai/analyze", strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req = req.WithContext(middleware.WithUserID(req.Context(), user.ID))
+		rec := httptest.NewRecorder()
+		h.AnalyzeAPI(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	body := `{"textToAnalyze":"お疲れ様です","context":"皆さん、 お疲れ様です。"}`
+	if rec := analyze(body, nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected first request to miss, got %q", rec.Header().Get("X-Cache"))
+	}
+
+	rec := analyze(`{"textToAnalyze":"お疲れ様です","context":"  皆さん、\n\n お疲れ様です。\n"}`, nil)
+	if rec.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("expected equivalent request to hit, got %q", rec.Header().Get("X-Cache"))
+	}
+	var resp handlers.AnalyzeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Meaning != "thanks for your hard work" {
+		t.Fatalf("unexpected cached meaning %q", resp.Meaning)
+	}
+	if client.calls != 1 {
+		t.Fatalf("expected a single model call, got %d", client.calls)
+	}
+
+	if rec := analyze(body, http.Header{"Cache-Control": {"no-cache"}}); rec.Header().Get("X-Cache") != "BYPASS" {
+		t.Fatalf("expected Cache-Control bypass, got %q", rec.Header().Get("X-Cache"))
+	}
+	if rec := analyze(`{"textToAnalyze":"お疲れ様です","context":"皆さん、 お疲れ様です。","noCache":true}`, nil); rec.Header().Get("X-Cache") != "BYPASS" {
+		t.Fatalf("expected noCache bypass, got %q", rec.Header().Get("X-Cache"))
+	}
+	if client.calls != 3 {
+		t.Fatalf("expected bypassed requests to call the model, got %d calls", client.calls)
+	}
+
+	user.PreferredLanguage = "ID"
+	if rec := analyze(body, nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected a different target language to miss, got %q", rec.Header().Get("X-Cache"))
+	}
+}
+
+func TestAnalyzeAPITargetLanguage(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	user := &models.User{Email: "lang@example.com", PreferredLanguage: "EN"}
+	if err := mockDB.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	client := &countingAnnotateClient{}
+	h := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), nil, nil)
+
+	analyze := func(body string) (*httptest.ResponseRecorder, handlers.AnalyzeResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/ai/analyze", strings.NewReader(body))
+		req = req.WithContext(middleware.WithUserID(req.Context(), user.ID))
+		rec := httptest.NewRecorder()
+		h.AnalyzeAPI(rec, req)
+
+		var resp handlers.AnalyzeResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	t.Run("uses the preferred language", func(t *testing.T) {
+		_, resp := analyze(`{"textToAnalyze":"残業"}`)
+		if client.lastLanguage != "EN" || resp.Language != "EN" {
+			t.Fatalf("expected EN, model got %q and response says %q", client.lastLanguage, resp.Language)
+		}
+	})
+
+	t.Run("request overrides the preferred language", func(t *testing.T) {
+		_, resp := analyze(`{"textToAnalyze":"残業","targetLanguage":"JP"}`)
+		if client.lastLanguage != "JP" || resp.Language != "JP" {
+			t.Fatalf("expected JP, model got %q and response says %q", client.lastLanguage, resp.Language)
+		}
+	})
+
+	t.Run("rejects unsupported override", func(t *testing.T) {
+		rec, _ := analyze(`{"textToAnalyze":"残業","targetLanguage":"XX"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("falls back to the default language", func(t *testing.T) {
+		user.PreferredLanguage = ""
+		_, resp := analyze(`{"textToAnalyze":"残業"}`)
+		if resp.Language != models.DefaultLanguage {
+			t.Fatalf("expected %s, got %q", models.DefaultLanguage, resp.Language)
+		}
+	})
+}
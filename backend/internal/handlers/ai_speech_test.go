@@ -3,6 +3,7 @@ package handlers_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -12,12 +13,14 @@ import (
 	"github.com/gemini-hackathon/app/internal/handlers"
 	"github.com/gemini-hackathon/app/internal/knowledge"
 	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
 	"github.com/gemini-hackathon/app/internal/testutil"
 )
 
 type mockSpeechGeminiClient struct {
-	resp *gemini.SpeechResponse
-	err  error
+	resp     *gemini.SpeechResponse
+	err      error
+	lastOpts gemini.SpeechOptions
 }
 
 func (m *mockSpeechGeminiClient) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
@@ -28,17 +31,22 @@ func (m *mockSpeechGeminiClient) Annotate(ctx context.Context, ocrText string, s
 	return nil, nil
 }
 
-func (m *mockSpeechGeminiClient) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry) (*gemini.AnnotationResponse, error) {
+func (m *mockSpeechGeminiClient) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*gemini.AnnotationResponse, error) {
 	return nil, nil
 }
 
-func (m *mockSpeechGeminiClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string) (*gemini.SpeechResponse, error) {
+func (m *mockSpeechGeminiClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	m.lastOpts = opts
 	return m.resp, m.err
 }
 
+func (m *mockSpeechGeminiClient) Furigana(ctx context.Context, text string) (*gemini.FuriganaResponse, error) {
+	return nil, nil
+}
+
 func TestSpeakAPI(t *testing.T) {
 	t.Run("returns unauthorized when user is missing", func(t *testing.T) {
-		h := handlers.NewAIHandlers(testutil.NewMockDB(), &mockSpeechGeminiClient{}, knowledge.NewEmptyService())
+		h := handlers.NewAIHandlers(testutil.NewMockDB(), &mockSpeechGeminiClient{}, knowledge.NewEmptyService(), nil, nil)
 		req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", strings.NewReader(`{"highlightedText":"テスト"}`))
 		rec := httptest.NewRecorder()
 
@@ -50,7 +58,7 @@ func TestSpeakAPI(t *testing.T) {
 	})
 
 	t.Run("returns bad request when highlighted text is empty", func(t *testing.T) {
-		h := handlers.NewAIHandlers(testutil.NewMockDB(), &mockSpeechGeminiClient{}, knowledge.NewEmptyService())
+		h := handlers.NewAIHandlers(testutil.NewMockDB(), &mockSpeechGeminiClient{}, knowledge.NewEmptyService(), nil, nil)
 		req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", strings.NewReader(`{"highlightedText":""}`))
 		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
 		rec := httptest.NewRecorder()
@@ -72,8 +80,10 @@ func TestSpeakAPI(t *testing.T) {
 				},
 			},
 			knowledge.NewEmptyService(),
+			nil,
+			nil,
 		)
-		body := bytes.NewBufferString(`{"highlightedText":"おはよう","contextText":"丁寧に挨拶する場面","tone":"ignored"}`)
+		body := bytes.NewBufferString(`{"highlightedText":"おはよう","contextText":"丁寧に挨拶する場面","tone":"polite"}`)
 		req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", body)
 		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
 		rec := httptest.NewRecorder()
@@ -96,6 +106,8 @@ func TestSpeakAPI(t *testing.T) {
 			testutil.NewMockDB(),
 			&mockSpeechGeminiClient{err: context.DeadlineExceeded},
 			knowledge.NewEmptyService(),
+			nil,
+			nil,
 		)
 		req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", strings.NewReader(`{"highlightedText":"テスト"}`))
 		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
@@ -108,3 +120,59 @@ func TestSpeakAPI(t *testing.T) {
 		}
 	})
 }
+
+func TestSpeakAPIOptions(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	user := &models.User{Email: "voice@example.com", PreferredVoice: "Puck"}
+	mockDB.CreateUser(context.Background(), user)
+	client := &mockSpeechGeminiClient{resp: &gemini.SpeechResponse{Audio: []byte("RIFF"), MIMEType: "audio/wav"}}
+	h := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), nil, nil)
+
+	speakAs := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", strings.NewReader(body))
+		req = req.WithContext(middleware.WithUserID(req.Context(), user.ID))
+		rec := httptest.NewRecorder()
+		h.SpeakAPI(rec, req)
+		return rec
+	}
+
+	if rec := speakAs(`{"highlightedText":"はい"}`); rec.Code != http.StatusOK || client.lastOpts.Voice != "Puck" {
+		t.Errorf("expected the preferred voice, got %d with %+v", rec.Code, client.lastOpts)
+	}
+
+	rec := speakAs(`{"highlightedText":"はい","voice":"charon","speakingRate":0.75,"tone":"business"}`)
+	want := gemini.SpeechOptions{Voice: "Charon", SpeakingRate: 0.75, Tone: gemini.ToneBusiness}
+	if rec.Code != http.StatusOK || client.lastOpts != want {
+		t.Errorf("expected %+v, got %d with %+v", want, rec.Code, client.lastOpts)
+	}
+
+	for _, body := range []string{
+		`{"highlightedText":"はい","voice":"Nobody"}`,
+		`{"highlightedText":"はい","speakingRate":3}`,
+		`{"highlightedText":"はい","tone":"angry"}`,
+	} {
+		if rec := speakAs(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestVoicesAPI(t *testing.T) {
+	h := handlers.NewAIHandlers(testutil.NewMockDB(), &mockSpeechGeminiClient{}, knowledge.NewEmptyService(), nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/ai/voices", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+
+	h.VoicesAPI(rec, req)
+
+	var resp handlers.GetVoicesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.DefaultVoice != gemini.DefaultVoice || len(resp.Voices) != len(gemini.GeminiVoices) {
+		t.Errorf("expected the Gemini catalog, got default %q and %d voices", resp.DefaultVoice, len(resp.Voices))
+	}
+	if len(resp.Tones) != 3 || resp.MinSpeakingRate != gemini.MinSpeakingRate || resp.MaxSpeakingRate != gemini.MaxSpeakingRate {
+		t.Errorf("unexpected options: %+v", resp)
+	}
+}
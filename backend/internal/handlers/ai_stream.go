@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/middleware"
+)
+
+// Events of POST /v1/ai/analyze/stream. Each field arrives once, in generation order; done
+// carries the complete annotation in the same shape as AnalyzeResponse.
+const (
+	analyzeEventField = "field"
+	analyzeEventDone  = "done"
+	analyzeEventError = "error"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// AnalyzeFieldEvent is one finished annotation field, named as in AnalyzeResponse.
+type AnalyzeFieldEvent struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// analyzeResponseFields maps model field names to their AnalyzeResponse JSON names.
+var analyzeResponseFields = map[string]string{
+	"meaning":              "meaning",
+	"usage_example":        "usageExample",
+	"when_to_use":          "usageTiming",
+	"word_breakdown":       "wordBreakdown",
+	"alternative_meanings": "alternativeMeaning",
+}
+
+// eventWriter sends named JSON events to a streaming client.
+type eventWriter interface {
+	Event(event string, data any) error
+}
+
+// ndjsonWriter writes one {"event": ..., "data": ...} object per line and flushes after each.
+type ndjsonWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newNDJSONWriter(w http.ResponseWriter) (*ndjsonWriter, error) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	nw := &ndjsonWriter{w: w, rc: http.NewResponseController(w)}
+	if err := nw.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+	return nw, nil
+}
+
+func (nw *ndjsonWriter) Event(event string, data any) error {
+	line, err := json.Marshal(struct {
+		Event string `json:"event"`
+		Data  any    `json:"data"`
+	}{event, data})
+	if err != nil {
+		return err
+	}
+	if _, err := nw.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return nw.rc.Flush()
+}
+
+// wantsNDJSON reports whether the client asked for NDJSON rather than Server-Sent Events.
+func wantsNDJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeStreamAPI handles POST /v1/ai/analyze/stream. It takes the same body as AnalyzeAPI
+// and streams the annotation field by field as Server-Sent Events, or as NDJSON when the
+// client sends Accept: application/x-ndjson. Cached answers are replayed immediately.
+func (h *AIHandlers) AnalyzeStreamAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	in, ok := h.parseAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	resp, cacheStatus := h.cachedAnnotation(r, in)
+	if resp != nil {
+		middleware.WaiveUsage(r.Context())
+	}
+	if cacheStatus != "" {
+		w.Header().Set("X-Cache", cacheStatus)
+	}
+
+	var stream eventWriter
+	var err error
+	if wantsNDJSON(r) {
+		stream, err = newNDJSONWriter(w)
+	} else {
+		stream, err = newSSEWriter(w)
+	}
+	if err != nil {
+		log.Printf("Failed to start analyze stream: %v", err)
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sent := make(map[string]bool)
+	sendField := func(field, value string) {
+		name, ok := analyzeResponseFields[field]
+		if !ok || sent[field] {
+			return
+		}
+		sent[field] = true
+		stream.Event(analyzeEventField, AnalyzeFieldEvent{Field: name, Value: value})
+	}
+
+	if resp == nil {
+		resp, err = h.generateAnnotation(r, in, sendField)
+		if err != nil {
+			log.Printf("Failed to stream annotation: %v", err)
+			// The stream has already answered 200, so the failure won't refund the call.
+			middleware.WaiveUsage(r.Context())
+			stream.Event(analyzeEventError, ErrorResponse{Error: "analysis_failed", Message: "Failed to analyze text"})
+			return
+		}
+	}
+
+	// Clients that can't stream, and cache hits, deliver every field here
+	for _, field := range gemini.AnnotationFields {
+		sendField(field, gemini.AnnotationFieldValue(resp, field))
+	}
+	stream.Event(analyzeEventDone, toAnalyzeResponse(resp, in.targetLanguage))
+}
@@ -0,0 +1,177 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/cache"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+// streamingAnnotateClient reports two fields while "generating" and leaves the rest to the
+// final response.
+type streamingAnnotateClient struct {
+	mockGeminiClient
+	streamed int
+}
+
+func (m *streamingAnnotateClient) AnnotateWithKnowledgeStream(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string, onField func(field, value string)) (*gemini.AnnotationResponse, error) {
+	m.streamed++
+	onField("meaning", "overtime")
+	onField("usage_example", "今日は残業です。")
+	return &gemini.AnnotationResponse{
+		Meaning:             "overtime",
+		UsageExample:        "今日は残業です。",
+		WhenToUse:           "after hours",
+		WordBreakdown:       "残 + 業",
+		AlternativeMeanings: "-",
+	}, nil
+}
+
+func newStreamTestHandlers(t *testing.T, client gemini.Client, annotationCache func(*testutil.MockDB) *cache.AnnotationCache) (*handlers.AIHandlers, int64) {
+	t.Helper()
+	mockDB := testutil.NewMockDB()
+	user := &models.User{Email: "stream@example.com", PreferredLanguage: "EN"}
+	if err := mockDB.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	var c *cache.AnnotationCache
+	if annotationCache != nil {
+		c = annotationCache(mockDB)
+	}
+	return handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), c, nil), user.ID
+}
+
+func analyzeStream(h *handlers.AIHandlers, userID int64, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/ai/analyze/stream", strings.NewReader(`{"textToAnalyze":"残業"}`))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	h.AnalyzeStreamAPI(rec, req)
+	return rec
+}
+
+func readFieldEvents(t *testing.T, reader *bufio.Reader) ([]string, handlers.AnalyzeResponse) {
+	t.Helper()
+	var fields []string
+	for {
+		name, data := readSSEEvent(t, reader)
+		switch name {
+		case "field":
+			var event handlers.AnalyzeFieldEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("invalid field event %q: %v", data, err)
+			}
+			fields = append(fields, event.Field)
+		case "done":
+			var done handlers.AnalyzeResponse
+			if err := json.Unmarshal([]byte(data), &done); err != nil {
+				t.Fatalf("invalid done event %q: %v", data, err)
+			}
+			return fields, done
+		default:
+			t.Fatalf("unexpected event %q: %s", name, data)
+		}
+	}
+}
+
+var allAnalyzeFields = []string{"meaning", "usageExample", "usageTiming", "wordBreakdown", "alternativeMeaning"}
+
+func TestAnalyzeStreamAPI(t *testing.T) {
+	t.Run("streams fields in order and finishes with the full annotation", func(t *testing.T) {
+		h, userID := newStreamTestHandlers(t, &streamingAnnotateClient{}, nil)
+		rec := analyzeStream(h, userID, "")
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected SSE, got %q", ct)
+		}
+		fields, done := readFieldEvents(t, bufio.NewReader(rec.Body))
+		if strings.Join(fields, ",") != strings.Join(allAnalyzeFields, ",") {
+			t.Fatalf("expected each field once in order, got %v", fields)
+		}
+		if done.UsageTiming != "after hours" || done.Language != "EN" {
+			t.Fatalf("unexpected done event: %+v", done)
+		}
+	})
+
+	t.Run("writes NDJSON when asked", func(t *testing.T) {
+		h, userID := newStreamTestHandlers(t, &streamingAnnotateClient{}, nil)
+		rec := analyzeStream(h, userID, "application/x-ndjson")
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Fatalf("expected NDJSON, got %q", ct)
+		}
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		if len(lines) != len(allAnalyzeFields)+1 {
+			t.Fatalf("expected %d lines, got %d: %s", len(allAnalyzeFields)+1, len(lines), rec.Body.String())
+		}
+		var first struct {
+			Event string                     `json:"event"`
+			Data  handlers.AnalyzeFieldEvent `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", lines[0], err)
+		}
+		if first.Event != "field" || first.Data.Field != "meaning" || first.Data.Value != "overtime" {
+			t.Fatalf("unexpected first line: %+v", first)
+		}
+		if !strings.HasPrefix(lines[len(lines)-1], `{"event":"done"`) {
+			t.Fatalf("expected done last, got %s", lines[len(lines)-1])
+		}
+	})
+
+	t.Run("falls back to a single call for clients that can't stream", func(t *testing.T) {
+		client := &countingAnnotateClient{}
+		h, userID := newStreamTestHandlers(t, client, nil)
+		rec := analyzeStream(h, userID, "")
+
+		fields, done := readFieldEvents(t, bufio.NewReader(rec.Body))
+		if len(fields) != len(allAnalyzeFields) || done.Meaning != "thanks for your hard work" || client.calls != 1 {
+			t.Fatalf("unexpected fallback stream: fields=%v done=%+v calls=%d", fields, done, client.calls)
+		}
+	})
+
+	t.Run("replays cached annotations without calling the model", func(t *testing.T) {
+		client := &streamingAnnotateClient{}
+		h, userID := newStreamTestHandlers(t, client, func(db *testutil.MockDB) *cache.AnnotationCache {
+			return cache.NewAnnotationCache(nil, db, time.Hour)
+		})
+
+		if rec := analyzeStream(h, userID, ""); rec.Header().Get("X-Cache") != "MISS" {
+			t.Fatalf("expected first stream to miss, got %q", rec.Header().Get("X-Cache"))
+		}
+		rec := analyzeStream(h, userID, "")
+		if rec.Header().Get("X-Cache") != "HIT" {
+			t.Fatalf("expected second stream to hit, got %q", rec.Header().Get("X-Cache"))
+		}
+		fields, done := readFieldEvents(t, bufio.NewReader(rec.Body))
+		if len(fields) != len(allAnalyzeFields) || done.WordBreakdown != "残 + 業" || client.streamed != 1 {
+			t.Fatalf("unexpected replay: fields=%v done=%+v streamed=%d", fields, done, client.streamed)
+		}
+	})
+
+	t.Run("validates before streaming", func(t *testing.T) {
+		h, userID := newStreamTestHandlers(t, &streamingAnnotateClient{}, nil)
+		req := httptest.NewRequest(http.MethodPost, "/v1/ai/analyze/stream", strings.NewReader(`{"textToAnalyze":""}`))
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		h.AnalyzeStreamAPI(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
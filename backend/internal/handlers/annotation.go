@@ -2,27 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/gemini-hackathon/app/internal/audio"
 	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/logger"
 	"github.com/gemini-hackathon/app/internal/middleware"
 	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/pagination"
 	"github.com/gemini-hackathon/app/internal/storage"
 )
 
 type AnnotationHandlers struct {
-	db     storage.DB
-	config *config.Config
+	db           storage.DB
+	geminiClient gemini.Client
+	knowledge    knowledge.Service
+	audio        *audio.Service
+	config       *config.Config
+	cursors      *pagination.Signer
 }
 
-func NewAnnotationHandlers(db storage.DB, cfg *config.Config) *AnnotationHandlers {
+// NewAnnotationHandlers creates the annotation handlers. audioSvc may be nil, in which case
+// annotation audio is unavailable.
+func NewAnnotationHandlers(db storage.DB, geminiClient gemini.Client, knowledgeSvc knowledge.Service, audioSvc *audio.Service, cfg *config.Config) *AnnotationHandlers {
 	return &AnnotationHandlers{
-		db:     db,
-		config: cfg,
+		db:           db,
+		geminiClient: geminiClient,
+		knowledge:    knowledgeSvc,
+		audio:        audioSvc,
+		config:       cfg,
+		cursors:      pagination.NewSigner(cfg.CursorSecret),
 	}
 }
 
@@ -31,6 +48,10 @@ type CreateAnnotationRequest struct {
 	HighlightedText string            `json:"highlightedText"`
 	ContextText     string            `json:"contextText"`
 	NuanceData      models.NuanceData `json:"nuanceData"`
+	// IsBookmarked defaults to true.
+	IsBookmarked *bool    `json:"isBookmarked,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
 }
 
 type CreateAnnotationResponse struct {
@@ -39,10 +60,12 @@ type CreateAnnotationResponse struct {
 }
 
 type AnnotationListItem struct {
-	ID              int64  `json:"id"`
-	HighlightedText string `json:"highlightedText"`
-	NuanceSummary   string `json:"nuanceSummary"`
-	CreatedAt       string `json:"createdAt"`
+	ID              int64    `json:"id"`
+	HighlightedText string   `json:"highlightedText"`
+	NuanceSummary   string   `json:"nuanceSummary"`
+	IsBookmarked    bool     `json:"isBookmarked"`
+	Tags            []string `json:"tags"`
+	CreatedAt       string   `json:"createdAt"`
 }
 
 type GetAnnotationResponse struct {
@@ -50,7 +73,16 @@ type GetAnnotationResponse struct {
 	HighlightedText string            `json:"highlightedText"`
 	ContextText     string            `json:"contextText,omitempty"`
 	NuanceData      models.NuanceData `json:"nuanceData"`
-	CreatedAt       string            `json:"createdAt"`
+	// CurrentVersion is the AI explanation nuanceData is based on; see
+	// GET /v1/annotations/{id}/versions.
+	CurrentVersion int      `json:"currentVersion"`
+	IsBookmarked   bool     `json:"isBookmarked"`
+	Notes          string   `json:"notes,omitempty"`
+	Tags           []string `json:"tags"`
+	// AudioURL plays the annotation's speech. It stays the same for the annotation's lifetime.
+	AudioURL  string `json:"audioUrl"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
 }
 
 type GetAnnotationsResponse struct {
@@ -81,18 +113,41 @@ func (h *AnnotationHandlers) CreateAnnotationAPI(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if req.NuanceData.Language != "" && !isValidLanguage(req.NuanceData.Language) {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid nuanceData.language")
+		return
+	}
+
+	tags, invalid := normalizeTags(req.Tags)
+	if invalid != "" {
+		h.writeJSONError(w, http.StatusBadRequest, invalid)
+		return
+	}
+	if utf8.RuneCountInString(req.Notes) > maxAnnotationNotesLength {
+		h.writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("notes must be at most %d characters", maxAnnotationNotesLength))
+		return
+	}
+
 	var scanID *int64
 	if req.ScanID > 0 {
 		scanID = &req.ScanID
 	}
 
+	isBookmarked := true
+	if req.IsBookmarked != nil {
+		isBookmarked = *req.IsBookmarked
+	}
+
 	annotation := &models.Annotation{
 		UserID:          userID,
 		ScanID:          scanID,
 		HighlightedText: req.HighlightedText,
 		ContextText:     &req.ContextText,
 		NuanceData:      req.NuanceData,
-		IsBookmarked:    true,
+		IsBookmarked:    isBookmarked,
+		Notes:           optionalString(req.Notes),
+		Tags:            tags,
+		AudioHash:       h.existingAudio(r, userID, req.HighlightedText, req.ContextText),
 		CreatedAt:       time.Now(),
 	}
 
@@ -114,16 +169,158 @@ func (h *AnnotationHandlers) CreateAnnotationAPI(w http.ResponseWriter, r *http.
 }
 
 func (h *AnnotationHandlers) AnnotationByIDAPI(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.getAnnotationHandler(w, r)
-	case http.MethodDelete:
-		h.deleteAnnotationHandler(w, r)
+	idStr, action := splitAnnotationPath(r.URL.Path)
+	if idStr == "export" && action == "" {
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.exportAnnotationsHandler(w, r)
+		return
+	}
+
+	switch action {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			h.getAnnotationHandler(w, r)
+		case http.MethodPatch:
+			h.updateAnnotationHandler(w, r)
+		case http.MethodDelete:
+			h.deleteAnnotationHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case "edits":
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.getAnnotationEditsHandler(w, r)
+	case "regenerate":
+		if r.Method != http.MethodPost {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.regenerateAnnotationHandler(w, r)
+	case "versions":
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.getAnnotationVersionsHandler(w, r)
+	case "audio":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.getAnnotationAudioHandler(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.writeJSONError(w, http.StatusNotFound, "Not found")
 	}
 }
 
+// splitAnnotationPath splits /v1/annotations/{id}/{action} into its ID and optional action.
+func splitAnnotationPath(path string) (string, string) {
+	path = strings.Trim(strings.TrimPrefix(path, "/v1/annotations/"), "/")
+	idStr, action, _ := strings.Cut(path, "/")
+	return idStr, action
+}
+
+// getAnnotationAudioHandler serves the annotation's speech. The first play synthesizes the
+// clip and links it to the annotation; later plays are served from storage.
+func (h *AnnotationHandlers) getAnnotationAudioHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if h.audio == nil {
+		h.writeJSONError(w, http.StatusServiceUnavailable, "Audio is not available")
+		return
+	}
+
+	idStr, _ := splitAnnotationPath(r.URL.Path)
+	annotationID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || annotationID <= 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid annotation ID")
+		return
+	}
+
+	log := logger.GetDefaultLogger().
+		WithRequestID(middleware.GetRequestID(r.Context())).
+		WithUserID(userID).
+		WithField("annotation_id", annotationID)
+
+	annotation, err := h.db.GetAnnotationByID(r.Context(), annotationID)
+	if err != nil || annotation == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Annotation not found")
+		return
+	}
+	if annotation.UserID != userID {
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if annotation.AudioHash != nil {
+		clip, err := h.audio.Open(r.Context(), *annotation.AudioHash)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to open annotation audio")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to open audio")
+			return
+		}
+		if clip != nil {
+			serveClip(w, r, h.audio, clip)
+			return
+		}
+	}
+
+	contextText := ""
+	if annotation.ContextText != nil {
+		contextText = *annotation.ContextText
+	}
+	clip, err := h.audio.Fetch(r.Context(), audio.Request{
+		Text:    annotation.HighlightedText,
+		Context: contextText,
+		Voice:   h.preferredVoice(r, userID),
+	})
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to synthesize annotation audio")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to synthesize speech")
+		return
+	}
+	if err := h.db.SetAnnotationAudio(r.Context(), annotation.ID, clip.Asset.Hash); err != nil {
+		log.ErrorWithErr(err, "Failed to link annotation audio")
+	}
+	serveClip(w, r, h.audio, clip)
+}
+
+// existingAudio returns the clip already synthesized for a new annotation's text in the user's
+// voice, typically because it was played before being saved, so the annotation keeps that
+// exact audio.
+func (h *AnnotationHandlers) existingAudio(r *http.Request, userID int64, text, contextText string) *string {
+	if h.audio == nil {
+		return nil
+	}
+	hash := h.audio.Hash(audio.Request{Text: text, Context: contextText, Voice: h.preferredVoice(r, userID)})
+	asset, err := h.db.GetAudioAsset(r.Context(), hash)
+	if err != nil || asset == nil {
+		return nil
+	}
+	return &asset.Hash
+}
+
+// preferredVoice returns the user's voice if the provider offers it, otherwise "" for the
+// provider default.
+func (h *AnnotationHandlers) preferredVoice(r *http.Request, userID int64) string {
+	user, err := h.db.GetUserByID(r.Context(), userID)
+	if err != nil || user == nil || user.PreferredVoice == "" {
+		return ""
+	}
+	return h.audio.ResolveVoice(user.PreferredVoice)
+}
+
 func (h *AnnotationHandlers) deleteAnnotationHandler(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	if userID == 0 {
@@ -132,7 +329,7 @@ func (h *AnnotationHandlers) deleteAnnotationHandler(w http.ResponseWriter, r *h
 	}
 
 	path := r.URL.Path
-	idStr := strings.TrimSuffix(path[len("/v1/annotations/"):], "/")
+	idStr, _ := splitAnnotationPath(path)
 	annotationID, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || annotationID <= 0 {
 		h.writeJSONError(w, http.StatusBadRequest, "Invalid annotation ID")
@@ -156,7 +353,7 @@ func (h *AnnotationHandlers) getAnnotationHandler(w http.ResponseWriter, r *http
 	}
 
 	path := r.URL.Path
-	idStr := strings.TrimSuffix(path[len("/v1/annotations/"):], "/")
+	idStr, _ := splitAnnotationPath(path)
 	annotationID, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		h.writeJSONError(w, http.StatusBadRequest, "Invalid annotation ID")
@@ -175,21 +372,33 @@ func (h *AnnotationHandlers) getAnnotationHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toGetAnnotationResponse(annotation))
+}
+
+func toGetAnnotationResponse(annotation *models.Annotation) GetAnnotationResponse {
 	contextText := ""
 	if annotation.ContextText != nil {
 		contextText = *annotation.ContextText
 	}
+	notes := ""
+	if annotation.Notes != nil {
+		notes = *annotation.Notes
+	}
 
-	response := GetAnnotationResponse{
+	return GetAnnotationResponse{
 		ID:              annotation.ID,
 		HighlightedText: annotation.HighlightedText,
 		ContextText:     contextText,
 		NuanceData:      annotation.NuanceData,
+		CurrentVersion:  annotation.CurrentVersion,
+		IsBookmarked:    annotation.IsBookmarked,
+		Notes:           notes,
+		Tags:            models.NonNilTags(annotation.Tags),
+		AudioURL:        fmt.Sprintf("/v1/annotations/%d/audio", annotation.ID),
 		CreatedAt:       annotation.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       annotation.UpdatedAt.Format(time.RFC3339),
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
 
 func (h *AnnotationHandlers) GetAnnotationsAPI(w http.ResponseWriter, r *http.Request) {
@@ -204,37 +413,71 @@ func (h *AnnotationHandlers) GetAnnotationsAPI(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	params, invalid := parseListParams(r, h.config.DefaultPageSize, h.cursors)
+	if invalid != "" {
+		h.writeJSONError(w, http.StatusBadRequest, invalid)
+		return
 	}
 
-	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
-	if size < 1 {
-		size = h.config.DefaultPageSize
+	query := r.URL.Query()
+	filter := storage.AnnotationFilter{
+		Language:      query.Get("language"),
+		CreatedFrom:   params.createdFrom,
+		CreatedBefore: params.createdBefore,
 	}
-	if size > 100 {
-		size = 100
-	}
-
-	scanIDParam := r.URL.Query().Get("scanId")
-	var annotations []*models.Annotation
-	var err error
-	if scanIDParam == "" {
-		annotations, err = h.db.GetAnnotationsByUserID(r.Context(), userID, page, size)
-	} else {
-		scanID, parseErr := strconv.ParseInt(scanIDParam, 10, 64)
-		if parseErr != nil || scanID <= 0 {
+	scanIDParam := query.Get("scanId")
+	documentIDParam := query.Get("documentId")
+	switch {
+	case scanIDParam != "" && documentIDParam != "":
+		h.writeJSONError(w, http.StatusBadRequest, "scanId and documentId cannot be combined")
+		return
+	case scanIDParam != "":
+		scanID, err := strconv.ParseInt(scanIDParam, 10, 64)
+		if err != nil || scanID <= 0 {
 			h.writeJSONError(w, http.StatusBadRequest, "scanId must be a positive integer")
 			return
 		}
-		annotations, err = h.db.GetAnnotationsByUserIDAndScanID(r.Context(), userID, scanID, page, size)
+		filter.ScanID = scanID
+	case documentIDParam != "":
+		documentID, err := strconv.ParseInt(documentIDParam, 10, 64)
+		if err != nil || documentID <= 0 {
+			h.writeJSONError(w, http.StatusBadRequest, "documentId must be a positive integer")
+			return
+		}
+		filter.DocumentID = documentID
+	}
+	if filter.Language != "" && !isValidLanguage(filter.Language) {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid language")
+		return
+	}
+	if bookmarkedParam := query.Get("bookmarked"); bookmarkedParam != "" {
+		bookmarked, err := strconv.ParseBool(bookmarkedParam)
+		if err != nil {
+			h.writeJSONError(w, http.StatusBadRequest, "bookmarked must be true or false")
+			return
+		}
+		filter.Bookmarked = &bookmarked
 	}
+
+	annotations, err := h.db.GetAnnotationsByUserID(r.Context(), userID, filter, params.Page)
 	if err != nil {
 		log.Printf("Failed to get annotations: %v", err)
 		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get annotations")
 		return
 	}
+	fetched := len(annotations)
+	annotations = annotations[:min(fetched, params.size)]
+
+	var total *int
+	if params.includeTotal {
+		count, err := h.db.CountAnnotations(r.Context(), userID, filter)
+		if err != nil {
+			log.Printf("Failed to count annotations: %v", err)
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to get annotations")
+			return
+		}
+		total = &count
+	}
 
 	data := make([]AnnotationListItem, len(annotations))
 	for i, ann := range annotations {
@@ -243,28 +486,21 @@ func (h *AnnotationHandlers) GetAnnotationsAPI(w http.ResponseWriter, r *http.Re
 			ID:              ann.ID,
 			HighlightedText: ann.HighlightedText,
 			NuanceSummary:   summary,
+			IsBookmarked:    ann.IsBookmarked,
+			Tags:            models.NonNilTags(ann.Tags),
 			CreatedAt:       ann.CreatedAt.Format(time.RFC3339),
 		}
 	}
 
-	var nextPage, prevPage *int
-	if len(annotations) == size {
-		nextPageVal := page + 1
-		nextPage = &nextPageVal
-	}
-	if page > 1 {
-		prevPageVal := page - 1
-		prevPage = &prevPageVal
+	var last storage.Keyset
+	if len(annotations) > 0 {
+		ann := annotations[len(annotations)-1]
+		last = storage.Keyset{CreatedAt: ann.CreatedAt, ID: ann.ID}
 	}
 
 	response := GetAnnotationsResponse{
 		Data: data,
-		Meta: PaginationMeta{
-			CurrentPage:  page,
-			PageSize:     size,
-			NextPage:     nextPage,
-			PreviousPage: prevPage,
-		},
+		Meta: params.meta(h.cursors, fetched, last, total),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const (
+	maxAnnotationTags        = 20
+	maxAnnotationTagLength   = 50
+	maxAnnotationNotesLength = 5000
+)
+
+// UpdateAnnotationRequest changes only the fields that are present. An empty notes string
+// clears the notes; tags replace the whole list. CurrentVersion restores an earlier AI
+// explanation and cannot be combined with nuanceData.
+type UpdateAnnotationRequest struct {
+	Notes          *string           `json:"notes"`
+	IsBookmarked   *bool             `json:"isBookmarked"`
+	Tags           *[]string         `json:"tags"`
+	NuanceData     *NuanceDataUpdate `json:"nuanceData"`
+	CurrentVersion *int              `json:"currentVersion"`
+}
+
+// NuanceDataUpdate corrects individual fields of an annotation's explanation.
+type NuanceDataUpdate struct {
+	Meaning            *string `json:"meaning"`
+	UsageExample       *string `json:"usageExample"`
+	UsageTiming        *string `json:"usageTiming"`
+	WordBreakdown      *string `json:"wordBreakdown"`
+	AlternativeMeaning *string `json:"alternativeMeaning"`
+	Language           *string `json:"language"`
+}
+
+type AnnotationEditItem struct {
+	ID           int64             `json:"id"`
+	NuanceData   models.NuanceData `json:"nuanceData"`
+	Notes        string            `json:"notes,omitempty"`
+	Tags         []string          `json:"tags"`
+	IsBookmarked bool              `json:"isBookmarked"`
+	// CreatedAt is when the annotation stopped looking like this.
+	CreatedAt string `json:"createdAt"`
+}
+
+type GetAnnotationEditsResponse struct {
+	Data []AnnotationEditItem `json:"data"`
+}
+
+// updateAnnotationHandler applies a user's edit. The previous version is kept, so the AI's
+// original explanation can always be recovered from GET /v1/annotations/{id}/edits.
+func (h *AnnotationHandlers) updateAnnotationHandler(w http.ResponseWriter, r *http.Request) {
+	annotation, log, ok := h.loadOwnedAnnotation(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Notes == nil && req.IsBookmarked == nil && req.Tags == nil && req.NuanceData == nil && req.CurrentVersion == nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Nothing to update")
+		return
+	}
+	if req.NuanceData != nil && req.CurrentVersion != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "nuanceData and currentVersion cannot be combined")
+		return
+	}
+
+	updated := *annotation
+	if req.Notes != nil {
+		if utf8.RuneCountInString(*req.Notes) > maxAnnotationNotesLength {
+			h.writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("notes must be at most %d characters", maxAnnotationNotesLength))
+			return
+		}
+		updated.Notes = optionalString(*req.Notes)
+	}
+	if req.IsBookmarked != nil {
+		updated.IsBookmarked = *req.IsBookmarked
+	}
+	if req.Tags != nil {
+		tags, invalid := normalizeTags(*req.Tags)
+		if invalid != "" {
+			h.writeJSONError(w, http.StatusBadRequest, invalid)
+			return
+		}
+		updated.Tags = tags
+	}
+	if req.NuanceData != nil {
+		if invalid := applyNuanceDataUpdate(&updated.NuanceData, req.NuanceData); invalid != "" {
+			h.writeJSONError(w, http.StatusBadRequest, invalid)
+			return
+		}
+	}
+	if req.CurrentVersion != nil {
+		version, err := h.findAnnotationVersion(r, annotation.ID, *req.CurrentVersion)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to get annotation versions")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to get annotation versions")
+			return
+		}
+		if version == nil {
+			h.writeJSONError(w, http.StatusBadRequest, "Unknown currentVersion")
+			return
+		}
+		updated.NuanceData = version.NuanceData
+		updated.CurrentVersion = version.Version
+	}
+	updated.UpdatedAt = time.Now()
+
+	if err := h.db.UpdateAnnotation(r.Context(), &updated, annotation.UpdatedAt); err != nil {
+		if errors.Is(err, storage.ErrAnnotationConflict) {
+			h.writeJSONError(w, http.StatusConflict, "Annotation was changed by another request. Reload it and try again.")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to update annotation")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to update annotation")
+		return
+	}
+
+	log.Infof("Annotation updated")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toGetAnnotationResponse(&updated))
+}
+
+func (h *AnnotationHandlers) getAnnotationEditsHandler(w http.ResponseWriter, r *http.Request) {
+	annotation, log, ok := h.loadOwnedAnnotation(w, r)
+	if !ok {
+		return
+	}
+
+	edits, err := h.db.GetAnnotationEdits(r.Context(), annotation.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get annotation edits")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get annotation edits")
+		return
+	}
+
+	data := make([]AnnotationEditItem, len(edits))
+	for i, edit := range edits {
+		notes := ""
+		if edit.Notes != nil {
+			notes = *edit.Notes
+		}
+		data[i] = AnnotationEditItem{
+			ID:           edit.ID,
+			NuanceData:   edit.NuanceData,
+			Notes:        notes,
+			Tags:         models.NonNilTags(edit.Tags),
+			IsBookmarked: edit.IsBookmarked,
+			CreatedAt:    edit.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetAnnotationEditsResponse{Data: data})
+}
+
+// loadOwnedAnnotation resolves the annotation in the request path and checks that it belongs
+// to the caller. It writes the error response itself and returns false when the request must
+// stop.
+func (h *AnnotationHandlers) loadOwnedAnnotation(w http.ResponseWriter, r *http.Request) (*models.Annotation, *logger.Logger, bool) {
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context()))
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, log, false
+	}
+
+	log = log.WithUserID(userID)
+
+	idStr, _ := splitAnnotationPath(r.URL.Path)
+	annotationID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || annotationID <= 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid annotation ID")
+		return nil, log, false
+	}
+
+	log = log.WithField("annotation_id", annotationID)
+
+	annotation, err := h.db.GetAnnotationByID(r.Context(), annotationID)
+	if err != nil || annotation == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Annotation not found")
+		return nil, log, false
+	}
+	if annotation.UserID != userID {
+		log.Warn("User attempted to access annotation belonging to another user")
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return nil, log, false
+	}
+
+	return annotation, log, true
+}
+
+// applyNuanceDataUpdate overwrites the fields present in update. It returns a message when
+// the update is invalid.
+func applyNuanceDataUpdate(nuance *models.NuanceData, update *NuanceDataUpdate) string {
+	if update.Language != nil {
+		if *update.Language != "" && !isValidLanguage(*update.Language) {
+			return "Invalid nuanceData.language"
+		}
+		nuance.Language = *update.Language
+	}
+
+	fields := []struct {
+		value *string
+		dst   *string
+	}{
+		{update.Meaning, &nuance.Meaning},
+		{update.UsageExample, &nuance.UsageExample},
+		{update.UsageTiming, &nuance.UsageTiming},
+		{update.WordBreakdown, &nuance.WordBreakdown},
+		{update.AlternativeMeaning, &nuance.AlternativeMeaning},
+	}
+	for _, f := range fields {
+		if f.value != nil {
+			*f.dst = strings.TrimSpace(*f.value)
+		}
+	}
+	return ""
+}
+
+// normalizeTags trims tags and drops empty and repeated ones, keeping the first spelling. It
+// returns a message when the list is too long or a tag is.
+func normalizeTags(tags []string) ([]string, string) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxAnnotationTagLength {
+			return nil, fmt.Sprintf("tags must be at most %d characters", maxAnnotationTagLength)
+		}
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxAnnotationTags {
+		return nil, fmt.Sprintf("Too many tags. Maximum is %d.", maxAnnotationTags)
+	}
+	return normalized, ""
+}
@@ -0,0 +1,160 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func annotationRequest(h *handlers.AnnotationHandlers, method, path string, userID int64, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	h.AnnotationByIDAPI(rec, req)
+	return rec
+}
+
+func TestUpdateAnnotation(t *testing.T) {
+	cfg := &config.Config{DefaultPageSize: 10}
+
+	newAnnotation := func(t *testing.T) (*testutil.MockDB, *handlers.AnnotationHandlers) {
+		t.Helper()
+		mockDB := testutil.NewMockDB()
+		mockDB.CreateAnnotation(context.Background(), &models.Annotation{
+			UserID:          1,
+			HighlightedText: "お疲れ様",
+			NuanceData:      models.NuanceData{Meaning: "AI meaning", UsageExample: "AI example"},
+			IsBookmarked:    true,
+			CreatedAt:       time.Now().Add(-time.Hour),
+		})
+		return mockDB, handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, cfg)
+	}
+
+	t.Run("edits fields and keeps the original", func(t *testing.T) {
+		_, h := newAnnotation(t)
+
+		body := `{"notes":"said by my boss","isBookmarked":false,"tags":[" work ","Work","greeting",""],"nuanceData":{"meaning":"Fixed meaning"}}`
+		rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp handlers.GetAnnotationResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp.Notes != "said by my boss" || resp.IsBookmarked {
+			t.Errorf("notes and bookmark not updated: %+v", resp)
+		}
+		if strings.Join(resp.Tags, ",") != "work,greeting" {
+			t.Errorf("expected normalized tags, got %q", resp.Tags)
+		}
+		if resp.NuanceData.Meaning != "Fixed meaning" || resp.NuanceData.UsageExample != "AI example" {
+			t.Errorf("expected only the meaning to change, got %+v", resp.NuanceData)
+		}
+		if resp.UpdatedAt == resp.CreatedAt {
+			t.Error("expected updatedAt to move")
+		}
+
+		rec = annotationRequest(h, http.MethodGet, "/v1/annotations/1/edits", 1, "")
+		var edits handlers.GetAnnotationEditsResponse
+		json.Unmarshal(rec.Body.Bytes(), &edits)
+		if len(edits.Data) != 1 || edits.Data[0].NuanceData.Meaning != "AI meaning" || !edits.Data[0].IsBookmarked {
+			t.Errorf("expected the original version in the history, got %+v", edits.Data)
+		}
+	})
+
+	t.Run("clears notes with an empty string", func(t *testing.T) {
+		_, h := newAnnotation(t)
+		annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, `{"notes":"x"}`)
+
+		rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, `{"notes":""}`)
+		var resp handlers.GetAnnotationResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusOK || resp.Notes != "" {
+			t.Errorf("expected notes cleared, got %d %+v", rec.Code, resp)
+		}
+	})
+
+	t.Run("rejects invalid edits", func(t *testing.T) {
+		_, h := newAnnotation(t)
+		for _, body := range []string{
+			`{}`,
+			`{"nuanceData":{"language":"xx"}}`,
+			`{"tags":["` + strings.Repeat("a", 51) + `"]}`,
+		} {
+			if rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", body, rec.Code)
+			}
+		}
+	})
+
+	t.Run("rejects an edit based on a stale read", func(t *testing.T) {
+		mockDB, _ := newAnnotation(t)
+		stale, _ := mockDB.GetAnnotationByID(context.Background(), 1)
+		staleCopy := *stale
+		h := handlers.NewAnnotationHandlers(&staleAnnotationDB{MockDB: mockDB, stale: &staleCopy}, nil, nil, nil, cfg)
+
+		if rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, `{"notes":"first"}`); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, `{"isBookmarked":false}`)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", rec.Code)
+		}
+
+		current, _ := mockDB.GetAnnotationByID(context.Background(), 1)
+		if current.Notes == nil || *current.Notes != "first" || !current.IsBookmarked {
+			t.Errorf("expected the first edit to survive, got %+v", current)
+		}
+	})
+
+	t.Run("rejects other users", func(t *testing.T) {
+		mockDB, h := newAnnotation(t)
+		if rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 2, `{"notes":"x"}`); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rec.Code)
+		}
+		if edits, _ := mockDB.GetAnnotationEdits(context.Background(), 1); len(edits) != 0 {
+			t.Errorf("expected no edits, got %d", len(edits))
+		}
+	})
+}
+
+func TestCreateAnnotationHonorsBookmark(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	h := handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, &config.Config{})
+
+	body := `{"highlightedText":"はい","isBookmarked":false,"notes":"  ","tags":["yes"]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/annotations", strings.NewReader(body))
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.CreateAnnotationAPI(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	annotation, _ := mockDB.GetAnnotationByID(context.Background(), 1)
+	if annotation.IsBookmarked || annotation.Notes != nil || len(annotation.Tags) != 1 {
+		t.Errorf("unexpected annotation %+v", annotation)
+	}
+}
+
+// staleAnnotationDB serves the same snapshot of an annotation on every read, as if each
+// request had read it before the others wrote.
+type staleAnnotationDB struct {
+	*testutil.MockDB
+	stale *models.Annotation
+}
+
+func (db *staleAnnotationDB) GetAnnotationByID(ctx context.Context, annotationID int64) (*models.Annotation, error) {
+	copied := *db.stale
+	return &copied, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/export"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const exportDeckName = "Annotations"
+
+// exportAnnotationsHandler streams the user's annotations as an Anki deck (format=apkg, the
+// default) or as CSV or TSV. scanId limits the export to one scan, as on GET /v1/annotations.
+// audio=false leaves the clips out of a deck; only clips already synthesized are included.
+func (h *AnnotationHandlers) exportAnnotationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	log := logger.GetDefaultLogger().
+		WithRequestID(middleware.GetRequestID(r.Context())).
+		WithUserID(userID)
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatAPKG
+	}
+	contentType := export.ContentType(format)
+	if contentType == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "format must be one of apkg, csv, tsv")
+		return
+	}
+
+	var scanID int64
+	if scanIDParam := query.Get("scanId"); scanIDParam != "" {
+		parsed, err := strconv.ParseInt(scanIDParam, 10, 64)
+		if err != nil || parsed <= 0 {
+			h.writeJSONError(w, http.StatusBadRequest, "scanId must be a positive integer")
+			return
+		}
+		scanID = parsed
+	}
+
+	includeAudio := true
+	if audioParam := query.Get("audio"); audioParam != "" {
+		parsed, err := strconv.ParseBool(audioParam)
+		if err != nil {
+			h.writeJSONError(w, http.StatusBadRequest, "audio must be true or false")
+			return
+		}
+		includeAudio = parsed
+	}
+
+	now := time.Now()
+	out := &countingWriter{w: w}
+	var writer export.Writer
+	switch format {
+	case export.FormatCSV:
+		writer = export.NewCSV(out)
+	case export.FormatTSV:
+		writer = export.NewTSV(out)
+	case export.FormatAPKG:
+		var media export.Media
+		if includeAudio && h.audio != nil {
+			media = h.openAudio
+		}
+		var err error
+		writer, err = export.NewAPKG(r.Context(), out, exportDeckName, media, now)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to create deck")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to export annotations")
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("annotations-%s.%s", now.UTC().Format("20060102"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	count := 0
+	err := h.db.ForEachAnnotation(r.Context(), userID, scanID, func(annotation *models.Annotation) error {
+		count++
+		return writer.Add(r.Context(), annotation)
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to export annotations")
+		if out.n > 0 {
+			// The status line is gone; abort so the client sees a truncated download.
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Del("Content-Disposition")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to export annotations")
+		return
+	}
+
+	log.Infof("Exported annotations: format=%s count=%d", format, count)
+}
+
+// openAudio adapts the audio service to export.Media.
+func (h *AnnotationHandlers) openAudio(ctx context.Context, hash string) (io.ReadCloser, string, error) {
+	clip, err := h.audio.Open(ctx, hash)
+	if err != nil || clip == nil {
+		return nil, "", err
+	}
+	return clip.Content, clip.Asset.MIMEType, nil
+}
+
+// countingWriter records whether any of the response body has been written.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
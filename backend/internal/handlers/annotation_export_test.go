@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestExportAnnotations(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	scanA, scanB := int64(1), int64(2)
+	base := time.Now().Add(-time.Hour)
+	for i, a := range []*models.Annotation{
+		{UserID: 1, ScanID: &scanA, HighlightedText: "お疲れ様", NuanceData: models.NuanceData{Meaning: "thanks"}},
+		{UserID: 1, ScanID: &scanB, HighlightedText: "よろしく"},
+		{UserID: 1, ScanID: &scanA, HighlightedText: "すみません"},
+		{UserID: 2, ScanID: &scanA, HighlightedText: "他人"},
+	} {
+		a.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		mockDB.CreateAnnotation(context.Background(), a)
+	}
+	h := handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, &config.Config{DefaultPageSize: 10})
+
+	t.Run("csv honors the scan filter", func(t *testing.T) {
+		rec := annotationRequest(h, http.MethodGet, "/v1/annotations/export?format=csv&scanId=1", 1, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("unexpected Content-Type %q", ct)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, ".csv") {
+			t.Errorf("unexpected Content-Disposition %q", cd)
+		}
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read CSV: %v", err)
+		}
+		if len(records) != 3 || records[1][0] != "お疲れ様" || records[1][2] != "thanks" || records[2][0] != "すみません" {
+			t.Errorf("expected the user's two annotations of scan 1, oldest first, got %q", records)
+		}
+	})
+
+	t.Run("apkg is the default", func(t *testing.T) {
+		rec := annotationRequest(h, http.MethodGet, "/v1/annotations/export", 1, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatalf("expected a zip package: %v", err)
+		}
+		names := make([]string, len(zr.File))
+		for i, f := range zr.File {
+			names[i] = f.Name
+		}
+		if strings.Join(names, ",") != "collection.anki2,media" {
+			t.Errorf("unexpected package contents %q", names)
+		}
+	})
+
+	t.Run("rejects bad parameters", func(t *testing.T) {
+		for _, query := range []string{"format=xlsx", "scanId=abc", "audio=maybe"} {
+			rec := annotationRequest(h, http.MethodGet, "/v1/annotations/export?"+query, 1, "")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+			}
+			if rec.Header().Get("Content-Disposition") != "" {
+				t.Errorf("%s: error response should not be an attachment", query)
+			}
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+type RegenerateAnnotationRequest struct {
+	// TargetLanguage defaults to the language of the current explanation, then the user's
+	// preferred language.
+	TargetLanguage string `json:"targetLanguage,omitempty"`
+}
+
+type AnnotationVersionItem struct {
+	Version    int               `json:"version"`
+	NuanceData models.NuanceData `json:"nuanceData"`
+	Current    bool              `json:"current"`
+	CreatedAt  string            `json:"createdAt"`
+}
+
+type GetAnnotationVersionsResponse struct {
+	Data []AnnotationVersionItem `json:"data"`
+}
+
+// regenerateAnnotationHandler asks the model again for an annotation's explanation and makes
+// the answer the current version. Earlier versions stay available; PATCH currentVersion
+// switches back to one.
+func (h *AnnotationHandlers) regenerateAnnotationHandler(w http.ResponseWriter, r *http.Request) {
+	annotation, log, ok := h.loadOwnedAnnotation(w, r)
+	if !ok {
+		return
+	}
+
+	var req RegenerateAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.TargetLanguage != "" && !isValidLanguage(req.TargetLanguage) {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid targetLanguage")
+		return
+	}
+
+	targetLanguage := req.TargetLanguage
+	if targetLanguage == "" {
+		targetLanguage = annotation.NuanceData.Language
+	}
+	if targetLanguage == "" {
+		user, err := h.db.GetUserByID(r.Context(), annotation.UserID)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to get user from database")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to get user")
+			return
+		}
+		if user != nil {
+			targetLanguage = user.PreferredLanguage
+		}
+	}
+	if !isValidLanguage(targetLanguage) {
+		targetLanguage = models.DefaultLanguage
+	}
+
+	contextText := ""
+	if annotation.ContextText != nil {
+		contextText = *annotation.ContextText
+	}
+	entries := h.knowledge.LookupInContext(annotation.HighlightedText, contextText, knowledge.MaxPromptEntries)
+
+	resp, err := h.geminiClient.AnnotateWithKnowledge(r.Context(), contextText, annotation.HighlightedText, entries, targetLanguage)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to regenerate annotation")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to regenerate annotation")
+		return
+	}
+
+	now := time.Now()
+	version, err := h.db.AddAnnotationVersion(r.Context(), annotation.ID, toNuanceData(resp, targetLanguage), now)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to store annotation version")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to save annotation")
+		return
+	}
+
+	updated, err := h.db.GetAnnotationByID(r.Context(), annotation.ID)
+	if err != nil || updated == nil {
+		log.ErrorWithErr(err, "Failed to reload annotation after regenerating it")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to load annotation")
+		return
+	}
+
+	log.Infof("Annotation regenerated: version=%d", version.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toGetAnnotationResponse(updated))
+}
+
+func (h *AnnotationHandlers) getAnnotationVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	annotation, log, ok := h.loadOwnedAnnotation(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.db.GetAnnotationVersions(r.Context(), annotation.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get annotation versions")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get annotation versions")
+		return
+	}
+
+	data := make([]AnnotationVersionItem, len(versions))
+	for i, version := range versions {
+		data[i] = AnnotationVersionItem{
+			Version:    version.Version,
+			NuanceData: version.NuanceData,
+			Current:    version.Version == annotation.CurrentVersion,
+			CreatedAt:  version.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetAnnotationVersionsResponse{Data: data})
+}
+
+// findAnnotationVersion returns the given version of an annotation, or nil when it has none.
+func (h *AnnotationHandlers) findAnnotationVersion(r *http.Request, annotationID int64, number int) (*models.AnnotationVersion, error) {
+	versions, err := h.db.GetAnnotationVersions(r.Context(), annotationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range versions {
+		if version.Version == number {
+			return version, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestRegenerateAnnotation(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	mockDB.CreateUser(context.Background(), &models.User{Email: "regen@example.com", PreferredLanguage: "EN"})
+	contextText := "お疲れ様です、と言われた"
+	mockDB.CreateAnnotation(context.Background(), &models.Annotation{
+		UserID:          1,
+		HighlightedText: "お疲れ様",
+		ContextText:     &contextText,
+		NuanceData:      models.NuanceData{Meaning: "weak meaning", Language: "JP"},
+		IsBookmarked:    true,
+		CreatedAt:       time.Now().Add(-time.Hour),
+	})
+	client := &countingAnnotateClient{}
+	h := handlers.NewAnnotationHandlers(mockDB, client, knowledge.NewEmptyService(), nil, &config.Config{})
+
+	rec := annotationRequest(h, http.MethodPost, "/v1/annotations/1/regenerate", 1, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp handlers.GetAnnotationResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.CurrentVersion != 2 || resp.NuanceData.Meaning != "thanks for your hard work" {
+		t.Errorf("expected version 2 with the new meaning, got %+v", resp)
+	}
+	if client.lastLanguage != "JP" {
+		t.Errorf("expected the explanation's language to be kept, got %q", client.lastLanguage)
+	}
+
+	rec = annotationRequest(h, http.MethodGet, "/v1/annotations/1/versions", 1, "")
+	var versions handlers.GetAnnotationVersionsResponse
+	json.Unmarshal(rec.Body.Bytes(), &versions)
+	if len(versions.Data) != 2 || versions.Data[0].Version != 2 || !versions.Data[0].Current || versions.Data[1].Current {
+		t.Fatalf("unexpected versions %+v", versions.Data)
+	}
+	if versions.Data[1].NuanceData.Meaning != "weak meaning" {
+		t.Errorf("expected the original explanation as version 1, got %+v", versions.Data[1])
+	}
+
+	rec = annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, `{"currentVersion":1}`)
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || resp.CurrentVersion != 1 || resp.NuanceData.Meaning != "weak meaning" {
+		t.Errorf("expected version 1 restored, got %d %+v", rec.Code, resp)
+	}
+
+	for _, body := range []string{`{"currentVersion":3}`, `{"currentVersion":2,"nuanceData":{"meaning":"x"}}`} {
+		if rec := annotationRequest(h, http.MethodPatch, "/v1/annotations/1", 1, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, rec.Code)
+		}
+	}
+
+	if rec := annotationRequest(h, http.MethodPost, "/v1/annotations/1/regenerate", 2, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for another user, got %d", rec.Code)
+	}
+	if client.calls != 1 {
+		t.Errorf("expected 1 model call, got %d", client.calls)
+	}
+}
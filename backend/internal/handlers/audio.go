@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/audio"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+)
+
+// AudioHandlers serve stored speech clips by hash.
+type AudioHandlers struct {
+	audio *audio.Service
+}
+
+func NewAudioHandlers(audioSvc *audio.Service) *AudioHandlers {
+	return &AudioHandlers{audio: audioSvc}
+}
+
+// audioURL is the stable address of a stored clip.
+func audioURL(hash string) string {
+	return "/v1/audio/" + hash
+}
+
+// AudioAPI serves GET /v1/audio/{hash}. Clips are shared between users: the hash only
+// identifies text the caller already had.
+func (h *AudioHandlers) AudioAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	hash := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/audio/"), "/")
+	if !isAudioHash(hash) {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid audio ID")
+		return
+	}
+
+	clip, err := h.audio.Open(r.Context(), hash)
+	if err != nil {
+		logger.GetDefaultLogger().
+			WithRequestID(middleware.GetRequestID(r.Context())).
+			WithUserID(userID).
+			WithField("audio_hash", hash).
+			ErrorWithErr(err, "Failed to open audio")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to open audio")
+		return
+	}
+	if clip == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Audio not found")
+		return
+	}
+
+	serveClip(w, r, h.audio, clip)
+}
+
+// serveClip writes a stored clip in the format negotiated from the Accept header, with the
+// clip's hash as a strong ETag. Other formats are encoded once and stored by svc.
+// http.ServeContent answers Range and If-None-Match requests and sets Content-Length, so
+// players can seek without downloading the clip again.
+func serveClip(w http.ResponseWriter, r *http.Request, svc *audio.Service, clip *audio.Clip) {
+	w.Header().Set("Vary", "Accept")
+	format, ok := audio.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		clip.Content.Close()
+		writeNotAcceptable(w)
+		return
+	}
+
+	original := clip.Asset
+	clip, err := svc.Encoded(r.Context(), clip, format)
+	if err != nil {
+		logger.GetDefaultLogger().
+			WithRequestID(middleware.GetRequestID(r.Context())).
+			WithField("audio_hash", original.Hash).
+			ErrorWithErr(err, "Failed to encode audio")
+		http.Error(w, "Failed to encode audio", http.StatusInternalServerError)
+		return
+	}
+	defer clip.Content.Close()
+
+	etag := original.Hash
+	if clip.Asset.MIMEType != original.MIMEType {
+		etag += "-" + format.Name
+	}
+
+	w.Header().Set("Content-Type", clip.Asset.MIMEType)
+	w.Header().Set("ETag", `"`+etag+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.Header().Set("X-Audio-URL", audioURL(original.Hash))
+	if clip.Cached {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+	http.ServeContent(w, r, "", original.CreatedAt, clip.Content)
+}
+
+// writeNotAcceptable lists the audio formats a client may ask for.
+func writeNotAcceptable(w http.ResponseWriter) {
+	http.Error(w, "Supported formats: "+audio.FormatWAV.MIMEType+", "+audio.FormatIMAADPCM.MIMEType, http.StatusNotAcceptable)
+}
+
+func isAudioHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *AudioHandlers) writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+	})
+}
@@ -0,0 +1,251 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/audio"
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type countingSpeechClient struct {
+	mockSpeechGeminiClient
+	calls int
+}
+
+func (c *countingSpeechClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	c.calls++
+	return &gemini.SpeechResponse{Audio: []byte("RIFF0123456789"), MIMEType: "audio/wav"}, nil
+}
+
+func newAudioTestService(t *testing.T, mockDB *testutil.MockDB, client gemini.Client) *audio.Service {
+	t.Helper()
+	files, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalFileStorage failed: %v", err)
+	}
+	return audio.NewService(mockDB, files, client, "gemini")
+}
+
+func speak(h *handlers.AIHandlers, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", strings.NewReader(body))
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.SpeakAPI(rec, req)
+	return rec
+}
+
+func TestSpeakAPIServesStoredAudio(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	client := &countingSpeechClient{}
+	audioSvc := newAudioTestService(t, mockDB, client)
+	h := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), nil, audioSvc)
+	body := `{"highlightedText":"おはよう","contextText":"朝の挨拶"}`
+
+	first := speak(h, body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", first.Code)
+	}
+	if got := first.Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("expected X-Cache MISS, got %q", got)
+	}
+
+	second := speak(h, body)
+	if got := second.Header().Get("X-Cache"); got != "HIT" {
+		t.Errorf("expected X-Cache HIT, got %q", got)
+	}
+	if second.Body.String() != "RIFF0123456789" {
+		t.Errorf("unexpected body %q", second.Body.String())
+	}
+	if client.calls != 1 {
+		t.Errorf("expected 1 synthesis, got %d", client.calls)
+	}
+
+	etag := first.Header().Get("ETag")
+	audioURL := first.Header().Get("X-Audio-URL")
+	if etag == "" || !strings.HasPrefix(audioURL, "/v1/audio/") {
+		t.Fatalf("expected ETag and X-Audio-URL, got %q and %q", etag, audioURL)
+	}
+
+	audioHandlers := handlers.NewAudioHandlers(audioSvc)
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, audioURL, nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), 2))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		audioHandlers.AudioAPI(rec, req)
+		return rec
+	}
+
+	if rec := get("Range", "bytes=4-7"); rec.Code != http.StatusPartialContent || rec.Body.String() != "0123" {
+		t.Errorf("expected 206 with %q, got %d with %q", "0123", rec.Code, rec.Body.String())
+	}
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", rec.Code)
+	}
+	if client.calls != 1 {
+		t.Errorf("expected serving by URL to skip synthesis, got %d calls", client.calls)
+	}
+}
+
+func TestAudioAPIRejectsUnknownHash(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	h := handlers.NewAudioHandlers(newAudioTestService(t, mockDB, &countingSpeechClient{}))
+
+	for path, want := range map[string]int{
+		"/v1/audio/not-a-hash":                  http.StatusBadRequest,
+		"/v1/audio/" + strings.Repeat("ab", 32): http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.AudioAPI(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestAnnotationAudio(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	client := &countingSpeechClient{}
+	audioSvc := newAudioTestService(t, mockDB, client)
+	h := handlers.NewAnnotationHandlers(mockDB, nil, nil, audioSvc, &config.Config{DefaultPageSize: 20})
+
+	contextText := "朝の挨拶"
+	annotationID, _ := mockDB.CreateAnnotation(context.Background(), &models.Annotation{
+		UserID:          1,
+		HighlightedText: "おはよう",
+		ContextText:     &contextText,
+	})
+
+	play := func(userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/annotations/1/audio", nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		h.AnnotationByIDAPI(rec, req)
+		return rec
+	}
+
+	if rec := play(2); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for another user, got %d", rec.Code)
+	}
+
+	first := play(1)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", first.Code)
+	}
+	annotation, _ := mockDB.GetAnnotationByID(context.Background(), annotationID)
+	if annotation.AudioHash == nil || `"`+*annotation.AudioHash+`"` != first.Header().Get("ETag") {
+		t.Fatalf("expected annotation to be linked to its audio, got %v", annotation.AudioHash)
+	}
+
+	if second := play(1); second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected second play to be served from storage")
+	}
+	if client.calls != 1 {
+		t.Errorf("expected 1 synthesis, got %d", client.calls)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/annotations/1", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.AnnotationByIDAPI(rec, req)
+	var resp handlers.GetAnnotationResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.AudioURL != "/v1/annotations/1/audio" {
+		t.Errorf("unexpected audioUrl %q", resp.AudioURL)
+	}
+}
+
+func TestCreateAnnotationLinksPlayedAudio(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	client := &countingSpeechClient{}
+	audioSvc := newAudioTestService(t, mockDB, client)
+	aiHandlers := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), nil, audioSvc)
+	annotationHandlers := handlers.NewAnnotationHandlers(mockDB, nil, nil, audioSvc, &config.Config{DefaultPageSize: 20})
+
+	played := speak(aiHandlers, `{"highlightedText":"残業","contextText":"今日も残業だ"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/annotations",
+		strings.NewReader(`{"highlightedText":"残業","contextText":"今日も残業だ","nuanceData":{"meaning":"overtime"}}`))
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	annotationHandlers.CreateAnnotationAPI(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+
+	annotation, _ := mockDB.GetAnnotationByID(context.Background(), 1)
+	if annotation.AudioHash == nil || `"`+*annotation.AudioHash+`"` != played.Header().Get("ETag") {
+		t.Errorf("expected the played clip to be linked, got %v", annotation.AudioHash)
+	}
+}
+
+type wavSpeechClient struct {
+	mockSpeechGeminiClient
+}
+
+func (c *wavSpeechClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
+	return &gemini.SpeechResponse{Audio: gemini.WrapPCMAsWAV(make([]byte, 48000), 24000, 1, 16), MIMEType: "audio/wav"}, nil
+}
+
+func TestSpeakAPINegotiatesFormat(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	client := &wavSpeechClient{}
+	cached := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), nil, newAudioTestService(t, mockDB, client))
+	direct := handlers.NewAIHandlers(mockDB, client, knowledge.NewEmptyService(), nil, nil)
+
+	speakWith := func(h *handlers.AIHandlers, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/ai/speech", strings.NewReader(`{"highlightedText":"はい"}`))
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		h.SpeakAPI(rec, req)
+		return rec
+	}
+
+	for name, h := range map[string]*handlers.AIHandlers{"cached": cached, "direct": direct} {
+		t.Run(name, func(t *testing.T) {
+			plain := speakWith(h, "")
+			compressed := speakWith(h, audio.FormatIMAADPCM.MIMEType)
+
+			if got := plain.Header().Get("Content-Type"); got != "audio/wav" {
+				t.Errorf("expected WAV by default, got %q", got)
+			}
+			if got := compressed.Header().Get("Content-Type"); got != audio.FormatIMAADPCM.MIMEType {
+				t.Errorf("expected ADPCM, got %q", got)
+			}
+			if compressed.Body.Len()*3 > plain.Body.Len() {
+				t.Errorf("expected ADPCM to be much smaller: %d vs %d bytes", compressed.Body.Len(), plain.Body.Len())
+			}
+			if got := compressed.Header().Get("Content-Length"); got != strconv.Itoa(compressed.Body.Len()) {
+				t.Errorf("Content-Length %q does not match body of %d bytes", got, compressed.Body.Len())
+			}
+			if etag := compressed.Header().Get("ETag"); etag != "" && etag == plain.Header().Get("ETag") {
+				t.Error("expected each format to have its own ETag")
+			}
+			if rec := speakWith(h, "audio/mpeg"); rec.Code != http.StatusNotAcceptable {
+				t.Errorf("expected status 406, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -173,7 +173,7 @@ func (h *AuthHandlers) GoogleCallbackAPI(w http.ResponseWriter, r *http.Request)
 			Email:             userInfo.Email,
 			Provider:          "google",
 			ProviderID:        userInfo.ID,
-			PreferredLanguage: "ID",
+			PreferredLanguage: models.DefaultLanguage,
 			CreatedAt:         time.Now(),
 			UpdatedAt:         time.Now(),
 		}
@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const maxDocumentTitleLength = 200
+
+type DocumentHandlers struct {
+	db     storage.DB
+	config *config.Config
+}
+
+func NewDocumentHandlers(db storage.DB, cfg *config.Config) *DocumentHandlers {
+	return &DocumentHandlers{
+		db:     db,
+		config: cfg,
+	}
+}
+
+type CreateDocumentRequest struct {
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	CoverImageURL string `json:"coverImageUrl"`
+	Language      string `json:"language"`
+}
+
+// UpdateDocumentRequest is a partial update: omitted fields are left unchanged and an
+// empty string clears an optional field.
+type UpdateDocumentRequest struct {
+	Title         *string `json:"title"`
+	Author        *string `json:"author"`
+	CoverImageURL *string `json:"coverImageUrl"`
+	Language      *string `json:"language"`
+}
+
+type DocumentResponse struct {
+	ID            int64   `json:"id"`
+	Title         string  `json:"title"`
+	Author        *string `json:"author,omitempty"`
+	CoverImageURL *string `json:"coverImageUrl,omitempty"`
+	Language      *string `json:"language,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
+
+type DocumentPageItem struct {
+	ScanID           int64   `json:"scanId"`
+	PageNumber       int     `json:"pageNumber"`
+	ImageURL         string  `json:"imageUrl"`
+	DetectedLanguage *string `json:"detectedLanguage,omitempty"`
+	Status           string  `json:"status"`
+}
+
+type GetDocumentResponse struct {
+	DocumentResponse
+	Pages []DocumentPageItem `json:"pages"`
+}
+
+type GetDocumentsResponse struct {
+	Data []DocumentResponse `json:"data"`
+	Meta PaginationMeta     `json:"meta"`
+}
+
+type GetDocumentPagesResponse struct {
+	Data []DocumentPageItem `json:"data"`
+}
+
+type AddDocumentPageRequest struct {
+	ScanID int64 `json:"scanId"`
+}
+
+type ReorderDocumentPagesRequest struct {
+	ScanIDs []int64 `json:"scanIds"`
+}
+
+func (h *DocumentHandlers) DocumentsAPI(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.createDocumentHandler(w, r)
+	case http.MethodGet:
+		h.getDocumentsHandler(w, r)
+	default:
+		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *DocumentHandlers) DocumentByIDAPI(w http.ResponseWriter, r *http.Request) {
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context()))
+
+	_, action, pageID := splitDocumentPath(r.URL.Path)
+	switch {
+	case action == "":
+		switch r.Method {
+		case http.MethodGet:
+			h.getDocumentHandler(w, r, log)
+		case http.MethodPatch:
+			h.updateDocumentHandler(w, r, log)
+		case http.MethodDelete:
+			h.deleteDocumentHandler(w, r, log)
+		default:
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	case action == "pages" && pageID == "":
+		switch r.Method {
+		case http.MethodGet:
+			h.getDocumentPagesHandler(w, r, log)
+		case http.MethodPost:
+			h.addDocumentPageHandler(w, r, log)
+		case http.MethodPut:
+			h.reorderDocumentPagesHandler(w, r, log)
+		default:
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	case action == "pages":
+		if r.Method != http.MethodDelete {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.removeDocumentPageHandler(w, r, log, pageID)
+	default:
+		h.writeJSONError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// splitDocumentPath splits /v1/documents/{id}[/{action}[/{pageID}]] into its parts.
+func splitDocumentPath(path string) (string, string, string) {
+	path = strings.Trim(strings.TrimPrefix(path, "/v1/documents/"), "/")
+	idStr, rest, _ := strings.Cut(path, "/")
+	action, pageID, _ := strings.Cut(rest, "/")
+	return idStr, action, pageID
+}
+
+func (h *DocumentHandlers) createDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context())).WithUserID(userID)
+
+	var req CreateDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	now := time.Now()
+	document := &models.Document{
+		UserID:        userID,
+		Title:         strings.TrimSpace(req.Title),
+		Author:        optionalString(req.Author),
+		CoverImageURL: optionalString(req.CoverImageURL),
+		Language:      optionalString(req.Language),
+		CreatedAt:     now,
+	}
+	if msg := validateDocument(document); msg != "" {
+		h.writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if _, err := h.db.CreateDocument(r.Context(), document); err != nil {
+		log.ErrorWithErr(err, "Failed to create document")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to create document")
+		return
+	}
+
+	log.Infof("Document created: id=%d", document.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toDocumentResponse(document))
+}
+
+func (h *DocumentHandlers) getDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context())).WithUserID(userID)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	if size < 1 {
+		size = h.config.DefaultPageSize
+	}
+	if size > 100 {
+		size = 100
+	}
+
+	documents, err := h.db.GetDocumentsByUserID(r.Context(), userID, page, size)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get documents from database")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get documents")
+		return
+	}
+
+	data := make([]DocumentResponse, len(documents))
+	for i, document := range documents {
+		data[i] = toDocumentResponse(document)
+	}
+
+	var nextPage, prevPage *int
+	if len(documents) == size {
+		nextPageVal := page + 1
+		nextPage = &nextPageVal
+	}
+	if page > 1 {
+		prevPageVal := page - 1
+		prevPage = &prevPageVal
+	}
+
+	response := GetDocumentsResponse{
+		Data: data,
+		Meta: PaginationMeta{
+			CurrentPage:  page,
+			PageSize:     size,
+			NextPage:     nextPage,
+			PreviousPage: prevPage,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DocumentHandlers) getDocumentHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	pages, err := h.db.GetDocumentPages(r.Context(), document.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get document pages")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetDocumentResponse{
+		DocumentResponse: toDocumentResponse(document),
+		Pages:            toDocumentPageItems(pages),
+	})
+}
+
+func (h *DocumentHandlers) updateDocumentHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	var req UpdateDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Title != nil {
+		document.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Author != nil {
+		document.Author = optionalString(*req.Author)
+	}
+	if req.CoverImageURL != nil {
+		document.CoverImageURL = optionalString(*req.CoverImageURL)
+	}
+	if req.Language != nil {
+		document.Language = optionalString(*req.Language)
+	}
+	if msg := validateDocument(document); msg != "" {
+		h.writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.db.UpdateDocument(r.Context(), document); err != nil {
+		log.ErrorWithErr(err, "Failed to update document")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to update document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toDocumentResponse(document))
+}
+
+func (h *DocumentHandlers) deleteDocumentHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteDocument(r.Context(), document.ID, document.UserID); err != nil {
+		log.ErrorWithErr(err, "Failed to delete document")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to delete document")
+		return
+	}
+
+	log.Infof("Document deleted successfully: id=%d", document.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DocumentHandlers) getDocumentPagesHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	pages, err := h.db.GetDocumentPages(r.Context(), document.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get document pages")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get document pages")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetDocumentPagesResponse{Data: toDocumentPageItems(pages)})
+}
+
+func (h *DocumentHandlers) addDocumentPageHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	var req AddDocumentPageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ScanID <= 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "scanId must be a positive integer")
+		return
+	}
+
+	scan, err := h.db.GetScanByID(r.Context(), req.ScanID)
+	if err != nil || scan == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Scan not found")
+		return
+	}
+	if scan.UserID != document.UserID {
+		log.Warn("User attempted to add scan belonging to another user to a document")
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if scan.DocumentID != nil && *scan.DocumentID == document.ID {
+		h.writeJSONError(w, http.StatusConflict, "Scan is already a page of this document")
+		return
+	}
+
+	pageNumber, err := h.db.AddDocumentPage(r.Context(), document.ID, scan.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to add document page")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to add page")
+		return
+	}
+	scan.DocumentID, scan.PageNumber = &document.ID, &pageNumber
+
+	log.Infof("Added scan %d to document as page %d", scan.ID, pageNumber)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toDocumentPageItem(scan))
+}
+
+// reorderDocumentPagesHandler takes the complete list of page scan IDs in their new order.
+func (h *DocumentHandlers) reorderDocumentPagesHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	var req ReorderDocumentPagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pages, err := h.db.GetDocumentPages(r.Context(), document.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get document pages")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to reorder pages")
+		return
+	}
+	if !samePages(pages, req.ScanIDs) {
+		h.writeJSONError(w, http.StatusBadRequest, "scanIds must list every page of the document exactly once")
+		return
+	}
+
+	if err := h.db.ReorderDocumentPages(r.Context(), document.ID, req.ScanIDs); err != nil {
+		log.ErrorWithErr(err, "Failed to reorder document pages")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to reorder pages")
+		return
+	}
+
+	pages, err = h.db.GetDocumentPages(r.Context(), document.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to reload document pages")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get document pages")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetDocumentPagesResponse{Data: toDocumentPageItems(pages)})
+}
+
+func (h *DocumentHandlers) removeDocumentPageHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger, pageID string) {
+	document, log, ok := h.loadOwnedDocument(w, r, log)
+	if !ok {
+		return
+	}
+
+	scanID, err := strconv.ParseInt(pageID, 10, 64)
+	if err != nil || scanID <= 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid scan ID")
+		return
+	}
+
+	if err := h.db.RemoveDocumentPage(r.Context(), document.ID, scanID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeJSONError(w, http.StatusNotFound, "Page not found")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to remove document page")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to remove page")
+		return
+	}
+
+	log.Infof("Removed scan %d from document", scanID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadOwnedDocument resolves the document in the request path and checks that it belongs
+// to the caller. It writes the error response itself and returns false when the request must stop.
+func (h *DocumentHandlers) loadOwnedDocument(w http.ResponseWriter, r *http.Request, log *logger.Logger) (*models.Document, *logger.Logger, bool) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, log, false
+	}
+
+	log = log.WithUserID(userID)
+
+	idStr, _, _ := splitDocumentPath(r.URL.Path)
+	documentID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || documentID <= 0 {
+		log.Warnf("Invalid document ID format: %s", idStr)
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid document ID")
+		return nil, log, false
+	}
+
+	log = log.WithField("document_id", documentID)
+
+	document, err := h.db.GetDocumentByID(r.Context(), documentID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get document by ID from database")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get document")
+		return nil, log, false
+	}
+	if document == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Document not found")
+		return nil, log, false
+	}
+	if document.UserID != userID {
+		log.Warn("User attempted to access document belonging to another user")
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return nil, log, false
+	}
+
+	return document, log, true
+}
+
+func validateDocument(document *models.Document) string {
+	if document.Title == "" {
+		return "title is required"
+	}
+	if len(document.Title) > maxDocumentTitleLength {
+		return "title is too long"
+	}
+	if document.Language != nil && !isValidLanguage(*document.Language) {
+		return "Invalid language"
+	}
+	return ""
+}
+
+// samePages reports whether scanIDs is a permutation of the document's current pages.
+func samePages(pages []*models.Scan, scanIDs []int64) bool {
+	if len(pages) != len(scanIDs) {
+		return false
+	}
+	remaining := make(map[int64]bool, len(pages))
+	for _, page := range pages {
+		remaining[page.ID] = true
+	}
+	for _, id := range scanIDs {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func toDocumentResponse(document *models.Document) DocumentResponse {
+	return DocumentResponse{
+		ID:            document.ID,
+		Title:         document.Title,
+		Author:        document.Author,
+		CoverImageURL: document.CoverImageURL,
+		Language:      document.Language,
+		CreatedAt:     document.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     document.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toDocumentPageItem(scan *models.Scan) DocumentPageItem {
+	pageNumber := 0
+	if scan.PageNumber != nil {
+		pageNumber = *scan.PageNumber
+	}
+	return DocumentPageItem{
+		ScanID:           scan.ID,
+		PageNumber:       pageNumber,
+		ImageURL:         scan.ImageURL,
+		DetectedLanguage: scan.DetectedLanguage,
+		Status:           scan.Status,
+	}
+}
+
+func toDocumentPageItems(pages []*models.Scan) []DocumentPageItem {
+	items := make([]DocumentPageItem, len(pages))
+	for i, scan := range pages {
+		items[i] = toDocumentPageItem(scan)
+	}
+	return items
+}
+
+func (h *DocumentHandlers) writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+	})
+}
@@ -0,0 +1,243 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func documentRequest(method, path string, userID int64, body any) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	return req.WithContext(middleware.WithUserID(req.Context(), userID))
+}
+
+func createDocument(t *testing.T, db *testutil.MockDB, userID int64, title string) *models.Document {
+	t.Helper()
+	document := &models.Document{UserID: userID, Title: title, CreatedAt: time.Now()}
+	if _, err := db.CreateDocument(context.Background(), document); err != nil {
+		t.Fatalf("CreateDocument failed: %v", err)
+	}
+	return document
+}
+
+func addPage(t *testing.T, h *handlers.DocumentHandlers, documentID, scanID int64) handlers.DocumentPageItem {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.DocumentByIDAPI(rec, documentRequest(http.MethodPost, documentPath(documentID, "/pages"), 1, handlers.AddDocumentPageRequest{ScanID: scanID}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 adding page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page handlers.DocumentPageItem
+	json.NewDecoder(rec.Body).Decode(&page)
+	return page
+}
+
+func documentPath(documentID int64, suffix string) string {
+	return "/v1/documents/" + strconv.FormatInt(documentID, 10) + suffix
+}
+
+func TestCreateDocument(t *testing.T) {
+	cfg := &config.Config{DefaultPageSize: 20}
+
+	t.Run("creates document", func(t *testing.T) {
+		h := handlers.NewDocumentHandlers(testutil.NewMockDB(), cfg)
+		rec := httptest.NewRecorder()
+		h.DocumentsAPI(rec, documentRequest(http.MethodPost, "/v1/documents", 1, handlers.CreateDocumentRequest{
+			Title:    " Shigoto Japan ",
+			Author:   "Tanaka",
+			Language: "JP",
+		}))
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.DocumentResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp.ID == 0 || resp.Title != "Shigoto Japan" || resp.Author == nil || *resp.Author != "Tanaka" {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+		if resp.CoverImageURL != nil {
+			t.Fatalf("expected no cover image, got %q", *resp.CoverImageURL)
+		}
+	})
+
+	t.Run("rejects missing title and unknown language", func(t *testing.T) {
+		h := handlers.NewDocumentHandlers(testutil.NewMockDB(), cfg)
+		for _, req := range []handlers.CreateDocumentRequest{
+			{Title: "  "},
+			{Title: "Chapter 1", Language: "XX"},
+		} {
+			rec := httptest.NewRecorder()
+			h.DocumentsAPI(rec, documentRequest(http.MethodPost, "/v1/documents", 1, req))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400 for %+v, got %d", req, rec.Code)
+			}
+		}
+	})
+}
+
+func TestDocumentPages(t *testing.T) {
+	cfg := &config.Config{DefaultPageSize: 20}
+
+	t.Run("appends, reorders and removes pages", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		h := handlers.NewDocumentHandlers(mockDB, cfg)
+		document := createDocument(t, mockDB, 1, "Chapter 1")
+		first := createPendingScan(t, mockDB, 1)
+		second := createPendingScan(t, mockDB, 1)
+		third := createPendingScan(t, mockDB, 1)
+
+		for i, scan := range []*models.Scan{first, second, third} {
+			if page := addPage(t, h, document.ID, scan.ID); page.PageNumber != i+1 {
+				t.Fatalf("expected page number %d, got %d", i+1, page.PageNumber)
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		h.DocumentByIDAPI(rec, documentRequest(http.MethodPut, documentPath(document.ID, "/pages"), 1,
+			handlers.ReorderDocumentPagesRequest{ScanIDs: []int64{third.ID, first.ID, second.ID}}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200 reordering, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var pages handlers.GetDocumentPagesResponse
+		json.NewDecoder(rec.Body).Decode(&pages)
+		if len(pages.Data) != 3 || pages.Data[0].ScanID != third.ID || pages.Data[2].ScanID != second.ID {
+			t.Fatalf("unexpected page order: %+v", pages.Data)
+		}
+
+		rec = httptest.NewRecorder()
+		h.DocumentByIDAPI(rec, documentRequest(http.MethodDelete, documentPath(document.ID, "/pages/"+strconv.FormatInt(third.ID, 10)), 1, nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204 removing page, got %d", rec.Code)
+		}
+		if third.DocumentID != nil {
+			t.Fatal("expected removed scan to be detached from the document")
+		}
+		if *first.PageNumber != 1 || *second.PageNumber != 2 {
+			t.Fatalf("expected remaining pages renumbered, got %d and %d", *first.PageNumber, *second.PageNumber)
+		}
+	})
+
+	t.Run("reorder must list every page exactly once", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		h := handlers.NewDocumentHandlers(mockDB, cfg)
+		document := createDocument(t, mockDB, 1, "Chapter 1")
+		first := createPendingScan(t, mockDB, 1)
+		second := createPendingScan(t, mockDB, 1)
+		addPage(t, h, document.ID, first.ID)
+		addPage(t, h, document.ID, second.ID)
+
+		for _, scanIDs := range [][]int64{{first.ID}, {first.ID, first.ID}, {first.ID, 99}} {
+			rec := httptest.NewRecorder()
+			h.DocumentByIDAPI(rec, documentRequest(http.MethodPut, documentPath(document.ID, "/pages"), 1,
+				handlers.ReorderDocumentPagesRequest{ScanIDs: scanIDs}))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400 for %v, got %d", scanIDs, rec.Code)
+			}
+		}
+	})
+
+	t.Run("rejects scans and documents of other users", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		h := handlers.NewDocumentHandlers(mockDB, cfg)
+		document := createDocument(t, mockDB, 1, "Chapter 1")
+		otherDocument := createDocument(t, mockDB, 2, "Someone else's")
+		otherScan := createPendingScan(t, mockDB, 2)
+		ownScan := createPendingScan(t, mockDB, 1)
+
+		rec := httptest.NewRecorder()
+		h.DocumentByIDAPI(rec, documentRequest(http.MethodPost, documentPath(document.ID, "/pages"), 1, handlers.AddDocumentPageRequest{ScanID: otherScan.ID}))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403 for another user's scan, got %d", rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		h.DocumentByIDAPI(rec, documentRequest(http.MethodPost, documentPath(otherDocument.ID, "/pages"), 1, handlers.AddDocumentPageRequest{ScanID: ownScan.ID}))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403 for another user's document, got %d", rec.Code)
+		}
+	})
+}
+
+func TestDeleteDocumentKeepsScans(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	h := handlers.NewDocumentHandlers(mockDB, &config.Config{DefaultPageSize: 20})
+	document := createDocument(t, mockDB, 1, "Chapter 1")
+	scan := createPendingScan(t, mockDB, 1)
+	addPage(t, h, document.ID, scan.ID)
+
+	rec := httptest.NewRecorder()
+	h.DocumentByIDAPI(rec, documentRequest(http.MethodDelete, documentPath(document.ID, ""), 1, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+
+	if remaining, _ := mockDB.GetScanByID(context.Background(), scan.ID); remaining == nil {
+		t.Fatal("expected scan to survive document deletion")
+	}
+	if scan.DocumentID != nil || scan.PageNumber != nil {
+		t.Fatal("expected scan to be detached from the deleted document")
+	}
+}
+
+func TestListByDocument(t *testing.T) {
+	cfg := &config.Config{DefaultPageSize: 20}
+	mockDB := testutil.NewMockDB()
+	documentHandlers := handlers.NewDocumentHandlers(mockDB, cfg)
+	document := createDocument(t, mockDB, 1, "Chapter 1")
+	page := createPendingScan(t, mockDB, 1)
+	loose := createPendingScan(t, mockDB, 1)
+	addPage(t, documentHandlers, document.ID, page.ID)
+
+	mockDB.CreateAnnotation(context.Background(), &models.Annotation{UserID: 1, ScanID: &page.ID, HighlightedText: "仕事"})
+	mockDB.CreateAnnotation(context.Background(), &models.Annotation{UserID: 1, ScanID: &loose.ID, HighlightedText: "会社"})
+
+	t.Run("scans", func(t *testing.T) {
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+		rec := httptest.NewRecorder()
+		h.GetScansAPI(rec, documentRequest(http.MethodGet, "/v1/scans?documentId="+strconv.FormatInt(document.ID, 10), 1, nil))
+
+		var resp handlers.GetScansResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if len(resp.Data) != 1 || resp.Data[0].ID != page.ID || resp.Data[0].PageNumber == nil || *resp.Data[0].PageNumber != 1 {
+			t.Fatalf("expected only the document page, got %+v", resp.Data)
+		}
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		h := handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, cfg)
+		rec := httptest.NewRecorder()
+		h.GetAnnotationsAPI(rec, documentRequest(http.MethodGet, "/v1/annotations?documentId="+strconv.FormatInt(document.ID, 10), 1, nil))
+
+		var resp handlers.GetAnnotationsResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if len(resp.Data) != 1 || resp.Data[0].HighlightedText != "仕事" {
+			t.Fatalf("expected only annotations on document pages, got %+v", resp.Data)
+		}
+	})
+
+	t.Run("annotations reject combined filters", func(t *testing.T) {
+		h := handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, cfg)
+		rec := httptest.NewRecorder()
+		h.GetAnnotationsAPI(rec, documentRequest(http.MethodGet, "/v1/annotations?documentId=1&scanId=1", 1, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
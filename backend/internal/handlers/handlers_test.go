@@ -11,6 +11,7 @@ import (
 
 	"github.com/gemini-hackathon/app/internal/auth"
 	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
 	"github.com/gemini-hackathon/app/internal/handlers"
 	"github.com/gemini-hackathon/app/internal/middleware"
 	"github.com/gemini-hackathon/app/internal/models"
@@ -135,11 +136,47 @@ func TestGetUserIDMiddleware(t *testing.T) {
 			t.Errorf("Expected user ID 456, got %d", gotUserID)
 		}
 	})
+
+	t.Run("WithQueryTokenForEventStream", func(t *testing.T) {
+		token, _, _ := tokenService.GenerateToken(789)
+
+		req := httptest.NewRequest("GET", "/v1/scans/1/events?token="+token, nil)
+		req.Header.Set("Accept", "text/event-stream")
+
+		var gotUserID int64
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotUserID = middleware.GetUserID(r.Context())
+		})
+
+		authMiddleware.HandleEventStream(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		if gotUserID != 789 {
+			t.Errorf("Expected user ID 789, got %d", gotUserID)
+		}
+	})
+
+	t.Run("QueryTokenIgnoredOutsideEventStream", func(t *testing.T) {
+		token, _, _ := tokenService.GenerateToken(789)
+
+		req := httptest.NewRequest("GET", "/v1/ai/analyze/stream?token="+token, nil)
+		req.Header.Set("Accept", "text/event-stream")
+
+		handler := authMiddleware.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Handler should not be called with a query token on a regular request")
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", rec.Code)
+		}
+	})
 }
 
 func TestUserHandlers(t *testing.T) {
 	mockDB := testutil.NewMockDB()
-	userHandlers := handlers.NewUserHandlers(mockDB)
+	userHandlers := handlers.NewUserHandlers(mockDB, gemini.GeminiVoices, &config.Config{})
 
 	user := &models.User{
 		ID:                1,
@@ -229,6 +266,35 @@ func TestUserHandlers(t *testing.T) {
 			t.Errorf("Expected status 400, got %d", rec.Code)
 		}
 	})
+
+	patchPreferences := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/v1/users/me", strings.NewReader(body))
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		userHandlers.UpdateUserPreferencesAPI(rec, req)
+		return rec
+	}
+
+	t.Run("UpdateUserPreferencesAPI_Voice", func(t *testing.T) {
+		rec := patchPreferences(`{"preferredVoice": "puck"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		var resp handlers.UpdateUserPreferencesResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp.PreferredVoice != "Puck" || resp.PreferredLanguage != "JP" {
+			t.Errorf("Expected voice Puck and language unchanged, got %+v", resp)
+		}
+
+		if rec := patchPreferences(`{"preferredVoice": "Nobody"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown voice, got %d", rec.Code)
+		}
+
+		patchPreferences(`{"preferredVoice": ""}`)
+		if stored, _ := mockDB.GetUserByID(context.Background(), 1); stored.PreferredVoice != "" {
+			t.Errorf("Expected empty voice to restore the default, got %q", stored.PreferredVoice)
+		}
+	})
 }
 
 func TestAuthMiddlewareWithXToken(t *testing.T) {
@@ -258,7 +324,7 @@ func TestAuthMiddlewareWithXToken(t *testing.T) {
 func TestScanHandlers(t *testing.T) {
 	mockDB := testutil.NewMockDB()
 	cfg := &config.Config{DefaultPageSize: 20, MaxUploadSize: 10 * 1024 * 1024}
-	scanHandlers := handlers.NewScanHandlers(mockDB, nil, nil, cfg)
+	scanHandlers := handlers.NewScanHandlers(mockDB, nil, nil, nil, nil, nil, cfg)
 
 	t.Run("GetScansAPI_Unauthorized", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/v1/scans", nil)
@@ -344,7 +410,7 @@ func TestScanHandlers(t *testing.T) {
 func TestAnnotationHandlers(t *testing.T) {
 	mockDB := testutil.NewMockDB()
 	cfg := &config.Config{DefaultPageSize: 20}
-	annotationHandlers := handlers.NewAnnotationHandlers(mockDB, cfg)
+	annotationHandlers := handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, cfg)
 
 	t.Run("GetAnnotationsAPI_Unauthorized", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/v1/annotations", nil)
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+// KnowledgeHandlers let administrators edit the knowledge base. Every change asks the
+// knowledge watcher to reload the lookup index in the background, so analyses see it moments
+// later without a restart.
+type KnowledgeHandlers struct {
+	db        storage.DB
+	knowledge *knowledge.DBService
+	config    *config.Config
+	now       func() time.Time
+}
+
+func NewKnowledgeHandlers(db storage.DB, knowledgeSvc *knowledge.DBService, cfg *config.Config) *KnowledgeHandlers {
+	return &KnowledgeHandlers{
+		db:        db,
+		knowledge: knowledgeSvc,
+		config:    cfg,
+		now:       time.Now,
+	}
+}
+
+// KnowledgeEntryRequest sets every field of an entry; PUT replaces the whole entry.
+type KnowledgeEntryRequest struct {
+	Kosakata        string   `json:"kosakata"`
+	Kana            string   `json:"kana"`
+	Arti            string   `json:"arti"`
+	CaraBaca        string   `json:"caraBaca"`
+	Deskripsi       string   `json:"deskripsi"`
+	BidangPekerjaan []string `json:"bidangPekerjaan"`
+	Industri        []string `json:"industri"`
+	Konteks         string   `json:"konteks"`
+}
+
+type KnowledgeEntryResponse struct {
+	ID              int64    `json:"id"`
+	Kosakata        string   `json:"kosakata"`
+	Kana            string   `json:"kana"`
+	Arti            string   `json:"arti"`
+	CaraBaca        string   `json:"caraBaca"`
+	Deskripsi       string   `json:"deskripsi"`
+	BidangPekerjaan []string `json:"bidangPekerjaan"`
+	Industri        []string `json:"industri"`
+	Konteks         string   `json:"konteks"`
+	CreatedAt       string   `json:"createdAt"`
+	UpdatedAt       string   `json:"updatedAt"`
+}
+
+type GetKnowledgeEntriesResponse struct {
+	Data []KnowledgeEntryResponse `json:"data"`
+}
+
+type ImportKnowledgeResponse struct {
+	// Read is the number of rows with a Kosakata; Created of those were new terms and the
+	// rest replaced existing entries.
+	Read    int `json:"read"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	// Total is the size of the knowledge base after the import.
+	Total int `json:"total"`
+}
+
+func (h *KnowledgeHandlers) KnowledgeAPI(w http.ResponseWriter, r *http.Request) {
+	log, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.listEntriesHandler(w, r, log)
+	case http.MethodPost:
+		h.createEntryHandler(w, r, log)
+	default:
+		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *KnowledgeHandlers) KnowledgeByIDAPI(w http.ResponseWriter, r *http.Request) {
+	log, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/admin/knowledge/"), "/")
+	if idStr == "import" {
+		if r.Method != http.MethodPost {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.importHandler(w, r, log)
+		return
+	}
+
+	entryID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || entryID <= 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid entry ID")
+		return
+	}
+	log = log.WithField("knowledge_entry_id", entryID)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getEntryHandler(w, r, log, entryID)
+	case http.MethodPut:
+		h.updateEntryHandler(w, r, log, entryID)
+	case http.MethodDelete:
+		h.deleteEntryHandler(w, r, log, entryID)
+	default:
+		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// requireAdmin lets the request through only for users listed in ADMIN_EMAILS. It writes the
+// error response itself and returns false when the request must stop.
+func (h *KnowledgeHandlers) requireAdmin(w http.ResponseWriter, r *http.Request) (*logger.Logger, bool) {
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context()))
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return log, false
+	}
+	log = log.WithUserID(userID)
+
+	user, err := h.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get user from database")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get user")
+		return log, false
+	}
+	if user == nil || !h.config.IsAdmin(user.Email) {
+		log.Warn("Non-admin user attempted to access the knowledge base admin")
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return log, false
+	}
+	return log, true
+}
+
+func (h *KnowledgeHandlers) listEntriesHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	entries, err := h.db.ListKnowledgeEntries(r.Context())
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to list knowledge entries")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to list knowledge entries")
+		return
+	}
+
+	// q narrows the list to entries whose term, reading or meaning contains it.
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	data := []KnowledgeEntryResponse{}
+	for _, entry := range entries {
+		if q != "" && !strings.Contains(strings.ToLower(entry.Kosakata+"\x00"+entry.Kana+"\x00"+entry.Arti), q) {
+			continue
+		}
+		data = append(data, toKnowledgeEntryResponse(entry))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetKnowledgeEntriesResponse{Data: data})
+}
+
+func (h *KnowledgeHandlers) getEntryHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger, entryID int64) {
+	entry, err := h.db.GetKnowledgeEntry(r.Context(), entryID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get knowledge entry")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get knowledge entry")
+		return
+	}
+	if entry == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Knowledge entry not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toKnowledgeEntryResponse(entry))
+}
+
+func (h *KnowledgeHandlers) createEntryHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	entry, ok := h.decodeEntry(w, r)
+	if !ok {
+		return
+	}
+	entry.CreatedAt = h.now()
+
+	if _, err := h.db.CreateKnowledgeEntry(r.Context(), entry); err != nil {
+		if errors.Is(err, storage.ErrDuplicateKosakata) {
+			h.writeJSONError(w, http.StatusConflict, "An entry for this kosakata already exists")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to create knowledge entry")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to create knowledge entry")
+		return
+	}
+
+	log.Infof("Knowledge entry created: id=%d", entry.ID)
+	h.knowledge.RequestReload()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toKnowledgeEntryResponse(entry))
+}
+
+func (h *KnowledgeHandlers) updateEntryHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger, entryID int64) {
+	existing, err := h.db.GetKnowledgeEntry(r.Context(), entryID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get knowledge entry")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get knowledge entry")
+		return
+	}
+	if existing == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Knowledge entry not found")
+		return
+	}
+
+	entry, ok := h.decodeEntry(w, r)
+	if !ok {
+		return
+	}
+	entry.ID = existing.ID
+	entry.CreatedAt = existing.CreatedAt
+	entry.UpdatedAt = h.now()
+
+	if err := h.db.UpdateKnowledgeEntry(r.Context(), entry); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrDuplicateKosakata):
+			h.writeJSONError(w, http.StatusConflict, "An entry for this kosakata already exists")
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeJSONError(w, http.StatusNotFound, "Knowledge entry not found")
+		default:
+			log.ErrorWithErr(err, "Failed to update knowledge entry")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to update knowledge entry")
+		}
+		return
+	}
+
+	log.Infof("Knowledge entry updated")
+	h.knowledge.RequestReload()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toKnowledgeEntryResponse(entry))
+}
+
+func (h *KnowledgeHandlers) deleteEntryHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger, entryID int64) {
+	if err := h.db.DeleteKnowledgeEntry(r.Context(), entryID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeJSONError(w, http.StatusNotFound, "Knowledge entry not found")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to delete knowledge entry")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to delete knowledge entry")
+		return
+	}
+
+	log.Infof("Knowledge entry deleted")
+	h.knowledge.RequestReload()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// importHandler adds the rows of a knowledge CSV, sent as the request body or as the "file"
+// field of a multipart form. Rows replace entries with the same kosakata; other entries stay.
+func (h *KnowledgeHandlers) importHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxUploadSize)
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(h.config.MaxUploadSize); err != nil {
+			h.writeJSONError(w, http.StatusBadRequest, "Failed to parse form")
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			h.writeJSONError(w, http.StatusBadRequest, "Missing file")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	entries, err := knowledge.ReadCSV(body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.writeJSONError(w, http.StatusRequestEntityTooLarge, "CSV is too large")
+			return
+		}
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+		return
+	}
+
+	created, err := h.db.UpsertKnowledgeEntries(r.Context(), knowledge.ToModels(entries), h.now())
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to import knowledge entries")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to import knowledge entries")
+		return
+	}
+
+	log.Infof("Knowledge CSV imported: rows=%d new=%d", len(entries), created)
+	h.knowledge.RequestReload()
+
+	total, err := h.db.CountKnowledgeEntries(r.Context())
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to count knowledge entries")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to count knowledge entries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ImportKnowledgeResponse{
+		Read:    len(entries),
+		Created: created,
+		Updated: len(entries) - created,
+		Total:   total,
+	})
+}
+
+// decodeEntry reads and validates an entry from the request body. It writes the error
+// response itself and returns false when the request must stop.
+func (h *KnowledgeHandlers) decodeEntry(w http.ResponseWriter, r *http.Request) (*models.KnowledgeEntry, bool) {
+	var req KnowledgeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	entry := &models.KnowledgeEntry{
+		Kosakata:        strings.TrimSpace(req.Kosakata),
+		Kana:            strings.TrimSpace(req.Kana),
+		Arti:            strings.TrimSpace(req.Arti),
+		CaraBaca:        strings.TrimSpace(req.CaraBaca),
+		Deskripsi:       strings.TrimSpace(req.Deskripsi),
+		BidangPekerjaan: cleanKnowledgeList(req.BidangPekerjaan),
+		Industri:        cleanKnowledgeList(req.Industri),
+		Konteks:         strings.TrimSpace(req.Konteks),
+	}
+	if entry.Kosakata == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "kosakata is required")
+		return nil, false
+	}
+	return entry, true
+}
+
+// cleanKnowledgeList trims items and strips Notion links the way the CSV import does,
+// dropping items left empty.
+func cleanKnowledgeList(items []string) []string {
+	cleaned := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(knowledge.StripNotionLinks(item)); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	return cleaned
+}
+
+func toKnowledgeEntryResponse(entry *models.KnowledgeEntry) KnowledgeEntryResponse {
+	return KnowledgeEntryResponse{
+		ID:              entry.ID,
+		Kosakata:        entry.Kosakata,
+		Kana:            entry.Kana,
+		Arti:            entry.Arti,
+		CaraBaca:        entry.CaraBaca,
+		Deskripsi:       entry.Deskripsi,
+		BidangPekerjaan: models.NonNilTags(entry.BidangPekerjaan),
+		Industri:        models.NonNilTags(entry.Industri),
+		Konteks:         entry.Konteks,
+		CreatedAt:       entry.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       entry.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func (h *KnowledgeHandlers) writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+	})
+}
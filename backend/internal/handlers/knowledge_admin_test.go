@@ -0,0 +1,167 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestKnowledgeAdmin(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	ctx := context.Background()
+
+	admin := &models.User{Email: "Admin@example.com"}
+	mockDB.CreateUser(ctx, admin)
+	member := &models.User{Email: "member@example.com"}
+	mockDB.CreateUser(ctx, member)
+
+	svc, err := knowledge.NewDBService(ctx, mockDB)
+	if err != nil {
+		t.Fatalf("NewDBService failed: %v", err)
+	}
+	h := handlers.NewKnowledgeHandlers(mockDB, svc, &config.Config{
+		AdminEmails:   []string{"admin@example.com"},
+		MaxUploadSize: 1 << 20,
+	})
+
+	// Handlers only request a reload from the watcher, which isn't running here; reload in
+	// its place before checking lookups.
+	reload := func(t *testing.T) {
+		t.Helper()
+		if err := svc.Reload(ctx); err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+	}
+
+	do := func(t *testing.T, userID int64, method, path, contentType string, body []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		if path == "/v1/admin/knowledge" || strings.HasPrefix(path, "/v1/admin/knowledge?") {
+			h.KnowledgeAPI(rec, req)
+		} else {
+			h.KnowledgeByIDAPI(rec, req)
+		}
+		return rec
+	}
+
+	t.Run("rejects non-admins", func(t *testing.T) {
+		if rec := do(t, member.ID, http.MethodGet, "/v1/admin/knowledge", "", nil); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rec.Code)
+		}
+		if rec := do(t, 0, http.MethodGet, "/v1/admin/knowledge", "", nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", rec.Code)
+		}
+	})
+
+	var created handlers.KnowledgeEntryResponse
+	t.Run("create reaches lookups after a reload", func(t *testing.T) {
+		body := []byte(`{"kosakata":" 稟議 ","kana":"りんぎ","arti":"Approval","bidangPekerjaan":["Bisnis (https://notion.so/x)"," "]}`)
+		rec := do(t, admin.ID, http.MethodPost, "/v1/admin/knowledge", "application/json", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		if created.Kosakata != "稟議" || len(created.BidangPekerjaan) != 1 || created.BidangPekerjaan[0] != "Bisnis" {
+			t.Errorf("expected a cleaned entry, got %+v", created)
+		}
+		reload(t)
+		if got := svc.Lookup("稟議書"); len(got) != 1 || got[0].Arti != "Approval" {
+			t.Errorf("expected the new entry in lookups, got %+v", got)
+		}
+
+		if rec := do(t, admin.ID, http.MethodPost, "/v1/admin/knowledge", "application/json", body); rec.Code != http.StatusConflict {
+			t.Errorf("expected status 409 for a duplicate, got %d", rec.Code)
+		}
+		if rec := do(t, admin.ID, http.MethodPost, "/v1/admin/knowledge", "application/json", []byte(`{"arti":"x"}`)); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 without kosakata, got %d", rec.Code)
+		}
+	})
+
+	t.Run("update replaces the entry", func(t *testing.T) {
+		path := fmt.Sprintf("/v1/admin/knowledge/%d", created.ID)
+		rec := do(t, admin.ID, http.MethodPut, path, "application/json", []byte(`{"kosakata":"稟議","arti":"Approval request"}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		reload(t)
+		if got := svc.Lookup("稟議"); len(got) != 1 || got[0].Arti != "Approval request" || got[0].Kana != "" {
+			t.Errorf("expected the replaced entry in lookups, got %+v", got)
+		}
+		if rec := do(t, admin.ID, http.MethodPut, "/v1/admin/knowledge/999", "application/json", []byte(`{"kosakata":"x"}`)); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("import upserts CSV rows", func(t *testing.T) {
+		csvData := "Kosakata,Kana,Arti (EN / ID),Cara Baca,Deskripsi,Bidang Pekerjaan,Industri,Konteks\n" +
+			"稟議,りんぎ,Approval (imported),ringi,,,,\n" +
+			"根回し,ねまわし,Groundwork,nemawashi,,\"Bisnis (https://notion.so/y)\",,\n"
+
+		var form bytes.Buffer
+		mw := multipart.NewWriter(&form)
+		part, _ := mw.CreateFormFile("file", "knowledge.csv")
+		part.Write([]byte(csvData))
+		mw.Close()
+
+		rec := do(t, admin.ID, http.MethodPost, "/v1/admin/knowledge/import", mw.FormDataContentType(), form.Bytes())
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.ImportKnowledgeResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp != (handlers.ImportKnowledgeResponse{Read: 2, Created: 1, Updated: 1, Total: 2}) {
+			t.Errorf("unexpected import result %+v", resp)
+		}
+		reload(t)
+		if got := svc.Lookup("根回し"); len(got) != 1 || got[0].BidangPekerjaan[0] != "Bisnis" {
+			t.Errorf("expected the imported entry in lookups, got %+v", got)
+		}
+
+		rec = do(t, admin.ID, http.MethodPost, "/v1/admin/knowledge/import", "text/csv", []byte(csvData))
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusOK || resp.Created != 0 || resp.Total != 2 {
+			t.Errorf("expected a raw CSV body to update both entries, got %d %+v", rec.Code, resp)
+		}
+	})
+
+	t.Run("list filters by q", func(t *testing.T) {
+		rec := do(t, admin.ID, http.MethodGet, "/v1/admin/knowledge?q=groundwork", "", nil)
+		var resp handlers.GetKnowledgeEntriesResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusOK || len(resp.Data) != 1 || resp.Data[0].Kosakata != "根回し" {
+			t.Errorf("expected only 根回し, got %d %+v", rec.Code, resp)
+		}
+	})
+
+	t.Run("delete removes the entry from lookups", func(t *testing.T) {
+		path := fmt.Sprintf("/v1/admin/knowledge/%d", created.ID)
+		if rec := do(t, admin.ID, http.MethodDelete, path, "", nil); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", rec.Code)
+		}
+		reload(t)
+		if got := svc.Lookup("稟議"); len(got) != 0 {
+			t.Errorf("expected no entries after delete, got %+v", got)
+		}
+		if rec := do(t, admin.ID, http.MethodGet, path, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404 after delete, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/pagination"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const maxPageSize = 100
+
+// listParams holds the paging parameters GET /v1/scans and GET /v1/annotations share.
+//
+// Clients page with cursor: each response carries meta.nextCursor until the last page. Old
+// clients may keep sending page, which skips rows and is slower on long lists. size defaults
+// to DEFAULT_PAGE_SIZE and is capped at 100. sort is newest (the default) or oldest. from and
+// to bound the creation time; dates are whole UTC days and to is inclusive, while RFC 3339
+// timestamps are exact and to is exclusive. includeTotal=true adds meta.totalCount.
+type listParams struct {
+	storage.Page
+	size int
+	// pageNumber is the requested page, or 0 when paging by cursor.
+	pageNumber   int
+	includeTotal bool
+	// query identifies the filters and sort order, so a cursor only continues the list it
+	// was issued for.
+	query         string
+	createdFrom   time.Time
+	createdBefore time.Time
+}
+
+// listQueryParams are excluded from a cursor's query because they may change between pages.
+var listQueryParams = []string{"cursor", "page", "size", "includeTotal"}
+
+// parseListParams reads the shared paging parameters. It returns a message when one is invalid.
+func parseListParams(r *http.Request, defaultSize int, cursors *pagination.Signer) (listParams, string) {
+	query := r.URL.Query()
+	var p listParams
+
+	p.size, _ = strconv.Atoi(query.Get("size"))
+	if p.size < 1 {
+		p.size = defaultSize
+	}
+	p.size = min(p.size, maxPageSize)
+	p.Limit = p.size + 1
+
+	switch query.Get("sort") {
+	case "", "newest":
+	case "oldest":
+		p.Oldest = true
+	default:
+		return p, "sort must be newest or oldest"
+	}
+
+	var ok bool
+	if p.createdFrom, ok = parseTimeBound(query.Get("from"), false); !ok {
+		return p, "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+	}
+	if p.createdBefore, ok = parseTimeBound(query.Get("to"), true); !ok {
+		return p, "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+	}
+	if !p.createdFrom.IsZero() && !p.createdBefore.IsZero() && !p.createdFrom.Before(p.createdBefore) {
+		return p, "from must be before to"
+	}
+
+	if includeTotal := query.Get("includeTotal"); includeTotal != "" {
+		parsed, err := strconv.ParseBool(includeTotal)
+		if err != nil {
+			return p, "includeTotal must be true or false"
+		}
+		p.includeTotal = parsed
+	}
+
+	filters := url.Values{}
+	for key, values := range query {
+		filters[key] = values
+	}
+	for _, key := range listQueryParams {
+		filters.Del(key)
+	}
+	p.query = filters.Encode()
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		position, err := cursors.Decode(cursor, p.query)
+		if err != nil {
+			return p, "Invalid cursor for this query"
+		}
+		p.After = &storage.Keyset{CreatedAt: position.CreatedAt, PageNumber: position.PageNumber, ID: position.ID}
+		return p, ""
+	}
+
+	p.pageNumber, _ = strconv.Atoi(query.Get("page"))
+	if p.pageNumber < 1 {
+		p.pageNumber = 1
+	}
+	p.Offset = (p.pageNumber - 1) * p.size
+	return p, ""
+}
+
+// parseTimeBound parses a from or to parameter. An empty value is the zero time.
+func parseTimeBound(value string, upper bool) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if upper {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, true
+}
+
+// meta builds the pagination metadata for a page fetched with p. fetched is how many rows the
+// query returned, one more than the page size when there are more; last is the position of the
+// last row kept.
+func (p listParams) meta(cursors *pagination.Signer, fetched int, last storage.Keyset, total *int) PaginationMeta {
+	meta := PaginationMeta{
+		CurrentPage: p.pageNumber,
+		PageSize:    p.size,
+		TotalCount:  total,
+	}
+	if fetched > p.size {
+		next := cursors.Encode(pagination.Cursor{
+			CreatedAt:  last.CreatedAt,
+			PageNumber: last.PageNumber,
+			ID:         last.ID,
+			Query:      p.query,
+		})
+		meta.NextCursor = &next
+		if p.pageNumber > 0 {
+			nextPage := p.pageNumber + 1
+			meta.NextPage = &nextPage
+		}
+	}
+	if p.pageNumber > 1 {
+		prevPage := p.pageNumber - 1
+		meta.PreviousPage = &prevPage
+	}
+	return meta
+}
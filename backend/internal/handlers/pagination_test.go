@@ -0,0 +1,179 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func listRequest(handler http.HandlerFunc, path string, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestScanListPagination(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	ja, en := "ja", "en"
+	for i := range 5 {
+		language := &ja
+		if i == 4 {
+			language = &en
+		}
+		mockDB.CreateScan(context.Background(), &models.Scan{
+			UserID:           1,
+			Status:           models.ScanStatusCompleted,
+			DetectedLanguage: language,
+			// Two scans share a timestamp so the ID breaks the tie.
+			CreatedAt: base.Add(time.Duration(min(i, 3)) * 24 * time.Hour),
+		})
+	}
+	h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, &config.Config{DefaultPageSize: 10, CursorSecret: "test"})
+
+	page := func(t *testing.T, query url.Values) handlers.GetScansResponse {
+		t.Helper()
+		rec := listRequest(h.GetScansAPI, "/v1/scans", query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.GetScansResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	t.Run("cursor walks the list without gaps or repeats", func(t *testing.T) {
+		query := url.Values{"size": {"2"}}
+		var ids []int64
+		for range 5 {
+			resp := page(t, query)
+			for _, scan := range resp.Data {
+				ids = append(ids, scan.ID)
+			}
+			if resp.Meta.NextCursor == nil {
+				break
+			}
+			query.Set("cursor", *resp.Meta.NextCursor)
+		}
+		if len(ids) != 5 || ids[0] != 5 || ids[1] != 4 || ids[4] != 1 {
+			t.Errorf("expected scans 5 to 1, got %v", ids)
+		}
+	})
+
+	t.Run("oldest first", func(t *testing.T) {
+		resp := page(t, url.Values{"sort": {"oldest"}, "size": {"3"}})
+		if len(resp.Data) != 3 || resp.Data[0].ID != 1 || resp.Data[2].ID != 3 {
+			t.Errorf("unexpected order %+v", resp.Data)
+		}
+	})
+
+	t.Run("page numbers stop at the last page", func(t *testing.T) {
+		resp := page(t, url.Values{"page": {"3"}, "size": {"2"}, "includeTotal": {"true"}})
+		if resp.Meta.CurrentPage != 3 || len(resp.Data) != 1 || resp.Meta.NextPage != nil || resp.Meta.NextCursor != nil {
+			t.Errorf("unexpected last page %+v", resp.Meta)
+		}
+		if resp.Meta.PreviousPage == nil || *resp.Meta.PreviousPage != 2 {
+			t.Errorf("expected previous page 2, got %v", resp.Meta.PreviousPage)
+		}
+		if resp.Meta.TotalCount == nil || *resp.Meta.TotalCount != 5 {
+			t.Errorf("expected a total of 5, got %v", resp.Meta.TotalCount)
+		}
+
+		// A list that fills the page exactly has no phantom next page.
+		resp = page(t, url.Values{"size": {"5"}})
+		if len(resp.Data) != 5 || resp.Meta.NextPage != nil || resp.Meta.NextCursor != nil {
+			t.Errorf("expected no next page, got %+v", resp.Meta)
+		}
+	})
+
+	t.Run("filters by date and language", func(t *testing.T) {
+		resp := page(t, url.Values{"from": {"2026-05-02"}, "to": {"2026-05-03"}, "includeTotal": {"true"}})
+		if len(resp.Data) != 2 || resp.Data[0].ID != 3 || resp.Data[1].ID != 2 || *resp.Meta.TotalCount != 2 {
+			t.Errorf("expected scans 3 and 2, got %+v", resp.Data)
+		}
+		resp = page(t, url.Values{"language": {"EN"}})
+		if len(resp.Data) != 1 || resp.Data[0].ID != 5 {
+			t.Errorf("expected scan 5, got %+v", resp.Data)
+		}
+	})
+
+	t.Run("rejects foreign cursors and bad parameters", func(t *testing.T) {
+		cursor := *page(t, url.Values{"size": {"2"}}).Meta.NextCursor
+
+		other := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, &config.Config{DefaultPageSize: 10, CursorSecret: "other"})
+		if rec := listRequest(other.GetScansAPI, "/v1/scans", url.Values{"cursor": {cursor}}); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected a cursor signed with another key to be rejected, got %d", rec.Code)
+		}
+
+		for _, query := range []url.Values{
+			{"cursor": {cursor}, "sort": {"oldest"}},
+			{"cursor": {cursor + "x"}},
+			{"sort": {"random"}},
+			{"from": {"yesterday"}},
+			{"from": {"2026-05-03"}, "to": {"2026-05-01"}},
+			{"includeTotal": {"maybe"}},
+		} {
+			if rec := listRequest(h.GetScansAPI, "/v1/scans", query); rec.Code != http.StatusBadRequest {
+				t.Errorf("%v: expected status 400, got %d", query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestAnnotationListFilters(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, a := range []*models.Annotation{
+		{HighlightedText: "一", IsBookmarked: true, NuanceData: models.NuanceData{Language: "EN"}},
+		{HighlightedText: "二", IsBookmarked: false, NuanceData: models.NuanceData{Language: "EN"}},
+		{HighlightedText: "三", IsBookmarked: true, NuanceData: models.NuanceData{Language: "ID"}},
+	} {
+		a.UserID = 1
+		a.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		mockDB.CreateAnnotation(context.Background(), a)
+	}
+	h := handlers.NewAnnotationHandlers(mockDB, nil, nil, nil, &config.Config{DefaultPageSize: 10})
+
+	list := func(t *testing.T, query url.Values) handlers.GetAnnotationsResponse {
+		t.Helper()
+		rec := listRequest(h.GetAnnotationsAPI, "/v1/annotations", query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.GetAnnotationsResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	resp := list(t, url.Values{"bookmarked": {"true"}, "size": {"1"}, "includeTotal": {"true"}})
+	if len(resp.Data) != 1 || resp.Data[0].HighlightedText != "三" || *resp.Meta.TotalCount != 2 || resp.Meta.NextCursor == nil {
+		t.Fatalf("unexpected first page %+v %+v", resp.Data, resp.Meta)
+	}
+	resp = list(t, url.Values{"bookmarked": {"true"}, "size": {"1"}, "cursor": {*resp.Meta.NextCursor}})
+	if len(resp.Data) != 1 || resp.Data[0].HighlightedText != "一" || resp.Meta.NextCursor != nil || resp.Meta.CurrentPage != 0 {
+		t.Errorf("unexpected second page %+v %+v", resp.Data, resp.Meta)
+	}
+
+	resp = list(t, url.Values{"language": {"EN"}, "to": {"2026-05-01T13:00:00Z"}})
+	if len(resp.Data) != 1 || resp.Data[0].HighlightedText != "一" {
+		t.Errorf("expected only 一, got %+v", resp.Data)
+	}
+
+	for _, query := range []url.Values{{"language": {"XX"}}, {"bookmarked": {"sometimes"}}} {
+		if rec := listRequest(h.GetAnnotationsAPI, "/v1/annotations", query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/srs"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const (
+	defaultReviewBatchSize = 20
+	maxReviewBatchSize     = 100
+	defaultReviewStatsDays = 30
+	maxReviewStatsDays     = 365
+)
+
+// ReviewHandlers serve spaced-repetition reviews of bookmarked annotations.
+type ReviewHandlers struct {
+	db     storage.DB
+	config *config.Config
+	now    func() time.Time
+}
+
+func NewReviewHandlers(db storage.DB, cfg *config.Config) *ReviewHandlers {
+	return &ReviewHandlers{
+		db:     db,
+		config: cfg,
+		now:    time.Now,
+	}
+}
+
+type ReviewCardItem struct {
+	CardID          int64             `json:"cardId"`
+	AnnotationID    int64             `json:"annotationId"`
+	HighlightedText string            `json:"highlightedText"`
+	ContextText     string            `json:"contextText,omitempty"`
+	NuanceData      models.NuanceData `json:"nuanceData"`
+	Notes           string            `json:"notes,omitempty"`
+	Repetitions     int               `json:"repetitions"`
+	IntervalDays    int               `json:"intervalDays"`
+	EaseFactor      float64           `json:"easeFactor"`
+	DueAt           string            `json:"dueAt"`
+	LastReviewedAt  *string           `json:"lastReviewedAt,omitempty"`
+}
+
+type GetDueReviewsResponse struct {
+	Data []ReviewCardItem `json:"data"`
+	// DueCount is every due card, which may be more than were returned.
+	DueCount int `json:"dueCount"`
+}
+
+type GradeReviewRequest struct {
+	// Grade is one of again, hard, good or easy.
+	Grade string `json:"grade"`
+}
+
+type GradeReviewResponse struct {
+	CardID       int64   `json:"cardId"`
+	Repetitions  int     `json:"repetitions"`
+	IntervalDays int     `json:"intervalDays"`
+	EaseFactor   float64 `json:"easeFactor"`
+	DueAt        string  `json:"dueAt"`
+}
+
+type ReviewDayItem struct {
+	Date    string         `json:"date"`
+	Reviews int            `json:"reviews"`
+	Grades  map[string]int `json:"grades"`
+}
+
+type GetReviewStatsResponse struct {
+	TotalCards int `json:"totalCards"`
+	DueNow     int `json:"dueNow"`
+	// Days covers every UTC day of the requested window, oldest first, including days
+	// without reviews.
+	Days []ReviewDayItem `json:"days"`
+	// Streak counts the days in a row, up to today, with at least one review. Today only
+	// breaks the streak once it is over.
+	Streak int `json:"streak"`
+}
+
+// ReviewsAPI routes /v1/reviews/due, /v1/reviews/stats and /v1/reviews/{cardId}/grade.
+func (h *ReviewHandlers) ReviewsAPI(w http.ResponseWriter, r *http.Request) {
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context()))
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/reviews/"), "/")
+	cardID, action, _ := strings.Cut(path, "/")
+	switch {
+	case path == "due":
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.getDueReviewsHandler(w, r, log)
+	case path == "stats":
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.getReviewStatsHandler(w, r, log)
+	case action == "grade":
+		if r.Method != http.MethodPost {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.gradeReviewHandler(w, r, log, cardID)
+	default:
+		h.writeJSONError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// getDueReviewsHandler returns the cards to review now, most overdue first. Annotations get
+// their card when they are bookmarked.
+func (h *ReviewHandlers) getDueReviewsHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	log = log.WithUserID(userID)
+
+	limit := defaultReviewBatchSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 {
+			h.writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxReviewBatchSize)
+	}
+
+	now := h.now()
+
+	cards, err := h.db.GetDueReviewCards(r.Context(), userID, now, limit)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get due review cards")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get reviews")
+		return
+	}
+	_, due, err := h.db.CountReviewCards(r.Context(), userID, now)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to count review cards")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get reviews")
+		return
+	}
+
+	response := GetDueReviewsResponse{Data: make([]ReviewCardItem, len(cards)), DueCount: due}
+	for i, card := range cards {
+		response.Data[i] = toReviewCardItem(card)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ReviewHandlers) gradeReviewHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger, cardIDStr string) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	log = log.WithUserID(userID)
+
+	cardID, err := strconv.ParseInt(cardIDStr, 10, 64)
+	if err != nil || cardID <= 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid card ID")
+		return
+	}
+	log = log.WithField("card_id", cardID)
+
+	var req GradeReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	grade, err := srs.ParseGrade(req.Grade)
+	if err != nil {
+		h.writeJSONError(w, http.StatusBadRequest, "grade must be one of again, hard, good, easy")
+		return
+	}
+
+	card, err := h.db.GetReviewCard(r.Context(), cardID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get review card")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get review card")
+		return
+	}
+	if card == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Review card not found")
+		return
+	}
+	if card.UserID != userID {
+		log.Warn("User attempted to grade a review card belonging to another user")
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	now := h.now()
+	previousReview := card.LastReviewedAt
+	next := srs.Schedule(srs.State{
+		Repetitions:  card.Repetitions,
+		IntervalDays: card.IntervalDays,
+		EaseFactor:   card.EaseFactor,
+		Due:          card.DueAt,
+	}, grade, now)
+	card.Repetitions = next.Repetitions
+	card.IntervalDays = next.IntervalDays
+	card.EaseFactor = next.EaseFactor
+	card.DueAt = next.Due
+	card.LastReviewedAt = &now
+
+	if err := h.db.RecordReview(r.Context(), card, &models.ReviewLog{
+		CardID:       card.ID,
+		UserID:       userID,
+		Grade:        string(grade),
+		IntervalDays: next.IntervalDays,
+		EaseFactor:   next.EaseFactor,
+		ReviewedAt:   now,
+	}, previousReview); err != nil {
+		if errors.Is(err, storage.ErrReviewConflict) {
+			h.writeJSONError(w, http.StatusConflict, "Review card was already graded by another request")
+			return
+		}
+		if errors.Is(err, storage.ErrReviewCardNotBookmarked) {
+			h.writeJSONError(w, http.StatusConflict, "Review card's annotation is no longer bookmarked")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to record review")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to record review")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GradeReviewResponse{
+		CardID:       card.ID,
+		Repetitions:  card.Repetitions,
+		IntervalDays: card.IntervalDays,
+		EaseFactor:   card.EaseFactor,
+		DueAt:        card.DueAt.Format(time.RFC3339),
+	})
+}
+
+func (h *ReviewHandlers) getReviewStatsHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	log = log.WithUserID(userID)
+
+	days := defaultReviewStatsDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed < 1 || parsed > maxReviewStatsDays {
+			h.writeJSONError(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+
+	now := h.now()
+	today := utcDay(now)
+	// The streak may reach further back than the window shown.
+	since := today.AddDate(0, 0, -(maxReviewStatsDays - 1))
+
+	stats, err := h.db.GetReviewStats(r.Context(), userID, since)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get review stats")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get review stats")
+		return
+	}
+	total, due, err := h.db.CountReviewCards(r.Context(), userID, now)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to count review cards")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get review stats")
+		return
+	}
+
+	byDay := make(map[string]map[string]int, len(stats))
+	for _, day := range stats {
+		byDay[day.Day.Format(time.DateOnly)] = day.Grades
+	}
+
+	response := GetReviewStatsResponse{TotalCards: total, DueNow: due, Days: make([]ReviewDayItem, 0, days)}
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format(time.DateOnly)
+		item := ReviewDayItem{Date: date, Grades: make(map[string]int, len(srs.Grades))}
+		for _, grade := range srs.Grades {
+			count := byDay[date][string(grade)]
+			item.Grades[string(grade)] = count
+			item.Reviews += count
+		}
+		response.Days = append(response.Days, item)
+	}
+
+	for i := 0; i < maxReviewStatsDays; i++ {
+		date := today.AddDate(0, 0, -i).Format(time.DateOnly)
+		if len(byDay[date]) > 0 {
+			response.Streak++
+		} else if i > 0 {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toReviewCardItem(card *models.ReviewCard) ReviewCardItem {
+	item := ReviewCardItem{
+		CardID:       card.ID,
+		AnnotationID: card.AnnotationID,
+		Repetitions:  card.Repetitions,
+		IntervalDays: card.IntervalDays,
+		EaseFactor:   card.EaseFactor,
+		DueAt:        card.DueAt.Format(time.RFC3339),
+	}
+	if card.LastReviewedAt != nil {
+		lastReviewedAt := card.LastReviewedAt.Format(time.RFC3339)
+		item.LastReviewedAt = &lastReviewedAt
+	}
+	if ann := card.Annotation; ann != nil {
+		item.HighlightedText = ann.HighlightedText
+		item.NuanceData = ann.NuanceData
+		if ann.ContextText != nil {
+			item.ContextText = *ann.ContextText
+		}
+		if ann.Notes != nil {
+			item.Notes = *ann.Notes
+		}
+	}
+	return item
+}
+
+func (h *ReviewHandlers) writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+	})
+}
+
+// utcDay returns the start of the UTC day t falls on, which review stats are grouped by.
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func reviewRequest(h *handlers.ReviewHandlers, method, path string, userID int64, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	h.ReviewsAPI(rec, req)
+	return rec
+}
+
+func TestReviews(t *testing.T) {
+	cfg := &config.Config{DefaultPageSize: 10}
+
+	newReviewsDB := func(t *testing.T) (*handlers.ReviewHandlers, *testutil.MockDB) {
+		t.Helper()
+		mockDB := testutil.NewMockDB()
+		for _, a := range []*models.Annotation{
+			{UserID: 1, HighlightedText: "お疲れ様", NuanceData: models.NuanceData{Meaning: "thanks"}, IsBookmarked: true},
+			{UserID: 1, HighlightedText: "よろしく", IsBookmarked: false},
+			{UserID: 2, HighlightedText: "すみません", IsBookmarked: true},
+		} {
+			a.CreatedAt = time.Now().Add(-time.Hour)
+			mockDB.CreateAnnotation(context.Background(), a)
+		}
+		return handlers.NewReviewHandlers(mockDB, cfg), mockDB
+	}
+	newReviews := func(t *testing.T) *handlers.ReviewHandlers {
+		h, _ := newReviewsDB(t)
+		return h
+	}
+
+	dueCards := func(t *testing.T, h *handlers.ReviewHandlers, userID int64) handlers.GetDueReviewsResponse {
+		t.Helper()
+		rec := reviewRequest(h, http.MethodGet, "/v1/reviews/due", userID, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.GetDueReviewsResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	t.Run("creates cards for bookmarked annotations", func(t *testing.T) {
+		h := newReviews(t)
+
+		resp := dueCards(t, h, 1)
+		if len(resp.Data) != 1 || resp.DueCount != 1 {
+			t.Fatalf("expected one due card, got %+v", resp)
+		}
+		card := resp.Data[0]
+		if card.HighlightedText != "お疲れ様" || card.NuanceData.Meaning != "thanks" || card.EaseFactor != 2.5 {
+			t.Errorf("unexpected card: %+v", card)
+		}
+	})
+
+	t.Run("grading schedules the next review", func(t *testing.T) {
+		h := newReviews(t)
+		cardID := dueCards(t, h, 1).Data[0].CardID
+
+		rec := reviewRequest(h, http.MethodPost, "/v1/reviews/"+strconv.FormatInt(cardID, 10)+"/grade", 1, `{"grade":"good"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var graded handlers.GradeReviewResponse
+		json.Unmarshal(rec.Body.Bytes(), &graded)
+		if graded.Repetitions != 1 || graded.IntervalDays != 1 {
+			t.Errorf("unexpected schedule: %+v", graded)
+		}
+		dueAt, _ := time.Parse(time.RFC3339, graded.DueAt)
+		if dueAt.Before(time.Now().Add(23 * time.Hour)) {
+			t.Errorf("expected the card to be due tomorrow, got %s", graded.DueAt)
+		}
+
+		if resp := dueCards(t, h, 1); len(resp.Data) != 0 || resp.DueCount != 0 {
+			t.Errorf("expected no due cards after grading, got %+v", resp)
+		}
+
+		rec = reviewRequest(h, http.MethodGet, "/v1/reviews/stats?days=7", 1, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var stats handlers.GetReviewStatsResponse
+		json.Unmarshal(rec.Body.Bytes(), &stats)
+		if len(stats.Days) != 7 || stats.TotalCards != 1 || stats.DueNow != 0 || stats.Streak != 1 {
+			t.Fatalf("unexpected stats: %+v", stats)
+		}
+		today := stats.Days[6]
+		if today.Date != time.Now().UTC().Format(time.DateOnly) || today.Reviews != 1 || today.Grades["good"] != 1 || today.Grades["again"] != 0 {
+			t.Errorf("unexpected today stats: %+v", today)
+		}
+	})
+
+	t.Run("rejects grading twice from the same read", func(t *testing.T) {
+		h, mockDB := newReviewsDB(t)
+		card := dueCards(t, h, 1).Data[0]
+		stale, _ := mockDB.GetReviewCard(context.Background(), card.CardID)
+		h = handlers.NewReviewHandlers(&staleReviewDB{MockDB: mockDB, stale: stale}, cfg)
+		path := "/v1/reviews/" + strconv.FormatInt(card.CardID, 10) + "/grade"
+
+		if rec := reviewRequest(h, http.MethodPost, path, 1, `{"grade":"good"}`); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec := reviewRequest(h, http.MethodPost, path, 1, `{"grade":"good"}`); rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409 for a repeated grade, got %d", rec.Code)
+		}
+
+		stats, _ := mockDB.GetReviewStats(context.Background(), 1, time.Now().Add(-time.Hour))
+		if len(stats) != 1 || stats[0].Grades["good"] != 1 {
+			t.Errorf("expected a single logged review, got %+v", stats)
+		}
+	})
+
+	t.Run("rejects cards whose annotation was unbookmarked", func(t *testing.T) {
+		h, mockDB := newReviewsDB(t)
+		card := dueCards(t, h, 1).Data[0]
+		annotation, _ := mockDB.GetAnnotationByID(context.Background(), card.AnnotationID)
+		updated := *annotation
+		updated.IsBookmarked = false
+		if err := mockDB.UpdateAnnotation(context.Background(), &updated, annotation.UpdatedAt); err != nil {
+			t.Fatalf("UpdateAnnotation: %v", err)
+		}
+
+		rec := reviewRequest(h, http.MethodPost, "/v1/reviews/"+strconv.FormatInt(card.CardID, 10)+"/grade", 1, `{"grade":"good"}`)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects invalid grades and other users' cards", func(t *testing.T) {
+		h := newReviews(t)
+		cardID := strconv.FormatInt(dueCards(t, h, 1).Data[0].CardID, 10)
+
+		if rec := reviewRequest(h, http.MethodPost, "/v1/reviews/"+cardID+"/grade", 1, `{"grade":"perfect"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for unknown grade, got %d", rec.Code)
+		}
+		if rec := reviewRequest(h, http.MethodPost, "/v1/reviews/"+cardID+"/grade", 2, `{"grade":"good"}`); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403 for another user's card, got %d", rec.Code)
+		}
+		if rec := reviewRequest(h, http.MethodPost, "/v1/reviews/999/grade", 1, `{"grade":"good"}`); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404 for unknown card, got %d", rec.Code)
+		}
+		if rec := reviewRequest(h, http.MethodGet, "/v1/reviews/stats?days=0", 1, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for days=0, got %d", rec.Code)
+		}
+	})
+}
+
+// staleReviewDB serves the same snapshot of a review card on every read, as if each request
+// had read it before the others graded it.
+type staleReviewDB struct {
+	*testutil.MockDB
+	stale *models.ReviewCard
+}
+
+func (db *staleReviewDB) GetReviewCard(ctx context.Context, cardID int64) (*models.ReviewCard, error) {
+	copied := *db.stale
+	return &copied, nil
+}
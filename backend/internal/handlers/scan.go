@@ -5,17 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/gemini-hackathon/app/internal/audio"
 	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/events"
 	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/jobs"
+	"github.com/gemini-hackathon/app/internal/knowledge"
 	"github.com/gemini-hackathon/app/internal/logger"
 	"github.com/gemini-hackathon/app/internal/middleware"
 	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/pagination"
 	"github.com/gemini-hackathon/app/internal/storage"
 )
 
@@ -23,15 +30,23 @@ type ScanHandlers struct {
 	db           storage.DB
 	fileStorage  storage.FileStorage
 	geminiClient gemini.Client
+	knowledge    knowledge.Service
+	events       events.Broker
+	audio        *audio.Service
 	config       *config.Config
+	cursors      *pagination.Signer
 }
 
-func NewScanHandlers(db storage.DB, fileStorage storage.FileStorage, geminiClient gemini.Client, cfg *config.Config) *ScanHandlers {
+func NewScanHandlers(db storage.DB, fileStorage storage.FileStorage, geminiClient gemini.Client, knowledgeSvc knowledge.Service, broker events.Broker, audioSvc *audio.Service, cfg *config.Config) *ScanHandlers {
 	return &ScanHandlers{
 		db:           db,
 		fileStorage:  fileStorage,
 		geminiClient: geminiClient,
+		knowledge:    knowledgeSvc,
+		events:       broker,
+		audio:        audioSvc,
 		config:       cfg,
+		cursors:      pagination.NewSigner(cfg.CursorSecret),
 	}
 }
 
@@ -45,6 +60,10 @@ type ScanListItem struct {
 	ID               int64   `json:"id"`
 	ImageURL         string  `json:"imageUrl"`
 	DetectedLanguage *string `json:"detectedLanguage,omitempty"`
+	Status           string  `json:"status"`
+	FailureReason    *string `json:"failureReason,omitempty"`
+	DocumentID       *int64  `json:"documentId,omitempty"`
+	PageNumber       *int    `json:"pageNumber,omitempty"`
 	CreatedAt        string  `json:"createdAt"`
 }
 
@@ -54,18 +73,28 @@ type GetScansResponse struct {
 }
 
 type PaginationMeta struct {
-	CurrentPage  int  `json:"currentPage"`
+	// CurrentPage is omitted when paging by cursor.
+	CurrentPage  int  `json:"currentPage,omitempty"`
 	PageSize     int  `json:"pageSize"`
 	NextPage     *int `json:"nextPage,omitempty"`
 	PreviousPage *int `json:"previousPage,omitempty"`
+	// NextCursor fetches the page after this one. It is omitted on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+	// TotalCount is every matching item, when requested with includeTotal=true.
+	TotalCount *int `json:"totalCount,omitempty"`
 }
 
 type GetScanResponse struct {
-	ID               int64   `json:"id"`
-	FullText         string  `json:"fullText,omitempty"`
-	ImageURL         string  `json:"imageUrl"`
-	DetectedLanguage *string `json:"detectedLanguage,omitempty"`
-	CreatedAt        string  `json:"createdAt"`
+	ID               int64             `json:"id"`
+	FullText         string            `json:"fullText,omitempty"`
+	ImageURL         string            `json:"imageUrl"`
+	DetectedLanguage *string           `json:"detectedLanguage,omitempty"`
+	Status           string            `json:"status"`
+	FailureReason    *string           `json:"failureReason,omitempty"`
+	Layout           *models.OCRLayout `json:"layout,omitempty"`
+	DocumentID       *int64            `json:"documentId,omitempty"`
+	PageNumber       *int              `json:"pageNumber,omitempty"`
+	CreatedAt        string            `json:"createdAt"`
 }
 
 type ErrorResponse struct {
@@ -101,26 +130,50 @@ func (h *ScanHandlers) CreateScanAPI(w http.ResponseWriter, r *http.Request) {
 		h.writeJSONError(w, http.StatusBadRequest, "Please select an image to upload")
 		return
 	}
-	defer file.Close()
+	file.Close()
 
+	response, uploadErr := h.createScanFromUpload(r.Context(), log, userID, header)
+	if uploadErr != nil {
+		h.writeJSONError(w, uploadErr.status, uploadErr.message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// uploadError is a failed image upload and the HTTP status it maps to.
+type uploadError struct {
+	status  int
+	message string
+}
+
+// createScanFromUpload validates one uploaded image, stores it, creates its scan and
+// queues OCR for it. It is shared by the single and batch upload endpoints.
+func (h *ScanHandlers) createScanFromUpload(ctx context.Context, log *logger.Logger, userID int64, header *multipart.FileHeader) (*CreateScanResponse, *uploadError) {
 	mimeType := header.Header.Get("Content-Type")
 	if !isValidImageType(mimeType) {
 		log.Warnf("Invalid image type received: %s", mimeType)
-		h.writeJSONError(w, http.StatusBadRequest, "Invalid image type. Please use JPEG, PNG, or WebP.")
-		return
+		return nil, &uploadError{http.StatusBadRequest, "Invalid image type. Please use JPEG, PNG, or WebP."}
 	}
 
 	if header.Size > h.config.MaxUploadSize {
 		log.Warnf("Image size %d exceeds max size %d", header.Size, h.config.MaxUploadSize)
-		h.writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("File too large. Maximum size is %v MB.", h.config.MaxUploadSize/(1024*1024)))
-		return
+		return nil, &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("File too large. Maximum size is %v MB.", h.config.MaxUploadSize/(1024*1024))}
 	}
 
+	file, err := header.Open()
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to open uploaded file")
+		return nil, &uploadError{http.StatusBadRequest, "Failed to read uploaded file"}
+	}
+	defer file.Close()
+
 	imageData, err := io.ReadAll(file)
 	if err != nil {
 		log.ErrorWithErr(err, "Failed to read uploaded file")
-		h.writeJSONError(w, http.StatusBadRequest, "Failed to read uploaded file")
-		return
+		return nil, &uploadError{http.StatusBadRequest, "Failed to read uploaded file"}
 	}
 
 	log.Infof("Received image upload: size=%d bytes, type=%s", len(imageData), mimeType)
@@ -129,46 +182,65 @@ func (h *ScanHandlers) CreateScanAPI(w http.ResponseWriter, r *http.Request) {
 	scan := &models.Scan{
 		UserID:    userID,
 		ImageURL:  "",
+		Status:    models.ScanStatusPending,
 		CreatedAt: now,
 	}
 
-	scanID, err := h.db.CreateScan(r.Context(), scan)
+	scanID, err := h.db.CreateScan(ctx, scan)
 	if err != nil {
 		log.ErrorWithErr(err, "Failed to create scan in database")
-		h.writeJSONError(w, http.StatusInternalServerError, "Failed to initialize scan")
-		return
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to initialize scan"}
 	}
 
 	storagePath, _, err := h.fileStorage.SaveImage(strconv.FormatInt(scanID, 10), imageData, mimeType)
 	if err != nil {
 		log.ErrorWithErr(err, "Failed to save image to storage")
-		h.writeJSONError(w, http.StatusInternalServerError, "Failed to save uploaded image")
-		return
+		// Without its image the scan can never be processed.
+		if err := h.db.DeleteScan(context.WithoutCancel(ctx), scanID, userID); err != nil {
+			log.ErrorWithErr(err, "Failed to delete scan without image")
+		}
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to save uploaded image"}
 	}
 
 	imageURL := fmt.Sprintf("/uploads/%s", filepath.Base(storagePath))
 
-	if err := h.db.UpdateScanImageURL(r.Context(), scanID, imageURL); err != nil {
+	if err := h.db.UpdateScanImageURL(ctx, scanID, imageURL); err != nil {
 		log.ErrorWithErr(err, "Failed to update scan with image URL")
 	}
 
+	job := &models.OCRJob{
+		ScanID:      scanID,
+		ImagePath:   storagePath,
+		MimeType:    mimeType,
+		MaxAttempts: h.ocrJobMaxAttempts(),
+		NextRunAt:   now,
+		CreatedAt:   now,
+	}
+	if _, err := h.db.CreateOCRJob(ctx, job); err != nil {
+		log.ErrorWithErr(err, "Failed to enqueue OCR job")
+		// Leave the scan failed rather than pending forever; POST /v1/scans/{id}/ocr can
+		// still process its stored image.
+		reason := "Failed to queue scan for processing"
+		if err := h.db.UpdateScanStatus(context.WithoutCancel(ctx), scanID, models.ScanStatusFailed, &reason); err != nil {
+			log.ErrorWithErr(err, "Failed to mark unqueued scan as failed")
+		}
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to queue scan for processing"}
+	}
+
 	log.WithFields(map[string]any{
 		"scan_id":   scanID,
 		"user_id":   userID,
 		"image_url": imageURL,
-	}).Infof("Scan created successfully, starting OCR processing")
+		"job_id":    job.ID,
+	}).Infof("Scan created successfully, OCR job queued")
 
-	go h.processOCR(context.Background(), scanID, imageData, mimeType, storagePath)
+	h.publishEvent(ctx, events.ScanEvent{ScanID: scanID, Type: events.TypeQueued})
 
-	response := CreateScanResponse{
+	return &CreateScanResponse{
 		ScanID:   scanID,
 		FullText: "",
 		ImageURL: imageURL,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	}, nil
 }
 
 func (h *ScanHandlers) GetScansAPI(w http.ResponseWriter, r *http.Request) {
@@ -185,27 +257,52 @@ func (h *ScanHandlers) GetScansAPI(w http.ResponseWriter, r *http.Request) {
 
 	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context())).WithUserID(userID)
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	params, invalid := parseListParams(r, h.config.DefaultPageSize, h.cursors)
+	if invalid != "" {
+		h.writeJSONError(w, http.StatusBadRequest, invalid)
+		return
 	}
 
-	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
-	if size < 1 {
-		size = h.config.DefaultPageSize
+	filter := storage.ScanFilter{
+		Status:        r.URL.Query().Get("status"),
+		Language:      r.URL.Query().Get("language"),
+		CreatedFrom:   params.createdFrom,
+		CreatedBefore: params.createdBefore,
 	}
-	if size > 100 {
-		size = 100
+	if filter.Status != "" && !models.IsValidScanStatus(filter.Status) {
+		h.writeJSONError(w, http.StatusBadRequest, "status must be one of pending, processing, completed, failed")
+		return
+	}
+	if documentIDParam := r.URL.Query().Get("documentId"); documentIDParam != "" {
+		documentID, err := strconv.ParseInt(documentIDParam, 10, 64)
+		if err != nil || documentID <= 0 {
+			h.writeJSONError(w, http.StatusBadRequest, "documentId must be a positive integer")
+			return
+		}
+		filter.DocumentID = documentID
 	}
 
-	scans, err := h.db.GetScansByUserID(r.Context(), userID, page, size)
+	scans, err := h.db.GetScansByUserID(r.Context(), userID, filter, params.Page)
 	if err != nil {
 		log.ErrorWithErr(err, "Failed to get scans from database")
 		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get scans")
 		return
 	}
+	fetched := len(scans)
+	scans = scans[:min(fetched, params.size)]
 
-	log.Infof("Retrieved %d scans for user (page=%d, size=%d)", len(scans), page, size)
+	var total *int
+	if params.includeTotal {
+		count, err := h.db.CountScans(r.Context(), userID, filter)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to count scans")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to get scans")
+			return
+		}
+		total = &count
+	}
+
+	log.Infof("Retrieved %d scans for user (page=%d, size=%d, cursor=%t, status=%q, document_id=%d)", len(scans), params.pageNumber, params.size, params.After != nil, filter.Status, filter.DocumentID)
 
 	data := make([]ScanListItem, len(scans))
 	for i, scan := range scans {
@@ -213,28 +310,26 @@ func (h *ScanHandlers) GetScansAPI(w http.ResponseWriter, r *http.Request) {
 			ID:               scan.ID,
 			ImageURL:         scan.ImageURL,
 			DetectedLanguage: scan.DetectedLanguage,
+			Status:           scan.Status,
+			FailureReason:    scan.FailureReason,
+			DocumentID:       scan.DocumentID,
+			PageNumber:       scan.PageNumber,
 			CreatedAt:        scan.CreatedAt.Format(time.RFC3339),
 		}
 	}
 
-	var nextPage, prevPage *int
-	if len(scans) == size {
-		nextPageVal := page + 1
-		nextPage = &nextPageVal
-	}
-	if page > 1 {
-		prevPageVal := page - 1
-		prevPage = &prevPageVal
+	var last storage.Keyset
+	if len(scans) > 0 {
+		scan := scans[len(scans)-1]
+		last = storage.Keyset{CreatedAt: scan.CreatedAt, ID: scan.ID}
+		if scan.PageNumber != nil {
+			last.PageNumber = *scan.PageNumber
+		}
 	}
 
 	response := GetScansResponse{
 		Data: data,
-		Meta: PaginationMeta{
-			CurrentPage:  page,
-			PageSize:     size,
-			NextPage:     nextPage,
-			PreviousPage: prevPage,
-		},
+		Meta: params.meta(h.cursors, fetched, last, total),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -248,14 +343,94 @@ func (h *ScanHandlers) GetScanAPI(w http.ResponseWriter, r *http.Request) {
 func (h *ScanHandlers) ScanByIDAPI(w http.ResponseWriter, r *http.Request) {
 	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context()))
 
-	switch r.Method {
-	case http.MethodGet:
-		h.getScanHandler(w, r, log)
-	case http.MethodDelete:
-		h.deleteScanHandler(w, r, log)
+	_, action := splitScanPath(r.URL.Path)
+	switch action {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			h.getScanHandler(w, r, log)
+		case http.MethodDelete:
+			h.deleteScanHandler(w, r, log)
+		default:
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	case "events":
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.scanEventsHandler(w, r, log)
+	case "audio":
+		if r.Method != http.MethodPost {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.scanAudioHandler(w, r, log)
+	case "furigana":
+		if r.Method != http.MethodGet {
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.getFuriganaHandler(w, r, log)
+	case "ocr":
+		switch r.Method {
+		case http.MethodPost:
+			h.retryOCRHandler(w, r, log)
+		case http.MethodGet:
+			h.getOCRRevisionsHandler(w, r, log)
+		default:
+			h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
 	default:
-		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		h.writeJSONError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// splitScanPath splits /v1/scans/{id}[/{action}] into the raw scan ID and the action.
+func splitScanPath(path string) (string, string) {
+	path = strings.Trim(strings.TrimPrefix(path, "/v1/scans/"), "/")
+	idStr, action, _ := strings.Cut(path, "/")
+	return idStr, action
+}
+
+// loadOwnedScan resolves the scan in the request path and checks that it belongs to the
+// caller. It writes the error response itself and returns false when the request must stop.
+func (h *ScanHandlers) loadOwnedScan(w http.ResponseWriter, r *http.Request, log *logger.Logger) (*models.Scan, *logger.Logger, bool) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, log, false
+	}
+
+	log = log.WithUserID(userID)
+
+	scanIDStr, _ := splitScanPath(r.URL.Path)
+	scanID, err := strconv.ParseInt(scanIDStr, 10, 64)
+	if err != nil || scanID <= 0 {
+		log.Warnf("Invalid scan ID format: %s", scanIDStr)
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid scan ID")
+		return nil, log, false
 	}
+
+	log = log.WithField("scan_id", scanID)
+
+	scan, err := h.db.GetScanByID(r.Context(), scanID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get scan by ID from database")
+		h.writeJSONError(w, http.StatusNotFound, "Scan not found")
+		return nil, log, false
+	}
+	if scan == nil {
+		h.writeJSONError(w, http.StatusNotFound, "Scan not found")
+		return nil, log, false
+	}
+	if scan.UserID != userID {
+		log.Warn("User attempted to access scan belonging to another user")
+		h.writeJSONError(w, http.StatusForbidden, "Access denied")
+		return nil, log, false
+	}
+
+	return scan, log, true
 }
 
 func (h *ScanHandlers) deleteScanHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
@@ -267,8 +442,7 @@ func (h *ScanHandlers) deleteScanHandler(w http.ResponseWriter, r *http.Request,
 
 	log = log.WithUserID(userID)
 
-	path := strings.TrimPrefix(r.URL.Path, "/v1/scans/")
-	scanIDStr := strings.TrimSuffix(path, "/")
+	scanIDStr, _ := splitScanPath(r.URL.Path)
 	scanID, err := strconv.ParseInt(scanIDStr, 10, 64)
 	if err != nil || scanID <= 0 {
 		log.Warnf("Invalid scan ID format: %s", scanIDStr)
@@ -318,8 +492,7 @@ func (h *ScanHandlers) getScanHandler(w http.ResponseWriter, r *http.Request, lo
 
 	log = log.WithUserID(userID)
 
-	path := strings.TrimPrefix(r.URL.Path, "/v1/scans/")
-	scanIDStr := strings.TrimSuffix(path, "/")
+	scanIDStr, _ := splitScanPath(r.URL.Path)
 	scanID, err := strconv.ParseInt(scanIDStr, 10, 64)
 	if err != nil {
 		log.Warnf("Invalid scan ID format: %s", scanIDStr)
@@ -348,50 +521,158 @@ func (h *ScanHandlers) getScanHandler(w http.ResponseWriter, r *http.Request, lo
 		return
 	}
 
+	response := toGetScanResponse(scan)
+
+	log.WithFields(map[string]any{
+		"has_ocr":         response.FullText != "",
+		"has_layout":      response.Layout != nil,
+		"ocr_text_length": len(response.FullText),
+		"language":        scan.DetectedLanguage,
+		"status":          scan.Status,
+	}).Infof("Successfully retrieved scan: id=%d", scanID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toGetScanResponse(scan *models.Scan) GetScanResponse {
 	fullText := ""
 	if scan.FullOCRText != nil {
 		fullText = *scan.FullOCRText
 	}
 
-	log.WithFields(map[string]any{
-		"has_ocr":          fullText != "",
-		"ocr_text_length":  len(fullText),
-		"language":         scan.DetectedLanguage,
-	}).Infof("Successfully retrieved scan: id=%d", scanID)
-
-	response := GetScanResponse{
+	return GetScanResponse{
 		ID:               scan.ID,
 		FullText:         fullText,
 		ImageURL:         scan.ImageURL,
 		DetectedLanguage: scan.DetectedLanguage,
+		Status:           scan.Status,
+		FailureReason:    scan.FailureReason,
+		Layout:           scan.OCRLayout,
+		DocumentID:       scan.DocumentID,
+		PageNumber:       scan.PageNumber,
 		CreatedAt:        scan.CreatedAt.Format(time.RFC3339),
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// ProcessOCRJob runs OCR for a queued job. It is the handler given to the OCR worker pool.
+func (h *ScanHandlers) ProcessOCRJob(ctx context.Context, job *models.OCRJob) error {
+	err := h.runOCRJob(ctx, job)
+	if err != nil {
+		h.recordOCRFailure(ctx, job, err)
+	}
+	return err
+}
+
+func (h *ScanHandlers) runOCRJob(ctx context.Context, job *models.OCRJob) error {
+	imageData, err := h.fileStorage.OpenImage(job.ImagePath)
+	if err != nil {
+		return fmt.Errorf("failed to open scan image: %w", err)
+	}
+	return h.processOCR(ctx, job.ScanID, imageData, job.MimeType)
 }
 
-func (h *ScanHandlers) processOCR(ctx context.Context, scanID int64, imageData []byte, mimeType string, storagePath string) {
+func (h *ScanHandlers) processOCR(ctx context.Context, scanID int64, imageData []byte, mimeType string) error {
 	log := logger.GetDefaultLogger().WithField("scan_id", scanID)
 
+	if err := h.db.UpdateScanStatus(ctx, scanID, models.ScanStatusProcessing, nil); err != nil {
+		log.ErrorWithErr(err, "Failed to mark scan as processing")
+	}
+	h.publishEvent(ctx, events.ScanEvent{ScanID: scanID, Type: events.TypeOCRStarted})
+
 	log.Infof("Starting OCR processing: image_size=%d bytes, mime_type=%s", len(imageData), mimeType)
 	ocrResp, err := h.geminiClient.OCR(ctx, imageData, mimeType)
 	if err != nil {
 		log.ErrorWithErr(err, "OCR processing failed")
-		return
+		return fmt.Errorf("OCR processing failed: %w", err)
 	}
-	log.Infof("OCR completed successfully: language=%s, text_length=%d", ocrResp.Language, len(ocrResp.RawText))
-
-	imageURL := fmt.Sprintf("/uploads/%s", storagePath)
+	log.Infof("OCR completed successfully: language=%s, text_length=%d, blocks=%d", ocrResp.Language, len(ocrResp.RawText), len(ocrResp.Blocks))
 
-	_ = imageURL
-
-	if err := h.db.UpdateScanOCR(ctx, scanID, ocrResp.RawText, ocrResp.Language); err != nil {
+	if err := h.db.UpdateScanOCR(ctx, scanID, ocrResp.RawText, ocrResp.Language, toOCRLayout(ocrResp)); err != nil {
 		log.ErrorWithErr(err, "Failed to update scan OCR in database")
-		return
+		return fmt.Errorf("failed to save OCR result: %w", err)
 	}
 
 	log.Infof("OCR results saved to database successfully")
+	h.publishEvent(ctx, events.ScanEvent{
+		ScanID:   scanID,
+		Type:     events.TypeOCRCompleted,
+		Text:     ocrResp.RawText,
+		Language: ocrResp.Language,
+	})
+	return nil
+}
+
+// toOCRLayout converts the layout reported by the OCR model. It returns nil when the
+// model only returned plain text.
+func toOCRLayout(ocrResp *gemini.OCRResponse) *models.OCRLayout {
+	if len(ocrResp.Blocks) == 0 {
+		return nil
+	}
+
+	layout := &models.OCRLayout{
+		Orientation: ocrResp.Orientation,
+		Blocks:      make([]models.TextBlock, len(ocrResp.Blocks)),
+	}
+	for i, block := range ocrResp.Blocks {
+		lines := make([]models.TextLine, len(block.Lines))
+		for j, line := range block.Lines {
+			lines[j] = models.TextLine{Text: line.Text, Box: models.BoundingBox(line.Box)}
+		}
+		layout.Blocks[i] = models.TextBlock{
+			Text:        block.Text,
+			Orientation: block.Orientation,
+			Box:         models.BoundingBox(block.Box),
+			Lines:       lines,
+		}
+	}
+	return layout
+}
+
+// recordOCRFailure moves the scan back to pending while the job still has retries
+// left, and to failed (with the reason) once it has given up.
+func (h *ScanHandlers) recordOCRFailure(ctx context.Context, job *models.OCRJob, ocrErr error) {
+	log := logger.GetDefaultLogger().WithFields(map[string]any{"scan_id": job.ScanID, "job_id": job.ID})
+
+	// The job context may have timed out; the status update still has to land.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	status := models.ScanStatusPending
+	event := events.ScanEvent{ScanID: job.ScanID, Type: events.TypeQueued}
+	var reason *string
+	if job.IsFinalAttempt() {
+		msg := failureReason(ocrErr)
+		status, reason = models.ScanStatusFailed, &msg
+		event.Type, event.Reason = events.TypeOCRFailed, msg
+	}
+
+	if err := h.db.UpdateScanStatus(saveCtx, job.ScanID, status, reason); err != nil {
+		log.ErrorWithErr(err, "Failed to record OCR failure on scan")
+	}
+	h.publishEvent(saveCtx, event)
+}
+
+// failureReason turns an OCR error into the message stored on the scan, capped so a
+// verbose upstream error doesn't end up in every list response.
+func failureReason(err error) string {
+	const maxLen = 500
+	msg := err.Error()
+	if len(msg) <= maxLen {
+		return msg
+	}
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + "..."
+}
+
+func (h *ScanHandlers) ocrJobMaxAttempts() int {
+	if h.config.OCRJobMaxAttempts > 0 {
+		return h.config.OCRJobMaxAttempts
+	}
+	return jobs.DefaultMaxAttempts
 }
 
 func (h *ScanHandlers) writeJSONError(w http.ResponseWriter, statusCode int, message string) {
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gemini-hackathon/app/internal/audio"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+)
+
+type ScanAudioRequest struct {
+	SpeechParams
+	// Concatenate also stores the whole passage as one WAV file.
+	Concatenate bool `json:"concatenate,omitempty"`
+}
+
+type ScanAudioSentence struct {
+	Index      int    `json:"index"`
+	Text       string `json:"text"`
+	AudioURL   string `json:"audioUrl"`
+	StartMs    int64  `json:"startMs"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type ScanAudioResponse struct {
+	ScanID          int64               `json:"scanId"`
+	Voice           string              `json:"voice"`
+	Sentences       []ScanAudioSentence `json:"sentences"`
+	TotalDurationMs int64               `json:"totalDurationMs"`
+	// AudioURL is the concatenated passage, when requested. Sentence start times are offsets
+	// into it. It is empty when the voice's clips can't be joined into one file.
+	AudioURL string `json:"audioUrl,omitempty"`
+}
+
+// scanAudioHandler reads a scan's text aloud sentence by sentence for shadowing practice. It
+// returns a manifest of per-sentence clips with their timings. Sentences already spoken with
+// the same delivery are served from the audio cache and not billed again.
+func (h *ScanHandlers) scanAudioHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	scan, log, ok := h.loadOwnedScan(w, r, log)
+	if !ok {
+		return
+	}
+	if h.audio == nil {
+		h.writeJSONError(w, http.StatusServiceUnavailable, "Speech is not available")
+		return
+	}
+
+	var req ScanAudioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if scan.FullOCRText == nil {
+		h.writeJSONError(w, http.StatusConflict, "Scan has no text yet")
+		return
+	}
+	sentences := audio.SplitSentences(*scan.FullOCRText)
+	if len(sentences) == 0 {
+		h.writeJSONError(w, http.StatusConflict, "Scan has no text to read")
+		return
+	}
+	if len(sentences) > h.config.ScanAudioMaxSentences {
+		h.writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Scan has %d sentences. Maximum is %d.", len(sentences), h.config.ScanAudioMaxSentences))
+		return
+	}
+
+	voices, defaultVoice := gemini.CatalogFor(h.geminiClient)
+	opts, invalid, err := resolveSpeechOptions(r.Context(), h.db, voices, scan.UserID, req.SpeechParams)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get user from database")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+	if invalid != "" {
+		h.writeJSONError(w, http.StatusBadRequest, invalid)
+		return
+	}
+
+	reqs := audio.SentenceRequests(sentences, audio.Request{
+		Voice:        opts.Voice,
+		SpeakingRate: opts.SpeakingRate,
+		Tone:         opts.Tone,
+	})
+
+	// The rate limiter billed this request as one speech call; bill the other uncached
+	// sentences too, or nothing when every sentence is cached.
+	missing, err := h.audio.Missing(r.Context(), reqs)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to look up cached sentence audio")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to synthesize speech")
+		return
+	}
+	if missing == 0 {
+		middleware.WaiveUsage(r.Context())
+	} else if err := middleware.ChargeUsage(r.Context(), missing-1); err != nil {
+		var quotaErr *middleware.QuotaError
+		if errors.As(err, &quotaErr) {
+			log.Warnf("Daily speech quota can't cover %d sentences", missing)
+			middleware.SetRetryAfter(w, quotaErr.RetryAfter)
+			h.writeJSONError(w, http.StatusTooManyRequests, "Daily speech quota can't cover this scan")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to record speech usage for scan")
+	}
+
+	log.Infof("Synthesizing scan audio: sentences=%d uncached=%d", len(sentences), missing)
+
+	clips, err := h.audio.FetchAll(r.Context(), reqs, h.config.ScanAudioConcurrency)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to synthesize scan audio")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to synthesize speech")
+		return
+	}
+
+	response := ScanAudioResponse{
+		ScanID:    scan.ID,
+		Voice:     opts.Voice,
+		Sentences: make([]ScanAudioSentence, len(clips)),
+	}
+	if response.Voice == "" {
+		response.Voice = defaultVoice
+	}
+	for i, clip := range clips {
+		duration := clip.Duration.Milliseconds()
+		response.Sentences[i] = ScanAudioSentence{
+			Index:      i,
+			Text:       clip.Text,
+			AudioURL:   audioURL(clip.Asset.Hash),
+			StartMs:    response.TotalDurationMs,
+			DurationMs: duration,
+		}
+		response.TotalDurationMs += duration
+	}
+
+	if req.Concatenate {
+		// The sentences are synthesized and billed by now, so a passage that can't be joined
+		// still gets its manifest rather than an error that would refund the usage.
+		asset, err := h.audio.Concat(r.Context(), clips)
+		switch {
+		case errors.Is(err, audio.ErrNotConcatenable):
+			log.Warnf("Scan audio can't be concatenated: voice=%s", response.Voice)
+		case err != nil:
+			log.ErrorWithErr(err, "Failed to concatenate scan audio")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to concatenate speech")
+			return
+		default:
+			response.AudioURL = audioURL(asset.Hash)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func postScanAudio(h *handlers.ScanHandlers, userID int64, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/scans/1/audio", strings.NewReader(body))
+	req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	h.ScanByIDAPI(rec, req)
+	return rec
+}
+
+func TestScanAudio(t *testing.T) {
+	cfg := &config.Config{ScanAudioConcurrency: 2, ScanAudioMaxSentences: 3}
+
+	newHandlersWith := func(t *testing.T, text *string, client gemini.Client) *handlers.ScanHandlers {
+		t.Helper()
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		if text != nil {
+			mockDB.UpdateScanOCR(context.Background(), scan.ID, *text, "JP", nil)
+		}
+		return handlers.NewScanHandlers(mockDB, &mockFileStorage{}, client, nil, nil, newAudioTestService(t, mockDB, client), cfg)
+	}
+	newHandlers := func(t *testing.T, text *string) *handlers.ScanHandlers {
+		return newHandlersWith(t, text, &wavSpeechClient{})
+	}
+
+	t.Run("returns a manifest with timings", func(t *testing.T) {
+		text := "おはようございます。\n今日は晴れです！"
+		h := newHandlers(t, &text)
+
+		rec := postScanAudio(h, 1, `{"concatenate":true,"tone":"polite"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp handlers.ScanAudioResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Voice != "Kore" {
+			t.Errorf("expected default voice Kore, got %q", resp.Voice)
+		}
+		if len(resp.Sentences) != 2 {
+			t.Fatalf("expected 2 sentences, got %+v", resp.Sentences)
+		}
+		second := resp.Sentences[1]
+		if second.Text != "今日は晴れです！" || second.StartMs != 1000 || second.DurationMs != 1000 {
+			t.Errorf("unexpected second sentence %+v", second)
+		}
+		if !strings.HasPrefix(second.AudioURL, "/v1/audio/") || second.AudioURL == resp.Sentences[0].AudioURL {
+			t.Errorf("expected distinct audio URLs, got %q and %q", resp.Sentences[0].AudioURL, second.AudioURL)
+		}
+		if resp.TotalDurationMs != 2000 {
+			t.Errorf("expected total 2000ms, got %d", resp.TotalDurationMs)
+		}
+		if !strings.HasPrefix(resp.AudioURL, "/v1/audio/") {
+			t.Errorf("expected a concatenated audio URL, got %q", resp.AudioURL)
+		}
+	})
+
+	t.Run("omits the concatenated file unless asked", func(t *testing.T) {
+		text := "はい。"
+		rec := postScanAudio(newHandlers(t, &text), 1, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.ScanAudioResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp.AudioURL != "" {
+			t.Errorf("expected no concatenated audio, got %q", resp.AudioURL)
+		}
+	})
+
+	t.Run("returns the manifest when the clips can't be joined", func(t *testing.T) {
+		text := "はい。いいえ。"
+		client := &mockSpeechGeminiClient{resp: &gemini.SpeechResponse{Audio: []byte("ID3"), MIMEType: "audio/mpeg"}}
+		rec := postScanAudio(newHandlersWith(t, &text, client), 1, `{"concatenate":true}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200 so the synthesized sentences stay billed, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.ScanAudioResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if len(resp.Sentences) != 2 || resp.AudioURL != "" {
+			t.Errorf("expected sentences without a concatenated file, got %+v", resp)
+		}
+	})
+
+	t.Run("rejects scans without text", func(t *testing.T) {
+		if rec := postScanAudio(newHandlers(t, nil), 1, ""); rec.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects too many sentences", func(t *testing.T) {
+		text := "一。二。三。四。"
+		if rec := postScanAudio(newHandlers(t, &text), 1, ""); rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects an unknown voice", func(t *testing.T) {
+		text := "はい。"
+		if rec := postScanAudio(newHandlers(t, &text), 1, `{"voice":"nobody"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects other users", func(t *testing.T) {
+		text := "はい。"
+		if rec := postScanAudio(newHandlers(t, &text), 2, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+)
+
+const (
+	BatchResultQueued = "queued"
+	BatchResultFailed = "failed"
+)
+
+// BatchScanResult is the outcome for one image of a batch upload, in upload order.
+type BatchScanResult struct {
+	Index      int    `json:"index"`
+	Filename   string `json:"filename"`
+	Status     string `json:"status"`
+	ScanID     int64  `json:"scanId,omitempty"`
+	ImageURL   string `json:"imageUrl,omitempty"`
+	PageNumber *int   `json:"pageNumber,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type CreateScanBatchResponse struct {
+	Results   []BatchScanResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// CreateScanBatchAPI accepts several "image" parts in one multipart form and creates one
+// scan per image. Files are handled independently: a bad file is reported in its result
+// entry without failing the rest. The response is 201 when every file succeeded and
+// 207 Multi-Status otherwise. An optional "documentId" field appends the new scans to
+// that document as pages, in upload order.
+func (h *ScanHandlers) CreateScanBatchAPI(w http.ResponseWriter, r *http.Request) {
+	log := logger.GetDefaultLogger().WithRequestID(middleware.GetRequestID(r.Context()))
+
+	if r.Method != http.MethodPost {
+		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	log = log.WithUserID(userID)
+
+	// Leave room for every file at full size plus the multipart framing.
+	maxBody := h.config.MaxUploadSize*int64(h.config.MaxBatchUploadFiles) + 1<<20
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+	if err := r.ParseMultipartForm(h.config.MaxUploadSize); err != nil {
+		log.Warnf("Failed to parse multipart form: %v", err)
+		h.writeJSONError(w, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	headers := r.MultipartForm.File["image"]
+	if len(headers) == 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "Please select at least one image to upload")
+		return
+	}
+	if len(headers) > h.config.MaxBatchUploadFiles {
+		h.writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Too many images. Maximum is %d per batch.", h.config.MaxBatchUploadFiles))
+		return
+	}
+
+	// The rate limiter billed this request as one OCR call; bill the other images too.
+	if err := middleware.ChargeUsage(r.Context(), len(headers)-1); err != nil {
+		var quotaErr *middleware.QuotaError
+		if errors.As(err, &quotaErr) {
+			log.Warnf("Daily OCR quota can't cover a batch of %d images", len(headers))
+			middleware.SetRetryAfter(w, quotaErr.RetryAfter)
+			h.writeJSONError(w, http.StatusTooManyRequests, "Daily OCR quota can't cover this many images")
+			return
+		}
+		log.ErrorWithErr(err, "Failed to record OCR usage for batch")
+	}
+
+	var documentID int64
+	if documentIDParam := r.FormValue("documentId"); documentIDParam != "" {
+		id, err := strconv.ParseInt(documentIDParam, 10, 64)
+		if err != nil || id <= 0 {
+			h.writeJSONError(w, http.StatusBadRequest, "documentId must be a positive integer")
+			return
+		}
+		document, err := h.db.GetDocumentByID(r.Context(), id)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to get document by ID from database")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to get document")
+			return
+		}
+		if document == nil {
+			h.writeJSONError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		if document.UserID != userID {
+			log.Warn("User attempted to upload scans into a document belonging to another user")
+			h.writeJSONError(w, http.StatusForbidden, "Access denied")
+			return
+		}
+		documentID = id
+		log = log.WithField("document_id", documentID)
+	}
+
+	log.Infof("Received batch upload: files=%d", len(headers))
+
+	response := CreateScanBatchResponse{Results: make([]BatchScanResult, len(headers))}
+	for i, header := range headers {
+		result := BatchScanResult{Index: i, Filename: header.Filename}
+
+		created, uploadErr := h.createScanFromUpload(r.Context(), log.WithField("file_index", i), userID, header)
+		if uploadErr != nil {
+			result.Status, result.Error = BatchResultFailed, uploadErr.message
+			response.Results[i] = result
+			response.Failed++
+			continue
+		}
+
+		result.Status, result.ScanID, result.ImageURL = BatchResultQueued, created.ScanID, created.ImageURL
+		if documentID > 0 {
+			// The scan exists and OCR is queued; a failed page link is logged, not fatal.
+			pageNumber, err := h.db.AddDocumentPage(r.Context(), documentID, created.ScanID)
+			if err != nil {
+				log.ErrorWithErr(err, "Failed to add uploaded scan to document")
+			} else {
+				result.PageNumber = &pageNumber
+			}
+		}
+		response.Results[i] = result
+		response.Succeeded++
+	}
+
+	log.Infof("Batch upload finished: succeeded=%d, failed=%d", response.Succeeded, response.Failed)
+
+	status := http.StatusCreated
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
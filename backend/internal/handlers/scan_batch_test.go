@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type batchFile struct {
+	name     string
+	mimeType string
+}
+
+func buildBatchUploadRequest(t *testing.T, files []batchFile, fields map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, file := range files {
+		fileHeader := make(textproto.MIMEHeader)
+		fileHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="image"; filename=%q`, file.name))
+		fileHeader.Set("Content-Type", file.mimeType)
+		part, err := writer.CreatePart(fileHeader)
+		if err != nil {
+			t.Fatalf("CreatePart failed: %v", err)
+		}
+		part.Write([]byte("fake image"))
+	}
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("writer.Close failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/scans/batch", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req.WithContext(middleware.WithUserID(req.Context(), 1))
+}
+
+func TestCreateScanBatch(t *testing.T) {
+	cfg := &config.Config{MaxUploadSize: 10 * 1024 * 1024, MaxBatchUploadFiles: 3}
+
+	t.Run("partial success", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.CreateScanBatchAPI(rec, buildBatchUploadRequest(t, []batchFile{
+			{"page1.jpg", "image/jpeg"},
+			{"notes.txt", "text/plain"},
+			{"page2.png", "image/png"},
+		}, nil))
+
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("expected 207, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.CreateScanBatchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Succeeded != 2 || resp.Failed != 1 || len(resp.Results) != 3 {
+			t.Fatalf("unexpected summary: %+v", resp)
+		}
+		if resp.Results[1].Status != handlers.BatchResultFailed || resp.Results[1].Filename != "notes.txt" || resp.Results[1].Error == "" {
+			t.Fatalf("expected second file to fail with an error, got %+v", resp.Results[1])
+		}
+		if resp.Results[0].ScanID == 0 || resp.Results[2].ScanID == 0 {
+			t.Fatalf("expected scans for the valid files, got %+v", resp.Results)
+		}
+		if jobs := mockDB.OCRJobs(); len(jobs) != 2 {
+			t.Fatalf("expected 2 queued OCR jobs, got %d", len(jobs))
+		}
+	})
+
+	t.Run("appends to document in upload order", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		document := createDocument(t, mockDB, 1, "Chapter 1")
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.CreateScanBatchAPI(rec, buildBatchUploadRequest(t, []batchFile{
+			{"page1.jpg", "image/jpeg"},
+			{"page2.jpg", "image/jpeg"},
+		}, map[string]string{"documentId": strconv.FormatInt(document.ID, 10)}))
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.CreateScanBatchResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		for i, result := range resp.Results {
+			if result.PageNumber == nil || *result.PageNumber != i+1 {
+				t.Fatalf("expected result %d to be page %d, got %+v", i, i+1, result)
+			}
+		}
+	})
+
+	t.Run("rejects too many files", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		files := make([]batchFile, cfg.MaxBatchUploadFiles+1)
+		for i := range files {
+			files[i] = batchFile{fmt.Sprintf("page%d.jpg", i), "image/jpeg"}
+		}
+		rec := httptest.NewRecorder()
+		h.CreateScanBatchAPI(rec, buildBatchUploadRequest(t, files, nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", rec.Code)
+		}
+		if jobs := mockDB.OCRJobs(); len(jobs) != 0 {
+			t.Fatalf("expected no scans to be created, got %d jobs", len(jobs))
+		}
+	})
+
+	t.Run("rejects another user's document", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		document := createDocument(t, mockDB, 2, "Someone else's")
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.CreateScanBatchAPI(rec, buildBatchUploadRequest(t, []batchFile{{"page1.jpg", "image/jpeg"}},
+			map[string]string{"documentId": strconv.FormatInt(document.ID, 10)}))
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d", rec.Code)
+		}
+	})
+}
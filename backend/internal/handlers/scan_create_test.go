@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/textproto"
+	"os"
 	"strconv"
 	"testing"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/gemini-hackathon/app/internal/handlers"
 	"github.com/gemini-hackathon/app/internal/knowledge"
 	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
 	"github.com/gemini-hackathon/app/internal/testutil"
 )
 
@@ -33,6 +37,14 @@ func (m *mockFileStorage) DeleteImage(path string) error {
 	return nil
 }
 
+func (m *mockFileStorage) SaveAudio(name string, data []byte, mimeType string) (string, error) {
+	return "data/uploads/audio/" + name + ".wav", nil
+}
+
+func (m *mockFileStorage) OpenAudio(path string) (io.ReadSeekCloser, error) {
+	return nil, os.ErrNotExist
+}
+
 type mockGeminiClient struct{}
 
 func (m *mockGeminiClient) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
@@ -47,14 +59,18 @@ func (m *mockGeminiClient) Annotate(ctx context.Context, ocrText string, selecte
 	return nil, nil
 }
 
-func (m *mockGeminiClient) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry) (*gemini.AnnotationResponse, error) {
+func (m *mockGeminiClient) AnnotateWithKnowledge(ctx context.Context, ocrText string, selectedText string, entries []knowledge.Entry, targetLanguage string) (*gemini.AnnotationResponse, error) {
 	return nil, nil
 }
 
-func (m *mockGeminiClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string) (*gemini.SpeechResponse, error) {
+func (m *mockGeminiClient) SynthesizeSpeech(ctx context.Context, highlightedText string, contextText string, opts gemini.SpeechOptions) (*gemini.SpeechResponse, error) {
 	return nil, nil
 }
 
+func (m *mockGeminiClient) Furigana(ctx context.Context, text string) (*gemini.FuriganaResponse, error) {
+	return &gemini.FuriganaResponse{Tokens: []gemini.FuriganaToken{{Surface: text}}}, nil
+}
+
 func buildUploadRequest(t *testing.T, path string) *http.Request {
 	t.Helper()
 
@@ -82,7 +98,7 @@ func buildUploadRequest(t *testing.T, path string) *http.Request {
 func TestCreateScanPersistsImageURLAndGetScanReturnsIt(t *testing.T) {
 	mockDB := testutil.NewMockDB()
 	cfg := &config.Config{MaxUploadSize: 10 * 1024 * 1024}
-	scanHandlers := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, cfg)
+	scanHandlers := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
 
 	createReq := buildUploadRequest(t, "/v1/scans")
 	createReq = createReq.WithContext(middleware.WithUserID(createReq.Context(), 1))
@@ -101,6 +117,17 @@ func TestCreateScanPersistsImageURLAndGetScanReturnsIt(t *testing.T) {
 		t.Fatalf("expected created image URL /uploads/1.jpg, got %q", created.ImageURL)
 	}
 
+	jobs := mockDB.OCRJobs()
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 queued OCR job, got %d", len(jobs))
+	}
+	if jobs[0].ScanID != created.ScanID || jobs[0].Status != models.OCRJobStatusQueued {
+		t.Fatalf("expected queued job for scan %d, got scan %d status %q", created.ScanID, jobs[0].ScanID, jobs[0].Status)
+	}
+	if jobs[0].ImagePath != "data/uploads/1.jpg" || jobs[0].MimeType != "image/jpeg" {
+		t.Fatalf("unexpected job image: path=%q mime=%q", jobs[0].ImagePath, jobs[0].MimeType)
+	}
+
 	getReq := httptest.NewRequest(http.MethodGet, "/v1/scans/"+strconv.FormatInt(created.ScanID, 10), nil)
 	getReq = getReq.WithContext(middleware.WithUserID(getReq.Context(), 1))
 	getRec := httptest.NewRecorder()
@@ -122,7 +149,7 @@ func TestCreateScanPersistsImageURLAndGetScanReturnsIt(t *testing.T) {
 func TestCreateScanUnauthorized(t *testing.T) {
 	mockDB := testutil.NewMockDB()
 	cfg := &config.Config{MaxUploadSize: 10 * 1024 * 1024}
-	scanHandlers := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, cfg)
+	scanHandlers := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
 
 	req := buildUploadRequest(t, "/v1/scans")
 	rec := httptest.NewRecorder()
@@ -132,3 +159,32 @@ func TestCreateScanUnauthorized(t *testing.T) {
 		t.Fatalf("expected 401, got %d", rec.Code)
 	}
 }
+
+// unqueueableDB fails to enqueue OCR jobs.
+type unqueueableDB struct {
+	*testutil.MockDB
+}
+
+func (db *unqueueableDB) CreateOCRJob(ctx context.Context, job *models.OCRJob) (int64, error) {
+	return 0, errors.New("queue unavailable")
+}
+
+func TestCreateScanMarksScanFailedWhenQueueingFails(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	cfg := &config.Config{MaxUploadSize: 10 * 1024 * 1024}
+	scanHandlers := handlers.NewScanHandlers(&unqueueableDB{mockDB}, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+	req := buildUploadRequest(t, "/v1/scans")
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+
+	scanHandlers.CreateScanAPI(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+
+	scan, _ := mockDB.GetScanByID(context.Background(), 1)
+	if scan == nil || scan.Status != models.ScanStatusFailed || scan.FailureReason == nil {
+		t.Fatalf("expected the scan to be marked failed, got %+v", scan)
+	}
+}
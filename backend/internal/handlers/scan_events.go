@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/events"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// scanEventsHandler streams OCR progress for a scan as Server-Sent Events. The
+// first event reflects the scan's current state; the stream ends after
+// ocr_completed or ocr_failed.
+func (h *ScanHandlers) scanEventsHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	scan, log, ok := h.loadOwnedScan(w, r, log)
+	if !ok {
+		return
+	}
+	if h.events == nil {
+		h.writeJSONError(w, http.StatusServiceUnavailable, "Scan events are not available")
+		return
+	}
+
+	stream, unsubscribe := h.events.Subscribe(scan.ID)
+	defer unsubscribe()
+
+	// Reload after subscribing so a transition between the two isn't lost.
+	scan, err := h.db.GetScanByID(r.Context(), scan.ID)
+	if err != nil || scan == nil {
+		log.ErrorWithErr(err, "Failed to reload scan for event stream")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to load scan")
+		return
+	}
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to start event stream")
+		return
+	}
+
+	current := scanStatusEvent(scan)
+	if err := sse.Event(current.Type, current); err != nil || current.IsTerminal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := sse.Ping(); err != nil {
+				return
+			}
+		case event := <-stream:
+			if err := sse.Event(event.Type, event); err != nil {
+				log.Warnf("Failed to write scan event: %v", err)
+				return
+			}
+			if event.IsTerminal() {
+				return
+			}
+		}
+	}
+}
+
+// scanStatusEvent describes a scan's persisted state as the event that led to it.
+func scanStatusEvent(scan *models.Scan) events.ScanEvent {
+	event := events.ScanEvent{ScanID: scan.ID}
+	switch scan.Status {
+	case models.ScanStatusProcessing:
+		event.Type = events.TypeOCRStarted
+	case models.ScanStatusCompleted:
+		event.Type = events.TypeOCRCompleted
+		if scan.FullOCRText != nil {
+			event.Text = *scan.FullOCRText
+		}
+		if scan.DetectedLanguage != nil {
+			event.Language = *scan.DetectedLanguage
+		}
+	case models.ScanStatusFailed:
+		event.Type = events.TypeOCRFailed
+		if scan.FailureReason != nil {
+			event.Reason = *scan.FailureReason
+		}
+	default:
+		event.Type = events.TypeQueued
+	}
+	return event
+}
+
+func (h *ScanHandlers) publishEvent(ctx context.Context, event events.ScanEvent) {
+	if h.events != nil {
+		h.events.Publish(ctx, event)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/events"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+// readSSEEvent returns the next "event:" name from an SSE stream, skipping comments.
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && name != "":
+			return name, data
+		}
+	}
+}
+
+func startScanEventServer(t *testing.T, h *handlers.ScanHandlers) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ScanByIDAPI(w, r.WithContext(middleware.WithUserID(r.Context(), 1)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScanEventsStream(t *testing.T) {
+	cfg := &config.Config{}
+
+	t.Run("streams progress until OCR completes", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		hub := events.NewHub()
+		server := startScanEventServer(t, handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, hub, nil, cfg))
+
+		resp, err := http.Get(server.URL + "/v1/scans/1/events")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Fatalf("expected text/event-stream, got %q", got)
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		if name, _ := readSSEEvent(t, reader); name != events.TypeQueued {
+			t.Fatalf("expected initial %q event, got %q", events.TypeQueued, name)
+		}
+
+		hub.Publish(context.Background(), events.ScanEvent{ScanID: scan.ID, Type: events.TypeOCRStarted})
+		hub.Publish(context.Background(), events.ScanEvent{ScanID: scan.ID, Type: events.TypeOCRCompleted, Text: "請求書"})
+
+		if name, _ := readSSEEvent(t, reader); name != events.TypeOCRStarted {
+			t.Fatalf("expected %q event, got %q", events.TypeOCRStarted, name)
+		}
+		name, data := readSSEEvent(t, reader)
+		if name != events.TypeOCRCompleted || !strings.Contains(data, "請求書") {
+			t.Fatalf("expected %q event with text, got %q %s", events.TypeOCRCompleted, name, data)
+		}
+	})
+
+	t.Run("ends immediately for a completed scan", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		mockDB.UpdateScanOCR(context.Background(), scan.ID, "完了", "JP", nil)
+		server := startScanEventServer(t, handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, events.NewHub(), nil, cfg))
+
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Get(server.URL + "/v1/scans/1/events")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		name, data := readSSEEvent(t, bufio.NewReader(resp.Body))
+		if name != events.TypeOCRCompleted || !strings.Contains(data, "完了") {
+			t.Fatalf("expected %q event with text, got %q %s", events.TypeOCRCompleted, name, data)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const (
+	FuriganaSourceModel     = "model"
+	FuriganaSourceKnowledge = "knowledge"
+)
+
+// FuriganaTokenItem is a ruby-ready span: render Surface with Reading above it. Tokens
+// concatenate to the full OCR text.
+type FuriganaTokenItem struct {
+	Surface string `json:"surface"`
+	Reading string `json:"reading,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+type GetFuriganaResponse struct {
+	ScanID int64               `json:"scanId"`
+	Tokens []FuriganaTokenItem `json:"tokens"`
+}
+
+// getFuriganaHandler returns the OCR text of a scan split into tokens with kana readings.
+// Model output is cached per scan and reused until the OCR text changes; readings from
+// the knowledge base are applied on every request so CSV updates take effect at once.
+func (h *ScanHandlers) getFuriganaHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	scan, log, ok := h.loadOwnedScan(w, r, log)
+	if !ok {
+		return
+	}
+
+	if scan.FullOCRText == nil {
+		h.writeJSONError(w, http.StatusConflict, "OCR has not completed for this scan")
+		return
+	}
+	text := *scan.FullOCRText
+	textHash := hashText(text)
+
+	cached, err := h.db.GetScanFurigana(r.Context(), scan.ID)
+	if err != nil {
+		// A broken cache shouldn't block the reader; fall through to the model.
+		log.ErrorWithErr(err, "Failed to read furigana cache")
+	}
+
+	var tokens []models.FuriganaToken
+	if cached != nil && cached.TextHash == textHash {
+		tokens = cached.Tokens
+	} else {
+		furigana, err := h.geminiClient.Furigana(r.Context(), text)
+		if err != nil {
+			log.ErrorWithErr(err, "Failed to generate furigana")
+			h.writeJSONError(w, http.StatusInternalServerError, "Failed to generate furigana")
+			return
+		}
+		tokens = alignFurigana(text, furigana.Tokens)
+
+		if err := h.db.SaveScanFurigana(r.Context(), &models.ScanFurigana{
+			ScanID:    scan.ID,
+			TextHash:  textHash,
+			Tokens:    tokens,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			log.ErrorWithErr(err, "Failed to cache furigana")
+		}
+		log.Infof("Generated furigana: tokens=%d", len(tokens))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetFuriganaResponse{
+		ScanID: scan.ID,
+		Tokens: h.applyKnowledgeReadings(tokens),
+	})
+}
+
+// applyKnowledgeReadings replaces model readings with the curated Kana of knowledge
+// entries whose term matches the token exactly.
+func (h *ScanHandlers) applyKnowledgeReadings(tokens []models.FuriganaToken) []FuriganaTokenItem {
+	items := make([]FuriganaTokenItem, len(tokens))
+	for i, token := range tokens {
+		item := FuriganaTokenItem{Surface: token.Surface, Reading: token.Reading}
+		if item.Reading != "" {
+			item.Source = FuriganaSourceModel
+		}
+		if h.knowledge != nil && strings.TrimSpace(token.Surface) != "" {
+			for _, entry := range h.knowledge.Lookup(token.Surface) {
+				if entry.Kosakata == token.Surface && entry.Kana != "" {
+					item.Reading, item.Source = entry.Kana, FuriganaSourceKnowledge
+					break
+				}
+			}
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// alignFurigana maps model tokens back onto the OCR text. The model occasionally drops,
+// merges or alters characters; any text it skipped becomes a token without a reading and
+// tokens that don't occur in the text are discarded, so the result always concatenates
+// to exactly the original text.
+func alignFurigana(text string, tokens []gemini.FuriganaToken) []models.FuriganaToken {
+	var aligned []models.FuriganaToken
+	pos := 0
+	for _, token := range tokens {
+		if token.Surface == "" {
+			continue
+		}
+		offset := strings.Index(text[pos:], token.Surface)
+		if offset < 0 {
+			continue
+		}
+		if offset > 0 {
+			aligned = append(aligned, models.FuriganaToken{Surface: text[pos : pos+offset]})
+		}
+		aligned = append(aligned, models.FuriganaToken{
+			Surface: token.Surface,
+			Reading: strings.TrimSpace(token.Reading),
+		})
+		pos += offset + len(token.Surface)
+	}
+	if pos < len(text) {
+		aligned = append(aligned, models.FuriganaToken{Surface: text[pos:]})
+	}
+	return aligned
+}
+
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
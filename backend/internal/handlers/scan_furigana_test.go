@@ -0,0 +1,118 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/knowledge"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type furiganaGeminiClient struct {
+	mockGeminiClient
+	tokens []gemini.FuriganaToken
+	calls  int
+}
+
+func (m *furiganaGeminiClient) Furigana(ctx context.Context, text string) (*gemini.FuriganaResponse, error) {
+	m.calls++
+	return &gemini.FuriganaResponse{Tokens: m.tokens}, nil
+}
+
+func newKnowledgeService(t *testing.T, csv string) knowledge.Service {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "knowledge.csv")
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatalf("failed to write knowledge CSV: %v", err)
+	}
+	svc, err := knowledge.NewService(path)
+	if err != nil {
+		t.Fatalf("failed to load knowledge CSV: %v", err)
+	}
+	return svc
+}
+
+func getFurigana(t *testing.T, h *handlers.ScanHandlers) (*httptest.ResponseRecorder, handlers.GetFuriganaResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/v1/scans/1/furigana", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.ScanByIDAPI(rec, req)
+
+	var resp handlers.GetFuriganaResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestGetFurigana(t *testing.T) {
+	cfg := &config.Config{}
+
+	t.Run("aligns tokens, caches and applies knowledge readings", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		text := "今日は残業です。\n"
+		mockDB.UpdateScanOCR(context.Background(), scan.ID, text, "JP", nil)
+
+		client := &furiganaGeminiClient{tokens: []gemini.FuriganaToken{
+			{Surface: "今日", Reading: "きょう"},
+			{Surface: "は"},
+			{Surface: "残業", Reading: "ざんぎょ"},
+			{Surface: "でした"}, // not in the text; dropped
+		}}
+		svc := newKnowledgeService(t, "Kosakata,Kana,Arti (EN / ID)\n残業,ざんぎょう,overtime\n")
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, client, svc, nil, nil, cfg)
+
+		rec, resp := getFurigana(t, h)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var surfaces strings.Builder
+		for _, token := range resp.Tokens {
+			surfaces.WriteString(token.Surface)
+		}
+		if surfaces.String() != text {
+			t.Fatalf("expected tokens to reproduce the text, got %q", surfaces.String())
+		}
+		if resp.Tokens[0].Reading != "きょう" || resp.Tokens[0].Source != handlers.FuriganaSourceModel {
+			t.Errorf("unexpected first token: %+v", resp.Tokens[0])
+		}
+		if resp.Tokens[2].Reading != "ざんぎょう" || resp.Tokens[2].Source != handlers.FuriganaSourceKnowledge {
+			t.Errorf("expected knowledge reading for 残業, got %+v", resp.Tokens[2])
+		}
+		if last := resp.Tokens[len(resp.Tokens)-1]; last.Surface != "です。\n" || last.Reading != "" {
+			t.Errorf("expected unmatched tail as plain token, got %+v", last)
+		}
+
+		getFurigana(t, h)
+		if client.calls != 1 {
+			t.Fatalf("expected cached furigana on second request, got %d model calls", client.calls)
+		}
+
+		mockDB.UpdateScanOCR(context.Background(), scan.ID, "今日", "JP", nil)
+		getFurigana(t, h)
+		if client.calls != 2 {
+			t.Fatalf("expected new OCR text to invalidate the cache, got %d model calls", client.calls)
+		}
+	})
+
+	t.Run("conflict before OCR completes", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		createPendingScan(t, mockDB, 1)
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &furiganaGeminiClient{}, nil, nil, nil, cfg)
+
+		if rec, _ := getFurigana(t, h); rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/events"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const ocrRerunTimeout = 3 * time.Minute
+
+type OCRRevisionItem struct {
+	ID               int64   `json:"id"`
+	FullText         string  `json:"fullText"`
+	DetectedLanguage *string `json:"detectedLanguage,omitempty"`
+	CreatedAt        string  `json:"createdAt"`
+}
+
+type GetOCRRevisionsResponse struct {
+	Data []OCRRevisionItem `json:"data"`
+}
+
+// retryOCRHandler re-runs OCR on the stored image of a scan and replaces its text.
+// The previous text is archived as a revision by UpdateScanOCR.
+func (h *ScanHandlers) retryOCRHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	scan, log, ok := h.loadOwnedScan(w, r, log)
+	if !ok {
+		return
+	}
+
+	imagePath := filepath.Join(h.config.UploadDir, filepath.Base(scan.ImageURL))
+	imageData, err := h.fileStorage.OpenImage(imagePath)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to read stored scan image")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to read scan image")
+		return
+	}
+
+	// A queued job or another re-run, possibly on another replica, may be running right now.
+	// The claim outlives ocrRerunTimeout only when its holder died mid-run.
+	now := time.Now()
+	claimed, err := h.db.ClaimScanForOCR(r.Context(), scan.ID, now, now.Add(-ocrRerunTimeout))
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to claim scan for OCR")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to re-run OCR")
+		return
+	}
+	if !claimed {
+		h.writeJSONError(w, http.StatusConflict, "OCR is already running for this scan")
+		return
+	}
+
+	// Finish the run even if the client goes away, so the scan isn't left processing.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), ocrRerunTimeout)
+	defer cancel()
+	defer func() {
+		if err := h.db.ReleaseScanOCRClaim(context.WithoutCancel(ctx), scan.ID); err != nil {
+			log.ErrorWithErr(err, "Failed to release OCR claim")
+		}
+	}()
+
+	log.Infof("Re-running OCR for scan: previous_status=%s", scan.Status)
+	if err := h.processOCR(ctx, scan.ID, imageData, storage.MimeTypeFromPath(imagePath)); err != nil {
+		// Keep a previously good result usable; otherwise surface the failure on the scan.
+		status, reason := scan.Status, scan.FailureReason
+		if scan.FullOCRText == nil {
+			msg := failureReason(err)
+			status, reason = models.ScanStatusFailed, &msg
+		}
+		if updateErr := h.db.UpdateScanStatus(ctx, scan.ID, status, reason); updateErr != nil {
+			log.ErrorWithErr(updateErr, "Failed to restore scan status after OCR re-run failure")
+		}
+		h.publishEvent(ctx, events.ScanEvent{ScanID: scan.ID, Type: events.TypeOCRFailed, Reason: failureReason(err)})
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to re-run OCR")
+		return
+	}
+
+	updated, err := h.db.GetScanByID(ctx, scan.ID)
+	if err != nil || updated == nil {
+		log.ErrorWithErr(err, "Failed to reload scan after OCR re-run")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to load scan")
+		return
+	}
+
+	log.Infof("OCR re-run completed successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toGetScanResponse(updated))
+}
+
+func (h *ScanHandlers) getOCRRevisionsHandler(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+	scan, log, ok := h.loadOwnedScan(w, r, log)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.db.GetScanOCRRevisions(r.Context(), scan.ID)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to get OCR revisions")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to get OCR revisions")
+		return
+	}
+
+	data := make([]OCRRevisionItem, len(revisions))
+	for i, revision := range revisions {
+		data[i] = OCRRevisionItem{
+			ID:               revision.ID,
+			FullText:         revision.FullOCRText,
+			DetectedLanguage: revision.DetectedLanguage,
+			CreatedAt:        revision.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetOCRRevisionsResponse{Data: data})
+}
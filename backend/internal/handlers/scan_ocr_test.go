@@ -0,0 +1,138 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type blockingOCRGeminiClient struct {
+	mockGeminiClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingOCRGeminiClient) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
+	close(m.started)
+	<-m.release
+	return m.mockGeminiClient.OCR(ctx, imageData, mimeType)
+}
+
+func newRetryOCRRequest(userID int64) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/scans/1/ocr", nil)
+	return req.WithContext(middleware.WithUserID(req.Context(), userID))
+}
+
+func TestRetryOCR(t *testing.T) {
+	cfg := &config.Config{UploadDir: "data/uploads"}
+
+	t.Run("replaces text and keeps the previous one as a revision", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		mockDB.UpdateScanOCR(context.Background(), scan.ID, "garbled", "JP", nil)
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.ScanByIDAPI(rec, newRetryOCRRequest(1))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		var resp handlers.GetScanResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.FullText != "OCR text" || resp.Status != models.ScanStatusCompleted {
+			t.Fatalf("unexpected scan after re-run: %+v", resp)
+		}
+
+		revisions, _ := mockDB.GetScanOCRRevisions(context.Background(), scan.ID)
+		if len(revisions) != 1 || revisions[0].FullOCRText != "garbled" {
+			t.Fatalf("expected previous text to be kept as a revision, got %+v", revisions)
+		}
+
+		rec = httptest.NewRecorder()
+		h.ScanByIDAPI(rec, newRetryOCRRequest(1))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected the claim to be released after the re-run, got status %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects another user's scan", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		createPendingScan(t, mockDB, 1)
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.ScanByIDAPI(rec, newRetryOCRRequest(2))
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected status 403, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects a scan with a queued OCR job", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		mockDB.CreateOCRJob(context.Background(), &models.OCRJob{ScanID: scan.ID, Status: models.OCRJobStatusQueued})
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.ScanByIDAPI(rec, newRetryOCRRequest(1))
+
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", rec.Code)
+		}
+	})
+
+	t.Run("takes over a claim left by a re-run that died", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		mockDB.UpdateScanStatus(context.Background(), scan.ID, models.ScanStatusProcessing, nil)
+		mockDB.SetScanOCRClaim(scan.ID, time.Now().Add(-time.Hour))
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		rec := httptest.NewRecorder()
+		h.ScanByIDAPI(rec, newRetryOCRRequest(1))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("rejects a concurrent re-run", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		createPendingScan(t, mockDB, 1)
+		client := &blockingOCRGeminiClient{started: make(chan struct{}), release: make(chan struct{})}
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, client, nil, nil, nil, cfg)
+
+		done := make(chan int)
+		go func() {
+			rec := httptest.NewRecorder()
+			h.ScanByIDAPI(rec, newRetryOCRRequest(1))
+			done <- rec.Code
+		}()
+		<-client.started
+
+		rec := httptest.NewRecorder()
+		h.ScanByIDAPI(rec, newRetryOCRRequest(1))
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409 for concurrent re-run, got %d", rec.Code)
+		}
+
+		close(client.release)
+		if code := <-done; code != http.StatusOK {
+			t.Fatalf("expected first re-run to succeed, got %d", code)
+		}
+	})
+}
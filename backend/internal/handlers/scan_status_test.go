@@ -0,0 +1,180 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+type failingOCRGeminiClient struct {
+	mockGeminiClient
+}
+
+func (m *failingOCRGeminiClient) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
+	return nil, errors.New("gemini unavailable")
+}
+
+type layoutOCRGeminiClient struct {
+	mockGeminiClient
+}
+
+func (m *layoutOCRGeminiClient) OCR(ctx context.Context, imageData []byte, mimeType string) (*gemini.OCRResponse, error) {
+	box := gemini.BoundingBox{X: 0.8, Y: 0.1, Width: 0.1, Height: 0.5}
+	return &gemini.OCRResponse{
+		RawText:     "仕事",
+		Language:    "JP",
+		Orientation: gemini.OrientationVertical,
+		Blocks: []gemini.OCRBlock{{
+			Text:        "仕事",
+			Orientation: gemini.OrientationVertical,
+			Box:         box,
+			Lines:       []gemini.OCRLine{{Text: "仕事", Box: box}},
+		}},
+	}, nil
+}
+
+func createPendingScan(t *testing.T, db *testutil.MockDB, userID int64) *models.Scan {
+	t.Helper()
+	scan := &models.Scan{UserID: userID, ImageURL: "/uploads/1.jpg", CreatedAt: time.Now()}
+	if _, err := db.CreateScan(context.Background(), scan); err != nil {
+		t.Fatalf("CreateScan failed: %v", err)
+	}
+	return scan
+}
+
+func TestProcessOCRJobUpdatesScanStatus(t *testing.T) {
+	cfg := &config.Config{DefaultPageSize: 20}
+
+	t.Run("completed on success", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+		job := &models.OCRJob{ScanID: scan.ID, Attempts: 1, MaxAttempts: 3}
+		if err := h.ProcessOCRJob(context.Background(), job); err != nil {
+			t.Fatalf("ProcessOCRJob returned error: %v", err)
+		}
+		if scan.Status != models.ScanStatusCompleted {
+			t.Fatalf("expected status %q, got %q", models.ScanStatusCompleted, scan.Status)
+		}
+	})
+
+	t.Run("pending while retries remain", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &failingOCRGeminiClient{}, nil, nil, nil, cfg)
+
+		job := &models.OCRJob{ScanID: scan.ID, Attempts: 1, MaxAttempts: 3}
+		if err := h.ProcessOCRJob(context.Background(), job); err == nil {
+			t.Fatal("expected ProcessOCRJob to return an error")
+		}
+		if scan.Status != models.ScanStatusPending || scan.FailureReason != nil {
+			t.Fatalf("expected pending scan without reason, got %q %v", scan.Status, scan.FailureReason)
+		}
+	})
+
+	t.Run("failed with reason on final attempt", func(t *testing.T) {
+		mockDB := testutil.NewMockDB()
+		scan := createPendingScan(t, mockDB, 1)
+		h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &failingOCRGeminiClient{}, nil, nil, nil, cfg)
+
+		job := &models.OCRJob{ScanID: scan.ID, Attempts: 3, MaxAttempts: 3}
+		_ = h.ProcessOCRJob(context.Background(), job)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/scans/1", nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.GetScanAPI(rec, req)
+
+		var resp handlers.GetScanResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Status != models.ScanStatusFailed {
+			t.Fatalf("expected status %q, got %q", models.ScanStatusFailed, resp.Status)
+		}
+		if resp.FailureReason == nil || *resp.FailureReason == "" {
+			t.Fatal("expected a failure reason")
+		}
+	})
+}
+
+func TestGetScansAPIStatusFilter(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	cfg := &config.Config{DefaultPageSize: 20}
+	h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &mockGeminiClient{}, nil, nil, nil, cfg)
+
+	createPendingScan(t, mockDB, 1)
+	failed := createPendingScan(t, mockDB, 1)
+	reason := "OCR processing failed"
+	mockDB.UpdateScanStatus(context.Background(), failed.ID, models.ScanStatusFailed, &reason)
+
+	t.Run("returns only failed scans", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/scans?status=failed", nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.GetScansAPI(rec, req)
+
+		var resp handlers.GetScansResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].ID != failed.ID {
+			t.Fatalf("expected only scan %d, got %+v", failed.ID, resp.Data)
+		}
+		if resp.Data[0].FailureReason == nil || *resp.Data[0].FailureReason != reason {
+			t.Fatalf("expected failure reason %q, got %v", reason, resp.Data[0].FailureReason)
+		}
+	})
+
+	t.Run("rejects unknown status", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/scans?status=done", nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.GetScansAPI(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestProcessOCRJobStoresLayout(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	scan := createPendingScan(t, mockDB, 1)
+	h := handlers.NewScanHandlers(mockDB, &mockFileStorage{}, &layoutOCRGeminiClient{}, nil, nil, nil, &config.Config{})
+
+	if err := h.ProcessOCRJob(context.Background(), &models.OCRJob{ScanID: scan.ID, Attempts: 1, MaxAttempts: 3}); err != nil {
+		t.Fatalf("ProcessOCRJob returned error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/scans/1", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.GetScanAPI(rec, req)
+
+	var resp handlers.GetScanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Layout == nil || resp.Layout.Orientation != gemini.OrientationVertical {
+		t.Fatalf("expected vertical layout, got %+v", resp.Layout)
+	}
+	if len(resp.Layout.Blocks) != 1 || len(resp.Layout.Blocks[0].Lines) != 1 {
+		t.Fatalf("expected one block with one line, got %+v", resp.Layout.Blocks)
+	}
+	if box := resp.Layout.Blocks[0].Box; box.X != 0.8 || box.Height != 0.5 {
+		t.Fatalf("unexpected block box: %+v", box)
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const (
+	maxSearchQueryLength = 200
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 50
+	// searchSnippetRadius is how many characters of context a snippet keeps on each side of
+	// the first match.
+	searchSnippetRadius = 40
+)
+
+type SearchHandlers struct {
+	db     storage.DB
+	config *config.Config
+}
+
+func NewSearchHandlers(db storage.DB, cfg *config.Config) *SearchHandlers {
+	return &SearchHandlers{
+		db:     db,
+		config: cfg,
+	}
+}
+
+type SearchHitItem struct {
+	// Type is "scan" or "annotation".
+	Type         string `json:"type"`
+	ScanID       *int64 `json:"scanId"`
+	AnnotationID *int64 `json:"annotationId,omitempty"`
+	// HighlightedText is the annotation's saved phrase.
+	HighlightedText string `json:"highlightedText,omitempty"`
+	// Field is the field the snippet is taken from: fullOcrText, highlightedText,
+	// contextText or nuanceData.
+	Field string `json:"field"`
+	// Snippet is HTML-escaped text around the first match, with every match wrapped in
+	// <mark>. It starts or ends with "…" when the field text was cut.
+	Snippet   string  `json:"snippet"`
+	Rank      float64 `json:"rank"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+type SearchResponse struct {
+	Query string          `json:"query"`
+	Data  []SearchHitItem `json:"data"`
+}
+
+// SearchAPI handles GET /v1/search?q=. type=scans or type=annotations searches only one kind;
+// limit caps the hits (default 20, maximum 50).
+func (h *SearchHandlers) SearchAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		h.writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	log := logger.GetDefaultLogger().
+		WithRequestID(middleware.GetRequestID(r.Context())).
+		WithUserID(userID)
+
+	params := r.URL.Query()
+	query := strings.Join(strings.Fields(params.Get("q")), " ")
+	if query == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		h.writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("q must be at most %d characters", maxSearchQueryLength))
+		return
+	}
+
+	var kind string
+	switch params.Get("type") {
+	case "", "all":
+	case "scans":
+		kind = models.SearchKindScan
+	case "annotations":
+		kind = models.SearchKindAnnotation
+	default:
+		h.writeJSONError(w, http.StatusBadRequest, "type must be one of all, scans, annotations")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitParam := params.Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 {
+			h.writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxSearchLimit)
+	}
+
+	hits, err := h.db.Search(r.Context(), userID, query, kind, limit)
+	if err != nil {
+		log.ErrorWithErr(err, "Failed to search")
+		h.writeJSONError(w, http.StatusInternalServerError, "Failed to search")
+		return
+	}
+
+	response := SearchResponse{Query: query, Data: make([]SearchHitItem, len(hits))}
+	for i, hit := range hits {
+		response.Data[i] = SearchHitItem{
+			Type:            hit.Kind,
+			ScanID:          hit.ScanID,
+			AnnotationID:    hit.AnnotationID,
+			HighlightedText: hit.HighlightedText,
+			Field:           hit.Field,
+			Snippet:         searchSnippet(hit.Text, query, searchSnippetRadius),
+			Rank:            hit.Rank,
+			CreatedAt:       hit.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// searchSnippet cuts text to radius characters either side of the first case-insensitive
+// match of query, escapes it for HTML and wraps every match in <mark>. Whitespace runs,
+// including OCR line breaks, become single spaces. Without a match it returns the start of
+// the text.
+func searchSnippet(text, query string, radius int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	needle := []rune(query)
+
+	first := indexFold(runes, needle, 0)
+	start, end := 0, min(len(runes), 2*radius)
+	if first >= 0 {
+		start = max(0, first-radius)
+		end = min(len(runes), first+len(needle)+radius)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		match := indexFold(runes[:end], needle, i)
+		if match < 0 {
+			b.WriteString(html.EscapeString(string(runes[i:end])))
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[i:match])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[match : match+len(needle)])))
+		b.WriteString("</mark>")
+		i = match + len(needle)
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// indexFold returns the index of the first case-insensitive match of needle in haystack at or
+// after from, or -1.
+func indexFold(haystack, needle []rune, from int) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := from; i+len(needle) <= len(haystack); i++ {
+		matched := true
+		for j, r := range needle {
+			if !equalFoldRune(haystack[i+j], r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalFoldRune(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+func (h *SearchHandlers) writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+	})
+}
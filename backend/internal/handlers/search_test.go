@@ -0,0 +1,110 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestSearch(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	ctx := context.Background()
+	now := time.Now()
+
+	page := "会議の前に" + strings.Repeat("資料を確認して", 10) + "\n稟議書を<提出>しました。" + strings.Repeat("上司の承認を待つ", 10)
+	scanID, _ := mockDB.CreateScan(ctx, &models.Scan{UserID: 1, FullOCRText: &page, CreatedAt: now})
+	other := "稟議"
+	mockDB.CreateScan(ctx, &models.Scan{UserID: 2, FullOCRText: &other, CreatedAt: now})
+
+	contextText := "稟議書を提出しました。"
+	mockDB.CreateAnnotation(ctx, &models.Annotation{
+		UserID:          1,
+		ScanID:          &scanID,
+		HighlightedText: "稟議書",
+		ContextText:     &contextText,
+		NuanceData:      models.NuanceData{Meaning: "A Ringi approval document"},
+		CreatedAt:       now,
+	})
+	mockDB.CreateAnnotation(ctx, &models.Annotation{
+		UserID:          1,
+		HighlightedText: "承認",
+		ContextText:     &contextText,
+		NuanceData:      models.NuanceData{Meaning: "approval"},
+		CreatedAt:       now,
+	})
+
+	h := handlers.NewSearchHandlers(mockDB, &config.Config{DefaultPageSize: 10})
+	search := func(t *testing.T, query string) (*httptest.ResponseRecorder, handlers.SearchResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/search?"+query, nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+		rec := httptest.NewRecorder()
+		h.SearchAPI(rec, req)
+		var resp handlers.SearchResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	t.Run("ranks annotations above scans with highlighted snippets", func(t *testing.T) {
+		rec, resp := search(t, "q=%E7%A8%9F%E8%AD%B0")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if resp.Query != "稟議" || len(resp.Data) != 3 {
+			t.Fatalf("expected 3 hits for 稟議, got %+v", resp)
+		}
+
+		first := resp.Data[0]
+		if first.Type != "annotation" || first.Field != "highlightedText" || first.Snippet != "<mark>稟議</mark>書" {
+			t.Errorf("expected the saved phrase first, got %+v", first)
+		}
+		if second := resp.Data[1]; second.Field != "contextText" || second.HighlightedText != "承認" || second.ScanID != nil {
+			t.Errorf("expected the context match second, got %+v", second)
+		}
+
+		scan := resp.Data[2]
+		if scan.Type != "scan" || scan.ScanID == nil || *scan.ScanID != scanID || scan.AnnotationID != nil {
+			t.Fatalf("expected the scan last, got %+v", scan)
+		}
+		if !strings.HasPrefix(scan.Snippet, "…") || !strings.HasSuffix(scan.Snippet, "…") {
+			t.Errorf("expected a cut snippet, got %q", scan.Snippet)
+		}
+		if !strings.Contains(scan.Snippet, "確認して <mark>稟議</mark>書を&lt;提出&gt;") {
+			t.Errorf("expected an escaped snippet with the match marked, got %q", scan.Snippet)
+		}
+	})
+
+	t.Run("matches explanations ignoring case", func(t *testing.T) {
+		_, resp := search(t, "q=RINGI&type=annotations")
+		if len(resp.Data) != 1 || resp.Data[0].Field != "nuanceData" || resp.Data[0].Snippet != "A <mark>Ringi</mark> approval document" {
+			t.Errorf("unexpected hits %+v", resp.Data)
+		}
+	})
+
+	t.Run("filters by type and limits", func(t *testing.T) {
+		if _, resp := search(t, "q=%E7%A8%9F%E8%AD%B0&type=scans"); len(resp.Data) != 1 || resp.Data[0].Type != "scan" {
+			t.Errorf("expected only the scan, got %+v", resp.Data)
+		}
+		if _, resp := search(t, "q=%E7%A8%9F%E8%AD%B0&limit=1"); len(resp.Data) != 1 {
+			t.Errorf("expected one hit, got %d", len(resp.Data))
+		}
+	})
+
+	t.Run("rejects bad parameters", func(t *testing.T) {
+		for _, query := range []string{"q=", "q=%20%20", "q=a&type=pages", "q=a&limit=0", "q=" + strings.Repeat("a", 201)} {
+			if rec, _ := search(t, query); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+			}
+		}
+	})
+}
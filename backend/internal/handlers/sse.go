@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// sseWriter writes Server-Sent Events and flushes after each one.
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// newSSEWriter sets the event-stream headers and sends them to the client.
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop reverse proxies (nginx) from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sw := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	if err := sw.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+	return sw, nil
+}
+
+func (sw *sseWriter) Event(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(sw.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
+
+// Ping writes an SSE comment to keep idle connections from being closed.
+func (sw *sseWriter) Ping() error {
+	if _, err := fmt.Fprint(sw.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
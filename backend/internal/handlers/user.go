@@ -2,18 +2,29 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/gemini"
 	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/ratelimit"
 	"github.com/gemini-hackathon/app/internal/storage"
 )
 
 type UserHandlers struct {
-	db storage.DB
+	db     storage.DB
+	voices []gemini.Voice
+	config *config.Config
+	now    func() time.Time
 }
 
-func NewUserHandlers(db storage.DB) *UserHandlers {
-	return &UserHandlers{db: db}
+// NewUserHandlers creates the user handlers. voices are the TTS voices a user may pick as
+// their default.
+func NewUserHandlers(db storage.DB, voices []gemini.Voice, cfg *config.Config) *UserHandlers {
+	return &UserHandlers{db: db, voices: voices, config: cfg, now: time.Now}
 }
 
 type Language struct {
@@ -27,22 +38,45 @@ type GetLanguagesResponse struct {
 
 type GetUserProfileResponse struct {
 	PreferredLanguage string `json:"preferredLanguage"`
+	// PreferredVoice is empty when the user speaks with the provider's default voice.
+	PreferredVoice string `json:"preferredVoice"`
 }
 
+// UpdateUserPreferencesRequest changes the fields that are present. An empty preferredVoice
+// restores the default voice.
 type UpdateUserPreferencesRequest struct {
-	PreferredLanguage string `json:"preferredLanguage"`
+	PreferredLanguage string  `json:"preferredLanguage"`
+	PreferredVoice    *string `json:"preferredVoice"`
 }
 
 type UpdateUserPreferencesResponse struct {
 	PreferredLanguage string `json:"preferredLanguage"`
+	PreferredVoice    string `json:"preferredVoice"`
+}
+
+// UsageEntry is today's AI usage of one route class. Limit and Remaining are null when the
+// class has no daily quota.
+type UsageEntry struct {
+	Class     string `json:"class"`
+	Used      int    `json:"used"`
+	Limit     *int   `json:"limit"`
+	Remaining *int   `json:"remaining"`
 }
 
-var supportedLanguages = []Language{
-	{Caption: "ID", ImageURL: "https://flagcdn.com/w40/id.png"},
-	{Caption: "JP", ImageURL: "https://flagcdn.com/w40/jp.png"},
-	{Caption: "EN", ImageURL: "https://flagcdn.com/w40/gb.png"},
+type GetUsageResponse struct {
+	Date     string       `json:"date"`
+	ResetsAt time.Time    `json:"resetsAt"`
+	Usage    []UsageEntry `json:"usage"`
 }
 
+var supportedLanguages = func() []Language {
+	languages := make([]Language, len(models.Languages))
+	for i, l := range models.Languages {
+		languages[i] = Language{Caption: l.Code, ImageURL: l.FlagURL}
+	}
+	return languages
+}()
+
 func (h *UserHandlers) GetLanguagesAPI(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -81,6 +115,7 @@ func (h *UserHandlers) GetUserProfileAPI(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(GetUserProfileResponse{
 		PreferredLanguage: user.PreferredLanguage,
+		PreferredVoice:    user.PreferredVoice,
 	})
 }
 
@@ -102,19 +137,50 @@ func (h *UserHandlers) UpdateUserPreferencesAPI(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if !isValidLanguage(req.PreferredLanguage) {
+	if req.PreferredLanguage == "" && req.PreferredVoice == nil {
 		http.Error(w, "Invalid language", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.db.UpdateUserLanguage(r.Context(), userID, req.PreferredLanguage); err != nil {
-		http.Error(w, "Failed to update user language", http.StatusInternalServerError)
+	if req.PreferredLanguage != "" && !isValidLanguage(req.PreferredLanguage) {
+		http.Error(w, "Invalid language", http.StatusBadRequest)
+		return
+	}
+
+	var voice string
+	if req.PreferredVoice != nil && *req.PreferredVoice != "" {
+		v, ok := gemini.LookupVoice(h.voices, *req.PreferredVoice)
+		if !ok {
+			http.Error(w, "Unknown voice", http.StatusBadRequest)
+			return
+		}
+		voice = v.Name
+	}
+
+	if req.PreferredLanguage != "" {
+		if err := h.db.UpdateUserLanguage(r.Context(), userID, req.PreferredLanguage); err != nil {
+			http.Error(w, "Failed to update user language", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.PreferredVoice != nil {
+		if err := h.db.UpdateUserVoice(r.Context(), userID, voice); err != nil {
+			http.Error(w, "Failed to update user voice", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), userID)
+	if err != nil || user == nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(UpdateUserPreferencesResponse{
-		PreferredLanguage: req.PreferredLanguage,
+		PreferredLanguage: user.PreferredLanguage,
+		PreferredVoice:    user.PreferredVoice,
 	})
 }
 
@@ -129,11 +195,49 @@ func (h *UserHandlers) UsersMeAPI(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func isValidLanguage(lang string) bool {
-	for _, l := range supportedLanguages {
-		if l.Caption == lang {
-			return true
+// GetUsageAPI handles GET /v1/users/me/usage: the user's AI calls today (UTC) against the
+// daily quota of each route class.
+func (h *UserHandlers) GetUsageAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	day := ratelimit.Day(h.now())
+	used, err := h.db.GetUsage(r.Context(), userID, day)
+	if err != nil {
+		log.Printf("Failed to get usage: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	quotas := ratelimit.DailyQuotas(h.config)
+	response := GetUsageResponse{
+		Date:     day.Format(time.DateOnly),
+		ResetsAt: day.Add(24 * time.Hour),
+		Usage:    make([]UsageEntry, 0, len(ratelimit.Classes)),
+	}
+	for _, class := range ratelimit.Classes {
+		entry := UsageEntry{Class: string(class), Used: used[string(class)]}
+		if quota := quotas[class]; quota > 0 {
+			remaining := max(quota-entry.Used, 0)
+			entry.Limit = &quota
+			entry.Remaining = &remaining
 		}
+		response.Usage = append(response.Usage, entry)
 	}
-	return false
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func isValidLanguage(lang string) bool {
+	_, ok := models.LookupLanguage(lang)
+	return ok
 }
@@ -0,0 +1,55 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/handlers"
+	"github.com/gemini-hackathon/app/internal/middleware"
+	"github.com/gemini-hackathon/app/internal/ratelimit"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestGetUsageAPI(t *testing.T) {
+	mockDB := testutil.NewMockDB()
+	today := ratelimit.Day(time.Now())
+	mockDB.IncrementUsage(context.Background(), 1, today, "ocr", 3, 0)
+	mockDB.IncrementUsage(context.Background(), 2, today, "ocr", 7, 0)
+
+	h := handlers.NewUserHandlers(mockDB, nil, &config.Config{DailyQuotaOCR: 10, DailyQuotaAnalyze: 50})
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/me/usage", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+	h.GetUsageAPI(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp handlers.GetUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Date != today.Format(time.DateOnly) || !resp.ResetsAt.Equal(today.Add(24*time.Hour)) {
+		t.Fatalf("unexpected date window: %s %s", resp.Date, resp.ResetsAt)
+	}
+	if len(resp.Usage) != 3 {
+		t.Fatalf("expected every class, got %+v", resp.Usage)
+	}
+
+	ocr := resp.Usage[0]
+	if ocr.Class != "ocr" || ocr.Used != 3 || *ocr.Limit != 10 || *ocr.Remaining != 7 {
+		t.Errorf("unexpected ocr usage: %+v", ocr)
+	}
+	if analyze := resp.Usage[1]; analyze.Used != 0 || *analyze.Remaining != 50 {
+		t.Errorf("unexpected analyze usage: %+v", analyze)
+	}
+	if speech := resp.Usage[2]; speech.Limit != nil || speech.Remaining != nil {
+		t.Errorf("expected speech to be unlimited, got %+v", speech)
+	}
+}
@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const (
+	DefaultMaxAttempts = 5
+
+	defaultWorkerCount  = 2
+	defaultPollInterval = 2 * time.Second
+	jobTimeout          = 3 * time.Minute
+	// lockLease is how long a job may stay running before it is considered
+	// abandoned by a dead worker and handed out again.
+	lockLease   = 10 * time.Minute
+	baseBackoff = 10 * time.Second
+	maxBackoff  = 10 * time.Minute
+)
+
+// OCRHandler processes a single claimed OCR job. A returned error fails the run
+// and the pool decides whether to retry it.
+type OCRHandler func(ctx context.Context, job *models.OCRJob) error
+
+// OCRWorkerPool polls the ocr_jobs table and runs due jobs on a fixed number of workers.
+type OCRWorkerPool struct {
+	db           storage.DB
+	handle       OCRHandler
+	workers      int
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+func NewOCRWorkerPool(db storage.DB, handle OCRHandler, cfg *config.Config) *OCRWorkerPool {
+	workers := cfg.OCRWorkerCount
+	if workers < 1 {
+		workers = defaultWorkerCount
+	}
+	pollInterval := time.Duration(cfg.OCRJobPollIntervalSeconds) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	return &OCRWorkerPool{
+		db:           db,
+		handle:       handle,
+		workers:      workers,
+		pollInterval: pollInterval,
+		now:          time.Now,
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled and every in-flight job has returned.
+func (p *OCRWorkerPool) Run(ctx context.Context) {
+	log := logger.GetDefaultLogger().WithField("component", "ocr_worker_pool")
+	log.Infof("Starting OCR worker pool: workers=%d, poll_interval=%v", p.workers, p.pollInterval)
+
+	p.requeueStale(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			p.work(ctx, workerID)
+		}(i + 1)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(lockLease / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.requeueStale(ctx)
+			}
+		}
+	}()
+
+	wg.Wait()
+	log.Infof("OCR worker pool stopped")
+}
+
+func (p *OCRWorkerPool) work(ctx context.Context, workerID int) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if p.processNext(ctx, workerID) {
+			continue
+		}
+
+		t := time.NewTimer(p.pollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// processNext claims and runs at most one job. It reports whether a job was claimed,
+// so the worker can immediately look for more work instead of sleeping.
+func (p *OCRWorkerPool) processNext(ctx context.Context, workerID int) bool {
+	log := logger.GetDefaultLogger().WithField("worker_id", workerID)
+
+	job, err := p.db.ClaimOCRJob(ctx, p.now())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.ErrorWithErr(err, "Failed to claim OCR job")
+		}
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	log = log.WithFields(map[string]any{
+		"job_id":   job.ID,
+		"scan_id":  job.ScanID,
+		"attempts": job.Attempts,
+	})
+	log.Infof("Claimed OCR job")
+
+	// A running job isn't cancelled when the pool stops, so shutdown drains it instead of
+	// spending one of its attempts.
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobTimeout)
+	handleErr := p.handle(jobCtx, job)
+	cancel()
+
+	// Bookkeeping uses a fresh context so a shutdown mid-job still records the outcome.
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+
+	if handleErr == nil {
+		if err := p.db.CompleteOCRJob(saveCtx, job.ID); err != nil {
+			log.ErrorWithErr(err, "Failed to mark OCR job as succeeded")
+		}
+		return true
+	}
+
+	if job.IsFinalAttempt() {
+		log.ErrorWithErr(handleErr, "OCR job failed permanently")
+		if err := p.db.FailOCRJob(saveCtx, job.ID, handleErr.Error()); err != nil {
+			log.ErrorWithErr(err, "Failed to mark OCR job as failed")
+		}
+		return true
+	}
+
+	nextRunAt := p.now().Add(retryBackoff(job.Attempts))
+	log.Warnf("OCR job failed, retrying at %s: %v", nextRunAt.Format(time.RFC3339), handleErr)
+	if err := p.db.RetryOCRJob(saveCtx, job.ID, handleErr.Error(), nextRunAt); err != nil {
+		log.ErrorWithErr(err, "Failed to reschedule OCR job")
+	}
+	return true
+}
+
+func (p *OCRWorkerPool) requeueStale(ctx context.Context) {
+	count, err := p.db.RequeueStaleOCRJobs(ctx, p.now().Add(-lockLease))
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.GetDefaultLogger().ErrorWithErr(err, "Failed to requeue stale OCR jobs")
+		}
+		return
+	}
+	if count > 0 {
+		logger.GetDefaultLogger().Warnf("Requeued %d stale OCR jobs", count)
+	}
+}
+
+// retryBackoff doubles the delay for every attempt already made, capped at maxBackoff,
+// with a little jitter so jobs that failed together don't retry together.
+func retryBackoff(attempts int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff / 5)))
+	return backoff + jitter
+}
@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func newTestPool(t *testing.T, db *testutil.MockDB, handle OCRHandler, now time.Time) *OCRWorkerPool {
+	t.Helper()
+	pool := NewOCRWorkerPool(db, handle, &config.Config{})
+	pool.now = func() time.Time { return now }
+	return pool
+}
+
+func enqueueJob(t *testing.T, db *testutil.MockDB, scanID int64, maxAttempts int, at time.Time) *models.OCRJob {
+	t.Helper()
+	job := &models.OCRJob{
+		ScanID:      scanID,
+		ImagePath:   "data/uploads/1.jpg",
+		MimeType:    "image/jpeg",
+		MaxAttempts: maxAttempts,
+		NextRunAt:   at,
+		CreatedAt:   at,
+	}
+	if _, err := db.CreateOCRJob(context.Background(), job); err != nil {
+		t.Fatalf("CreateOCRJob failed: %v", err)
+	}
+	return job
+}
+
+func TestProcessNext_CompletesJob(t *testing.T) {
+	db := testutil.NewMockDB()
+	now := time.Now()
+	enqueueJob(t, db, 7, 3, now)
+
+	var handledScanID int64
+	pool := newTestPool(t, db, func(ctx context.Context, job *models.OCRJob) error {
+		handledScanID = job.ScanID
+		return nil
+	}, now)
+
+	if !pool.processNext(context.Background(), 1) {
+		t.Fatal("expected a job to be processed")
+	}
+	if handledScanID != 7 {
+		t.Fatalf("expected handler to run for scan 7, got %d", handledScanID)
+	}
+	if got := db.OCRJobs()[0].Status; got != models.OCRJobStatusSucceeded {
+		t.Fatalf("expected job status %q, got %q", models.OCRJobStatusSucceeded, got)
+	}
+	if pool.processNext(context.Background(), 1) {
+		t.Fatal("expected no more jobs to process")
+	}
+}
+
+func TestProcessNext_RetriesWithBackoff(t *testing.T) {
+	db := testutil.NewMockDB()
+	now := time.Now()
+	enqueueJob(t, db, 1, 3, now)
+
+	pool := newTestPool(t, db, func(ctx context.Context, job *models.OCRJob) error {
+		return errors.New("gemini unavailable")
+	}, now)
+
+	if !pool.processNext(context.Background(), 1) {
+		t.Fatal("expected a job to be processed")
+	}
+
+	job := db.OCRJobs()[0]
+	if job.Status != models.OCRJobStatusQueued {
+		t.Fatalf("expected job to be requeued, got %q", job.Status)
+	}
+	if job.LastError == nil || *job.LastError != "gemini unavailable" {
+		t.Fatalf("expected last error to be recorded, got %v", job.LastError)
+	}
+	if !job.NextRunAt.After(now) {
+		t.Fatalf("expected next run to be delayed, got %v", job.NextRunAt)
+	}
+	if pool.processNext(context.Background(), 1) {
+		t.Fatal("expected retried job not to be due yet")
+	}
+}
+
+func TestProcessNext_FailsAfterMaxAttempts(t *testing.T) {
+	db := testutil.NewMockDB()
+	now := time.Now()
+	enqueueJob(t, db, 1, 2, now)
+
+	calls := 0
+	pool := newTestPool(t, db, func(ctx context.Context, job *models.OCRJob) error {
+		calls++
+		return errors.New("bad image")
+	}, now)
+
+	pool.processNext(context.Background(), 1)
+	pool.now = func() time.Time { return now.Add(time.Hour) }
+	pool.processNext(context.Background(), 1)
+
+	job := db.OCRJobs()[0]
+	if calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls)
+	}
+	if job.Status != models.OCRJobStatusFailed {
+		t.Fatalf("expected job status %q, got %q", models.OCRJobStatusFailed, job.Status)
+	}
+}
+
+func TestRequeueStale(t *testing.T) {
+	db := testutil.NewMockDB()
+	now := time.Now()
+	enqueueJob(t, db, 1, 3, now.Add(-2*time.Hour))
+
+	// Simulate a worker that claimed the job an hour ago and then died.
+	if job, err := db.ClaimOCRJob(context.Background(), now.Add(-time.Hour)); err != nil || job == nil {
+		t.Fatalf("ClaimOCRJob failed: job=%v err=%v", job, err)
+	}
+
+	pool := newTestPool(t, db, nil, now)
+	pool.requeueStale(context.Background())
+
+	if got := db.OCRJobs()[0].Status; got != models.OCRJobStatusQueued {
+		t.Fatalf("expected stale job to be requeued, got %q", got)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	if got := retryBackoff(1); got < baseBackoff || got > baseBackoff+baseBackoff/5 {
+		t.Fatalf("unexpected first backoff: %v", got)
+	}
+	if got := retryBackoff(50); got < maxBackoff || got > maxBackoff+maxBackoff/5 {
+		t.Fatalf("expected backoff to be capped, got %v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package knowledge
+
+import "sort"
+
+// automaton is an Aho–Corasick matcher over the bytes of a fixed set of terms. It reports
+// every occurrence of every term in one pass over the text. Terms and text are UTF-8, which
+// is self-synchronizing, so byte matches of whole terms always fall on rune boundaries.
+//
+// Edges are stored flat: the children of node n are edgeKey/edgeTo[edgeStart[n]:edgeStart[n+1]],
+// sorted by byte. The root keeps a dense table since almost every text byte starts there.
+type automaton struct {
+	root      [256]int32
+	edgeStart []int32
+	edgeKey   []byte
+	edgeTo    []int32
+	fail      []int32
+	// term is the term ending at a node, or -1; dict is the nearest node on the fail chain
+	// that ends a term, or -1.
+	term  []int32
+	dict  []int32
+	depth []int32
+}
+
+// newAutomaton builds a matcher for terms. Term IDs are indices into terms; empty terms are
+// never reported.
+func newAutomaton(terms []string) *automaton {
+	// Build the trie with maps first, then flatten it.
+	children := []map[byte]int32{{}}
+	term := []int32{-1}
+	depth := []int32{0}
+	for id, t := range terms {
+		n := int32(0)
+		for i := 0; i < len(t); i++ {
+			next, ok := children[n][t[i]]
+			if !ok {
+				next = int32(len(children))
+				children = append(children, map[byte]int32{})
+				term = append(term, -1)
+				depth = append(depth, depth[n]+1)
+				children[n][t[i]] = next
+			}
+			n = next
+		}
+		if n != 0 {
+			term[n] = int32(id)
+		}
+	}
+
+	a := &automaton{
+		edgeStart: make([]int32, len(children)+1),
+		fail:      make([]int32, len(children)),
+		term:      term,
+		dict:      make([]int32, len(children)),
+		depth:     depth,
+	}
+	for n, edges := range children {
+		a.edgeStart[n+1] = a.edgeStart[n] + int32(len(edges))
+		keys := make([]int, 0, len(edges))
+		for b := range edges {
+			keys = append(keys, int(b))
+		}
+		sort.Ints(keys)
+		for _, b := range keys {
+			a.edgeKey = append(a.edgeKey, byte(b))
+			a.edgeTo = append(a.edgeTo, edges[byte(b)])
+		}
+	}
+	for b := range a.root {
+		a.root[b] = a.child(0, byte(b))
+	}
+
+	// Breadth-first, so a node's fail target is always finished before the node.
+	a.dict[0] = -1
+	queue := make([]int32, 0, len(children))
+	for e := a.edgeStart[0]; e < a.edgeStart[1]; e++ {
+		queue = append(queue, a.edgeTo[e])
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		if f := a.fail[n]; a.term[f] >= 0 {
+			a.dict[n] = f
+		} else {
+			a.dict[n] = a.dict[f]
+		}
+
+		for e := a.edgeStart[n]; e < a.edgeStart[n+1]; e++ {
+			b, next := a.edgeKey[e], a.edgeTo[e]
+			if n != 0 {
+				a.fail[next] = a.step(a.fail[n], b)
+			}
+			queue = append(queue, next)
+		}
+	}
+	return a
+}
+
+// child returns the trie child of n for b, or -1.
+func (a *automaton) child(n int32, b byte) int32 {
+	lo, hi := a.edgeStart[n], a.edgeStart[n+1]
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		switch k := a.edgeKey[mid]; {
+		case k == b:
+			return a.edgeTo[mid]
+		case k < b:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return -1
+}
+
+// step follows b from n, falling back along fail links as needed.
+func (a *automaton) step(n int32, b byte) int32 {
+	for n != 0 {
+		if next := a.child(n, b); next >= 0 {
+			return next
+		}
+		n = a.fail[n]
+	}
+	if next := a.root[b]; next >= 0 {
+		return next
+	}
+	return 0
+}
+
+// match calls fn for every occurrence of a term in text, with the byte offset where the
+// occurrence starts. Occurrences are reported in order of their end, longest first.
+func (a *automaton) match(text string, fn func(term int32, start int)) {
+	n := int32(0)
+	for i := 0; i < len(text); i++ {
+		n = a.step(n, text[i])
+		for m := n; m >= 0; m = a.dict[m] {
+			if t := a.term[m]; t >= 0 {
+				fn(t, i+1-int(a.depth[m]))
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package knowledge
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -10,8 +11,7 @@ import (
 
 // csvService implements Service by loading vocabulary from a CSV file.
 type csvService struct {
-	entries []Entry
-	index   map[string]*Entry // index by Kosakata for exact match
+	*index
 }
 
 // NewService creates a new knowledge service from a CSV file.
@@ -24,7 +24,18 @@ func NewService(csvPath string) (Service, error) {
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	entries, err := ReadCSV(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvService{index: newIndex(entries)}, nil
+}
+
+// ReadCSV parses knowledge entries in the CSV layout NewService expects. Rows without a
+// Kosakata are skipped.
+func ReadCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
 
 	// Read header row
 	header, err := reader.Read()
@@ -35,7 +46,7 @@ func NewService(csvPath string) (Service, error) {
 	// Map column names to indices
 	colIndex := make(map[string]int)
 	for i, col := range header {
-		colIndex[strings.TrimSpace(col)] = i
+		colIndex[strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))] = i
 	}
 
 	// Read all rows
@@ -45,8 +56,6 @@ func NewService(csvPath string) (Service, error) {
 	}
 
 	entries := make([]Entry, 0, len(records))
-	index := make(map[string]*Entry)
-
 	for _, row := range records {
 		entry := Entry{
 			Kosakata:        getColumn(row, colIndex, "Kosakata"),
@@ -65,60 +74,15 @@ func NewService(csvPath string) (Service, error) {
 		}
 
 		entries = append(entries, entry)
-		// Add to index (pointer to the last element)
-		index[entry.Kosakata] = &entries[len(entries)-1]
 	}
 
-	return &csvService{
-		entries: entries,
-		index:   index,
-	}, nil
+	return entries, nil
 }
 
 // NewEmptyService creates an empty knowledge service (no-op).
 // This is useful when no CSV file is configured.
 func NewEmptyService() Service {
-	return &csvService{
-		entries: []Entry{},
-		index:   make(map[string]*Entry),
-	}
-}
-
-// Lookup finds entries matching the text.
-// It first tries exact match on Kosakata, then substring match.
-func (s *csvService) Lookup(text string) []Entry {
-	if text == "" {
-		return nil
-	}
-
-	var results []Entry
-
-	// 1. Try exact match on Kosakata
-	if entry, ok := s.index[text]; ok {
-		results = append(results, *entry)
-	}
-
-	// 2. Try substring match: text contains Kosakata, or Kosakata contains text
-	for i := range s.entries {
-		entry := &s.entries[i]
-		// Skip if already found via exact match
-		if entry.Kosakata == text {
-			continue
-		}
-
-		// Check if the selected text contains this vocabulary term
-		if strings.Contains(text, entry.Kosakata) {
-			results = append(results, *entry)
-			continue
-		}
-
-		// Check if the vocabulary term contains the selected text
-		if strings.Contains(entry.Kosakata, text) {
-			results = append(results, *entry)
-		}
-	}
-
-	return results
+	return &csvService{index: newIndex(nil)}
 }
 
 // getColumn safely retrieves a column value from a row.
@@ -0,0 +1,161 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// Store is the storage a DBService reads the knowledge base from.
+type Store interface {
+	ListKnowledgeEntries(ctx context.Context) ([]*models.KnowledgeEntry, error)
+	// KnowledgeEntriesVersion grows whenever the stored entries change.
+	KnowledgeEntriesVersion(ctx context.Context) (int64, error)
+}
+
+// DBService implements Service over the knowledge_entries table. Lookups are answered from
+// an in-memory index that Reload replaces; lookups already running finish on the old one.
+type DBService struct {
+	store   Store
+	current atomic.Pointer[index]
+	reloads chan struct{}
+
+	mu      sync.Mutex // serializes reloads
+	version int64
+}
+
+// NewDBService loads the knowledge base from store.
+func NewDBService(ctx context.Context, store Store) (*DBService, error) {
+	s := &DBService{store: store, reloads: make(chan struct{}, 1)}
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup finds entries matching the text (exact or substring), best match first.
+func (s *DBService) Lookup(text string) []Entry {
+	return s.current.Load().Lookup(text)
+}
+
+// LookupInContext finds entries for text and then for terms only in context, at most limit.
+func (s *DBService) LookupInContext(text, context string, limit int) []Entry {
+	return s.current.Load().LookupInContext(text, context, limit)
+}
+
+// Len returns the number of entries in the current index.
+func (s *DBService) Len() int {
+	return len(s.current.Load().entries)
+}
+
+// Reload reads the knowledge base again and swaps in a new index.
+func (s *DBService) Reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Read the version first: a change made while listing then triggers another reload.
+	version, err := s.store.KnowledgeEntriesVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read knowledge base version: %w", err)
+	}
+	stored, err := s.store.ListKnowledgeEntries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read knowledge base: %w", err)
+	}
+
+	entries := make([]Entry, len(stored))
+	for i, entry := range stored {
+		entries[i] = entryFromModel(entry)
+	}
+	s.current.Store(newIndex(entries))
+	s.version = version
+	return nil
+}
+
+// RequestReload asks Watch to check for changes now rather than at its next tick. It does not
+// wait for the reload, and requests made while one is pending are merged into it.
+func (s *DBService) RequestReload() {
+	select {
+	case s.reloads <- struct{}{}:
+	default:
+	}
+}
+
+// Watch reloads the index whenever the stored version changes, checking every interval and
+// on RequestReload until ctx is cancelled. It picks up edits made through other replicas.
+func (s *DBService) Watch(ctx context.Context, interval time.Duration) {
+	log := logger.GetDefaultLogger().WithField("component", "knowledge_watcher")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.reloads:
+		}
+
+		version, err := s.store.KnowledgeEntriesVersion(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.ErrorWithErr(err, "Failed to read knowledge base version")
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		stale := version != s.version
+		s.mu.Unlock()
+		if !stale {
+			continue
+		}
+
+		if err := s.Reload(ctx); err != nil {
+			log.ErrorWithErr(err, "Failed to reload knowledge base")
+			continue
+		}
+		log.Infof("Reloaded knowledge base: entries=%d", s.Len())
+	}
+}
+
+// ToModels converts entries read by ReadCSV to their stored form.
+func ToModels(entries []Entry) []*models.KnowledgeEntry {
+	stored := make([]*models.KnowledgeEntry, len(entries))
+	for i, entry := range entries {
+		stored[i] = entry.Model()
+	}
+	return stored
+}
+
+// Model converts the entry to its stored form.
+func (e Entry) Model() *models.KnowledgeEntry {
+	return &models.KnowledgeEntry{
+		Kosakata:        e.Kosakata,
+		Kana:            e.Kana,
+		Arti:            e.Arti,
+		CaraBaca:        e.CaraBaca,
+		Deskripsi:       e.Deskripsi,
+		BidangPekerjaan: e.BidangPekerjaan,
+		Industri:        e.Industri,
+		Konteks:         e.Konteks,
+	}
+}
+
+func entryFromModel(entry *models.KnowledgeEntry) Entry {
+	return Entry{
+		Kosakata:        entry.Kosakata,
+		Kana:            entry.Kana,
+		Arti:            entry.Arti,
+		CaraBaca:        entry.CaraBaca,
+		Deskripsi:       entry.Deskripsi,
+		BidangPekerjaan: entry.BidangPekerjaan,
+		Industri:        entry.Industri,
+		Konteks:         entry.Konteks,
+	}
+}
@@ -0,0 +1,81 @@
+package knowledge
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestDBServiceReload(t *testing.T) {
+	ctx := context.Background()
+	store := testutil.NewMockDB()
+
+	csvData := "\ufeffKosakata,Kana,Arti (EN / ID),Cara Baca,Deskripsi,Bidang Pekerjaan,Industri,Konteks\n" +
+		"稟議,りんぎ,Approval,ringi,Desc,\"Bisnis (https://notion.so/x), Keuangan\",,Kantor\n" +
+		",,skipped,,,,,\n"
+	entries, err := ReadCSV(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].BidangPekerjaan[0] != "Bisnis" || len(entries[0].BidangPekerjaan) != 2 {
+		t.Fatalf("expected one entry with links stripped, got %+v", entries)
+	}
+	if _, err := store.UpsertKnowledgeEntries(ctx, ToModels(entries), time.Now()); err != nil {
+		t.Fatalf("UpsertKnowledgeEntries failed: %v", err)
+	}
+
+	svc, err := NewDBService(ctx, store)
+	if err != nil {
+		t.Fatalf("NewDBService failed: %v", err)
+	}
+	if got := svc.Lookup("稟議書"); len(got) != 1 || got[0].Arti != "Approval" {
+		t.Fatalf("expected the imported entry, got %+v", got)
+	}
+
+	// Changes are invisible until a reload swaps the index.
+	stored, _ := store.ListKnowledgeEntries(ctx)
+	fixed := *stored[0]
+	fixed.Arti = "Approval request"
+	fixed.UpdatedAt = time.Now().Add(time.Second)
+	store.UpdateKnowledgeEntry(ctx, &fixed)
+	if got := svc.Lookup("稟議"); got[0].Arti != "Approval" {
+		t.Fatalf("expected the old index before reload, got %+v", got)
+	}
+
+	if err := svc.Reload(ctx); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if got := svc.Lookup("稟議"); len(got) != 1 || got[0].Arti != "Approval request" {
+		t.Errorf("expected the edited entry after reload, got %+v", got)
+	}
+	if svc.Len() != 1 {
+		t.Errorf("expected 1 entry, got %d", svc.Len())
+	}
+}
+
+func TestDBServiceWatchReloadsOnRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := testutil.NewMockDB()
+
+	svc, err := NewDBService(ctx, store)
+	if err != nil {
+		t.Fatalf("NewDBService failed: %v", err)
+	}
+	store.UpsertKnowledgeEntries(ctx, ToModels([]Entry{{Kosakata: "稟議", Arti: "Approval"}}), time.Now())
+
+	// The interval is far off, so only the request can trigger the reload.
+	go svc.Watch(ctx, time.Hour)
+	svc.RequestReload()
+
+	deadline := time.Now().Add(time.Second)
+	for svc.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the requested reload to load the new entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,193 @@
+package knowledge
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// How a term was found, best first.
+const (
+	matchExact      = iota // the term is the selected text
+	matchInText            // the selected text contains the term
+	matchContaining        // the term contains the selected text
+	matchInContext         // only the surrounding context contains the term
+)
+
+// index answers lookups over a fixed set of entries. It is never modified after it is built,
+// so services can swap in a new one while lookups are running.
+//
+// Entries are grouped by Kosakata into terms. An Aho–Corasick automaton finds every term
+// inside a text in one pass; the reverse direction, terms containing a short selection, is
+// answered from a posting list of the terms each rune occurs in.
+type index struct {
+	entries []Entry
+
+	terms       []string
+	termRunes   []int
+	termEntries [][]int32
+	byTerm      map[string]int32
+	byRune      map[rune][]int32
+	maxTermLen  int
+	matcher     *automaton
+}
+
+func newIndex(entries []Entry) *index {
+	ix := &index{
+		byTerm: make(map[string]int32),
+		byRune: make(map[rune][]int32),
+	}
+
+	// Drop repeated rows; rows that share a term but differ stay as separate entries.
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if id := entry.ID(); !seen[id] {
+			seen[id] = true
+			ix.entries = append(ix.entries, entry)
+		}
+	}
+
+	for i, entry := range ix.entries {
+		id, ok := ix.byTerm[entry.Kosakata]
+		if !ok {
+			id = int32(len(ix.terms))
+			ix.byTerm[entry.Kosakata] = id
+			ix.terms = append(ix.terms, entry.Kosakata)
+			ix.termRunes = append(ix.termRunes, utf8.RuneCountInString(entry.Kosakata))
+			ix.termEntries = append(ix.termEntries, nil)
+			ix.maxTermLen = max(ix.maxTermLen, len(entry.Kosakata))
+
+			var last rune = -1
+			for _, r := range sortedRunes(entry.Kosakata) {
+				if r != last {
+					ix.byRune[r] = append(ix.byRune[r], id)
+					last = r
+				}
+			}
+		}
+		ix.termEntries[id] = append(ix.termEntries[id], int32(i))
+	}
+
+	ix.matcher = newAutomaton(ix.terms)
+	return ix
+}
+
+// Lookup finds entries matching the text: the entry for the text itself, then entries for
+// terms inside the text, longest first, then entries for terms containing the text,
+// shortest first.
+func (ix *index) Lookup(text string) []Entry {
+	if text == "" {
+		return nil
+	}
+	return ix.lookup(text, "", 0)
+}
+
+// LookupInContext ranks the entries Lookup finds for text ahead of entries for terms that
+// occur only in context, longest first. At most limit entries are returned; a limit of zero
+// or less returns them all.
+func (ix *index) LookupInContext(text, context string, limit int) []Entry {
+	if text == "" && context == "" {
+		return nil
+	}
+	return ix.lookup(text, context, limit)
+}
+
+type termHit struct {
+	term  int32
+	kind  int
+	start int
+}
+
+func (ix *index) lookup(text, context string, limit int) []Entry {
+	hits := make(map[int32]termHit)
+	add := func(term int32, kind, start int) {
+		if hit, ok := hits[term]; ok && (hit.kind < kind || (hit.kind == kind && hit.start <= start)) {
+			return
+		}
+		hits[term] = termHit{term: term, kind: kind, start: start}
+	}
+
+	if text != "" {
+		if term, ok := ix.byTerm[text]; ok {
+			add(term, matchExact, 0)
+		}
+		ix.matcher.match(text, func(term int32, start int) {
+			add(term, matchInText, start)
+		})
+		ix.containing(text, func(term int32) {
+			add(term, matchContaining, 0)
+		})
+	}
+	if context != "" {
+		ix.matcher.match(context, func(term int32, start int) {
+			add(term, matchInContext, start)
+		})
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	ranked := make([]termHit, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, hit)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if la, lb := ix.termRunes[a.term], ix.termRunes[b.term]; la != lb {
+			// A term containing the selection is closer the shorter it is.
+			if a.kind == matchContaining {
+				return la < lb
+			}
+			return la > lb
+		}
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		return a.term < b.term
+	})
+
+	var results []Entry
+	for _, hit := range ranked {
+		for _, i := range ix.termEntries[hit.term] {
+			if limit > 0 && len(results) == limit {
+				return results
+			}
+			results = append(results, ix.entries[i])
+		}
+	}
+	return results
+}
+
+// containing calls fn for every term longer than text that contains it.
+func (ix *index) containing(text string, fn func(term int32)) {
+	if len(text) >= ix.maxTermLen {
+		return
+	}
+
+	// Only terms holding the selection's rarest rune can contain it.
+	var candidates []int32
+	for i, r := range sortedRunes(text) {
+		posting, ok := ix.byRune[r]
+		if !ok {
+			return
+		}
+		if i == 0 || len(posting) < len(candidates) {
+			candidates = posting
+		}
+	}
+
+	for _, term := range candidates {
+		if t := ix.terms[term]; len(t) > len(text) && strings.Contains(t, text) {
+			fn(term)
+		}
+	}
+}
+
+func sortedRunes(s string) []rune {
+	runes := []rune(s)
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
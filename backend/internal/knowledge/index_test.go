@@ -0,0 +1,150 @@
+package knowledge
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// naiveLookup is the linear scan the index replaced; the index must find the same terms.
+func naiveLookup(entries []Entry, text string) []string {
+	var terms []string
+	for _, entry := range entries {
+		if strings.Contains(text, entry.Kosakata) || strings.Contains(entry.Kosakata, text) {
+			terms = append(terms, entry.Kosakata)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func kosakata(entries []Entry) []string {
+	terms := make([]string, len(entries))
+	for i, entry := range entries {
+		terms[i] = entry.Kosakata
+	}
+	return terms
+}
+
+func TestLookupRanking(t *testing.T) {
+	ix := newIndex([]Entry{
+		{Kosakata: "請求", Arti: "claim"},
+		{Kosakata: "請求書", Arti: "invoice"},
+		{Kosakata: "請求書", Arti: "invoice"}, // repeated row
+		{Kosakata: "請求書", Arti: "bill"},
+		{Kosakata: "書", Arti: "document"},
+		{Kosakata: "見積もり", Arti: "estimate"},
+		{Kosakata: "送付", Arti: "sending"},
+		{Kosakata: "未請求", Arti: "unbilled"},
+		{Kosakata: "請求書類", Arti: "billing papers"},
+	})
+
+	got := ix.Lookup("請求書")
+	want := []string{"請求書", "請求書", "請求", "書", "請求書類"}
+	if !reflect.DeepEqual(kosakata(got), want) {
+		t.Errorf("Lookup(請求書) = %v; want %v", kosakata(got), want)
+	}
+	if got[0].Arti != "invoice" || got[1].Arti != "bill" {
+		t.Errorf("expected entries of a term in load order without repeats, got %+v", got[:2])
+	}
+
+	got = ix.Lookup("請求")
+	// Terms of the same length keep load order.
+	want = []string{"請求", "請求書", "請求書", "未請求", "請求書類"}
+	if !reflect.DeepEqual(kosakata(got), want) {
+		t.Errorf("Lookup(請求) = %v; want %v", kosakata(got), want)
+	}
+
+	got = ix.LookupInContext("請求", "見積もりと請求書を送付します", 0)
+	want = []string{"請求", "請求書", "請求書", "未請求", "請求書類", "見積もり", "送付", "書"}
+	if !reflect.DeepEqual(kosakata(got), want) {
+		t.Errorf("LookupInContext = %v; want %v", kosakata(got), want)
+	}
+
+	got = ix.LookupInContext("請求", "見積もりと請求書を送付します", 3)
+	if want := []string{"請求", "請求書", "請求書"}; !reflect.DeepEqual(kosakata(got), want) {
+		t.Errorf("LookupInContext with limit 3 = %v; want %v", kosakata(got), want)
+	}
+
+	if got := ix.LookupInContext("", "", 5); got != nil {
+		t.Errorf("expected nil for empty text and context, got %v", got)
+	}
+}
+
+func TestLookupMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	entries := syntheticEntries(rng, 2000, 40)
+	ix := newIndex(entries)
+
+	for i := 0; i < 500; i++ {
+		text := syntheticText(rng, 40, 1+rng.Intn(12))
+		got := kosakata(ix.Lookup(text))
+		sort.Strings(got)
+		if want := naiveLookup(entries, text); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Lookup(%q) = %v; want %v", text, got, want)
+		}
+	}
+}
+
+// syntheticEntries makes n distinct terms of 1–4 runes drawn from an alphabet of the given
+// size, so terms overlap the way real vocabulary does.
+func syntheticEntries(rng *rand.Rand, n, alphabet int) []Entry {
+	seen := make(map[string]bool, n)
+	entries := make([]Entry, 0, n)
+	for len(entries) < n {
+		term := syntheticText(rng, alphabet, 1+rng.Intn(4))
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		entries = append(entries, Entry{Kosakata: term, Arti: fmt.Sprint(len(entries))})
+	}
+	return entries
+}
+
+func syntheticText(rng *rand.Rand, alphabet, length int) string {
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		sb.WriteRune(rune(0x4E00 + rng.Intn(alphabet)))
+	}
+	return sb.String()
+}
+
+func BenchmarkLookup(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	entries := syntheticEntries(rng, 50000, 3000)
+	ix := newIndex(entries)
+	selections := make([]string, 256)
+	contexts := make([]string, len(selections))
+	for i := range selections {
+		selections[i] = syntheticText(rng, 3000, 2+rng.Intn(4))
+		contexts[i] = syntheticText(rng, 3000, 80)
+	}
+
+	b.Run("Lookup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ix.Lookup(selections[i%len(selections)])
+		}
+	})
+	b.Run("LookupInContext", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ix.LookupInContext(selections[i%len(selections)], contexts[i%len(contexts)], MaxPromptEntries)
+		}
+	})
+	b.Run("LinearScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			naiveLookup(entries, selections[i%len(selections)])
+		}
+	})
+}
+
+func BenchmarkNewIndex(b *testing.B) {
+	entries := syntheticEntries(rand.New(rand.NewSource(1)), 50000, 3000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newIndex(entries)
+	}
+}
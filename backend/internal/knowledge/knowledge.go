@@ -1,5 +1,11 @@
 package knowledge
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
 // Entry represents a Japanese vocabulary term from the CSV knowledge base.
 type Entry struct {
 	Kosakata        string   // Japanese term (kanji) - primary lookup key
@@ -12,8 +18,25 @@ type Entry struct {
 	Konteks         string   // Additional context
 }
 
+// ID identifies the entry by its content, so editing a row of the knowledge base yields a new ID.
+func (e Entry) ID() string {
+	fields := []string{
+		e.Kosakata, e.Kana, e.Arti, e.CaraBaca, e.Deskripsi,
+		strings.Join(e.BidangPekerjaan, ","), strings.Join(e.Industri, ","), e.Konteks,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// MaxPromptEntries caps the reference entries put into an annotation prompt, so a long
+// context can't crowd out the text being explained.
+const MaxPromptEntries = 12
+
 // Service provides vocabulary lookup functionality.
 type Service interface {
-	// Lookup finds entries matching the text (exact or substring).
+	// Lookup finds entries matching the text (exact or substring), best match first.
 	Lookup(text string) []Entry
+	// LookupInContext also finds terms that occur only in context, ranked after the matches
+	// for text, and returns at most limit entries.
+	LookupInContext(text, context string, limit int) []Entry
 }
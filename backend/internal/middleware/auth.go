@@ -22,9 +22,24 @@ func NewAuthMiddleware(tokenService *auth.TokenService) *AuthMiddleware {
 	}
 }
 
+// Handle requires a token in the x-token or Authorization header.
 func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
+	return m.handle(next, false)
+}
+
+// HandleEventStream is Handle for Server-Sent Events routes. Browsers' EventSource cannot set
+// headers, so these also accept the token as a ?token= query parameter. Keep it off other
+// routes: tokens in URLs end up in access logs, browser history and Referer headers.
+func (m *AuthMiddleware) HandleEventStream(next http.Handler) http.Handler {
+	return m.handle(next, true)
+}
+
+func (m *AuthMiddleware) handle(next http.Handler, allowQueryToken bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
+		if token == "" && allowQueryToken {
+			token = r.URL.Query().Get("token")
+		}
 		if token == "" {
 			http.Error(w, "Unauthorized: missing token", http.StatusUnauthorized)
 			return
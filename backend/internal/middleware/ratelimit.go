@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/logger"
+	"github.com/gemini-hackathon/app/internal/ratelimit"
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+const usageKey contextKey = "usage"
+
+// QuotaError is returned by ChargeUsage when the user's daily quota can't cover the charge.
+type QuotaError struct {
+	Class      ratelimit.Class
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("daily %s quota exceeded", e.Class)
+}
+
+// usage is what one request has charged to the daily quota. Everything it charged is given
+// back when the request fails or is waived.
+type usage struct {
+	mu      sync.Mutex
+	charge  func(ctx context.Context, amount int) error
+	charged int
+	waived  bool
+}
+
+func (u *usage) add(ctx context.Context, amount int) error {
+	if err := u.charge(ctx, amount); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.charged += amount
+	u.mu.Unlock()
+	return nil
+}
+
+// refundable returns the amount to give back for a request that finished with status.
+func (u *usage) refundable(status int) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.waived || status >= http.StatusBadRequest {
+		return u.charged
+	}
+	return 0
+}
+
+// RateLimitMiddleware limits the AI routes of each user: a token bucket per route class
+// smooths bursts, and a daily quota caps spend. It runs after AuthMiddleware. Limiter and
+// database failures are logged and let the request through.
+//
+// Each request is charged one call up front, so an exhausted quota is rejected before any
+// work is done. The charge is refunded when the handler fails with a 4xx or 5xx status, or
+// waives it with WaiveUsage because no model call was made.
+type RateLimitMiddleware struct {
+	limiter ratelimit.Limiter
+	db      storage.DB
+	limits  map[ratelimit.Class]ratelimit.Limit
+	quotas  map[ratelimit.Class]int
+	now     func() time.Time
+}
+
+func NewRateLimitMiddleware(limiter ratelimit.Limiter, db storage.DB, cfg *config.Config) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter: limiter,
+		db:      db,
+		limits:  ratelimit.Limits(cfg),
+		quotas:  ratelimit.DailyQuotas(cfg),
+		now:     time.Now,
+	}
+}
+
+// classifyRequest returns the class a request is billed to, or "" when it makes no AI call.
+func classifyRequest(r *http.Request) ratelimit.Class {
+	if r.Method != http.MethodPost {
+		return ""
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/v1/scans", path == "/v1/scans/batch":
+		return ratelimit.ClassOCR
+	case strings.HasPrefix(path, "/v1/scans/") && strings.HasSuffix(path, "/ocr"):
+		return ratelimit.ClassOCR
+	case path == "/v1/ai/analyze", path == "/v1/ai/analyze/stream":
+		return ratelimit.ClassAnalyze
+	case strings.HasPrefix(path, "/v1/annotations/") && strings.HasSuffix(path, "/regenerate"):
+		return ratelimit.ClassAnalyze
+	case path == "/v1/ai/speech":
+		return ratelimit.ClassSpeech
+	case strings.HasPrefix(path, "/v1/scans/") && strings.HasSuffix(path, "/audio"):
+		return ratelimit.ClassSpeech
+	}
+	return ""
+}
+
+func (m *RateLimitMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := classifyRequest(r)
+		userID := GetUserID(r.Context())
+		if class == "" || userID == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log := logger.GetDefaultLogger().WithRequestID(GetRequestID(r.Context())).WithUserID(userID).WithField("class", class)
+
+		decision, err := m.limiter.Allow(r.Context(), fmt.Sprintf("%s:%d", class, userID), m.limits[class])
+		if err != nil {
+			log.ErrorWithErr(err, "Rate limiter failed, allowing request")
+		} else if !decision.Allowed {
+			log.Warnf("Rate limit exceeded, retry after %s", decision.RetryAfter)
+			writeTooManyRequests(w, decision.RetryAfter, "rate_limited", "Too many requests. Please slow down.")
+			return
+		} else if decision.Remaining >= 0 {
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		}
+
+		u := &usage{charge: m.charger(class, userID, m.now())}
+		if err := u.add(r.Context(), 1); err != nil {
+			var quotaErr *QuotaError
+			if errors.As(err, &quotaErr) {
+				log.Warn("Daily quota exceeded")
+				writeTooManyRequests(w, quotaErr.RetryAfter, "quota_exceeded", "Daily AI quota reached. It resets at midnight UTC.")
+				return
+			}
+			log.ErrorWithErr(err, "Failed to record AI usage, allowing request")
+		}
+
+		ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), usageKey, u)))
+
+		if amount := u.refundable(ww.statusCode); amount > 0 {
+			if err := u.charge(context.WithoutCancel(r.Context()), -amount); err != nil {
+				log.ErrorWithErr(err, "Failed to refund AI usage")
+			}
+		}
+	})
+}
+
+// charger bills the user's quota for class on the day of now, so a refund lands on the day
+// that was charged. Negative amounts are refunds and are never limited.
+func (m *RateLimitMiddleware) charger(class ratelimit.Class, userID int64, now time.Time) func(ctx context.Context, amount int) error {
+	day := ratelimit.Day(now)
+	return func(ctx context.Context, amount int) error {
+		limit := m.quotas[class]
+		if amount < 0 {
+			limit = 0
+		}
+		if _, ok, err := m.db.IncrementUsage(ctx, userID, day, string(class), amount, limit); err != nil {
+			return err
+		} else if !ok {
+			return &QuotaError{Class: class, RetryAfter: day.Add(24 * time.Hour).Sub(now)}
+		}
+		return nil
+	}
+}
+
+// ChargeUsage bills amount extra calls to the daily quota of the current request's class,
+// for handlers that make several AI calls per request. It returns a *QuotaError when the
+// quota can't cover them, and nil for requests that aren't rate limited.
+func ChargeUsage(ctx context.Context, amount int) error {
+	u, ok := ctx.Value(usageKey).(*usage)
+	if !ok || amount <= 0 {
+		return nil
+	}
+	return u.add(ctx, amount)
+}
+
+// WaiveUsage marks the current request as free, for handlers that answered without calling
+// the model, such as from a cache. Everything charged for the request is refunded when it
+// finishes.
+func WaiveUsage(ctx context.Context) {
+	if u, ok := ctx.Value(usageKey).(*usage); ok {
+		u.mu.Lock()
+		u.waived = true
+		u.mu.Unlock()
+	}
+}
+
+// SetRetryAfter sets the Retry-After header in whole seconds, rounded up.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, code, message string) {
+	SetRetryAfter(w, retryAfter)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   code,
+		"message": message,
+	})
+}
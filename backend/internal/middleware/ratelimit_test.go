@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/ratelimit"
+	"github.com/gemini-hackathon/app/internal/testutil"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   ratelimit.Class
+	}{
+		{http.MethodPost, "/v1/scans", ratelimit.ClassOCR},
+		{http.MethodPost, "/v1/scans/batch", ratelimit.ClassOCR},
+		{http.MethodPost, "/v1/scans/12/ocr", ratelimit.ClassOCR},
+		{http.MethodGet, "/v1/scans/12/ocr", ""},
+		{http.MethodGet, "/v1/scans", ""},
+		{http.MethodPost, "/v1/ai/analyze", ratelimit.ClassAnalyze},
+		{http.MethodPost, "/v1/ai/analyze/stream", ratelimit.ClassAnalyze},
+		{http.MethodPost, "/v1/annotations/7/regenerate", ratelimit.ClassAnalyze},
+		{http.MethodPost, "/v1/ai/speech", ratelimit.ClassSpeech},
+		{http.MethodPost, "/v1/scans/12/audio", ratelimit.ClassSpeech},
+		{http.MethodPost, "/v1/annotations", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := classifyRequest(req); got != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
+
+func newRateLimitTest(cfg *config.Config, next http.Handler) (http.Handler, *RateLimitMiddleware) {
+	m := NewRateLimitMiddleware(ratelimit.NewMemoryLimiter(), testutil.NewMockDB(), cfg)
+	return m.Handle(next), m
+}
+
+func serve(h http.Handler, method, path string, userID int64) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req = req.WithContext(WithUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddleware_TokenBucket(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h, _ := newRateLimitTest(&config.Config{RateLimitAnalyzePerMinute: 2}, ok)
+
+	for i := 0; i < 2; i++ {
+		if rec := serve(h, http.MethodPost, "/v1/ai/analyze", 1); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+
+	rec := serve(h, http.MethodPost, "/v1/ai/analyze", 1)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected Retry-After 30, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	if rec := serve(h, http.MethodPost, "/v1/ai/analyze", 2); rec.Code != http.StatusOK {
+		t.Fatalf("expected other users to be unaffected, got %d", rec.Code)
+	}
+	if rec := serve(h, http.MethodPost, "/v1/ai/speech", 1); rec.Code != http.StatusOK {
+		t.Fatalf("expected other classes to be unaffected, got %d", rec.Code)
+	}
+	if rec := serve(h, http.MethodGet, "/v1/scans", 1); rec.Code != http.StatusOK {
+		t.Fatalf("expected unclassified routes to pass, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddleware_DailyQuota(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h, m := newRateLimitTest(&config.Config{DailyQuotaSpeech: 2}, ok)
+	m.now = func() time.Time { return time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC) }
+
+	for i := 0; i < 2; i++ {
+		if rec := serve(h, http.MethodPost, "/v1/ai/speech", 1); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+
+	rec := serve(h, http.MethodPost, "/v1/ai/speech", 1)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "3600" {
+		t.Fatalf("expected Retry-After until midnight UTC, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	m.now = func() time.Time { return time.Date(2026, 3, 2, 0, 0, 1, 0, time.UTC) }
+	if rec := serve(h, http.MethodPost, "/v1/ai/speech", 1); rec.Code != http.StatusOK {
+		t.Fatalf("expected quota to reset the next day, got %d", rec.Code)
+	}
+}
+
+func TestChargeUsage(t *testing.T) {
+	var chargeErr error
+	// The middleware bills one call per request; a 5-image batch bills the other four.
+	h, _ := newRateLimitTest(&config.Config{DailyQuotaOCR: 6}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chargeErr = ChargeUsage(r.Context(), 4)
+	}))
+
+	serve(h, http.MethodPost, "/v1/scans/batch", 1)
+	if chargeErr != nil {
+		t.Fatalf("expected a 5-image batch to fit the quota, got %v", chargeErr)
+	}
+
+	if rec := serve(h, http.MethodPost, "/v1/scans/batch", 1); rec.Code != http.StatusOK {
+		t.Fatalf("expected the sixth call to pass the middleware, got %d", rec.Code)
+	}
+	if _, ok := chargeErr.(*QuotaError); !ok {
+		t.Fatalf("expected QuotaError once the quota is used up, got %v", chargeErr)
+	}
+
+	if err := ChargeUsage(context.Background(), 3); err != nil {
+		t.Fatalf("expected no-op outside rate-limited requests, got %v", err)
+	}
+}
+
+func TestRateLimitMiddleware_Refunds(t *testing.T) {
+	status := http.StatusOK
+	waive := false
+	h, m := newRateLimitTest(&config.Config{DailyQuotaAnalyze: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if waive {
+			WaiveUsage(r.Context())
+		}
+		w.WriteHeader(status)
+	}))
+	m.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	// Failed and waived requests give their call back, so the single call stays available.
+	for _, tt := range []struct {
+		status int
+		waive  bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusBadGateway, false},
+		{http.StatusOK, true},
+	} {
+		status, waive = tt.status, tt.waive
+		if rec := serve(h, http.MethodPost, "/v1/ai/analyze", 1); rec.Code != tt.status {
+			t.Fatalf("expected %d, got %d", tt.status, rec.Code)
+		}
+	}
+
+	status, waive = http.StatusOK, false
+	if rec := serve(h, http.MethodPost, "/v1/ai/analyze", 1); rec.Code != http.StatusOK {
+		t.Fatalf("expected the quota to be intact after refunds, got %d", rec.Code)
+	}
+	if rec := serve(h, http.MethodPost, "/v1/ai/analyze", 1); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a billed call to use up the quota, got %d", rec.Code)
+	}
+}
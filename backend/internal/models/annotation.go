@@ -8,6 +8,9 @@ type NuanceData struct {
 	UsageTiming        string `json:"usageTiming"`
 	WordBreakdown      string `json:"wordBreakdown"`
 	AlternativeMeaning string `json:"alternativeMeaning"`
+	// Language is the code of the language the explanation is written in. Empty on
+	// annotations saved before explanations honored the user's language.
+	Language string `json:"language,omitempty"`
 }
 
 type Annotation struct {
@@ -18,5 +21,43 @@ type Annotation struct {
 	ContextText     *string
 	NuanceData      NuanceData
 	IsBookmarked    bool
-	CreatedAt       time.Time
+	// Notes are the user's own words about the annotation.
+	Notes *string
+	Tags  []string
+	// CurrentVersion is the AI explanation NuanceData is based on. Versions are numbered from 1.
+	CurrentVersion int
+	// AudioHash links the annotation to its synthesized clip, nil until it is first played.
+	AudioHash *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AnnotationVersion is one explanation the AI wrote for an annotation.
+type AnnotationVersion struct {
+	ID           int64
+	AnnotationID int64
+	Version      int
+	NuanceData   NuanceData
+	CreatedAt    time.Time
+}
+
+// AnnotationEdit is an annotation as it was before one of the user's edits. The oldest edit
+// holds the explanation as the AI wrote it.
+type AnnotationEdit struct {
+	ID           int64
+	AnnotationID int64
+	NuanceData   NuanceData
+	Notes        *string
+	Tags         []string
+	IsBookmarked bool
+	CreatedAt    time.Time
+}
+
+// NonNilTags returns tags, or an empty list when tags is nil, so a missing list is stored as
+// an empty array and encodes as [] rather than null.
+func NonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
 }
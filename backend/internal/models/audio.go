@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// AudioAsset is a synthesized speech clip stored through FileStorage, addressed by the hash
+// of everything that shaped it.
+type AudioAsset struct {
+	Hash      string
+	Path      string
+	MIMEType  string
+	SizeBytes int64
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Document groups scans into an ordered set of pages, e.g. a book chapter.
+type Document struct {
+	ID            int64
+	UserID        int64
+	Title         string
+	Author        *string
+	CoverImageURL *string
+	Language      *string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
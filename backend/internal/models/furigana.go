@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// FuriganaToken is a span of OCR text with its kana reading. Reading is empty for spans
+// without kanji; concatenating the surfaces reproduces the text.
+type FuriganaToken struct {
+	Surface string `json:"surface"`
+	Reading string `json:"reading,omitempty"`
+}
+
+// ScanFurigana caches the tokens generated for the OCR text identified by TextHash.
+type ScanFurigana struct {
+	ScanID    int64
+	TextHash  string
+	Tokens    []FuriganaToken
+	CreatedAt time.Time
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// KnowledgeEntry is a stored vocabulary term of the knowledge base. Kosakata is unique.
+type KnowledgeEntry struct {
+	ID              int64
+	Kosakata        string
+	Kana            string
+	Arti            string
+	CaraBaca        string
+	Deskripsi       string
+	BidangPekerjaan []string
+	Industri        []string
+	Konteks         string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+// DefaultLanguage is the explanation language of users who have not picked one.
+const DefaultLanguage = "ID"
+
+// Language is an explanation language users can pick. Code is what clients send and what is
+// stored; Name is what the model is asked to write in.
+type Language struct {
+	Code    string
+	Name    string
+	FlagURL string
+}
+
+// Languages lists the supported explanation languages. Adding an entry makes it selectable in
+// the profile and usable for annotations.
+var Languages = []Language{
+	{Code: "ID", Name: "Indonesian", FlagURL: "https://flagcdn.com/w40/id.png"},
+	{Code: "JP", Name: "Japanese", FlagURL: "https://flagcdn.com/w40/jp.png"},
+	{Code: "EN", Name: "English", FlagURL: "https://flagcdn.com/w40/gb.png"},
+}
+
+// LookupLanguage returns the supported language with the given code.
+func LookupLanguage(code string) (Language, bool) {
+	for _, l := range Languages {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	OCRJobStatusQueued    = "queued"
+	OCRJobStatusRunning   = "running"
+	OCRJobStatusSucceeded = "succeeded"
+	OCRJobStatusFailed    = "failed"
+)
+
+type OCRJob struct {
+	ID          int64
+	ScanID      int64
+	ImagePath   string
+	MimeType    string
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	LastError   *string
+	NextRunAt   time.Time
+	LockedAt    *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// IsFinalAttempt reports whether a failure of the current run should be permanent.
+func (j *OCRJob) IsFinalAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
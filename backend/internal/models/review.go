@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ReviewCard schedules reviews of one bookmarked annotation.
+type ReviewCard struct {
+	ID             int64
+	UserID         int64
+	AnnotationID   int64
+	Repetitions    int
+	IntervalDays   int
+	EaseFactor     float64
+	DueAt          time.Time
+	LastReviewedAt *time.Time
+	CreatedAt      time.Time
+	// Annotation is filled in by queries that list cards for review.
+	Annotation *Annotation
+}
+
+// ReviewLog records one answer and the schedule it produced.
+type ReviewLog struct {
+	ID           int64
+	CardID       int64
+	UserID       int64
+	Grade        string
+	IntervalDays int
+	EaseFactor   float64
+	ReviewedAt   time.Time
+}
+
+// ReviewDayStats counts one UTC day's answers by grade.
+type ReviewDayStats struct {
+	Day    time.Time
+	Grades map[string]int
+}
@@ -2,11 +2,66 @@ package models
 
 import "time"
 
+const (
+	ScanStatusPending    = "pending"
+	ScanStatusProcessing = "processing"
+	ScanStatusCompleted  = "completed"
+	ScanStatusFailed     = "failed"
+)
+
 type Scan struct {
 	ID               int64
 	UserID           int64
 	ImageURL         string
 	FullOCRText      *string
 	DetectedLanguage *string
+	Status           string
+	FailureReason    *string
+	OCRLayout        *OCRLayout
+	DocumentID       *int64
+	PageNumber       *int
+	CreatedAt        time.Time
+}
+
+func IsValidScanStatus(status string) bool {
+	switch status {
+	case ScanStatusPending, ScanStatusProcessing, ScanStatusCompleted, ScanStatusFailed:
+		return true
+	}
+	return false
+}
+
+// ScanOCRRevision is OCR output that was replaced by a later OCR run.
+type ScanOCRRevision struct {
+	ID               int64
+	ScanID           int64
+	FullOCRText      string
+	DetectedLanguage *string
 	CreatedAt        time.Time
 }
+
+// OCRLayout is the structure of the recognized text on the page. Blocks are in reading
+// order; bounding boxes are normalized to 0..1 with the origin at the top-left corner.
+type OCRLayout struct {
+	Orientation string      `json:"orientation"`
+	Blocks      []TextBlock `json:"blocks"`
+}
+
+type TextBlock struct {
+	Text        string      `json:"text"`
+	Orientation string      `json:"orientation"`
+	Box         BoundingBox `json:"box"`
+	Lines       []TextLine  `json:"lines,omitempty"`
+}
+
+type TextLine struct {
+	Text string      `json:"text"`
+	Box  BoundingBox `json:"box"`
+}
+
+type BoundingBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
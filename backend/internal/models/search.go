@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	SearchKindScan       = "scan"
+	SearchKindAnnotation = "annotation"
+)
+
+// Fields a search hit can match in.
+const (
+	SearchFieldFullOCRText     = "fullOcrText"
+	SearchFieldHighlightedText = "highlightedText"
+	SearchFieldContextText     = "contextText"
+	SearchFieldNuanceData      = "nuanceData"
+)
+
+// SearchHit is a scan or annotation matching a search, with the text of the best-ranked field
+// it matched in.
+type SearchHit struct {
+	Kind string
+	// ScanID is nil for annotations whose scan was deleted.
+	ScanID *int64
+	// AnnotationID and HighlightedText are only set for annotations.
+	AnnotationID    *int64
+	HighlightedText string
+	Field           string
+	Text            string
+	Rank            float64
+	CreatedAt       time.Time
+}
@@ -9,6 +9,8 @@ type User struct {
 	ProviderID        string
 	AvatarURL         *string
 	PreferredLanguage string
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	// PreferredVoice is the default TTS voice, empty for the provider default.
+	PreferredVoice string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
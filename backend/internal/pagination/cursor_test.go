@@ -0,0 +1,46 @@
+package pagination
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	s := NewSigner("secret")
+	c := Cursor{CreatedAt: time.Date(2026, 5, 1, 9, 0, 0, 123456000, time.UTC), PageNumber: 3, ID: 42, Query: "sort=newest"}
+
+	got, err := s.Decode(s.Encode(c), "sort=newest")
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.PageNumber != 3 || got.ID != 42 {
+		t.Errorf("expected %+v, got %+v", c, got)
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	s := NewSigner("secret")
+	token := s.Encode(Cursor{CreatedAt: time.Now(), ID: 42, Query: "sort=newest"})
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged := NewSigner("secret").Encode(Cursor{CreatedAt: time.Now(), ID: 7, Query: "sort=newest"})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := map[string]struct {
+		cursor string
+		query  string
+	}{
+		"other query":    {token, "sort=oldest"},
+		"other key":      {NewSigner("other").Encode(Cursor{ID: 42, Query: "sort=newest"}), "sort=newest"},
+		"edited payload": {forgedPayload + "." + signature, "sort=newest"},
+		"no signature":   {payload, "sort=newest"},
+		"garbage":        {"not-a-cursor.!!", "sort=newest"},
+	}
+	for name, tt := range tests {
+		if _, err := s.Decode(tt.cursor, tt.query); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", name, err)
+		}
+	}
+}
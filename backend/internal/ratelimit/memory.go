@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// memoryLimiter keeps buckets in process memory. Limits are per replica.
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	sweptAt time.Time
+}
+
+func NewMemoryLimiter() Limiter {
+	return newMemoryLimiter(time.Now)
+}
+
+func newMemoryLimiter(now func() time.Time) *memoryLimiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     now,
+	}
+}
+
+func (l *memoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	if limit.Unlimited() {
+		return Decision{Allowed: true, Remaining: -1}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.burst(), updated: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := float64(now.Sub(b.updated) / time.Millisecond)
+	b.tokens = math.Min(limit.burst(), b.tokens+max(elapsed, 0)*limit.perMillisecond())
+	b.updated = now
+
+	var decision Decision
+	b.tokens, decision = take(b.tokens, limit)
+	return decision, nil
+}
+
+// sweep drops buckets idle for long enough to have refilled completely, at most once a minute.
+// Any limit refills within an hour unless it allows less than one call per hour.
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < time.Minute {
+		return
+	}
+	l.sweptAt = now
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) > time.Hour {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newMemoryLimiter(func() time.Time { return now })
+	limit := Limit{PerMinute: 2}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if d, _ := l.Allow(ctx, "u1", limit); !d.Allowed {
+			t.Fatalf("request %d: expected burst to be allowed", i)
+		}
+	}
+
+	d, _ := l.Allow(ctx, "u1", limit)
+	if d.Allowed {
+		t.Fatal("expected third request to be limited")
+	}
+	if d.RetryAfter != 30*time.Second {
+		t.Fatalf("expected 30s until the next token, got %s", d.RetryAfter)
+	}
+
+	if d, _ := l.Allow(ctx, "u2", limit); !d.Allowed {
+		t.Fatal("expected other keys to have their own bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if d, _ := l.Allow(ctx, "u1", limit); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("expected one refilled token, got %+v", d)
+	}
+
+	now = now.Add(time.Hour)
+	if d, _ := l.Allow(ctx, "u1", limit); !d.Allowed || d.Remaining != 1 {
+		t.Fatalf("expected refill to stop at the burst, got %+v", d)
+	}
+}
+
+func TestMemoryLimiterUnlimited(t *testing.T) {
+	l := NewMemoryLimiter()
+	for i := 0; i < 100; i++ {
+		if d, _ := l.Allow(context.Background(), "u1", Limit{}); !d.Allowed || d.Remaining != -1 {
+			t.Fatalf("expected unlimited decision, got %+v", d)
+		}
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	return Decision{}, errors.New("redis: connection refused")
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	l := NewFallbackLimiter(failingLimiter{}, NewMemoryLimiter())
+	limit := Limit{PerMinute: 1}
+
+	d, err := l.Allow(context.Background(), "u1", limit)
+	if err != nil || !d.Allowed {
+		t.Fatalf("expected fallback to allow, got %+v %v", d, err)
+	}
+	if d, _ := l.Allow(context.Background(), "u1", limit); d.Allowed {
+		t.Fatal("expected fallback buckets to keep limiting")
+	}
+}
@@ -0,0 +1,118 @@
+// Package ratelimit implements per-key token buckets, shared through Redis when it is
+// available and kept in process memory otherwise.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/config"
+	"github.com/gemini-hackathon/app/internal/logger"
+)
+
+// Class groups routes that cost the same kind of AI call.
+type Class string
+
+const (
+	ClassOCR     Class = "ocr"
+	ClassAnalyze Class = "analyze"
+	ClassSpeech  Class = "speech"
+)
+
+// Classes lists every class in display order.
+var Classes = []Class{ClassOCR, ClassAnalyze, ClassSpeech}
+
+// Limit is a token bucket holding at most Burst tokens and refilled at PerMinute tokens per
+// minute. A zero PerMinute means unlimited.
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// Unlimited reports whether the limit lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.PerMinute <= 0
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMinute)
+}
+
+// perMillisecond is the refill rate in tokens per millisecond.
+func (l Limit) perMillisecond() float64 {
+	return float64(l.PerMinute) / float64(time.Minute/time.Millisecond)
+}
+
+// Decision is the outcome of taking a token.
+type Decision struct {
+	Allowed bool
+	// Remaining is the number of tokens left, or -1 when the limit is unlimited.
+	Remaining int
+	// RetryAfter is how long until a token is available when the request was not allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// take applies the token-bucket arithmetic shared by every limiter. tokens is the bucket
+// content after refilling.
+func take(tokens float64, limit Limit) (float64, Decision) {
+	if tokens >= 1 {
+		tokens--
+		return tokens, Decision{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := math.Ceil((1 - tokens) / limit.perMillisecond())
+	return tokens, Decision{RetryAfter: time.Duration(wait) * time.Millisecond}
+}
+
+// fallbackLimiter uses primary and switches to fallback for any call primary fails.
+type fallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+}
+
+// NewFallbackLimiter returns a limiter that asks primary (Redis) and falls back to fallback
+// (memory) when primary is unreachable, so an outage degrades to per-replica limits instead
+// of failing requests.
+func NewFallbackLimiter(primary, fallback Limiter) Limiter {
+	return &fallbackLimiter{primary: primary, fallback: fallback}
+}
+
+func (l *fallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	decision, err := l.primary.Allow(ctx, key, limit)
+	if err == nil {
+		return decision, nil
+	}
+	logger.GetDefaultLogger().WithField("key", key).Warnf("Rate limiter unavailable, using in-memory buckets: %v", err)
+	return l.fallback.Allow(ctx, key, limit)
+}
+
+// Limits returns the per-minute token buckets configured for each class.
+func Limits(cfg *config.Config) map[Class]Limit {
+	return map[Class]Limit{
+		ClassOCR:     {PerMinute: cfg.RateLimitOCRPerMinute},
+		ClassAnalyze: {PerMinute: cfg.RateLimitAnalyzePerMinute},
+		ClassSpeech:  {PerMinute: cfg.RateLimitSpeechPerMinute},
+	}
+}
+
+// DailyQuotas returns the calls per UTC day allowed for each class; zero means unlimited.
+func DailyQuotas(cfg *config.Config) map[Class]int {
+	return map[Class]int{
+		ClassOCR:     cfg.DailyQuotaOCR,
+		ClassAnalyze: cfg.DailyQuotaAnalyze,
+		ClassSpeech:  cfg.DailyQuotaSpeech,
+	}
+}
+
+// Day returns the UTC day t falls on, which is what daily quotas are counted by.
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/storage"
+)
+
+// tokenBucketScript refills and takes from a bucket stored as a hash of {tokens, ts}, using
+// the Redis clock so every replica agrees. It returns {allowed, remaining, retry_after_ms}.
+const tokenBucketScript = `
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, math.floor(tokens), wait}
+`
+
+// redisLimiter shares buckets between replicas through Redis.
+type redisLimiter struct {
+	redis storage.RedisClient
+}
+
+func NewRedisLimiter(redis storage.RedisClient) Limiter {
+	return &redisLimiter{redis: redis}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	if limit.Unlimited() {
+		return Decision{Allowed: true, Remaining: -1}, nil
+	}
+
+	result, err := l.redis.RunScript(ctx, tokenBucketScript, []string{"ratelimit:" + key},
+		strconv.FormatFloat(limit.burst(), 'f', -1, 64),
+		strconv.FormatFloat(limit.perMillisecond(), 'f', -1, 64),
+	)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	values, ok := result.([]any)
+	if !ok || len(values) != 3 {
+		return Decision{}, fmt.Errorf("unexpected token bucket reply: %v", result)
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	wait, _ := values[2].(int64)
+
+	return Decision{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,98 @@
+// Package srs schedules flashcard reviews with the SM-2 algorithm, using the four answer
+// buttons learners know from Anki in place of SM-2's 0-5 quality scale.
+package srs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Grade is how well the learner recalled a card.
+type Grade string
+
+const (
+	GradeAgain Grade = "again"
+	GradeHard  Grade = "hard"
+	GradeGood  Grade = "good"
+	GradeEasy  Grade = "easy"
+)
+
+// Grades lists every grade from worst to best.
+var Grades = []Grade{GradeAgain, GradeHard, GradeGood, GradeEasy}
+
+// ParseGrade returns the grade named s.
+func ParseGrade(s string) (Grade, error) {
+	for _, g := range Grades {
+		if string(g) == s {
+			return g, nil
+		}
+	}
+	return "", fmt.Errorf("unknown grade %q", s)
+}
+
+// quality maps a grade onto SM-2's scale, where anything below 3 is a lapse.
+func (g Grade) quality() int {
+	switch g {
+	case GradeHard:
+		return 3
+	case GradeGood:
+		return 4
+	case GradeEasy:
+		return 5
+	default:
+		return 0
+	}
+}
+
+const (
+	// InitialEaseFactor is the ease of a card that has never been reviewed.
+	InitialEaseFactor = 2.5
+	// MinEaseFactor keeps cards that are often forgotten from being shown every day forever.
+	MinEaseFactor = 1.3
+)
+
+// State is the schedule of one card.
+type State struct {
+	// Repetitions counts the reviews in a row that were not lapses.
+	Repetitions  int
+	IntervalDays int
+	EaseFactor   float64
+	Due          time.Time
+}
+
+// New returns the schedule of a card that is due now.
+func New(now time.Time) State {
+	return State{EaseFactor: InitialEaseFactor, Due: now}
+}
+
+// Schedule returns the state after reviewing a card with grade g at now. A lapse starts the
+// card over at a one-day interval; otherwise the intervals go 1 day, 6 days, then grow by the
+// ease factor. The ease factor moves with every grade as in SM-2.
+func Schedule(s State, g Grade, now time.Time) State {
+	q := g.quality()
+	if s.EaseFactor == 0 {
+		s.EaseFactor = InitialEaseFactor
+	}
+
+	next := State{}
+	if q < 3 {
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	} else {
+		next.Repetitions = s.Repetitions + 1
+		switch next.Repetitions {
+		case 1:
+			next.IntervalDays = 1
+		case 2:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.EaseFactor))
+		}
+	}
+
+	d := float64(5 - q)
+	next.EaseFactor = math.Max(MinEaseFactor, s.EaseFactor+0.1-d*(0.08+d*0.02))
+	next.Due = now.AddDate(0, 0, next.IntervalDays)
+	return next
+}
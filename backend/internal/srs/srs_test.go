@@ -0,0 +1,61 @@
+package srs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := New(now)
+
+	wantIntervals := []int{1, 6, 15, 38}
+	for i, want := range wantIntervals {
+		s = Schedule(s, GradeGood, now)
+		if s.IntervalDays != want || s.Repetitions != i+1 {
+			t.Fatalf("review %d: interval %d reps %d, want %d reps %d", i+1, s.IntervalDays, s.Repetitions, want, i+1)
+		}
+	}
+	if s.EaseFactor != InitialEaseFactor {
+		t.Errorf("good should keep the ease factor, got %v", s.EaseFactor)
+	}
+	if !s.Due.Equal(now.AddDate(0, 0, 38)) {
+		t.Errorf("unexpected due date %s", s.Due)
+	}
+
+	lapsed := Schedule(s, GradeAgain, now)
+	if lapsed.Repetitions != 0 || lapsed.IntervalDays != 1 {
+		t.Errorf("again should restart the card, got %+v", lapsed)
+	}
+	if want := InitialEaseFactor - 0.8; lapsed.EaseFactor < want-1e-9 || lapsed.EaseFactor > want+1e-9 {
+		t.Errorf("again should lower the ease by 0.8, got %v", lapsed.EaseFactor)
+	}
+
+	if hard := Schedule(New(now), GradeHard, now); hard.EaseFactor >= InitialEaseFactor || hard.IntervalDays != 1 {
+		t.Errorf("hard should pass with a lower ease, got %+v", hard)
+	}
+	if easy := Schedule(New(now), GradeEasy, now); easy.EaseFactor <= InitialEaseFactor {
+		t.Errorf("easy should raise the ease, got %+v", easy)
+	}
+}
+
+func TestScheduleEaseFloor(t *testing.T) {
+	s := New(time.Now())
+	for i := 0; i < 10; i++ {
+		s = Schedule(s, GradeAgain, time.Now())
+	}
+	if s.EaseFactor != MinEaseFactor {
+		t.Errorf("expected the ease to bottom out at %v, got %v", MinEaseFactor, s.EaseFactor)
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	for _, g := range Grades {
+		if got, err := ParseGrade(string(g)); err != nil || got != g {
+			t.Errorf("ParseGrade(%q) = %q, %v", g, got, err)
+		}
+	}
+	if _, err := ParseGrade("perfect"); err == nil {
+		t.Error("expected an error for an unknown grade")
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// GetAudioAsset returns the audio clip stored under hash, or nil when there is none.
+func (s *postgresDB) GetAudioAsset(ctx context.Context, hash string) (*models.AudioAsset, error) {
+	query := `
+		SELECT hash, path, mime_type, size_bytes, created_at
+		FROM audio_assets
+		WHERE hash = $1
+	`
+	var asset models.AudioAsset
+	err := s.db.QueryRowContext(ctx, query, hash).Scan(
+		&asset.Hash,
+		&asset.Path,
+		&asset.MIMEType,
+		&asset.SizeBytes,
+		&asset.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// SaveAudioAsset records a stored clip, replacing the row when the file was written again.
+func (s *postgresDB) SaveAudioAsset(ctx context.Context, asset *models.AudioAsset) error {
+	query := `
+		INSERT INTO audio_assets (hash, path, mime_type, size_bytes, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (hash) DO UPDATE
+		SET path = EXCLUDED.path, mime_type = EXCLUDED.mime_type, size_bytes = EXCLUDED.size_bytes
+	`
+	_, err := s.db.ExecContext(ctx, query, asset.Hash, asset.Path, asset.MIMEType, asset.SizeBytes, asset.CreatedAt)
+	return err
+}
+
+// GetAudioVariant returns the clip stored under hash re-encoded as format, or nil when it has
+// not been encoded yet.
+func (s *postgresDB) GetAudioVariant(ctx context.Context, hash, format string) (*models.AudioAsset, error) {
+	query := `
+		SELECT hash, path, mime_type, size_bytes, created_at
+		FROM audio_asset_variants
+		WHERE hash = $1 AND format = $2
+	`
+	var variant models.AudioAsset
+	err := s.db.QueryRowContext(ctx, query, hash, format).Scan(
+		&variant.Hash,
+		&variant.Path,
+		&variant.MIMEType,
+		&variant.SizeBytes,
+		&variant.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// SaveAudioVariant records a re-encoded clip. variant.Hash is the hash of the original clip.
+func (s *postgresDB) SaveAudioVariant(ctx context.Context, format string, variant *models.AudioAsset) error {
+	query := `
+		INSERT INTO audio_asset_variants (hash, format, path, mime_type, size_bytes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (hash, format) DO UPDATE
+		SET path = EXCLUDED.path, mime_type = EXCLUDED.mime_type, size_bytes = EXCLUDED.size_bytes
+	`
+	_, err := s.db.ExecContext(ctx, query, variant.Hash, format, variant.Path, variant.MIMEType, variant.SizeBytes, variant.CreatedAt)
+	return err
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/lib/pq"
 )
 
 type DB interface {
@@ -16,19 +18,122 @@ type DB interface {
 	GetUserByProvider(ctx context.Context, provider, providerID string) (*models.User, error)
 	GetUserByID(ctx context.Context, userID int64) (*models.User, error)
 	UpdateUserLanguage(ctx context.Context, userID int64, language string) error
+	UpdateUserVoice(ctx context.Context, userID int64, voice string) error
 
 	CreateScan(ctx context.Context, scan *models.Scan) (int64, error)
 	GetScanByID(ctx context.Context, scanID int64) (*models.Scan, error)
-	GetScansByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Scan, error)
+	GetScansByUserID(ctx context.Context, userID int64, filter ScanFilter, page Page) ([]*models.Scan, error)
+	CountScans(ctx context.Context, userID int64, filter ScanFilter) (int, error)
 	UpdateScanImageURL(ctx context.Context, scanID int64, imageURL string) error
-	UpdateScanOCR(ctx context.Context, scanID int64, text, language string) error
+	UpdateScanOCR(ctx context.Context, scanID int64, text, language string, layout *models.OCRLayout) error
+	UpdateScanStatus(ctx context.Context, scanID int64, status string, failureReason *string) error
+	ClaimScanForOCR(ctx context.Context, scanID int64, now, staleBefore time.Time) (bool, error)
+	ReleaseScanOCRClaim(ctx context.Context, scanID int64) error
+	GetScanOCRRevisions(ctx context.Context, scanID int64) ([]*models.ScanOCRRevision, error)
+	GetScanFurigana(ctx context.Context, scanID int64) (*models.ScanFurigana, error)
+	SaveScanFurigana(ctx context.Context, furigana *models.ScanFurigana) error
 	DeleteScan(ctx context.Context, scanID, userID int64) error
 
+	CreateDocument(ctx context.Context, document *models.Document) (int64, error)
+	GetDocumentByID(ctx context.Context, documentID int64) (*models.Document, error)
+	GetDocumentsByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Document, error)
+	UpdateDocument(ctx context.Context, document *models.Document) error
+	DeleteDocument(ctx context.Context, documentID, userID int64) error
+	GetDocumentPages(ctx context.Context, documentID int64) ([]*models.Scan, error)
+	AddDocumentPage(ctx context.Context, documentID, scanID int64) (int, error)
+	RemoveDocumentPage(ctx context.Context, documentID, scanID int64) error
+	ReorderDocumentPages(ctx context.Context, documentID int64, scanIDs []int64) error
+
 	CreateAnnotation(ctx context.Context, annotation *models.Annotation) (int64, error)
 	GetAnnotationByID(ctx context.Context, annotationID int64) (*models.Annotation, error)
-	GetAnnotationsByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Annotation, error)
-	GetAnnotationsByUserIDAndScanID(ctx context.Context, userID, scanID int64, page, size int) ([]*models.Annotation, error)
+	GetAnnotationsByUserID(ctx context.Context, userID int64, filter AnnotationFilter, page Page) ([]*models.Annotation, error)
+	CountAnnotations(ctx context.Context, userID int64, filter AnnotationFilter) (int, error)
+	ForEachAnnotation(ctx context.Context, userID, scanID int64, fn func(*models.Annotation) error) error
 	DeleteAnnotation(ctx context.Context, annotationID, userID int64) error
+	SetAnnotationAudio(ctx context.Context, annotationID int64, audioHash string) error
+	UpdateAnnotation(ctx context.Context, annotation *models.Annotation, expectedUpdatedAt time.Time) error
+	GetAnnotationEdits(ctx context.Context, annotationID int64) ([]*models.AnnotationEdit, error)
+	AddAnnotationVersion(ctx context.Context, annotationID int64, nuance models.NuanceData, createdAt time.Time) (*models.AnnotationVersion, error)
+	GetAnnotationVersions(ctx context.Context, annotationID int64) ([]*models.AnnotationVersion, error)
+
+	CreateOCRJob(ctx context.Context, job *models.OCRJob) (int64, error)
+	ClaimOCRJob(ctx context.Context, now time.Time) (*models.OCRJob, error)
+	CompleteOCRJob(ctx context.Context, jobID int64) error
+	RetryOCRJob(ctx context.Context, jobID int64, lastError string, nextRunAt time.Time) error
+	FailOCRJob(ctx context.Context, jobID int64, lastError string) error
+	RequeueStaleOCRJobs(ctx context.Context, lockedBefore time.Time) (int64, error)
+
+	GetAnnotationCacheEntry(ctx context.Context, key string, now time.Time) ([]byte, error)
+	SaveAnnotationCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error
+	DeleteExpiredAnnotationCacheEntries(ctx context.Context, now time.Time) (int64, error)
+
+	IncrementUsage(ctx context.Context, userID int64, day time.Time, class string, amount, limit int) (int, bool, error)
+	GetUsage(ctx context.Context, userID int64, day time.Time) (map[string]int, error)
+
+	GetAudioAsset(ctx context.Context, hash string) (*models.AudioAsset, error)
+	SaveAudioAsset(ctx context.Context, asset *models.AudioAsset) error
+	GetAudioVariant(ctx context.Context, hash, format string) (*models.AudioAsset, error)
+	SaveAudioVariant(ctx context.Context, format string, variant *models.AudioAsset) error
+
+	GetDueReviewCards(ctx context.Context, userID int64, now time.Time, limit int) ([]*models.ReviewCard, error)
+	CountReviewCards(ctx context.Context, userID int64, now time.Time) (total, due int, err error)
+	GetReviewCard(ctx context.Context, cardID int64) (*models.ReviewCard, error)
+	RecordReview(ctx context.Context, card *models.ReviewCard, entry *models.ReviewLog, expectedLastReviewedAt *time.Time) error
+	GetReviewStats(ctx context.Context, userID int64, since time.Time) ([]*models.ReviewDayStats, error)
+
+	Search(ctx context.Context, userID int64, query, kind string, limit int) ([]*models.SearchHit, error)
+
+	ListKnowledgeEntries(ctx context.Context) ([]*models.KnowledgeEntry, error)
+	KnowledgeEntriesVersion(ctx context.Context) (int64, error)
+	CountKnowledgeEntries(ctx context.Context) (int, error)
+	GetKnowledgeEntry(ctx context.Context, entryID int64) (*models.KnowledgeEntry, error)
+	CreateKnowledgeEntry(ctx context.Context, entry *models.KnowledgeEntry) (int64, error)
+	UpdateKnowledgeEntry(ctx context.Context, entry *models.KnowledgeEntry) error
+	DeleteKnowledgeEntry(ctx context.Context, entryID int64) error
+	UpsertKnowledgeEntries(ctx context.Context, entries []*models.KnowledgeEntry, now time.Time) (int, error)
+}
+
+// ScanFilter narrows GetScansByUserID. Zero values mean "no filter".
+type ScanFilter struct {
+	Status     string
+	DocumentID int64
+	// Language is the detected language of the scan's text.
+	Language string
+	// CreatedFrom is inclusive and CreatedBefore exclusive.
+	CreatedFrom   time.Time
+	CreatedBefore time.Time
+}
+
+// AnnotationFilter narrows GetAnnotationsByUserID. Zero values mean "no filter".
+type AnnotationFilter struct {
+	ScanID int64
+	// DocumentID selects annotations made on any page of a document.
+	DocumentID int64
+	// Language is the language the explanation is written in.
+	Language   string
+	Bookmarked *bool
+	// CreatedFrom is inclusive and CreatedBefore exclusive.
+	CreatedFrom   time.Time
+	CreatedBefore time.Time
+}
+
+// Page selects part of a list. With After set the list continues after that position, which
+// stays stable while rows are added; otherwise Offset rows are skipped.
+type Page struct {
+	After  *Keyset
+	Offset int
+	Limit  int
+	// Oldest lists oldest first instead of newest first. A document's scans are always in
+	// page order.
+	Oldest bool
+}
+
+// Keyset is a position in a list ordered by (created_at, id), or by (page_number, id) for
+// a document's scans.
+type Keyset struct {
+	CreatedAt  time.Time
+	PageNumber int
+	ID         int64
 }
 
 type postgresDB struct {
@@ -59,7 +164,7 @@ func (s *postgresDB) CreateUser(ctx context.Context, user *models.User) error {
 
 func (s *postgresDB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, provider, provider_id, avatar_url, preferred_language, created_at, updated_at
+		SELECT id, email, provider, provider_id, avatar_url, preferred_language, preferred_voice, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -72,7 +177,7 @@ func (s *postgresDB) GetUserByEmail(ctx context.Context, email string) (*models.
 
 func (s *postgresDB) GetUserByProvider(ctx context.Context, provider, providerID string) (*models.User, error) {
 	query := `
-		SELECT id, email, provider, provider_id, avatar_url, preferred_language, created_at, updated_at
+		SELECT id, email, provider, provider_id, avatar_url, preferred_language, preferred_voice, created_at, updated_at
 		FROM users
 		WHERE provider = $1 AND provider_id = $2
 	`
@@ -85,7 +190,7 @@ func (s *postgresDB) GetUserByProvider(ctx context.Context, provider, providerID
 
 func (s *postgresDB) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
 	query := `
-		SELECT id, email, provider, provider_id, avatar_url, preferred_language, created_at, updated_at
+		SELECT id, email, provider, provider_id, avatar_url, preferred_language, preferred_voice, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -106,10 +211,22 @@ func (s *postgresDB) UpdateUserLanguage(ctx context.Context, userID int64, langu
 	return err
 }
 
+// UpdateUserVoice sets the user's default TTS voice. An empty voice restores the provider default.
+func (s *postgresDB) UpdateUserVoice(ctx context.Context, userID int64, voice string) error {
+	query := `
+		UPDATE users
+		SET preferred_voice = NULLIF($1, ''), updated_at = $2
+		WHERE id = $3
+	`
+	_, err := s.db.ExecContext(ctx, query, voice, time.Now(), userID)
+	return err
+}
+
 func (s *postgresDB) scanUser(row *sql.Row) (*models.User, error) {
 	var user models.User
 	var avatarURL sql.NullString
 	var preferredLanguage string
+	var preferredVoice sql.NullString
 	var createdAt, updatedAt time.Time
 
 	err := row.Scan(
@@ -119,6 +236,7 @@ func (s *postgresDB) scanUser(row *sql.Row) (*models.User, error) {
 		&user.ProviderID,
 		&avatarURL,
 		&preferredLanguage,
+		&preferredVoice,
 		&createdAt,
 		&updatedAt,
 	)
@@ -130,16 +248,28 @@ func (s *postgresDB) scanUser(row *sql.Row) (*models.User, error) {
 		user.AvatarURL = &avatarURL.String
 	}
 	user.PreferredLanguage = preferredLanguage
+	user.PreferredVoice = preferredVoice.String
 	user.CreatedAt = createdAt
 	user.UpdatedAt = updatedAt
 
 	return &user, nil
 }
 
+const scanColumns = `id, user_id, image_url, full_ocr_text, detected_language, status, failure_reason, ocr_layout, document_id, page_number, created_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 func (s *postgresDB) CreateScan(ctx context.Context, scan *models.Scan) (int64, error) {
+	if scan.Status == "" {
+		scan.Status = models.ScanStatusPending
+	}
+
 	query := `
-		INSERT INTO scans (user_id, image_url, full_ocr_text, detected_language, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO scans (user_id, image_url, full_ocr_text, detected_language, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 	err := s.db.QueryRowContext(ctx, query,
@@ -147,28 +277,36 @@ func (s *postgresDB) CreateScan(ctx context.Context, scan *models.Scan) (int64,
 		scan.ImageURL,
 		scan.FullOCRText,
 		scan.DetectedLanguage,
+		scan.Status,
 		scan.CreatedAt,
 	).Scan(&scan.ID)
 	return scan.ID, err
 }
 
 func (s *postgresDB) GetScanByID(ctx context.Context, scanID int64) (*models.Scan, error) {
-	query := `
-		SELECT id, user_id, image_url, full_ocr_text, detected_language, created_at
-		FROM scans
-		WHERE id = $1
-	`
+	query := `SELECT ` + scanColumns + ` FROM scans WHERE id = $1`
+	return readScan(s.db.QueryRowContext(ctx, query, scanID))
+}
+
+func readScan(row rowScanner) (*models.Scan, error) {
 	var scan models.Scan
-	var fullOCRText, detectedLanguage sql.NullString
-	var createdAt time.Time
+	var fullOCRText, detectedLanguage, failureReason sql.NullString
+	var ocrLayout []byte
+	var documentID sql.NullInt64
+	var pageNumber sql.NullInt32
 
-	err := s.db.QueryRowContext(ctx, query, scanID).Scan(
+	err := row.Scan(
 		&scan.ID,
 		&scan.UserID,
 		&scan.ImageURL,
 		&fullOCRText,
 		&detectedLanguage,
-		&createdAt,
+		&scan.Status,
+		&failureReason,
+		&ocrLayout,
+		&documentID,
+		&pageNumber,
+		&scan.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -180,65 +318,232 @@ func (s *postgresDB) GetScanByID(ctx context.Context, scanID int64) (*models.Sca
 	if detectedLanguage.Valid {
 		scan.DetectedLanguage = &detectedLanguage.String
 	}
-	scan.CreatedAt = createdAt
+	if failureReason.Valid {
+		scan.FailureReason = &failureReason.String
+	}
+	if ocrLayout != nil {
+		if err := json.Unmarshal(ocrLayout, &scan.OCRLayout); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal ocr_layout: %w", err)
+		}
+	}
+	if documentID.Valid {
+		scan.DocumentID = &documentID.Int64
+	}
+	if pageNumber.Valid {
+		page := int(pageNumber.Int32)
+		scan.PageNumber = &page
+	}
 
 	return &scan, nil
 }
 
-func (s *postgresDB) GetScansByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Scan, error) {
-	offset := (page - 1) * size
-	query := `
-		SELECT id, user_id, image_url, full_ocr_text, detected_language, created_at
+// UpdateScanOCR stores the OCR result and marks the scan as completed. Any OCR text
+// already on the scan is archived as a revision in the same transaction. A nil layout
+// clears the stored one, since it would no longer match the text.
+func (s *postgresDB) UpdateScanOCR(ctx context.Context, scanID int64, text, language string, layout *models.OCRLayout) error {
+	var layoutJSON any
+	if layout != nil {
+		encoded, err := json.Marshal(layout)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ocr_layout: %w", err)
+		}
+		layoutJSON = encoded
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	archiveQuery := `
+		INSERT INTO scan_ocr_revisions (scan_id, full_ocr_text, detected_language, created_at)
+		SELECT id, full_ocr_text, detected_language, $2
 		FROM scans
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		WHERE id = $1 AND full_ocr_text IS NOT NULL
+	`
+	if _, err := tx.ExecContext(ctx, archiveQuery, scanID, time.Now()); err != nil {
+		return fmt.Errorf("failed to archive previous OCR text: %w", err)
+	}
+
+	updateQuery := `
+		UPDATE scans
+		SET full_ocr_text = $1, detected_language = $2, ocr_layout = $3, status = $4, failure_reason = NULL
+		WHERE id = $5
 	`
-	rows, err := s.db.QueryContext(ctx, query, userID, size, offset)
+	if _, err := tx.ExecContext(ctx, updateQuery, text, language, layoutJSON, models.ScanStatusCompleted, scanID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *postgresDB) GetScanOCRRevisions(ctx context.Context, scanID int64) ([]*models.ScanOCRRevision, error) {
+	query := `
+		SELECT id, scan_id, full_ocr_text, detected_language, created_at
+		FROM scan_ocr_revisions
+		WHERE scan_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := s.db.QueryContext(ctx, query, scanID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var scans []*models.Scan
+	var revisions []*models.ScanOCRRevision
 	for rows.Next() {
-		var scan models.Scan
-		var fullOCRText, detectedLanguage sql.NullString
-		var createdAt time.Time
-
-		err := rows.Scan(
-			&scan.ID,
-			&scan.UserID,
-			&scan.ImageURL,
-			&fullOCRText,
+		var revision models.ScanOCRRevision
+		var detectedLanguage sql.NullString
+
+		if err := rows.Scan(
+			&revision.ID,
+			&revision.ScanID,
+			&revision.FullOCRText,
 			&detectedLanguage,
-			&createdAt,
-		)
-		if err != nil {
+			&revision.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 
-		if fullOCRText.Valid {
-			scan.FullOCRText = &fullOCRText.String
-		}
 		if detectedLanguage.Valid {
-			scan.DetectedLanguage = &detectedLanguage.String
+			revision.DetectedLanguage = &detectedLanguage.String
 		}
-		scan.CreatedAt = createdAt
+		revisions = append(revisions, &revision)
+	}
 
-		scans = append(scans, &scan)
+	return revisions, rows.Err()
+}
+
+// GetScanFurigana returns the cached furigana of a scan, or nil when there is none.
+func (s *postgresDB) GetScanFurigana(ctx context.Context, scanID int64) (*models.ScanFurigana, error) {
+	query := `
+		SELECT scan_id, text_hash, tokens, created_at
+		FROM scan_furigana
+		WHERE scan_id = $1
+	`
+	var furigana models.ScanFurigana
+	var tokens []byte
+
+	err := s.db.QueryRowContext(ctx, query, scanID).Scan(
+		&furigana.ScanID,
+		&furigana.TextHash,
+		&tokens,
+		&furigana.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return scans, rows.Err()
+	if err := json.Unmarshal(tokens, &furigana.Tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal furigana tokens: %w", err)
+	}
+	return &furigana, nil
+}
+
+// SaveScanFurigana stores the furigana of a scan, replacing any earlier entry.
+func (s *postgresDB) SaveScanFurigana(ctx context.Context, furigana *models.ScanFurigana) error {
+	tokens, err := json.Marshal(furigana.Tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal furigana tokens: %w", err)
+	}
+
+	query := `
+		INSERT INTO scan_furigana (scan_id, text_hash, tokens, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scan_id) DO UPDATE
+		SET text_hash = EXCLUDED.text_hash, tokens = EXCLUDED.tokens, created_at = EXCLUDED.created_at
+	`
+	_, err = s.db.ExecContext(ctx, query, furigana.ScanID, furigana.TextHash, tokens, furigana.CreatedAt)
+	return err
+}
+
+// GetAnnotationCacheEntry returns the cached annotation stored under key, or nil when there is
+// none or it expired before now.
+func (s *postgresDB) GetAnnotationCacheEntry(ctx context.Context, key string, now time.Time) ([]byte, error) {
+	query := `
+		SELECT response
+		FROM annotation_cache
+		WHERE cache_key = $1 AND expires_at > $2
+	`
+	var value []byte
+	err := s.db.QueryRowContext(ctx, query, key, now).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// SaveAnnotationCacheEntry stores an annotation under key, replacing any earlier entry.
+func (s *postgresDB) SaveAnnotationCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	query := `
+		INSERT INTO annotation_cache (cache_key, response, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (cache_key) DO UPDATE
+		SET response = EXCLUDED.response, expires_at = EXCLUDED.expires_at, created_at = CURRENT_TIMESTAMP
+	`
+	_, err := s.db.ExecContext(ctx, query, key, value, expiresAt)
+	return err
+}
+
+// DeleteExpiredAnnotationCacheEntries removes entries that expired before now and returns how
+// many were removed.
+func (s *postgresDB) DeleteExpiredAnnotationCacheEntries(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM annotation_cache WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *postgresDB) UpdateScanStatus(ctx context.Context, scanID int64, status string, failureReason *string) error {
+	query := `
+		UPDATE scans
+		SET status = $1, failure_reason = $2
+		WHERE id = $3
+	`
+	_, err := s.db.ExecContext(ctx, query, status, failureReason, scanID)
+	return err
 }
 
-func (s *postgresDB) UpdateScanOCR(ctx context.Context, scanID int64, text, language string) error {
+// ClaimScanForOCR marks a scan as processing for a synchronous OCR re-run. It returns false
+// while another re-run holds an unexpired claim (one taken after staleBefore) or the scan has
+// a queued or running OCR job, so only one run writes the scan's text at a time.
+func (s *postgresDB) ClaimScanForOCR(ctx context.Context, scanID int64, now, staleBefore time.Time) (bool, error) {
 	query := `
 		UPDATE scans
-		SET full_ocr_text = $1, detected_language = $2
+		SET status = $1, failure_reason = NULL, ocr_claimed_at = $2
 		WHERE id = $3
+			AND (ocr_claimed_at IS NULL OR ocr_claimed_at < $4)
+			AND NOT EXISTS (
+				SELECT 1 FROM ocr_jobs
+				WHERE scan_id = $3 AND status IN ($5, $6)
+			)
+		RETURNING id
 	`
-	_, err := s.db.ExecContext(ctx, query, text, language, scanID)
+	var id int64
+	err := s.db.QueryRowContext(ctx, query,
+		models.ScanStatusProcessing, now, scanID, staleBefore,
+		models.OCRJobStatusQueued, models.OCRJobStatusRunning,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseScanOCRClaim ends the claim taken by ClaimScanForOCR.
+func (s *postgresDB) ReleaseScanOCRClaim(ctx context.Context, scanID int64) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE scans SET ocr_claimed_at = NULL WHERE id = $1`, scanID)
 	return err
 }
 
@@ -265,121 +570,270 @@ func (s *postgresDB) DeleteScan(ctx context.Context, scanID, userID int64) error
 	return nil
 }
 
+// CreateAnnotation stores a new annotation with its explanation as version 1.
 func (s *postgresDB) CreateAnnotation(ctx context.Context, annotation *models.Annotation) (int64, error) {
 	nuanceJSON, err := json.Marshal(annotation.NuanceData)
 	if err != nil {
 		return 0, fmt.Errorf("failed to marshal nuance_data: %w", err)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO annotations (user_id, scan_id, highlighted_text, context_text, nuance_data, is_bookmarked, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO annotations (user_id, scan_id, highlighted_text, context_text, nuance_data, is_bookmarked, notes, tags, current_version, audio_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 1, $9, $10, $10)
 		RETURNING id
 	`
-	err = s.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		annotation.UserID,
 		annotation.ScanID,
 		annotation.HighlightedText,
 		annotation.ContextText,
 		nuanceJSON,
 		annotation.IsBookmarked,
+		annotation.Notes,
+		pq.Array(models.NonNilTags(annotation.Tags)),
+		annotation.AudioHash,
 		annotation.CreatedAt,
 	).Scan(&annotation.ID)
-	return annotation.ID, err
+	if err != nil {
+		return 0, err
+	}
+
+	versionQuery := `
+		INSERT INTO annotation_versions (annotation_id, version, nuance_data, created_at)
+		VALUES ($1, 1, $2, $3)
+	`
+	if _, err := tx.ExecContext(ctx, versionQuery, annotation.ID, nuanceJSON, annotation.CreatedAt); err != nil {
+		return 0, fmt.Errorf("failed to store first annotation version: %w", err)
+	}
+	if err := addReviewCard(ctx, tx, annotation.ID, annotation.CreatedAt); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	annotation.CurrentVersion = 1
+	annotation.UpdatedAt = annotation.CreatedAt
+	return annotation.ID, nil
 }
 
-func (s *postgresDB) GetAnnotationByID(ctx context.Context, annotationID int64) (*models.Annotation, error) {
+// ErrAnnotationConflict is returned when an annotation changed after the caller read it.
+var ErrAnnotationConflict = errors.New("storage: annotation was modified concurrently")
+
+// UpdateAnnotation saves the user-editable fields of an annotation: its explanation and the
+// version it is based on, notes, tags and bookmark. The annotation as it was is archived as an edit in the same transaction.
+// It returns sql.ErrNoRows when the annotation does not exist or belongs to another user, and
+// ErrAnnotationConflict when its updated_at is no longer expectedUpdatedAt.
+func (s *postgresDB) UpdateAnnotation(ctx context.Context, annotation *models.Annotation, expectedUpdatedAt time.Time) error {
+	nuanceJSON, err := json.Marshal(annotation.NuanceData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal nuance_data: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var updatedAt time.Time
+	lockQuery := `SELECT updated_at FROM annotations WHERE id = $1 AND user_id = $2 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, lockQuery, annotation.ID, annotation.UserID).Scan(&updatedAt); err != nil {
+		return err
+	}
+	if !updatedAt.Equal(expectedUpdatedAt) {
+		return ErrAnnotationConflict
+	}
+
+	if err := archiveAnnotation(ctx, tx, annotation.ID, annotation.UpdatedAt); err != nil {
+		return err
+	}
+
+	updateQuery := `
+		UPDATE annotations
+		SET nuance_data = $3, notes = $4, tags = $5, is_bookmarked = $6, current_version = $7, updated_at = $8
+		WHERE id = $1 AND user_id = $2
+	`
+	if _, err := tx.ExecContext(ctx, updateQuery,
+		annotation.ID,
+		annotation.UserID,
+		nuanceJSON,
+		annotation.Notes,
+		pq.Array(models.NonNilTags(annotation.Tags)),
+		annotation.IsBookmarked,
+		annotation.CurrentVersion,
+		annotation.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	if err := addReviewCard(ctx, tx, annotation.ID, annotation.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// archiveAnnotation keeps the annotation as it is now as an edit made at editedAt. The
+// annotation must be locked by tx.
+func archiveAnnotation(ctx context.Context, tx *sql.Tx, annotationID int64, editedAt time.Time) error {
 	query := `
-		SELECT id, user_id, scan_id, highlighted_text, context_text, nuance_data, is_bookmarked, created_at
+		INSERT INTO annotation_edits (annotation_id, nuance_data, notes, tags, is_bookmarked, created_at)
+		SELECT id, nuance_data, notes, tags, is_bookmarked, $2
 		FROM annotations
 		WHERE id = $1
 	`
-	var annotation models.Annotation
-	var scanID sql.NullInt64
-	var contextText sql.NullString
-	var nuanceData []byte
-	var createdAt time.Time
+	if _, err := tx.ExecContext(ctx, query, annotationID, editedAt); err != nil {
+		return fmt.Errorf("failed to archive annotation: %w", err)
+	}
+	return nil
+}
 
-	err := s.db.QueryRowContext(ctx, query, annotationID).Scan(
-		&annotation.ID,
-		&annotation.UserID,
-		&scanID,
-		&annotation.HighlightedText,
-		&contextText,
-		&nuanceData,
-		&annotation.IsBookmarked,
-		&createdAt,
-	)
+// GetAnnotationEdits returns the archived versions of an annotation, newest first.
+func (s *postgresDB) GetAnnotationEdits(ctx context.Context, annotationID int64) ([]*models.AnnotationEdit, error) {
+	query := `
+		SELECT id, annotation_id, nuance_data, notes, tags, is_bookmarked, created_at
+		FROM annotation_edits
+		WHERE annotation_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := s.db.QueryContext(ctx, query, annotationID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if scanID.Valid {
-		annotation.ScanID = &scanID.Int64
+	var edits []*models.AnnotationEdit
+	for rows.Next() {
+		var edit models.AnnotationEdit
+		var nuanceData []byte
+		var notes sql.NullString
+
+		if err := rows.Scan(
+			&edit.ID,
+			&edit.AnnotationID,
+			&nuanceData,
+			&notes,
+			pq.Array(&edit.Tags),
+			&edit.IsBookmarked,
+			&edit.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if notes.Valid {
+			edit.Notes = &notes.String
+		}
+		if err := json.Unmarshal(nuanceData, &edit.NuanceData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal nuance_data: %w", err)
+		}
+		edits = append(edits, &edit)
 	}
-	if contextText.Valid {
-		annotation.ContextText = &contextText.String
+
+	return edits, rows.Err()
+}
+
+// AddAnnotationVersion stores a new AI explanation for an annotation under the next version
+// number and makes it current. The annotation as it was is archived as an edit. All of it
+// happens in one transaction, so the explanation never disagrees with current_version. It
+// returns sql.ErrNoRows when the annotation does not exist.
+func (s *postgresDB) AddAnnotationVersion(ctx context.Context, annotationID int64, nuance models.NuanceData, createdAt time.Time) (*models.AnnotationVersion, error) {
+	nuanceJSON, err := json.Marshal(nuance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal nuance_data: %w", err)
 	}
-	if err := json.Unmarshal(nuanceData, &annotation.NuanceData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal nuance_data: %w", err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
-	annotation.CreatedAt = createdAt
+	defer tx.Rollback()
 
-	return &annotation, nil
+	// Lock the annotation so concurrent regenerations number their versions in turn.
+	var locked int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM annotations WHERE id = $1 FOR UPDATE`, annotationID).Scan(&locked); err != nil {
+		return nil, err
+	}
+
+	if err := archiveAnnotation(ctx, tx, annotationID, createdAt); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO annotation_versions (annotation_id, version, nuance_data, created_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3
+		FROM annotation_versions
+		WHERE annotation_id = $1
+		RETURNING id, version
+	`
+	version := &models.AnnotationVersion{AnnotationID: annotationID, NuanceData: nuance, CreatedAt: createdAt}
+	if err := tx.QueryRowContext(ctx, query, annotationID, nuanceJSON, createdAt).Scan(&version.ID, &version.Version); err != nil {
+		return nil, err
+	}
+
+	updateQuery := `
+		UPDATE annotations
+		SET nuance_data = $2, current_version = $3, updated_at = $4
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, updateQuery, annotationID, nuanceJSON, version.Version, createdAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return version, nil
 }
 
-func (s *postgresDB) GetAnnotationsByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Annotation, error) {
-	offset := (page - 1) * size
+// GetAnnotationVersions returns every AI explanation of an annotation, newest first.
+func (s *postgresDB) GetAnnotationVersions(ctx context.Context, annotationID int64) ([]*models.AnnotationVersion, error) {
 	query := `
-		SELECT id, user_id, scan_id, highlighted_text, context_text, nuance_data, is_bookmarked, created_at
-		FROM annotations
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		SELECT id, annotation_id, version, nuance_data, created_at
+		FROM annotation_versions
+		WHERE annotation_id = $1
+		ORDER BY version DESC
 	`
-	rows, err := s.db.QueryContext(ctx, query, userID, size, offset)
+	rows, err := s.db.QueryContext(ctx, query, annotationID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var annotations []*models.Annotation
+	var versions []*models.AnnotationVersion
 	for rows.Next() {
-		var annotation models.Annotation
-		var scanID sql.NullInt64
-		var contextText sql.NullString
+		var version models.AnnotationVersion
 		var nuanceData []byte
-		var createdAt time.Time
-
-		err := rows.Scan(
-			&annotation.ID,
-			&annotation.UserID,
-			&scanID,
-			&annotation.HighlightedText,
-			&contextText,
+
+		if err := rows.Scan(
+			&version.ID,
+			&version.AnnotationID,
+			&version.Version,
 			&nuanceData,
-			&annotation.IsBookmarked,
-			&createdAt,
-		)
-		if err != nil {
+			&version.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 
-		if scanID.Valid {
-			annotation.ScanID = &scanID.Int64
-		}
-		if contextText.Valid {
-			annotation.ContextText = &contextText.String
-		}
-		if err := json.Unmarshal(nuanceData, &annotation.NuanceData); err != nil {
+		if err := json.Unmarshal(nuanceData, &version.NuanceData); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal nuance_data: %w", err)
 		}
-		annotation.CreatedAt = createdAt
-
-		annotations = append(annotations, &annotation)
+		versions = append(versions, &version)
 	}
 
-	return annotations, rows.Err()
+	return versions, rows.Err()
+}
+
+const annotationColumns = `id, user_id, scan_id, highlighted_text, context_text, nuance_data, is_bookmarked, notes, tags, current_version, audio_hash, created_at, updated_at`
+
+func (s *postgresDB) GetAnnotationByID(ctx context.Context, annotationID int64) (*models.Annotation, error) {
+	query := `SELECT ` + annotationColumns + ` FROM annotations WHERE id = $1`
+	return readAnnotation(s.db.QueryRowContext(ctx, query, annotationID))
 }
 
 func (s *postgresDB) DeleteAnnotation(ctx context.Context, annotationID, userID int64) error {
@@ -395,20 +849,43 @@ func (s *postgresDB) DeleteAnnotation(ctx context.Context, annotationID, userID
 	return nil
 }
 
-func (s *postgresDB) GetAnnotationsByUserIDAndScanID(
-	ctx context.Context,
-	userID, scanID int64,
-	page, size int,
-) ([]*models.Annotation, error) {
-	offset := (page - 1) * size
+// SetAnnotationAudio links an annotation to a stored audio clip.
+func (s *postgresDB) SetAnnotationAudio(ctx context.Context, annotationID int64, audioHash string) error {
+	query := `UPDATE annotations SET audio_hash = $2 WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, annotationID, audioHash)
+	return err
+}
+
+// ForEachAnnotation calls fn with each of the user's annotations, oldest first, reading them
+// from the database as fn consumes them. A scanID of 0 selects every scan. An error from fn
+// stops the iteration and is returned.
+func (s *postgresDB) ForEachAnnotation(ctx context.Context, userID, scanID int64, fn func(*models.Annotation) error) error {
 	query := `
-		SELECT id, user_id, scan_id, highlighted_text, context_text, nuance_data, is_bookmarked, created_at
+		SELECT ` + annotationColumns + `
 		FROM annotations
-		WHERE user_id = $1 AND scan_id = $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4
+		WHERE user_id = $1 AND ($2 = 0 OR scan_id = $2)
+		ORDER BY created_at, id
 	`
-	rows, err := s.db.QueryContext(ctx, query, userID, scanID, size, offset)
+	rows, err := s.db.QueryContext(ctx, query, userID, scanID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		annotation, err := readAnnotation(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(annotation); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *postgresDB) queryAnnotations(ctx context.Context, query string, args ...any) ([]*models.Annotation, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -416,39 +893,58 @@ func (s *postgresDB) GetAnnotationsByUserIDAndScanID(
 
 	var annotations []*models.Annotation
 	for rows.Next() {
-		var annotation models.Annotation
-		var currentScanID sql.NullInt64
-		var contextText sql.NullString
-		var nuanceData []byte
-		var createdAt time.Time
-
-		err := rows.Scan(
-			&annotation.ID,
-			&annotation.UserID,
-			&currentScanID,
-			&annotation.HighlightedText,
-			&contextText,
-			&nuanceData,
-			&annotation.IsBookmarked,
-			&createdAt,
-		)
+		annotation, err := readAnnotation(rows)
 		if err != nil {
 			return nil, err
 		}
+		annotations = append(annotations, annotation)
+	}
 
-		if currentScanID.Valid {
-			annotation.ScanID = &currentScanID.Int64
-		}
-		if contextText.Valid {
-			annotation.ContextText = &contextText.String
-		}
-		if err := json.Unmarshal(nuanceData, &annotation.NuanceData); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal nuance_data: %w", err)
-		}
-		annotation.CreatedAt = createdAt
+	return annotations, rows.Err()
+}
+
+func readAnnotation(row rowScanner) (*models.Annotation, error) {
+	var annotation models.Annotation
+	var scanID sql.NullInt64
+	var contextText sql.NullString
+	var nuanceData []byte
+	var notes sql.NullString
+	var audioHash sql.NullString
 
-		annotations = append(annotations, &annotation)
+	err := row.Scan(
+		&annotation.ID,
+		&annotation.UserID,
+		&scanID,
+		&annotation.HighlightedText,
+		&contextText,
+		&nuanceData,
+		&annotation.IsBookmarked,
+		&notes,
+		pq.Array(&annotation.Tags),
+		&annotation.CurrentVersion,
+		&audioHash,
+		&annotation.CreatedAt,
+		&annotation.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	return annotations, rows.Err()
+	if scanID.Valid {
+		annotation.ScanID = &scanID.Int64
+	}
+	if notes.Valid {
+		annotation.Notes = &notes.String
+	}
+	if audioHash.Valid {
+		annotation.AudioHash = &audioHash.String
+	}
+	if contextText.Valid {
+		annotation.ContextText = &contextText.String
+	}
+	if err := json.Unmarshal(nuanceData, &annotation.NuanceData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal nuance_data: %w", err)
+	}
+
+	return &annotation, nil
 }
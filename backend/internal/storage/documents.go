@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const documentColumns = `id, user_id, title, author, cover_image_url, language, created_at, updated_at`
+
+func (s *postgresDB) CreateDocument(ctx context.Context, document *models.Document) (int64, error) {
+	query := `
+		INSERT INTO documents (user_id, title, author, cover_image_url, language, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING id
+	`
+	err := s.db.QueryRowContext(ctx, query,
+		document.UserID,
+		document.Title,
+		document.Author,
+		document.CoverImageURL,
+		document.Language,
+		document.CreatedAt,
+	).Scan(&document.ID)
+	document.UpdatedAt = document.CreatedAt
+	return document.ID, err
+}
+
+func (s *postgresDB) GetDocumentByID(ctx context.Context, documentID int64) (*models.Document, error) {
+	query := `SELECT ` + documentColumns + ` FROM documents WHERE id = $1`
+	document, err := readDocument(s.db.QueryRowContext(ctx, query, documentID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return document, err
+}
+
+func (s *postgresDB) GetDocumentsByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Document, error) {
+	offset := (page - 1) * size
+	query := `
+		SELECT ` + documentColumns + `
+		FROM documents
+		WHERE user_id = $1
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, size, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []*models.Document
+	for rows.Next() {
+		document, err := readDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, rows.Err()
+}
+
+func (s *postgresDB) UpdateDocument(ctx context.Context, document *models.Document) error {
+	document.UpdatedAt = time.Now()
+	query := `
+		UPDATE documents
+		SET title = $1, author = $2, cover_image_url = $3, language = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7
+	`
+	result, err := s.db.ExecContext(ctx, query,
+		document.Title,
+		document.Author,
+		document.CoverImageURL,
+		document.Language,
+		document.UpdatedAt,
+		document.ID,
+		document.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDocument removes the document but keeps its scans, which become standalone again.
+func (s *postgresDB) DeleteDocument(ctx context.Context, documentID, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The foreign key clears document_id; page_number has to be cleared by hand.
+	if _, err := tx.ExecContext(ctx, `UPDATE scans SET page_number = NULL WHERE document_id = $1 AND user_id = $2`, documentID, userID); err != nil {
+		return fmt.Errorf("failed to detach document pages: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE id = $1 AND user_id = $2`, documentID, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+func (s *postgresDB) GetDocumentPages(ctx context.Context, documentID int64) ([]*models.Scan, error) {
+	query := `
+		SELECT ` + scanColumns + `
+		FROM scans
+		WHERE document_id = $1
+		ORDER BY page_number, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []*models.Scan
+	for rows.Next() {
+		scan, err := readScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, scan)
+	}
+
+	return pages, rows.Err()
+}
+
+// AddDocumentPage appends the scan as the last page of the document and returns its
+// page number. A scan that was a page of another document is moved.
+func (s *postgresDB) AddDocumentPage(ctx context.Context, documentID, scanID int64) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the document row so concurrent appends don't hand out the same page number.
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM documents WHERE id = $1 FOR UPDATE`, documentID); err != nil {
+		return 0, err
+	}
+
+	var previousDocumentID sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT document_id FROM scans WHERE id = $1`, scanID).Scan(&previousDocumentID); err != nil {
+		return 0, err
+	}
+
+	var pageNumber int
+	query := `
+		UPDATE scans
+		SET document_id = $1,
+			page_number = (SELECT COALESCE(MAX(page_number), 0) + 1 FROM scans WHERE document_id = $1)
+		WHERE id = $2
+		RETURNING page_number
+	`
+	if err := tx.QueryRowContext(ctx, query, documentID, scanID).Scan(&pageNumber); err != nil {
+		return 0, err
+	}
+
+	if previousDocumentID.Valid && previousDocumentID.Int64 != documentID {
+		if err := renumberPages(ctx, tx, previousDocumentID.Int64); err != nil {
+			return 0, err
+		}
+	}
+	if err := touchDocument(ctx, tx, documentID); err != nil {
+		return 0, err
+	}
+
+	return pageNumber, tx.Commit()
+}
+
+// RemoveDocumentPage detaches the scan from the document and closes the gap it leaves.
+func (s *postgresDB) RemoveDocumentPage(ctx context.Context, documentID, scanID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE scans
+		SET document_id = NULL, page_number = NULL
+		WHERE id = $1 AND document_id = $2
+	`
+	result, err := tx.ExecContext(ctx, query, scanID, documentID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := renumberPages(ctx, tx, documentID); err != nil {
+		return err
+	}
+	if err := touchDocument(ctx, tx, documentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderDocumentPages assigns page numbers in the order of scanIDs. The caller is
+// expected to pass exactly the document's current pages.
+func (s *postgresDB) ReorderDocumentPages(ctx context.Context, documentID int64, scanIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE scans SET page_number = $1 WHERE id = $2 AND document_id = $3`
+	for i, scanID := range scanIDs {
+		result, err := tx.ExecContext(ctx, query, i+1, scanID, documentID)
+		if err != nil {
+			return err
+		}
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			return fmt.Errorf("scan %d is not a page of document %d: %w", scanID, documentID, sql.ErrNoRows)
+		}
+	}
+
+	if err := touchDocument(ctx, tx, documentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// renumberPages rewrites page numbers as 1..n, keeping the current order.
+func renumberPages(ctx context.Context, tx *sql.Tx, documentID int64) error {
+	query := `
+		UPDATE scans
+		SET page_number = ordered.page_number
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY page_number, id) AS page_number
+			FROM scans
+			WHERE document_id = $1
+		) AS ordered
+		WHERE scans.id = ordered.id
+	`
+	if _, err := tx.ExecContext(ctx, query, documentID); err != nil {
+		return fmt.Errorf("failed to renumber document pages: %w", err)
+	}
+	return nil
+}
+
+func touchDocument(ctx context.Context, tx *sql.Tx, documentID int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE documents SET updated_at = $1 WHERE id = $2`, time.Now(), documentID)
+	return err
+}
+
+func readDocument(row rowScanner) (*models.Document, error) {
+	var document models.Document
+	var author, coverImageURL, language sql.NullString
+
+	err := row.Scan(
+		&document.ID,
+		&document.UserID,
+		&document.Title,
+		&author,
+		&coverImageURL,
+		&language,
+		&document.CreatedAt,
+		&document.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if author.Valid {
+		document.Author = &author.String
+	}
+	if coverImageURL.Valid {
+		document.CoverImageURL = &coverImageURL.String
+	}
+	if language.Valid {
+		document.Language = &language.String
+	}
+
+	return &document, nil
+}
@@ -7,12 +7,16 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type FileStorage interface {
 	SaveImage(scanID string, data []byte, mimeType string) (string, *string, error)
 	OpenImage(path string) ([]byte, error)
 	DeleteImage(path string) error
+	// SaveAudio stores a synthesized clip under name and returns its path.
+	SaveAudio(name string, data []byte, mimeType string) (string, error)
+	OpenAudio(path string) (io.ReadSeekCloser, error)
 }
 
 type localFileStorage struct {
@@ -56,6 +60,58 @@ func (l *localFileStorage) DeleteImage(path string) error {
 	return nil
 }
 
+// SaveAudio writes through a temporary file so a concurrent reader never sees a partial clip.
+func (l *localFileStorage) SaveAudio(name string, data []byte, mimeType string) (string, error) {
+	dir := filepath.Join(l.baseDir, "audio")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create audio directory: %w", err)
+	}
+
+	path := filepath.Join(dir, name+audioExtension(mimeType))
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create audio file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write audio file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write audio file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write audio file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write audio file: %w", err)
+	}
+	return path, nil
+}
+
+// OpenAudio opens a stored clip. The error wraps os.ErrNotExist when the file is gone.
+func (l *localFileStorage) OpenAudio(path string) (io.ReadSeekCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	return f, nil
+}
+
+func audioExtension(mimeType string) string {
+	switch mimeType {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/ogg":
+		return ".ogg"
+	default:
+		return ".bin"
+	}
+}
+
 func getExtensionFromMimeType(mimeType string) string {
 	switch mimeType {
 	case "image/jpeg", "image/jpg":
@@ -69,6 +125,20 @@ func getExtensionFromMimeType(mimeType string) string {
 	}
 }
 
+// MimeTypeFromPath is the inverse of getExtensionFromMimeType for stored images.
+func MimeTypeFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "application/octet-stream"
+	}
+}
+
 func CalculateSHA256(reader io.Reader) (string, error) {
 	hash := sha256.New()
 	if _, err := io.Copy(hash, reader); err != nil {
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateKosakata is returned when a knowledge entry would repeat another entry's term.
+var ErrDuplicateKosakata = errors.New("storage: kosakata already exists")
+
+const knowledgeEntryColumns = `id, kosakata, kana, arti, cara_baca, deskripsi, bidang_pekerjaan, industri, konteks, created_at, updated_at`
+
+// ListKnowledgeEntries returns the whole knowledge base ordered by term.
+func (s *postgresDB) ListKnowledgeEntries(ctx context.Context) ([]*models.KnowledgeEntry, error) {
+	query := `SELECT ` + knowledgeEntryColumns + ` FROM knowledge_entries ORDER BY kosakata, id`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.KnowledgeEntry
+	for rows.Next() {
+		entry, err := readKnowledgeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// KnowledgeEntriesVersion grows whenever an entry is added, edited or removed, so replicas
+// can tell that their copy of the knowledge base is stale without reading it. A trigger on
+// knowledge_entries maintains it.
+func (s *postgresDB) KnowledgeEntriesVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx, `SELECT version FROM knowledge_version`).Scan(&version)
+	return version, err
+}
+
+// CountKnowledgeEntries returns the size of the knowledge base.
+func (s *postgresDB) CountKnowledgeEntries(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM knowledge_entries`).Scan(&count)
+	return count, err
+}
+
+// GetKnowledgeEntry returns the entry with the given ID, or nil when there is none.
+func (s *postgresDB) GetKnowledgeEntry(ctx context.Context, entryID int64) (*models.KnowledgeEntry, error) {
+	query := `SELECT ` + knowledgeEntryColumns + ` FROM knowledge_entries WHERE id = $1`
+	entry, err := readKnowledgeEntry(s.db.QueryRowContext(ctx, query, entryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// CreateKnowledgeEntry stores a new entry. It returns ErrDuplicateKosakata when the term is
+// already in the knowledge base.
+func (s *postgresDB) CreateKnowledgeEntry(ctx context.Context, entry *models.KnowledgeEntry) (int64, error) {
+	query := `
+		INSERT INTO knowledge_entries (kosakata, kana, arti, cara_baca, deskripsi, bidang_pekerjaan, industri, konteks, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING id
+	`
+	err := s.db.QueryRowContext(ctx, query,
+		entry.Kosakata,
+		entry.Kana,
+		entry.Arti,
+		entry.CaraBaca,
+		entry.Deskripsi,
+		pq.Array(models.NonNilTags(entry.BidangPekerjaan)),
+		pq.Array(models.NonNilTags(entry.Industri)),
+		entry.Konteks,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+	if isUniqueViolation(err) {
+		return 0, ErrDuplicateKosakata
+	}
+	entry.UpdatedAt = entry.CreatedAt
+	return entry.ID, err
+}
+
+// UpdateKnowledgeEntry saves every field of the entry. It returns sql.ErrNoRows when the
+// entry does not exist and ErrDuplicateKosakata when the new term belongs to another entry.
+func (s *postgresDB) UpdateKnowledgeEntry(ctx context.Context, entry *models.KnowledgeEntry) error {
+	query := `
+		UPDATE knowledge_entries
+		SET kosakata = $1, kana = $2, arti = $3, cara_baca = $4, deskripsi = $5,
+			bidang_pekerjaan = $6, industri = $7, konteks = $8, updated_at = $9
+		WHERE id = $10
+	`
+	result, err := s.db.ExecContext(ctx, query,
+		entry.Kosakata,
+		entry.Kana,
+		entry.Arti,
+		entry.CaraBaca,
+		entry.Deskripsi,
+		pq.Array(models.NonNilTags(entry.BidangPekerjaan)),
+		pq.Array(models.NonNilTags(entry.Industri)),
+		entry.Konteks,
+		entry.UpdatedAt,
+		entry.ID,
+	)
+	if isUniqueViolation(err) {
+		return ErrDuplicateKosakata
+	}
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteKnowledgeEntry removes the entry. It returns sql.ErrNoRows when there is none.
+func (s *postgresDB) DeleteKnowledgeEntry(ctx context.Context, entryID int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM knowledge_entries WHERE id = $1`, entryID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpsertKnowledgeEntries adds the entries in one transaction, overwriting any entry with the
+// same term. It returns how many terms were new.
+func (s *postgresDB) UpsertKnowledgeEntries(ctx context.Context, entries []*models.KnowledgeEntry, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO knowledge_entries (kosakata, kana, arti, cara_baca, deskripsi, bidang_pekerjaan, industri, konteks, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT (kosakata) DO UPDATE
+		SET kana = EXCLUDED.kana, arti = EXCLUDED.arti, cara_baca = EXCLUDED.cara_baca,
+			deskripsi = EXCLUDED.deskripsi, bidang_pekerjaan = EXCLUDED.bidang_pekerjaan,
+			industri = EXCLUDED.industri, konteks = EXCLUDED.konteks, updated_at = EXCLUDED.updated_at
+		RETURNING xmax = 0
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	created := 0
+	for _, entry := range entries {
+		var inserted bool
+		err := stmt.QueryRowContext(ctx,
+			entry.Kosakata,
+			entry.Kana,
+			entry.Arti,
+			entry.CaraBaca,
+			entry.Deskripsi,
+			pq.Array(models.NonNilTags(entry.BidangPekerjaan)),
+			pq.Array(models.NonNilTags(entry.Industri)),
+			entry.Konteks,
+			now,
+		).Scan(&inserted)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import %q: %w", entry.Kosakata, err)
+		}
+		if inserted {
+			created++
+		}
+	}
+
+	return created, tx.Commit()
+}
+
+func readKnowledgeEntry(row rowScanner) (*models.KnowledgeEntry, error) {
+	var entry models.KnowledgeEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.Kosakata,
+		&entry.Kana,
+		&entry.Arti,
+		&entry.CaraBaca,
+		&entry.Deskripsi,
+		pq.Array(&entry.BidangPekerjaan),
+		pq.Array(&entry.Industri),
+		&entry.Konteks,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate key.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// conditions collects a WHERE clause and its arguments.
+type conditions struct {
+	clauses []string
+	args    []any
+}
+
+// add appends clause, in which every %s is replaced by the placeholder of the next value.
+func (c *conditions) add(clause string, values ...any) {
+	placeholders := make([]any, len(values))
+	for i, value := range values {
+		c.args = append(c.args, value)
+		placeholders[i] = fmt.Sprintf("$%d", len(c.args))
+	}
+	c.clauses = append(c.clauses, fmt.Sprintf(clause, placeholders...))
+}
+
+func (c *conditions) where() string {
+	return strings.Join(c.clauses, " AND ")
+}
+
+// paginate adds page's keyset condition and returns the ORDER BY and LIMIT/OFFSET clauses.
+// byPage orders by page number instead of creation time.
+func (c *conditions) paginate(page Page, byPage bool) string {
+	column, direction, comparison := "created_at", "DESC", "<"
+	if byPage {
+		column = "page_number"
+	}
+	if byPage || page.Oldest {
+		direction, comparison = "ASC", ">"
+	}
+
+	if page.After != nil {
+		var position any = page.After.CreatedAt
+		if byPage {
+			position = page.After.PageNumber
+		}
+		c.add("("+column+", id) "+comparison+" (%s, %s)", position, page.After.ID)
+	}
+
+	c.args = append(c.args, page.Limit)
+	clause := fmt.Sprintf("ORDER BY %s %s, id %s LIMIT $%d", column, direction, direction, len(c.args))
+	if page.After == nil && page.Offset > 0 {
+		c.args = append(c.args, page.Offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(c.args))
+	}
+	return clause
+}
+
+func scanConditions(userID int64, filter ScanFilter) *conditions {
+	c := &conditions{}
+	c.add("user_id = %s", userID)
+	if filter.Status != "" {
+		c.add("status = %s", filter.Status)
+	}
+	if filter.DocumentID > 0 {
+		c.add("document_id = %s", filter.DocumentID)
+	}
+	if filter.Language != "" {
+		c.add("LOWER(detected_language) = LOWER(%s)", filter.Language)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		c.add("created_at >= %s", filter.CreatedFrom)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		c.add("created_at < %s", filter.CreatedBefore)
+	}
+	return c
+}
+
+// GetScansByUserID lists the user's scans newest first, or a document's pages in page order
+// when the filter names a document.
+func (s *postgresDB) GetScansByUserID(ctx context.Context, userID int64, filter ScanFilter, page Page) ([]*models.Scan, error) {
+	c := scanConditions(userID, filter)
+	tail := c.paginate(page, filter.DocumentID > 0)
+	query := `SELECT ` + scanColumns + ` FROM scans WHERE ` + c.where() + ` ` + tail
+
+	rows, err := s.db.QueryContext(ctx, query, c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scans []*models.Scan
+	for rows.Next() {
+		scan, err := readScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+
+	return scans, rows.Err()
+}
+
+func (s *postgresDB) CountScans(ctx context.Context, userID int64, filter ScanFilter) (int, error) {
+	c := scanConditions(userID, filter)
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM scans WHERE `+c.where(), c.args...).Scan(&count)
+	return count, err
+}
+
+func annotationConditions(userID int64, filter AnnotationFilter) *conditions {
+	c := &conditions{}
+	c.add("user_id = %s", userID)
+	if filter.ScanID > 0 {
+		c.add("scan_id = %s", filter.ScanID)
+	}
+	if filter.DocumentID > 0 {
+		c.add("scan_id IN (SELECT id FROM scans WHERE document_id = %s)", filter.DocumentID)
+	}
+	if filter.Language != "" {
+		c.add("nuance_data->>'language' = %s", filter.Language)
+	}
+	if filter.Bookmarked != nil {
+		c.add("is_bookmarked = %s", *filter.Bookmarked)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		c.add("created_at >= %s", filter.CreatedFrom)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		c.add("created_at < %s", filter.CreatedBefore)
+	}
+	return c
+}
+
+// GetAnnotationsByUserID lists the user's annotations newest first.
+func (s *postgresDB) GetAnnotationsByUserID(ctx context.Context, userID int64, filter AnnotationFilter, page Page) ([]*models.Annotation, error) {
+	c := annotationConditions(userID, filter)
+	tail := c.paginate(page, false)
+	query := `SELECT ` + annotationColumns + ` FROM annotations WHERE ` + c.where() + ` ` + tail
+	return s.queryAnnotations(ctx, query, c.args...)
+}
+
+func (s *postgresDB) CountAnnotations(ctx context.Context, userID int64, filter AnnotationFilter) (int, error) {
+	c := annotationConditions(userID, filter)
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM annotations WHERE `+c.where(), c.args...).Scan(&count)
+	return count, err
+}
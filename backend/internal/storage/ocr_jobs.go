@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+func (s *postgresDB) CreateOCRJob(ctx context.Context, job *models.OCRJob) (int64, error) {
+	query := `
+		INSERT INTO ocr_jobs (scan_id, image_path, mime_type, status, max_attempts, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id
+	`
+	err := s.db.QueryRowContext(ctx, query,
+		job.ScanID,
+		job.ImagePath,
+		job.MimeType,
+		models.OCRJobStatusQueued,
+		job.MaxAttempts,
+		job.NextRunAt,
+		job.CreatedAt,
+	).Scan(&job.ID)
+	return job.ID, err
+}
+
+// ClaimOCRJob atomically moves the oldest due queued job to running and returns it.
+// It returns nil when no job is due. SKIP LOCKED lets several workers (or replicas)
+// poll concurrently without handing out the same job twice.
+func (s *postgresDB) ClaimOCRJob(ctx context.Context, now time.Time) (*models.OCRJob, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, attempts = attempts + 1, locked_at = $2, updated_at = $2
+		WHERE id = (
+			SELECT id
+			FROM ocr_jobs
+			WHERE status = $3 AND next_run_at <= $2
+			ORDER BY next_run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, scan_id, image_path, mime_type, status, attempts, max_attempts,
+			last_error, next_run_at, locked_at, created_at, updated_at
+	`
+	var job models.OCRJob
+	var lastError sql.NullString
+	var lockedAt sql.NullTime
+
+	err := s.db.QueryRowContext(ctx, query, models.OCRJobStatusRunning, now, models.OCRJobStatusQueued).Scan(
+		&job.ID,
+		&job.ScanID,
+		&job.ImagePath,
+		&job.MimeType,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&lastError,
+		&job.NextRunAt,
+		&lockedAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lastError.Valid {
+		job.LastError = &lastError.String
+	}
+	if lockedAt.Valid {
+		job.LockedAt = &lockedAt.Time
+	}
+
+	return &job, nil
+}
+
+func (s *postgresDB) CompleteOCRJob(ctx context.Context, jobID int64) error {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, last_error = NULL, locked_at = NULL, updated_at = $2
+		WHERE id = $3
+	`
+	_, err := s.db.ExecContext(ctx, query, models.OCRJobStatusSucceeded, time.Now(), jobID)
+	return err
+}
+
+func (s *postgresDB) RetryOCRJob(ctx context.Context, jobID int64, lastError string, nextRunAt time.Time) error {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, last_error = $2, next_run_at = $3, locked_at = NULL, updated_at = $4
+		WHERE id = $5
+	`
+	_, err := s.db.ExecContext(ctx, query, models.OCRJobStatusQueued, lastError, nextRunAt, time.Now(), jobID)
+	return err
+}
+
+func (s *postgresDB) FailOCRJob(ctx context.Context, jobID int64, lastError string) error {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, last_error = $2, locked_at = NULL, updated_at = $3
+		WHERE id = $4
+	`
+	_, err := s.db.ExecContext(ctx, query, models.OCRJobStatusFailed, lastError, time.Now(), jobID)
+	return err
+}
+
+// RequeueStaleOCRJobs puts running jobs whose lock is older than lockedBefore back
+// in the queue. Those belong to workers that died mid-run (e.g. a process restart).
+func (s *postgresDB) RequeueStaleOCRJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, locked_at = NULL, next_run_at = $2, updated_at = $2
+		WHERE status = $3 AND locked_at < $4
+	`
+	result, err := s.db.ExecContext(ctx, query, models.OCRJobStatusQueued, time.Now(), models.OCRJobStatusRunning, lockedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
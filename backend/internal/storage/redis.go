@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -11,11 +12,21 @@ type RedisClient interface {
 	SetState(ctx context.Context, state, sessionID string, ttl time.Duration) error
 	GetState(ctx context.Context, state string) (string, error)
 	DeleteState(ctx context.Context, state string) error
+	// GetValue returns the value stored at key; ok is false when the key does not exist.
+	GetValue(ctx context.Context, key string) (value string, ok bool, err error)
+	SetValue(ctx context.Context, key, value string, ttl time.Duration) error
+	// RunScript runs a Lua script atomically, loading it into the script cache on first use.
+	RunScript(ctx context.Context, script string, keys []string, args ...any) (any, error)
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe streams messages published on channel until ctx is cancelled,
+	// then closes the returned channel.
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
 	Close() error
 }
 
 type redisClientImpl struct {
-	client *redis.Client
+	client  *redis.Client
+	scripts sync.Map // script source -> *redis.Script
 }
 
 func NewRedisClient(addr string) (RedisClient, error) {
@@ -48,6 +59,65 @@ func (c *redisClientImpl) DeleteState(ctx context.Context, state string) error {
 	return c.client.Del(ctx, key).Err()
 }
 
+func (c *redisClientImpl) GetValue(ctx context.Context, key string) (string, bool, error) {
+	value, err := c.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (c *redisClientImpl) SetValue(ctx context.Context, key, value string, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *redisClientImpl) RunScript(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	cached, ok := c.scripts.Load(script)
+	if !ok {
+		cached, _ = c.scripts.LoadOrStore(script, redis.NewScript(script))
+	}
+	return cached.(*redis.Script).Run(ctx, c.client, keys, args...).Result()
+}
+
+func (c *redisClientImpl) Publish(ctx context.Context, channel, message string) error {
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+func (c *redisClientImpl) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := c.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
 func (c *redisClientImpl) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+const reviewCardColumns = `c.id, c.user_id, c.annotation_id, c.repetitions, c.interval_days, c.ease_factor, c.due_at, c.last_reviewed_at, c.created_at`
+
+// reviewAnnotationColumns are annotationColumns of the annotation joined as a.
+const reviewAnnotationColumns = `a.id, a.user_id, a.scan_id, a.highlighted_text, a.context_text, a.nuance_data, a.is_bookmarked, a.notes, a.tags, a.current_version, a.audio_hash, a.created_at, a.updated_at`
+
+// addReviewCard gives the annotation a new card due at now if it is bookmarked and has none,
+// so cards exist from the moment an annotation is bookmarked.
+func addReviewCard(ctx context.Context, tx *sql.Tx, annotationID int64, now time.Time) error {
+	query := `
+		INSERT INTO review_cards (user_id, annotation_id, due_at, created_at)
+		SELECT user_id, id, $2, $2
+		FROM annotations
+		WHERE id = $1 AND is_bookmarked
+		ON CONFLICT (annotation_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, annotationID, now); err != nil {
+		return fmt.Errorf("failed to create review card: %w", err)
+	}
+	return nil
+}
+
+// GetDueReviewCards returns up to limit cards of bookmarked annotations due at or before now,
+// most overdue first, with their annotations.
+func (s *postgresDB) GetDueReviewCards(ctx context.Context, userID int64, now time.Time, limit int) ([]*models.ReviewCard, error) {
+	query := `
+		SELECT ` + reviewCardColumns + `, ` + reviewAnnotationColumns + `
+		FROM review_cards c
+		JOIN annotations a ON a.id = c.annotation_id
+		WHERE c.user_id = $1 AND a.is_bookmarked AND c.due_at <= $2
+		ORDER BY c.due_at, c.id
+		LIMIT $3
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*models.ReviewCard
+	for rows.Next() {
+		var card models.ReviewCard
+		var lastReviewedAt sql.NullTime
+		annotation, err := readAnnotation(prefixedScanner{rows, []any{
+			&card.ID,
+			&card.UserID,
+			&card.AnnotationID,
+			&card.Repetitions,
+			&card.IntervalDays,
+			&card.EaseFactor,
+			&card.DueAt,
+			&lastReviewedAt,
+			&card.CreatedAt,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		if lastReviewedAt.Valid {
+			card.LastReviewedAt = &lastReviewedAt.Time
+		}
+		card.Annotation = annotation
+		cards = append(cards, &card)
+	}
+	return cards, rows.Err()
+}
+
+// CountReviewCards counts the user's cards of bookmarked annotations, and those due at or
+// before now.
+func (s *postgresDB) CountReviewCards(ctx context.Context, userID int64, now time.Time) (total, due int, err error) {
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE c.due_at <= $2)
+		FROM review_cards c
+		JOIN annotations a ON a.id = c.annotation_id
+		WHERE c.user_id = $1 AND a.is_bookmarked
+	`
+	err = s.db.QueryRowContext(ctx, query, userID, now).Scan(&total, &due)
+	return total, due, err
+}
+
+// GetReviewCard returns the card with the given ID, or nil when there is none.
+func (s *postgresDB) GetReviewCard(ctx context.Context, cardID int64) (*models.ReviewCard, error) {
+	query := `SELECT ` + reviewCardColumns + ` FROM review_cards c WHERE c.id = $1`
+
+	var card models.ReviewCard
+	var lastReviewedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, cardID).Scan(
+		&card.ID,
+		&card.UserID,
+		&card.AnnotationID,
+		&card.Repetitions,
+		&card.IntervalDays,
+		&card.EaseFactor,
+		&card.DueAt,
+		&lastReviewedAt,
+		&card.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastReviewedAt.Valid {
+		card.LastReviewedAt = &lastReviewedAt.Time
+	}
+	return &card, nil
+}
+
+var (
+	// ErrReviewConflict is returned when a card was graded after the caller read it.
+	ErrReviewConflict = errors.New("storage: review card was graded concurrently")
+	// ErrReviewCardNotBookmarked is returned when grading a card whose annotation was unbookmarked.
+	ErrReviewCardNotBookmarked = errors.New("storage: review card's annotation is not bookmarked")
+)
+
+// RecordReview saves a card's new schedule and logs the answer that produced it in the same
+// transaction. It returns sql.ErrNoRows when the card does not exist, ErrReviewConflict when
+// its last_reviewed_at is no longer expectedLastReviewedAt, and ErrReviewCardNotBookmarked
+// when its annotation is not bookmarked.
+func (s *postgresDB) RecordReview(ctx context.Context, card *models.ReviewCard, entry *models.ReviewLog, expectedLastReviewedAt *time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var lastReviewedAt sql.NullTime
+	var bookmarked bool
+	lockQuery := `
+		SELECT c.last_reviewed_at, a.is_bookmarked
+		FROM review_cards c
+		JOIN annotations a ON a.id = c.annotation_id
+		WHERE c.id = $1
+		FOR UPDATE OF c
+	`
+	if err := tx.QueryRowContext(ctx, lockQuery, card.ID).Scan(&lastReviewedAt, &bookmarked); err != nil {
+		return err
+	}
+	if !bookmarked {
+		return ErrReviewCardNotBookmarked
+	}
+	if lastReviewedAt.Valid != (expectedLastReviewedAt != nil) ||
+		(lastReviewedAt.Valid && !lastReviewedAt.Time.Equal(*expectedLastReviewedAt)) {
+		return ErrReviewConflict
+	}
+
+	updateQuery := `
+		UPDATE review_cards
+		SET repetitions = $2, interval_days = $3, ease_factor = $4, due_at = $5, last_reviewed_at = $6
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, updateQuery,
+		card.ID,
+		card.Repetitions,
+		card.IntervalDays,
+		card.EaseFactor,
+		card.DueAt,
+		card.LastReviewedAt,
+	); err != nil {
+		return err
+	}
+
+	logQuery := `
+		INSERT INTO review_logs (card_id, user_id, grade, interval_days, ease_factor, reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	if err := tx.QueryRowContext(ctx, logQuery,
+		entry.CardID,
+		entry.UserID,
+		entry.Grade,
+		entry.IntervalDays,
+		entry.EaseFactor,
+		entry.ReviewedAt,
+	).Scan(&entry.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetReviewStats counts the user's answers by UTC day and grade from since on, oldest day
+// first. Days without reviews are absent.
+func (s *postgresDB) GetReviewStats(ctx context.Context, userID int64, since time.Time) ([]*models.ReviewDayStats, error) {
+	query := `
+		SELECT (reviewed_at AT TIME ZONE 'UTC')::date AS day, grade, COUNT(*)
+		FROM review_logs
+		WHERE user_id = $1 AND reviewed_at >= $2
+		GROUP BY day, grade
+		ORDER BY day
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*models.ReviewDayStats
+	for rows.Next() {
+		var day time.Time
+		var grade string
+		var count int
+		if err := rows.Scan(&day, &grade, &count); err != nil {
+			return nil, err
+		}
+		if len(stats) == 0 || !stats[len(stats)-1].Day.Equal(day) {
+			stats = append(stats, &models.ReviewDayStats{Day: day, Grades: make(map[string]int)})
+		}
+		stats[len(stats)-1].Grades[grade] = count
+	}
+	return stats, rows.Err()
+}
+
+// prefixedScanner scans leading columns into dest before handing the rest of the row to a
+// reader that expects only its own columns.
+type prefixedScanner struct {
+	row  rowScanner
+	dest []any
+}
+
+func (p prefixedScanner) Scan(dest ...any) error {
+	return p.row.Scan(append(p.dest, dest...)...)
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/gemini-hackathon/app/internal/models"
+)
+
+// Search ranks. A hit in the phrase the user saved beats one in its explanation, which beats
+// one in the surrounding sentence or a scanned page. Within a field, closer trigram similarity
+// to the query ranks higher.
+const (
+	searchWeightHighlightedText = 1.0
+	searchWeightNuanceData      = 0.6
+	searchWeightContextText     = 0.5
+	searchWeightFullOCRText     = 0.4
+)
+
+// Search finds the user's scans and annotations containing query, ignoring case, best ranked
+// first. kind limits the search to models.SearchKindScan or models.SearchKindAnnotation; ""
+// searches both. An annotation matching in several fields is returned once, for its best field.
+func (s *postgresDB) Search(ctx context.Context, userID int64, query, kind string, limit int) ([]*models.SearchHit, error) {
+	var branches []string
+	if kind == "" || kind == models.SearchKindAnnotation {
+		matches := []string{
+			annotationSearchBranch(models.SearchFieldHighlightedText, "a.highlighted_text", searchWeightHighlightedText),
+			annotationSearchBranch(models.SearchFieldNuanceData, "annotation_nuance_text(a.nuance_data)", searchWeightNuanceData),
+			annotationSearchBranch(models.SearchFieldContextText, "a.context_text", searchWeightContextText),
+		}
+		branches = append(branches, `
+			SELECT * FROM (
+				SELECT DISTINCT ON (annotation_id) *
+				FROM (`+strings.Join(matches, " UNION ALL ")+`) matches
+				ORDER BY annotation_id, rank DESC
+			) annotation_hits`)
+	}
+	if kind == "" || kind == models.SearchKindScan {
+		branches = append(branches, `
+			SELECT 'scan', s.id, NULL::BIGINT, NULL::TEXT, '`+models.SearchFieldFullOCRText+`', s.full_ocr_text,
+				`+searchRank("s.full_ocr_text", searchWeightFullOCRText)+`, s.created_at
+			FROM scans s
+			WHERE s.user_id = $1 AND s.full_ocr_text ILIKE $3`)
+	}
+
+	sqlQuery := `
+		SELECT kind, scan_id, annotation_id, highlighted_text, field, text, rank, created_at
+		FROM (` + strings.Join(branches, " UNION ALL ") + `) hits (kind, scan_id, annotation_id, highlighted_text, field, text, rank, created_at)
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $4
+	`
+	rows, err := s.db.QueryContext(ctx, sqlQuery, userID, query, likePattern(query), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []*models.SearchHit
+	for rows.Next() {
+		var hit models.SearchHit
+		var scanID, annotationID sql.NullInt64
+		var highlightedText sql.NullString
+		if err := rows.Scan(&hit.Kind, &scanID, &annotationID, &highlightedText, &hit.Field, &hit.Text, &hit.Rank, &hit.CreatedAt); err != nil {
+			return nil, err
+		}
+		if scanID.Valid {
+			hit.ScanID = &scanID.Int64
+		}
+		if annotationID.Valid {
+			hit.AnnotationID = &annotationID.Int64
+		}
+		hit.HighlightedText = highlightedText.String
+		hits = append(hits, &hit)
+	}
+	return hits, rows.Err()
+}
+
+func annotationSearchBranch(field, column string, weight float64) string {
+	return `
+		SELECT 'annotation' AS kind, a.scan_id, a.id AS annotation_id, a.highlighted_text,
+			'` + field + `' AS field, ` + column + ` AS text, ` + searchRank(column, weight) + ` AS rank, a.created_at
+		FROM annotations a
+		WHERE a.user_id = $1 AND ` + column + ` ILIKE $3`
+}
+
+func searchRank(column string, weight float64) string {
+	return strconv.FormatFloat(weight, 'f', -1, 64) + ` + 0.5 * similarity(` + column + `, $2)`
+}
+
+// likePattern matches text containing query, with LIKE wildcards in query taken literally.
+func likePattern(query string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IncrementUsage adds amount to the user's usage of class on day, unless that would take it
+// past limit. A limit of zero or less means no limit. It returns the new count and whether
+// the usage was recorded. A negative amount gives usage back.
+func (s *postgresDB) IncrementUsage(ctx context.Context, userID int64, day time.Time, class string, amount, limit int) (int, bool, error) {
+	if limit > 0 && amount > limit {
+		return 0, false, nil
+	}
+
+	query := `
+		INSERT INTO ai_usage (user_id, day, class, count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, day, class) DO UPDATE
+		SET count = ai_usage.count + EXCLUDED.count
+		WHERE $5 <= 0 OR ai_usage.count + EXCLUDED.count <= $5
+		RETURNING count
+	`
+	var count int
+	err := s.db.QueryRowContext(ctx, query, userID, day.Format(time.DateOnly), class, amount, limit).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+// GetUsage returns the user's usage on day by class. Classes without usage are absent.
+func (s *postgresDB) GetUsage(ctx context.Context, userID int64, day time.Time) (map[string]int, error) {
+	query := `
+		SELECT class, count
+		FROM ai_usage
+		WHERE user_id = $1 AND day = $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, day.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int)
+	for rows.Next() {
+		var class string
+		var count int
+		if err := rows.Scan(&class, &count); err != nil {
+			return nil, err
+		}
+		usage[class] = count
+	}
+	return usage, rows.Err()
+}
@@ -3,32 +3,67 @@ package testutil
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gemini-hackathon/app/internal/models"
+	"github.com/gemini-hackathon/app/internal/storage"
 )
 
 type MockDB struct {
-	users          map[int64]*models.User
-	scans          map[int64]*models.Scan
-	annotations    map[int64]*models.Annotation
-	userByEmail    map[string]*models.User
-	userByProvider map[string]*models.User
-	nextUserID     int64
-	nextScanID     int64
-	nextAnnID      int64
+	users              map[int64]*models.User
+	scans              map[int64]*models.Scan
+	annotations        map[int64]*models.Annotation
+	ocrJobs            map[int64]*models.OCRJob
+	ocrClaims          map[int64]time.Time
+	documents          map[int64]*models.Document
+	furigana           map[int64]*models.ScanFurigana
+	cacheEntries       map[string]mockCacheEntry
+	audioAssets        map[string]*models.AudioAsset
+	audioVariants      map[string]*models.AudioAsset
+	usage              map[string]int
+	ocrRevisions       []*models.ScanOCRRevision
+	annotationEdits    []*models.AnnotationEdit
+	annotationVersions []*models.AnnotationVersion
+	reviewCards        map[int64]*models.ReviewCard
+	reviewLogs         []*models.ReviewLog
+	knowledgeEntries   map[int64]*models.KnowledgeEntry
+	userByEmail        map[string]*models.User
+	userByProvider     map[string]*models.User
+	nextUserID         int64
+	nextScanID         int64
+	nextAnnID          int64
+	nextOCRJobID       int64
+	nextDocumentID     int64
+	nextKnowledgeID    int64
+	knowledgeVersion   int64
 }
 
 func NewMockDB() *MockDB {
 	return &MockDB{
-		users:          make(map[int64]*models.User),
-		scans:          make(map[int64]*models.Scan),
-		annotations:    make(map[int64]*models.Annotation),
-		userByEmail:    make(map[string]*models.User),
-		userByProvider: make(map[string]*models.User),
-		nextUserID:     1,
-		nextScanID:     1,
-		nextAnnID:      1,
+		users:            make(map[int64]*models.User),
+		scans:            make(map[int64]*models.Scan),
+		annotations:      make(map[int64]*models.Annotation),
+		ocrJobs:          make(map[int64]*models.OCRJob),
+		ocrClaims:        make(map[int64]time.Time),
+		documents:        make(map[int64]*models.Document),
+		furigana:         make(map[int64]*models.ScanFurigana),
+		cacheEntries:     make(map[string]mockCacheEntry),
+		audioAssets:      make(map[string]*models.AudioAsset),
+		audioVariants:    make(map[string]*models.AudioAsset),
+		reviewCards:      make(map[int64]*models.ReviewCard),
+		knowledgeEntries: make(map[int64]*models.KnowledgeEntry),
+		usage:            make(map[string]int),
+		userByEmail:      make(map[string]*models.User),
+		userByProvider:   make(map[string]*models.User),
+		nextUserID:       1,
+		nextScanID:       1,
+		nextAnnID:        1,
+		nextOCRJobID:     1,
+		nextDocumentID:   1,
+		nextKnowledgeID:  1,
 	}
 }
 
@@ -61,7 +96,18 @@ func (m *MockDB) UpdateUserLanguage(ctx context.Context, userID int64, language
 	return nil
 }
 
+func (m *MockDB) UpdateUserVoice(ctx context.Context, userID int64, voice string) error {
+	if user, ok := m.users[userID]; ok {
+		user.PreferredVoice = voice
+		user.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
 func (m *MockDB) CreateScan(ctx context.Context, scan *models.Scan) (int64, error) {
+	if scan.Status == "" {
+		scan.Status = models.ScanStatusPending
+	}
 	scan.ID = m.nextScanID
 	m.nextScanID++
 	m.scans[scan.ID] = scan
@@ -72,24 +118,179 @@ func (m *MockDB) GetScanByID(ctx context.Context, scanID int64) (*models.Scan, e
 	return m.scans[scanID], nil
 }
 
-func (m *MockDB) GetScansByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Scan, error) {
+func (m *MockDB) scansMatching(userID int64, filter storage.ScanFilter) []*models.Scan {
 	var result []*models.Scan
 	for _, scan := range m.scans {
-		if scan.UserID == userID {
-			result = append(result, scan)
+		if scan.UserID != userID {
+			continue
+		}
+		if filter.Status != "" && scan.Status != filter.Status {
+			continue
+		}
+		if filter.DocumentID > 0 && (scan.DocumentID == nil || *scan.DocumentID != filter.DocumentID) {
+			continue
+		}
+		if filter.Language != "" && (scan.DetectedLanguage == nil || !strings.EqualFold(*scan.DetectedLanguage, filter.Language)) {
+			continue
 		}
+		if !inCreatedRange(scan.CreatedAt, filter.CreatedFrom, filter.CreatedBefore) {
+			continue
+		}
+		result = append(result, scan)
 	}
-	return result, nil
+	return result
+}
+
+func (m *MockDB) GetScansByUserID(ctx context.Context, userID int64, filter storage.ScanFilter, page storage.Page) ([]*models.Scan, error) {
+	scans := m.scansMatching(userID, filter)
+	return paginate(scans, page, filter.DocumentID > 0, func(scan *models.Scan) storage.Keyset {
+		keyset := storage.Keyset{CreatedAt: scan.CreatedAt, ID: scan.ID}
+		if scan.PageNumber != nil {
+			keyset.PageNumber = *scan.PageNumber
+		}
+		return keyset
+	}), nil
 }
 
-func (m *MockDB) UpdateScanOCR(ctx context.Context, scanID int64, text, language string) error {
+func (m *MockDB) CountScans(ctx context.Context, userID int64, filter storage.ScanFilter) (int, error) {
+	return len(m.scansMatching(userID, filter)), nil
+}
+
+func (m *MockDB) UpdateScanOCR(ctx context.Context, scanID int64, text, language string, layout *models.OCRLayout) error {
 	if scan, ok := m.scans[scanID]; ok {
+		if scan.FullOCRText != nil {
+			m.ocrRevisions = append(m.ocrRevisions, &models.ScanOCRRevision{
+				ID:               int64(len(m.ocrRevisions) + 1),
+				ScanID:           scanID,
+				FullOCRText:      *scan.FullOCRText,
+				DetectedLanguage: scan.DetectedLanguage,
+				CreatedAt:        time.Now(),
+			})
+		}
 		scan.FullOCRText = &text
 		scan.DetectedLanguage = &language
+		scan.OCRLayout = layout
+		scan.Status = models.ScanStatusCompleted
+		scan.FailureReason = nil
+	}
+	return nil
+}
+
+func (m *MockDB) UpdateScanStatus(ctx context.Context, scanID int64, status string, failureReason *string) error {
+	if scan, ok := m.scans[scanID]; ok {
+		scan.Status = status
+		scan.FailureReason = failureReason
+	}
+	return nil
+}
+
+func (m *MockDB) ClaimScanForOCR(ctx context.Context, scanID int64, now, staleBefore time.Time) (bool, error) {
+	scan, ok := m.scans[scanID]
+	if !ok {
+		return false, nil
+	}
+	if claimedAt, ok := m.ocrClaims[scanID]; ok && !claimedAt.Before(staleBefore) {
+		return false, nil
+	}
+	for _, job := range m.ocrJobs {
+		if job.ScanID == scanID && (job.Status == models.OCRJobStatusQueued || job.Status == models.OCRJobStatusRunning) {
+			return false, nil
+		}
+	}
+	m.ocrClaims[scanID] = now
+	scan.Status = models.ScanStatusProcessing
+	scan.FailureReason = nil
+	return true, nil
+}
+
+func (m *MockDB) ReleaseScanOCRClaim(ctx context.Context, scanID int64) error {
+	delete(m.ocrClaims, scanID)
+	return nil
+}
+
+// SetScanOCRClaim records a re-run claim taken at claimedAt, such as one left by a process
+// that died mid-run.
+func (m *MockDB) SetScanOCRClaim(scanID int64, claimedAt time.Time) {
+	m.ocrClaims[scanID] = claimedAt
+}
+
+func (m *MockDB) GetScanOCRRevisions(ctx context.Context, scanID int64) ([]*models.ScanOCRRevision, error) {
+	var result []*models.ScanOCRRevision
+	for i := len(m.ocrRevisions) - 1; i >= 0; i-- {
+		if m.ocrRevisions[i].ScanID == scanID {
+			result = append(result, m.ocrRevisions[i])
+		}
+	}
+	return result, nil
+}
+
+func (m *MockDB) GetScanFurigana(ctx context.Context, scanID int64) (*models.ScanFurigana, error) {
+	return m.furigana[scanID], nil
+}
+
+func (m *MockDB) SaveScanFurigana(ctx context.Context, furigana *models.ScanFurigana) error {
+	m.furigana[furigana.ScanID] = furigana
+	return nil
+}
+
+type mockCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (m *MockDB) GetAnnotationCacheEntry(ctx context.Context, key string, now time.Time) ([]byte, error) {
+	entry, ok := m.cacheEntries[key]
+	if !ok || !entry.expiresAt.After(now) {
+		return nil, nil
 	}
+	return entry.value, nil
+}
+
+func (m *MockDB) SaveAnnotationCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	m.cacheEntries[key] = mockCacheEntry{value: value, expiresAt: expiresAt}
 	return nil
 }
 
+func (m *MockDB) DeleteExpiredAnnotationCacheEntries(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for key, entry := range m.cacheEntries {
+		if !entry.expiresAt.After(now) {
+			delete(m.cacheEntries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// AnnotationCacheEntries returns the number of stored annotation cache entries.
+func (m *MockDB) AnnotationCacheEntries() int {
+	return len(m.cacheEntries)
+}
+
+func usageKey(userID int64, day time.Time, class string) string {
+	return fmt.Sprintf("%d/%s/%s", userID, day.Format(time.DateOnly), class)
+}
+
+func (m *MockDB) IncrementUsage(ctx context.Context, userID int64, day time.Time, class string, amount, limit int) (int, bool, error) {
+	key := usageKey(userID, day, class)
+	if limit > 0 && m.usage[key]+amount > limit {
+		return 0, false, nil
+	}
+	m.usage[key] += amount
+	return m.usage[key], true, nil
+}
+
+func (m *MockDB) GetUsage(ctx context.Context, userID int64, day time.Time) (map[string]int, error) {
+	usage := make(map[string]int)
+	prefix := fmt.Sprintf("%d/%s/", userID, day.Format(time.DateOnly))
+	for key, count := range m.usage {
+		if class, ok := strings.CutPrefix(key, prefix); ok {
+			usage[class] = count
+		}
+	}
+	return usage, nil
+}
+
 func (m *MockDB) UpdateScanImageURL(ctx context.Context, scanID int64, imageURL string) error {
 	if scan, ok := m.scans[scanID]; ok {
 		scan.ImageURL = imageURL
@@ -105,25 +306,264 @@ func (m *MockDB) DeleteScan(ctx context.Context, scanID, userID int64) error {
 	return sql.ErrNoRows
 }
 
+func (m *MockDB) CreateDocument(ctx context.Context, document *models.Document) (int64, error) {
+	document.ID = m.nextDocumentID
+	m.nextDocumentID++
+	document.UpdatedAt = document.CreatedAt
+	m.documents[document.ID] = document
+	return document.ID, nil
+}
+
+func (m *MockDB) GetDocumentByID(ctx context.Context, documentID int64) (*models.Document, error) {
+	return m.documents[documentID], nil
+}
+
+func (m *MockDB) GetDocumentsByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Document, error) {
+	var result []*models.Document
+	for _, document := range m.documents {
+		if document.UserID == userID {
+			result = append(result, document)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+	return result, nil
+}
+
+func (m *MockDB) UpdateDocument(ctx context.Context, document *models.Document) error {
+	if existing, ok := m.documents[document.ID]; ok && existing.UserID == document.UserID {
+		document.UpdatedAt = time.Now()
+		m.documents[document.ID] = document
+		return nil
+	}
+	return sql.ErrNoRows
+}
+
+func (m *MockDB) DeleteDocument(ctx context.Context, documentID, userID int64) error {
+	document, ok := m.documents[documentID]
+	if !ok || document.UserID != userID {
+		return sql.ErrNoRows
+	}
+	for _, scan := range m.documentPages(documentID) {
+		scan.DocumentID = nil
+		scan.PageNumber = nil
+	}
+	delete(m.documents, documentID)
+	return nil
+}
+
+func (m *MockDB) GetDocumentPages(ctx context.Context, documentID int64) ([]*models.Scan, error) {
+	return m.documentPages(documentID), nil
+}
+
+func (m *MockDB) AddDocumentPage(ctx context.Context, documentID, scanID int64) (int, error) {
+	scan, ok := m.scans[scanID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	previous := scan.DocumentID
+
+	pageNumber := len(m.documentPages(documentID)) + 1
+	scan.DocumentID = &documentID
+	scan.PageNumber = &pageNumber
+
+	if previous != nil && *previous != documentID {
+		m.renumberPages(*previous)
+	}
+	return pageNumber, nil
+}
+
+func (m *MockDB) RemoveDocumentPage(ctx context.Context, documentID, scanID int64) error {
+	scan, ok := m.scans[scanID]
+	if !ok || scan.DocumentID == nil || *scan.DocumentID != documentID {
+		return sql.ErrNoRows
+	}
+	scan.DocumentID = nil
+	scan.PageNumber = nil
+	m.renumberPages(documentID)
+	return nil
+}
+
+func (m *MockDB) ReorderDocumentPages(ctx context.Context, documentID int64, scanIDs []int64) error {
+	for i, scanID := range scanIDs {
+		scan, ok := m.scans[scanID]
+		if !ok || scan.DocumentID == nil || *scan.DocumentID != documentID {
+			return sql.ErrNoRows
+		}
+		pageNumber := i + 1
+		scan.PageNumber = &pageNumber
+	}
+	return nil
+}
+
+// documentPages returns the scans of a document in page order.
+func (m *MockDB) documentPages(documentID int64) []*models.Scan {
+	var pages []*models.Scan
+	for _, scan := range m.scans {
+		if scan.DocumentID != nil && *scan.DocumentID == documentID {
+			pages = append(pages, scan)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if *pages[i].PageNumber == *pages[j].PageNumber {
+			return pages[i].ID < pages[j].ID
+		}
+		return *pages[i].PageNumber < *pages[j].PageNumber
+	})
+	return pages
+}
+
+func (m *MockDB) renumberPages(documentID int64) {
+	for i, scan := range m.documentPages(documentID) {
+		pageNumber := i + 1
+		scan.PageNumber = &pageNumber
+	}
+}
+
 func (m *MockDB) CreateAnnotation(ctx context.Context, annotation *models.Annotation) (int64, error) {
 	annotation.ID = m.nextAnnID
 	m.nextAnnID++
+	annotation.CurrentVersion = 1
+	annotation.UpdatedAt = annotation.CreatedAt
 	m.annotations[annotation.ID] = annotation
+	m.addAnnotationVersion(annotation.ID, annotation.NuanceData, annotation.CreatedAt)
+	m.addReviewCard(annotation, annotation.CreatedAt)
 	return annotation.ID, nil
 }
 
+func (m *MockDB) AddAnnotationVersion(ctx context.Context, annotationID int64, nuance models.NuanceData, createdAt time.Time) (*models.AnnotationVersion, error) {
+	current, ok := m.annotations[annotationID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	version := m.addAnnotationVersion(annotationID, nuance, createdAt)
+
+	m.annotationEdits = append(m.annotationEdits, &models.AnnotationEdit{
+		ID:           int64(len(m.annotationEdits) + 1),
+		AnnotationID: current.ID,
+		NuanceData:   current.NuanceData,
+		Notes:        current.Notes,
+		Tags:         current.Tags,
+		IsBookmarked: current.IsBookmarked,
+		CreatedAt:    createdAt,
+	})
+	updated := *current
+	updated.NuanceData = nuance
+	updated.CurrentVersion = version.Version
+	updated.UpdatedAt = createdAt
+	m.annotations[annotationID] = &updated
+	return version, nil
+}
+
+func (m *MockDB) addAnnotationVersion(annotationID int64, nuance models.NuanceData, createdAt time.Time) *models.AnnotationVersion {
+	version := &models.AnnotationVersion{
+		ID:           int64(len(m.annotationVersions) + 1),
+		AnnotationID: annotationID,
+		Version:      1,
+		NuanceData:   nuance,
+		CreatedAt:    createdAt,
+	}
+	for _, v := range m.annotationVersions {
+		if v.AnnotationID == annotationID && v.Version >= version.Version {
+			version.Version = v.Version + 1
+		}
+	}
+	m.annotationVersions = append(m.annotationVersions, version)
+	return version
+}
+
+func (m *MockDB) GetAnnotationVersions(ctx context.Context, annotationID int64) ([]*models.AnnotationVersion, error) {
+	var versions []*models.AnnotationVersion
+	for i := len(m.annotationVersions) - 1; i >= 0; i-- {
+		if m.annotationVersions[i].AnnotationID == annotationID {
+			versions = append(versions, m.annotationVersions[i])
+		}
+	}
+	return versions, nil
+}
+
+func (m *MockDB) UpdateAnnotation(ctx context.Context, annotation *models.Annotation, expectedUpdatedAt time.Time) error {
+	current, ok := m.annotations[annotation.ID]
+	if !ok || current.UserID != annotation.UserID {
+		return sql.ErrNoRows
+	}
+	if !current.UpdatedAt.Equal(expectedUpdatedAt) {
+		return storage.ErrAnnotationConflict
+	}
+	m.annotationEdits = append(m.annotationEdits, &models.AnnotationEdit{
+		ID:           int64(len(m.annotationEdits) + 1),
+		AnnotationID: current.ID,
+		NuanceData:   current.NuanceData,
+		Notes:        current.Notes,
+		Tags:         current.Tags,
+		IsBookmarked: current.IsBookmarked,
+		CreatedAt:    annotation.UpdatedAt,
+	})
+	updated := *current
+	updated.NuanceData = annotation.NuanceData
+	updated.Notes = annotation.Notes
+	updated.Tags = annotation.Tags
+	updated.IsBookmarked = annotation.IsBookmarked
+	updated.CurrentVersion = annotation.CurrentVersion
+	updated.UpdatedAt = annotation.UpdatedAt
+	m.annotations[annotation.ID] = &updated
+	m.addReviewCard(&updated, annotation.UpdatedAt)
+	return nil
+}
+
+func (m *MockDB) GetAnnotationEdits(ctx context.Context, annotationID int64) ([]*models.AnnotationEdit, error) {
+	var edits []*models.AnnotationEdit
+	for i := len(m.annotationEdits) - 1; i >= 0; i-- {
+		if m.annotationEdits[i].AnnotationID == annotationID {
+			edits = append(edits, m.annotationEdits[i])
+		}
+	}
+	return edits, nil
+}
+
 func (m *MockDB) GetAnnotationByID(ctx context.Context, annotationID int64) (*models.Annotation, error) {
 	return m.annotations[annotationID], nil
 }
 
-func (m *MockDB) GetAnnotationsByUserID(ctx context.Context, userID int64, page, size int) ([]*models.Annotation, error) {
+func (m *MockDB) annotationsMatching(userID int64, filter storage.AnnotationFilter) []*models.Annotation {
 	var result []*models.Annotation
 	for _, ann := range m.annotations {
-		if ann.UserID == userID {
-			result = append(result, ann)
+		if ann.UserID != userID {
+			continue
+		}
+		if filter.ScanID > 0 && (ann.ScanID == nil || *ann.ScanID != filter.ScanID) {
+			continue
+		}
+		if filter.DocumentID > 0 {
+			if ann.ScanID == nil {
+				continue
+			}
+			if scan, ok := m.scans[*ann.ScanID]; !ok || scan.DocumentID == nil || *scan.DocumentID != filter.DocumentID {
+				continue
+			}
+		}
+		if filter.Language != "" && ann.NuanceData.Language != filter.Language {
+			continue
+		}
+		if filter.Bookmarked != nil && ann.IsBookmarked != *filter.Bookmarked {
+			continue
+		}
+		if !inCreatedRange(ann.CreatedAt, filter.CreatedFrom, filter.CreatedBefore) {
+			continue
 		}
+		result = append(result, ann)
 	}
-	return result, nil
+	return result
+}
+
+func (m *MockDB) GetAnnotationsByUserID(ctx context.Context, userID int64, filter storage.AnnotationFilter, page storage.Page) ([]*models.Annotation, error) {
+	annotations := m.annotationsMatching(userID, filter)
+	return paginate(annotations, page, false, func(ann *models.Annotation) storage.Keyset {
+		return storage.Keyset{CreatedAt: ann.CreatedAt, ID: ann.ID}
+	}), nil
+}
+
+func (m *MockDB) CountAnnotations(ctx context.Context, userID int64, filter storage.AnnotationFilter) (int, error) {
+	return len(m.annotationsMatching(userID, filter)), nil
 }
 
 func (m *MockDB) DeleteAnnotation(ctx context.Context, annotationID, userID int64) error {
@@ -134,19 +574,443 @@ func (m *MockDB) DeleteAnnotation(ctx context.Context, annotationID, userID int6
 	return sql.ErrNoRows
 }
 
-func (m *MockDB) GetAnnotationsByUserIDAndScanID(
-	ctx context.Context,
-	userID, scanID int64,
-	page, size int,
-) ([]*models.Annotation, error) {
-	var result []*models.Annotation
+func (m *MockDB) SetAnnotationAudio(ctx context.Context, annotationID int64, audioHash string) error {
+	if annotation, ok := m.annotations[annotationID]; ok {
+		annotation.AudioHash = &audioHash
+	}
+	return nil
+}
+
+func (m *MockDB) GetAudioAsset(ctx context.Context, hash string) (*models.AudioAsset, error) {
+	return m.audioAssets[hash], nil
+}
+
+func (m *MockDB) SaveAudioAsset(ctx context.Context, asset *models.AudioAsset) error {
+	m.audioAssets[asset.Hash] = asset
+	return nil
+}
+
+func (m *MockDB) GetAudioVariant(ctx context.Context, hash, format string) (*models.AudioAsset, error) {
+	return m.audioVariants[hash+"/"+format], nil
+}
+
+func (m *MockDB) SaveAudioVariant(ctx context.Context, format string, variant *models.AudioAsset) error {
+	m.audioVariants[variant.Hash+"/"+format] = variant
+	return nil
+}
+
+func (m *MockDB) ForEachAnnotation(ctx context.Context, userID, scanID int64, fn func(*models.Annotation) error) error {
+	var annotations []*models.Annotation
 	for _, ann := range m.annotations {
-		if ann.UserID != userID || ann.ScanID == nil {
+		if ann.UserID != userID || (scanID != 0 && (ann.ScanID == nil || *ann.ScanID != scanID)) {
 			continue
 		}
-		if *ann.ScanID == scanID {
-			result = append(result, ann)
+		annotations = append(annotations, ann)
+	}
+	sort.Slice(annotations, func(i, j int) bool {
+		if !annotations[i].CreatedAt.Equal(annotations[j].CreatedAt) {
+			return annotations[i].CreatedAt.Before(annotations[j].CreatedAt)
+		}
+		return annotations[i].ID < annotations[j].ID
+	})
+	for _, ann := range annotations {
+		if err := fn(ann); err != nil {
+			return err
 		}
 	}
-	return result, nil
+	return nil
+}
+
+func (m *MockDB) CreateOCRJob(ctx context.Context, job *models.OCRJob) (int64, error) {
+	job.ID = m.nextOCRJobID
+	m.nextOCRJobID++
+	job.Status = models.OCRJobStatusQueued
+	job.UpdatedAt = job.CreatedAt
+	m.ocrJobs[job.ID] = job
+	return job.ID, nil
+}
+
+func (m *MockDB) ClaimOCRJob(ctx context.Context, now time.Time) (*models.OCRJob, error) {
+	var due []*models.OCRJob
+	for _, job := range m.ocrJobs {
+		if job.Status == models.OCRJobStatusQueued && !job.NextRunAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].NextRunAt.Equal(due[j].NextRunAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].NextRunAt.Before(due[j].NextRunAt)
+	})
+
+	job := due[0]
+	job.Status = models.OCRJobStatusRunning
+	job.Attempts++
+	job.LockedAt = &now
+	job.UpdatedAt = now
+	claimed := *job
+	return &claimed, nil
+}
+
+func (m *MockDB) CompleteOCRJob(ctx context.Context, jobID int64) error {
+	if job, ok := m.ocrJobs[jobID]; ok {
+		job.Status = models.OCRJobStatusSucceeded
+		job.LastError = nil
+		job.LockedAt = nil
+	}
+	return nil
+}
+
+func (m *MockDB) RetryOCRJob(ctx context.Context, jobID int64, lastError string, nextRunAt time.Time) error {
+	if job, ok := m.ocrJobs[jobID]; ok {
+		job.Status = models.OCRJobStatusQueued
+		job.LastError = &lastError
+		job.NextRunAt = nextRunAt
+		job.LockedAt = nil
+	}
+	return nil
+}
+
+func (m *MockDB) FailOCRJob(ctx context.Context, jobID int64, lastError string) error {
+	if job, ok := m.ocrJobs[jobID]; ok {
+		job.Status = models.OCRJobStatusFailed
+		job.LastError = &lastError
+		job.LockedAt = nil
+	}
+	return nil
+}
+
+func (m *MockDB) RequeueStaleOCRJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	var count int64
+	for _, job := range m.ocrJobs {
+		if job.Status == models.OCRJobStatusRunning && job.LockedAt != nil && job.LockedAt.Before(lockedBefore) {
+			job.Status = models.OCRJobStatusQueued
+			job.LockedAt = nil
+			count++
+		}
+	}
+	return count, nil
+}
+
+// OCRJobs returns every OCR job recorded by the mock, ordered by ID.
+func (m *MockDB) OCRJobs() []*models.OCRJob {
+	jobs := make([]*models.OCRJob, 0, len(m.ocrJobs))
+	for _, job := range m.ocrJobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// addReviewCard gives a bookmarked annotation without a card a new card due at now.
+func (m *MockDB) addReviewCard(ann *models.Annotation, now time.Time) {
+	if !ann.IsBookmarked {
+		return
+	}
+	for _, card := range m.reviewCards {
+		if card.AnnotationID == ann.ID {
+			return
+		}
+	}
+	card := &models.ReviewCard{
+		ID:           int64(len(m.reviewCards) + 1),
+		UserID:       ann.UserID,
+		AnnotationID: ann.ID,
+		EaseFactor:   2.5,
+		DueAt:        now,
+		CreatedAt:    now,
+	}
+	m.reviewCards[card.ID] = card
+}
+
+// reviewCardsOf returns the user's cards of bookmarked annotations, or only those due at or
+// before now when due is set, most overdue first.
+func (m *MockDB) reviewCardsOf(userID int64, now time.Time, due bool) []*models.ReviewCard {
+	var cards []*models.ReviewCard
+	for _, card := range m.reviewCards {
+		ann, ok := m.annotations[card.AnnotationID]
+		if card.UserID != userID || !ok || !ann.IsBookmarked || (due && card.DueAt.After(now)) {
+			continue
+		}
+		c := *card
+		c.Annotation = ann
+		cards = append(cards, &c)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if !cards[i].DueAt.Equal(cards[j].DueAt) {
+			return cards[i].DueAt.Before(cards[j].DueAt)
+		}
+		return cards[i].ID < cards[j].ID
+	})
+	return cards
+}
+
+func (m *MockDB) GetDueReviewCards(ctx context.Context, userID int64, now time.Time, limit int) ([]*models.ReviewCard, error) {
+	cards := m.reviewCardsOf(userID, now, true)
+	if len(cards) > limit {
+		cards = cards[:limit]
+	}
+	return cards, nil
+}
+
+func (m *MockDB) CountReviewCards(ctx context.Context, userID int64, now time.Time) (int, int, error) {
+	return len(m.reviewCardsOf(userID, now, false)), len(m.reviewCardsOf(userID, now, true)), nil
+}
+
+func (m *MockDB) GetReviewCard(ctx context.Context, cardID int64) (*models.ReviewCard, error) {
+	card, ok := m.reviewCards[cardID]
+	if !ok {
+		return nil, nil
+	}
+	c := *card
+	return &c, nil
+}
+
+func (m *MockDB) RecordReview(ctx context.Context, card *models.ReviewCard, entry *models.ReviewLog, expectedLastReviewedAt *time.Time) error {
+	current, ok := m.reviewCards[card.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if annotation := m.annotations[current.AnnotationID]; annotation == nil || !annotation.IsBookmarked {
+		return storage.ErrReviewCardNotBookmarked
+	}
+	if (current.LastReviewedAt == nil) != (expectedLastReviewedAt == nil) ||
+		(current.LastReviewedAt != nil && !current.LastReviewedAt.Equal(*expectedLastReviewedAt)) {
+		return storage.ErrReviewConflict
+	}
+	c := *card
+	c.Annotation = nil
+	m.reviewCards[card.ID] = &c
+	entry.ID = int64(len(m.reviewLogs) + 1)
+	m.reviewLogs = append(m.reviewLogs, entry)
+	return nil
+}
+
+func (m *MockDB) GetReviewStats(ctx context.Context, userID int64, since time.Time) ([]*models.ReviewDayStats, error) {
+	byDay := make(map[time.Time]*models.ReviewDayStats)
+	for _, entry := range m.reviewLogs {
+		if entry.UserID != userID || entry.ReviewedAt.Before(since) {
+			continue
+		}
+		day := entry.ReviewedAt.UTC().Truncate(24 * time.Hour)
+		if byDay[day] == nil {
+			byDay[day] = &models.ReviewDayStats{Day: day, Grades: make(map[string]int)}
+		}
+		byDay[day].Grades[entry.Grade]++
+	}
+
+	stats := make([]*models.ReviewDayStats, 0, len(byDay))
+	for _, day := range byDay {
+		stats = append(stats, day)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Day.Before(stats[j].Day) })
+	return stats, nil
+}
+
+// Search matches substrings ignoring case and ranks by field alone, without the trigram
+// similarity Postgres adds.
+func (m *MockDB) Search(ctx context.Context, userID int64, query, kind string, limit int) ([]*models.SearchHit, error) {
+	query = strings.ToLower(query)
+	contains := func(text string) bool { return strings.Contains(strings.ToLower(text), query) }
+
+	var hits []*models.SearchHit
+	if kind == "" || kind == models.SearchKindAnnotation {
+		for _, ann := range m.annotations {
+			if ann.UserID != userID {
+				continue
+			}
+			nuance := strings.Join([]string{
+				ann.NuanceData.Meaning,
+				ann.NuanceData.UsageExample,
+				ann.NuanceData.UsageTiming,
+				ann.NuanceData.WordBreakdown,
+				ann.NuanceData.AlternativeMeaning,
+			}, "\n")
+			contextText := ""
+			if ann.ContextText != nil {
+				contextText = *ann.ContextText
+			}
+			for _, f := range []struct {
+				field string
+				text  string
+				rank  float64
+			}{
+				{models.SearchFieldHighlightedText, ann.HighlightedText, 1.0},
+				{models.SearchFieldNuanceData, nuance, 0.6},
+				{models.SearchFieldContextText, contextText, 0.5},
+			} {
+				if contains(f.text) {
+					id := ann.ID
+					hits = append(hits, &models.SearchHit{
+						Kind:            models.SearchKindAnnotation,
+						ScanID:          ann.ScanID,
+						AnnotationID:    &id,
+						HighlightedText: ann.HighlightedText,
+						Field:           f.field,
+						Text:            f.text,
+						Rank:            f.rank,
+						CreatedAt:       ann.CreatedAt,
+					})
+					break
+				}
+			}
+		}
+	}
+	if kind == "" || kind == models.SearchKindScan {
+		for _, scan := range m.scans {
+			if scan.UserID != userID || scan.FullOCRText == nil || !contains(*scan.FullOCRText) {
+				continue
+			}
+			id := scan.ID
+			hits = append(hits, &models.SearchHit{
+				Kind:      models.SearchKindScan,
+				ScanID:    &id,
+				Field:     models.SearchFieldFullOCRText,
+				Text:      *scan.FullOCRText,
+				Rank:      0.4,
+				CreatedAt: scan.CreatedAt,
+			})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].CreatedAt.After(hits[j].CreatedAt)
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func inCreatedRange(createdAt, from, before time.Time) bool {
+	return (from.IsZero() || !createdAt.Before(from)) && (before.IsZero() || createdAt.Before(before))
+}
+
+// paginate orders items the way Postgres does for the page and applies its keyset or offset
+// and limit.
+func paginate[T any](items []T, page storage.Page, byPage bool, keyOf func(T) storage.Keyset) []T {
+	less := func(a, b storage.Keyset) bool {
+		if byPage {
+			if a.PageNumber != b.PageNumber {
+				return a.PageNumber < b.PageNumber
+			}
+		} else if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	}
+	ascending := byPage || page.Oldest
+	before := func(a, b storage.Keyset) bool {
+		if ascending {
+			return less(a, b)
+		}
+		return less(b, a)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return before(keyOf(items[i]), keyOf(items[j])) })
+
+	start := min(page.Offset, len(items))
+	if page.After != nil {
+		start = sort.Search(len(items), func(i int) bool { return before(*page.After, keyOf(items[i])) })
+	}
+	items = items[start:]
+	if page.Limit > 0 && len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+func (m *MockDB) ListKnowledgeEntries(ctx context.Context) ([]*models.KnowledgeEntry, error) {
+	var entries []*models.KnowledgeEntry
+	for _, entry := range m.knowledgeEntries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kosakata != entries[j].Kosakata {
+			return entries[i].Kosakata < entries[j].Kosakata
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+func (m *MockDB) KnowledgeEntriesVersion(ctx context.Context) (int64, error) {
+	return m.knowledgeVersion, nil
+}
+
+func (m *MockDB) CountKnowledgeEntries(ctx context.Context) (int, error) {
+	return len(m.knowledgeEntries), nil
+}
+
+func (m *MockDB) GetKnowledgeEntry(ctx context.Context, entryID int64) (*models.KnowledgeEntry, error) {
+	return m.knowledgeEntries[entryID], nil
+}
+
+func (m *MockDB) knowledgeEntryByKosakata(kosakata string) *models.KnowledgeEntry {
+	for _, entry := range m.knowledgeEntries {
+		if entry.Kosakata == kosakata {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (m *MockDB) CreateKnowledgeEntry(ctx context.Context, entry *models.KnowledgeEntry) (int64, error) {
+	if m.knowledgeEntryByKosakata(entry.Kosakata) != nil {
+		return 0, storage.ErrDuplicateKosakata
+	}
+	entry.ID = m.nextKnowledgeID
+	entry.UpdatedAt = entry.CreatedAt
+	m.nextKnowledgeID++
+	m.knowledgeEntries[entry.ID] = entry
+	m.knowledgeVersion++
+	return entry.ID, nil
+}
+
+func (m *MockDB) UpdateKnowledgeEntry(ctx context.Context, entry *models.KnowledgeEntry) error {
+	if _, ok := m.knowledgeEntries[entry.ID]; !ok {
+		return sql.ErrNoRows
+	}
+	if other := m.knowledgeEntryByKosakata(entry.Kosakata); other != nil && other.ID != entry.ID {
+		return storage.ErrDuplicateKosakata
+	}
+	m.knowledgeEntries[entry.ID] = entry
+	m.knowledgeVersion++
+	return nil
+}
+
+func (m *MockDB) DeleteKnowledgeEntry(ctx context.Context, entryID int64) error {
+	if _, ok := m.knowledgeEntries[entryID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.knowledgeEntries, entryID)
+	m.knowledgeVersion++
+	return nil
+}
+
+func (m *MockDB) UpsertKnowledgeEntries(ctx context.Context, entries []*models.KnowledgeEntry, now time.Time) (int, error) {
+	created := 0
+	for _, entry := range entries {
+		stored := *entry
+		stored.UpdatedAt = now
+		if existing := m.knowledgeEntryByKosakata(entry.Kosakata); existing != nil {
+			stored.ID = existing.ID
+			stored.CreatedAt = existing.CreatedAt
+		} else {
+			stored.ID = m.nextKnowledgeID
+			stored.CreatedAt = now
+			m.nextKnowledgeID++
+			created++
+		}
+		m.knowledgeEntries[stored.ID] = &stored
+	}
+	m.knowledgeVersion++
+	return created, nil
 }
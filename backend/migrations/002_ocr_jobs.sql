@@ -0,0 +1,22 @@
+-- Migration 002: Durable OCR job queue
+-- OCR jobs: one row per OCR run for a scan, claimed by workers with FOR UPDATE SKIP LOCKED
+
+CREATE TABLE ocr_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    scan_id BIGINT NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    image_path TEXT NOT NULL,
+    mime_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    last_error TEXT,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for claiming due jobs and recovering stale ones
+
+CREATE INDEX idx_ocr_jobs_status_next_run_at ON ocr_jobs(status, next_run_at);
+CREATE INDEX idx_ocr_jobs_scan_id ON ocr_jobs(scan_id);
@@ -0,0 +1,14 @@
+-- Migration 003: Scan processing status
+-- status: pending -> processing -> completed | failed; failure_reason is set for failed scans
+
+ALTER TABLE scans ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'pending';
+ALTER TABLE scans ADD COLUMN failure_reason TEXT;
+
+-- Backfill: scans with OCR text are done; scans without it predate the job queue and will never finish
+
+UPDATE scans SET status = 'completed' WHERE full_ocr_text IS NOT NULL;
+UPDATE scans SET status = 'failed', failure_reason = 'OCR did not complete'
+WHERE full_ocr_text IS NULL
+  AND NOT EXISTS (SELECT 1 FROM ocr_jobs WHERE ocr_jobs.scan_id = scans.id);
+
+CREATE INDEX idx_scans_user_id_status ON scans(user_id, status);
@@ -0,0 +1,12 @@
+-- Migration 004: OCR revisions
+-- Previous OCR output of a scan, archived whenever OCR is re-run and the text is replaced
+
+CREATE TABLE scan_ocr_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    scan_id BIGINT NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    full_ocr_text TEXT NOT NULL,
+    detected_language VARCHAR(10),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_scan_ocr_revisions_scan_id ON scan_ocr_revisions(scan_id);
@@ -0,0 +1,21 @@
+-- Migration 005: Documents
+-- A document (book, chapter, handout) groups scans as ordered pages; page_number is 1-based
+
+CREATE TABLE documents (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    author TEXT,
+    cover_image_url TEXT,
+    language VARCHAR(10),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Deleting a document keeps its scans; they simply stop being pages
+
+ALTER TABLE scans ADD COLUMN document_id BIGINT REFERENCES documents(id) ON DELETE SET NULL;
+ALTER TABLE scans ADD COLUMN page_number INTEGER;
+
+CREATE INDEX idx_documents_user_id ON documents(user_id);
+CREATE INDEX idx_scans_document_id_page_number ON scans(document_id, page_number);
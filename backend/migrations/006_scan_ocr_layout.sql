@@ -0,0 +1,5 @@
+-- Migration 006: OCR layout
+-- Text blocks and lines with normalized bounding boxes, in reading order, for overlaying
+-- highlights on the original photo. NULL for scans processed before layout was captured.
+
+ALTER TABLE scans ADD COLUMN ocr_layout JSONB;
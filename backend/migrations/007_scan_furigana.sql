@@ -0,0 +1,10 @@
+-- Migration 007: Furigana cache
+-- Model-generated readings for the OCR text of a scan. text_hash identifies the OCR text the
+-- tokens were generated for, so re-running OCR invalidates the entry.
+
+CREATE TABLE scan_furigana (
+    scan_id BIGINT PRIMARY KEY REFERENCES scans(id) ON DELETE CASCADE,
+    text_hash CHAR(64) NOT NULL,
+    tokens JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,13 @@
+-- Migration 008: Annotation cache
+-- Durable tier of the AI annotation cache. Redis holds the hot copy; this table survives Redis
+-- restarts and is shared by every replica. cache_key is a sha256 over the selected text, the
+-- normalized context, knowledge entry IDs, target language and prompt version.
+
+CREATE TABLE annotation_cache (
+    cache_key CHAR(64) PRIMARY KEY,
+    response JSONB NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_annotation_cache_expires_at ON annotation_cache(expires_at);
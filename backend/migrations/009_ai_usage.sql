@@ -0,0 +1,11 @@
+-- Migration 009: Daily AI usage
+-- One row per user, UTC day and route class (ocr, analyze, speech), counting the AI calls the
+-- user made that day. Backs the daily quota and GET /v1/users/me/usage.
+
+CREATE TABLE ai_usage (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    class VARCHAR(32) NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day, class)
+);
@@ -0,0 +1,14 @@
+-- Migration 010: Audio assets
+-- Synthesized speech stored through FileStorage. hash is a sha256 over the spoken text, the
+-- trimmed context, voice and tone, so repeated plays are served from disk instead of the TTS
+-- model. Annotations keep a link to their clip so the audio survives changes to the key.
+
+CREATE TABLE audio_assets (
+    hash CHAR(64) PRIMARY KEY,
+    path TEXT NOT NULL,
+    mime_type VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE annotations ADD COLUMN audio_hash CHAR(64) REFERENCES audio_assets(hash) ON DELETE SET NULL;
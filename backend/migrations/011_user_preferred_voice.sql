@@ -0,0 +1,4 @@
+-- Migration 011: Preferred voice
+-- Default TTS voice for a user. NULL means the AI provider's default voice.
+
+ALTER TABLE users ADD COLUMN preferred_voice VARCHAR(64);
@@ -0,0 +1,22 @@
+-- Migration 012: Annotation edits
+-- Users can correct an annotation's explanation, keep notes and tag it. Each edit archives
+-- the annotation as it was, so the oldest edit holds the original AI output.
+
+ALTER TABLE annotations ADD COLUMN notes TEXT;
+ALTER TABLE annotations ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE annotations ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE;
+UPDATE annotations SET updated_at = created_at;
+ALTER TABLE annotations ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE annotations ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE annotation_edits (
+    id BIGSERIAL PRIMARY KEY,
+    annotation_id BIGINT NOT NULL REFERENCES annotations(id) ON DELETE CASCADE,
+    nuance_data JSONB NOT NULL,
+    notes TEXT,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    is_bookmarked BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_annotation_edits_annotation_id ON annotation_edits(annotation_id);
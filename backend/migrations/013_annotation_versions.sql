@@ -0,0 +1,23 @@
+-- Migration 013: Annotation versions
+-- Every explanation the AI has written for an annotation. current_version is the one the
+-- annotation's nuance_data is based on; the user may have edited it since.
+
+CREATE TABLE annotation_versions (
+    id BIGSERIAL PRIMARY KEY,
+    annotation_id BIGINT NOT NULL REFERENCES annotations(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    nuance_data JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (annotation_id, version)
+);
+
+ALTER TABLE annotations ADD COLUMN current_version INT NOT NULL DEFAULT 1;
+
+-- Version 1 is the explanation the annotation was saved with, which the oldest edit holds if
+-- the user has edited it since.
+INSERT INTO annotation_versions (annotation_id, version, nuance_data, created_at)
+SELECT a.id, 1, COALESCE(
+    (SELECT e.nuance_data FROM annotation_edits e WHERE e.annotation_id = a.id ORDER BY e.created_at, e.id LIMIT 1),
+    a.nuance_data
+), a.created_at
+FROM annotations a;
@@ -0,0 +1,30 @@
+-- Migration 014: Spaced-repetition reviews
+-- One review card per bookmarked annotation, scheduled with SM-2, and a log of every answer.
+-- Cards are created the first time the user asks for due reviews after bookmarking; a card
+-- whose annotation is no longer bookmarked is skipped but keeps its schedule.
+
+CREATE TABLE review_cards (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    annotation_id BIGINT NOT NULL UNIQUE REFERENCES annotations(id) ON DELETE CASCADE,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_cards_user_due ON review_cards(user_id, due_at);
+
+CREATE TABLE review_logs (
+    id BIGSERIAL PRIMARY KEY,
+    card_id BIGINT NOT NULL REFERENCES review_cards(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    grade VARCHAR(8) NOT NULL,
+    interval_days INTEGER NOT NULL,
+    ease_factor DOUBLE PRECISION NOT NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_review_logs_user_reviewed_at ON review_logs(user_id, reviewed_at);
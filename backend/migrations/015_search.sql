@@ -0,0 +1,26 @@
+-- Migration 015: Full-text search
+-- Japanese has no spaces between words, so word-based tsvector search does not apply. Search is
+-- a substring match backed by pg_trgm trigram indexes, which work on any script as long as the
+-- database uses a UTF-8 locale other than C. Queries shorter than three characters, such as
+-- most two-kanji words, cannot use the trigram index and fall back to a scan of the user's rows.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- annotation_nuance_text joins the explanation fields of nuance_data so they can be indexed and
+-- matched without also matching the JSON keys. Search queries must use this exact expression
+-- for the index to apply.
+CREATE FUNCTION annotation_nuance_text(nuance JSONB) RETURNS TEXT
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT concat_ws(E'\n',
+        nuance->>'meaning',
+        nuance->>'usageExample',
+        nuance->>'usageTiming',
+        nuance->>'wordBreakdown',
+        nuance->>'alternativeMeaning')
+$$;
+
+CREATE INDEX idx_scans_full_ocr_text_trgm ON scans USING GIN (full_ocr_text gin_trgm_ops);
+CREATE INDEX idx_annotations_highlighted_text_trgm ON annotations USING GIN (highlighted_text gin_trgm_ops);
+CREATE INDEX idx_annotations_context_text_trgm ON annotations USING GIN (context_text gin_trgm_ops);
+CREATE INDEX idx_annotations_nuance_text_trgm ON annotations USING GIN (annotation_nuance_text(nuance_data) gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- Migration 016: Keyset pagination indexes
+-- Scan and annotation lists are paged by (created_at, id) within a user, in either direction.
+
+CREATE INDEX idx_scans_user_created_at_id ON scans(user_id, created_at, id);
+CREATE INDEX idx_annotations_user_created_at_id ON annotations(user_id, created_at, id);
@@ -0,0 +1,18 @@
+-- Migration 017: Knowledge base in Postgres
+-- Replaces the static knowledge CSV so entries can be corrected without a redeploy. The
+-- server seeds the table from KNOWLEDGE_CSV_PATH while it is empty; later imports upsert by
+-- kosakata.
+
+CREATE TABLE knowledge_entries (
+    id BIGSERIAL PRIMARY KEY,
+    kosakata TEXT NOT NULL UNIQUE,
+    kana TEXT NOT NULL DEFAULT '',
+    arti TEXT NOT NULL DEFAULT '',
+    cara_baca TEXT NOT NULL DEFAULT '',
+    deskripsi TEXT NOT NULL DEFAULT '',
+    bidang_pekerjaan TEXT[] NOT NULL DEFAULT '{}',
+    industri TEXT[] NOT NULL DEFAULT '{}',
+    konteks TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,24 @@
+-- Migration 018: Knowledge base version counter
+-- Replicas poll this counter to notice knowledge base edits made through other replicas.
+-- Every statement that changes knowledge_entries bumps it in the same transaction, so it
+-- only ever grows and, unlike timestamps, does not depend on any clock.
+
+CREATE TABLE knowledge_version (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO knowledge_version DEFAULT VALUES;
+
+CREATE FUNCTION bump_knowledge_version() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE knowledge_version SET version = version + 1;
+    RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER knowledge_entries_version
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON knowledge_entries
+FOR EACH STATEMENT EXECUTE FUNCTION bump_knowledge_version();
@@ -0,0 +1,10 @@
+-- Migration 019: Review cards on bookmark
+-- Cards are now created with the annotation or when it is bookmarked, instead of the first
+-- time the user asks for due reviews. Give annotations bookmarked before this change their
+-- card, due at once.
+
+INSERT INTO review_cards (user_id, annotation_id, due_at, created_at)
+SELECT user_id, id, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM annotations
+WHERE is_bookmarked
+ON CONFLICT (annotation_id) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Migration 020: Audio asset variants
+-- Clips re-encoded for clients that ask for another format, such as IMA-ADPCM, are stored once
+-- next to the original so later requests, including Range requests, are served from disk.
+
+CREATE TABLE audio_asset_variants (
+    hash CHAR(64) NOT NULL REFERENCES audio_assets(hash) ON DELETE CASCADE,
+    format VARCHAR(32) NOT NULL,
+    path TEXT NOT NULL,
+    mime_type VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (hash, format)
+);
@@ -0,0 +1,5 @@
+-- Migration 021: OCR re-run claims
+-- A synchronous OCR re-run claims its scan until it finishes. ocr_claimed_at lets a claim
+-- left behind by a process that died mid-run expire instead of blocking re-runs forever.
+
+ALTER TABLE scans ADD COLUMN ocr_claimed_at TIMESTAMP WITH TIME ZONE;